@@ -0,0 +1,169 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+)
+
+/*
+* accountHistoryRequest - type for extracting the filters of the account history
+* Cursor: optional - the next_cursor of the previous page, the newest rows when omitted
+* FromTime/ToTime: optional - RFC3339 time range [from_time, to_time)
+* MinAmount/MaxAmount: optional - absolute amount range (in the account currency)
+* Direction: optional - credit (money in) or debit (money out)
+ */
+type accountHistoryRequest struct {
+	Cursor    *int64     `form:"cursor" binding:"omitempty,min=1"`
+	PageSize  int32      `form:"page_size" binding:"required,min=5,max=100"`
+	FromTime  *time.Time `form:"from_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	ToTime    *time.Time `form:"to_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	MinAmount *int64     `form:"min_amount" binding:"omitempty,min=0"`
+	MaxAmount *int64     `form:"max_amount" binding:"omitempty,min=0"`
+	Direction *string    `form:"direction" binding:"omitempty,oneof=credit debit"`
+}
+
+// listAccountEntriesResponse - a page of entries (newest first), NextCursor is omitted on the last page
+type listAccountEntriesResponse struct {
+	Entries    []db.ListAccountEntriesRow `json:"entries"`
+	NextCursor int64                      `json:"next_cursor,omitempty"`
+}
+
+// listAccountEntries - API endpoint for listing the entries of an account with their running balance
+func (server *Server) listAccountEntries(ctx *gin.Context) {
+	account, req, valid := server.accountHistoryRequest(ctx)
+	if !valid {
+		return
+	}
+
+	entries, err := server.store.ListAccountEntries(ctx, db.ListAccountEntriesParams{
+		AccountID: account.ID,
+		Cursor:    nullInt64(req.Cursor),
+		FromTime:  nullTime(req.FromTime),
+		ToTime:    nullTime(req.ToTime),
+		MinAmount: nullInt64(req.MinAmount),
+		MaxAmount: nullInt64(req.MaxAmount),
+		Direction: nullString(req.Direction),
+		PageSize:  req.PageSize,
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	rsp := listAccountEntriesResponse{Entries: entries}
+	// a full page may be followed by more entries
+	if len(entries) == int(req.PageSize) {
+		rsp.NextCursor = entries[len(entries)-1].ID
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// listAccountTransfersResponse - a page of transfers (newest first), NextCursor is omitted on the last page
+type listAccountTransfersResponse struct {
+	Transfers  []db.Transfer `json:"transfers"`
+	NextCursor int64         `json:"next_cursor,omitempty"`
+}
+
+// listAccountTransfers - API endpoint for listing the incoming and outgoing transfers of an account
+func (server *Server) listAccountTransfers(ctx *gin.Context) {
+	account, req, valid := server.accountHistoryRequest(ctx)
+	if !valid {
+		return
+	}
+
+	transfers, err := server.store.ListAccountTransfers(ctx, db.ListAccountTransfersParams{
+		AccountID: account.ID,
+		Cursor:    nullInt64(req.Cursor),
+		FromTime:  nullTime(req.FromTime),
+		ToTime:    nullTime(req.ToTime),
+		MinAmount: nullInt64(req.MinAmount),
+		MaxAmount: nullInt64(req.MaxAmount),
+		Direction: nullString(req.Direction),
+		PageSize:  req.PageSize,
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	rsp := listAccountTransfersResponse{Transfers: transfers}
+	// a full page may be followed by more transfers
+	if len(transfers) == int(req.PageSize) {
+		rsp.NextCursor = transfers[len(transfers)-1].ID
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// accountHistoryRequest - extracting the account + the history filters, on error writing the error response
+func (server *Server) accountHistoryRequest(ctx *gin.Context) (db.Account, accountHistoryRequest, bool) {
+	var uri getAccountRequest
+	var req accountHistoryRequest
+	// extracting the account id + the filters - if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return db.Account{}, req, false
+	}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return db.Account{}, req, false
+	}
+
+	account, valid := server.readableAccount(ctx, uri.ID)
+	return account, req, valid
+}
+
+/*
+* readableAccount - getting the account by the given id, on error (or when the user can't read it) writing the error response
+* the account is readable by its owner and by the users allowed to read any account (bankers and admins)
+ */
+func (server *Server) readableAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return db.Account{}, false
+	}
+
+	account, valid := server.existingAccount(ctx, accountID)
+	if !valid {
+		return account, false
+	}
+
+	// checking if the authenticated user has the authorization to get the requested account
+	if account.Owner != authPayload.Username && !authPayload.HasScope(token.ScopeAccountsReadAll) {
+		writeError(ctx, errAccountAccessDenied)
+		return account, false
+	}
+	return account, true
+}
+
+// nullInt64 - converting an optional request field to a nullable query param
+func nullInt64(value *int64) sql.NullInt64 {
+	if value == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *value, Valid: true}
+}
+
+// nullTime - converting an optional request field to a nullable query param
+func nullTime(value *time.Time) sql.NullTime {
+	if value == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *value, Valid: true}
+}
+
+// nullString - converting an optional request field to a nullable query param
+func nullString(value *string) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *value, Valid: true}
+}
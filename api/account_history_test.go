@@ -0,0 +1,223 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAccountEntriesAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account := randomAccount(user1.Username)
+
+	pageSize := 5
+	entries := make([]db.ListAccountEntriesRow, pageSize)
+	for i := range entries {
+		entries[i] = db.ListAccountEntriesRow{
+			ID:             int64(100 - i),
+			AccountID:      account.ID,
+			Amount:         10,
+			RunningBalance: int64(10 * (pageSize - i)),
+		}
+	}
+
+	fromTime := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:  "FirstPage",
+		query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			arg := db.ListAccountEntriesParams{
+				AccountID: account.ID,
+				PageSize:  int32(pageSize),
+			}
+			store.EXPECT().
+				ListAccountEntries(gomock.Any(), gomock.Eq(arg)).
+				Times(1).
+				Return(entries, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp listAccountEntriesResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Equal(t, entries, rsp.Entries)
+			// a full page points to the next page
+			require.Equal(t, entries[pageSize-1].ID, rsp.NextCursor)
+		},
+	}, {
+		name: "Filters",
+		query: url.Values{
+			"page_size":  {fmt.Sprint(pageSize)},
+			"cursor":     {"96"},
+			"from_time":  {fromTime.Format(time.RFC3339)},
+			"min_amount": {"5"},
+			"max_amount": {"50"},
+			"direction":  {"debit"},
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				ListAccountEntries(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.ListAccountEntriesParams) ([]db.ListAccountEntriesRow, error) {
+					require.Equal(t, sql.NullInt64{Int64: 96, Valid: true}, arg.Cursor)
+					require.True(t, arg.FromTime.Valid)
+					require.True(t, fromTime.Equal(arg.FromTime.Time))
+					require.False(t, arg.ToTime.Valid)
+					require.Equal(t, sql.NullInt64{Int64: 5, Valid: true}, arg.MinAmount)
+					require.Equal(t, sql.NullInt64{Int64: 50, Valid: true}, arg.MaxAmount)
+					require.Equal(t, sql.NullString{String: "debit", Valid: true}, arg.Direction)
+					return entries[:2], nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp listAccountEntriesResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Len(t, rsp.Entries, 2)
+			// the last page has no cursor
+			require.Zero(t, rsp.NextCursor)
+		},
+	}, {
+		name: "InvalidDirection",
+		query: url.Values{
+			"page_size": {fmt.Sprint(pageSize)},
+			"direction": {"sideways"},
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				ListAccountEntries(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name:  "UnauthorizedUser",
+		query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				ListAccountEntries(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/accounts/%d/entries?%s", account.ID, tc.query.Encode())
+
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.token)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAccountTransfersAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	transfers := []db.Transfer{
+		{ID: 2, FromAccountID: account.ID, Amount: 10, ToAmount: 10},
+		{ID: 1, ToAccountID: account.ID, Amount: 20, ToAmount: 20},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+
+	arg := db.ListAccountTransfersParams{
+		AccountID: account.ID,
+		Direction: sql.NullString{String: "credit", Valid: true},
+		PageSize:  5,
+	}
+	store.EXPECT().
+		ListAccountTransfers(gomock.Any(), gomock.Eq(arg)).
+		Times(1).
+		Return(transfers, nil)
+
+	server := NewTestServer(t, store)
+	recorder := httptest.NewRecorder()
+	url := fmt.Sprintf("/accounts/%d/transfers?page_size=5&direction=credit", account.ID)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, req, server.token, authorizationTypeBearer, user.Username, time.Minute)
+	server.Router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp listAccountTransfersResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Len(t, rsp.Transfers, 2)
+	require.Zero(t, rsp.NextCursor)
+}
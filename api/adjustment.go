@@ -0,0 +1,67 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+)
+
+/*
+* adjustBalanceRequest - type for posting a manual adjustment to an account
+* Amount: added to the balance - negative for debiting the account, can't be zero
+* Currency: must match the account currency
+* Reason: why the balance is adjusted - recorded with the adjustment
+ */
+type adjustBalanceRequest struct {
+	Amount   int64  `json:"amount" binding:"required"`
+	Currency string `json:"currency" binding:"required,currency"`
+	Reason   string `json:"reason" binding:"required,max=500"`
+}
+
+// adjustAccountBalance - API endpoint for posting a manual adjustment entry to any account (admins only)
+func (server *Server) adjustAccountBalance(ctx *gin.Context) {
+	var uri getAccountRequest
+	// extracting the account id - if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	var req adjustBalanceRequest
+	// if one of the required fields is missed - then return code 400(BadRequest)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// getting the admin through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// validating the account id + currency
+	if _, valid := server.validAccount(ctx, uri.ID, req.Currency); !valid {
+		return
+	}
+
+	// adjusting the balance and checking for errors
+	// if the account can't cover the debit return code 422(UnprocessableEntity)
+	result, err := server.store.AdjustBalanceTx(ctx, db.AdjustBalanceTxParams{
+		AccountID: uri.ID,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+		Audit:     auditParams(ctx, authPayload.Username),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
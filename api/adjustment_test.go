@@ -0,0 +1,341 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustAccountBalanceAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	account := randomAccount(user.Username)
+	account.Currency = "ILS"
+
+	amount := int64(-25)
+	reason := "refunding a duplicated card fee"
+	entry := db.Entry{
+		ID:        util.RandomInt(1, 1000),
+		AccountID: account.ID,
+		Amount:    amount,
+	}
+	adjusted := account
+	adjusted.Balance += amount
+	result := db.AdjustBalanceTxResult{
+		Adjustment: db.BalanceAdjustment{
+			ID:        util.RandomInt(1, 1000),
+			AccountID: account.ID,
+			EntryID:   entry.ID,
+			Amount:    amount,
+			Reason:    reason,
+			CreatedBy: admin.Username,
+		},
+		Account: adjusted,
+		Entry:   entry,
+	}
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:      "OK",
+		accountID: account.ID,
+		body: gin.H{
+			"amount":   amount,
+			"currency": account.Currency,
+			"reason":   reason,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				AdjustBalanceTx(gomock.Any(), gomock.Eq(db.AdjustBalanceTxParams{
+					AccountID: account.ID,
+					Amount:    amount,
+					Reason:    reason,
+					Audit: db.AuditParams{
+						Actor:     admin.Username,
+						RequestID: testRequestID,
+					},
+				})).
+				Times(1).
+				Return(result, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp db.AdjustBalanceTxResult
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Equal(t, result.Adjustment.ID, rsp.Adjustment.ID)
+			require.Equal(t, reason, rsp.Adjustment.Reason)
+			require.Equal(t, adjusted.Balance, rsp.Account.Balance)
+		},
+	}, {
+		name:      "CustomerForbidden",
+		accountID: account.ID,
+		body: gin.H{
+			"amount":   amount,
+			"currency": account.Currency,
+			"reason":   reason,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				AdjustBalanceTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+		},
+	}, {
+		name:      "BankerForbidden",
+		accountID: account.ID,
+		body: gin.H{
+			"amount":   amount,
+			"currency": account.Currency,
+			"reason":   reason,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, "banker", util.BankerRole, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				AdjustBalanceTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+		},
+	}, {
+		name:      "NoAuthorization",
+		accountID: account.ID,
+		body: gin.H{
+			"amount":   amount,
+			"currency": account.Currency,
+			"reason":   reason,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				AdjustBalanceTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name:      "ZeroAmount",
+		accountID: account.ID,
+		body: gin.H{
+			"amount":   0,
+			"currency": account.Currency,
+			"reason":   reason,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				AdjustBalanceTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name:      "MissingReason",
+		accountID: account.ID,
+		body: gin.H{
+			"amount":   amount,
+			"currency": account.Currency,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				AdjustBalanceTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name:      "AccountNotFound",
+		accountID: account.ID,
+		body: gin.H{
+			"amount":   amount,
+			"currency": account.Currency,
+			"reason":   reason,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(db.Account{}, db.ErrNotFound)
+
+			store.EXPECT().
+				AdjustBalanceTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+		},
+	}, {
+		name:      "CurrencyMismatch",
+		accountID: account.ID,
+		body: gin.H{
+			"amount":   amount,
+			"currency": "USD",
+			"reason":   reason,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				AdjustBalanceTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name:      "InsufficientFunds",
+		accountID: account.ID,
+		body: gin.H{
+			"amount":   amount,
+			"currency": account.Currency,
+			"reason":   reason,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				AdjustBalanceTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.AdjustBalanceTxResult{}, fmt.Errorf("transaction error: %w", db.ErrInsufficientFunds))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}, {
+		name:      "FrozenAccount",
+		accountID: account.ID,
+		body: gin.H{
+			"amount":   amount,
+			"currency": account.Currency,
+			"reason":   reason,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				AdjustBalanceTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.AdjustBalanceTxResult{}, fmt.Errorf("transaction error: %w: account %d is frozen", db.ErrAccountInactive, account.ID))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+		},
+	}, {
+		name:      "InternalError",
+		accountID: account.ID,
+		body: gin.H{
+			"amount":   amount,
+			"currency": account.Currency,
+			"reason":   reason,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				AdjustBalanceTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.AdjustBalanceTxResult{}, sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/admin/accounts/%d/adjustments", tc.accountID)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			req.Header.Set(requestIDHeader, testRequestID)
+
+			tc.setupAuth(t, req, server.token)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+)
+
+// likeEscaper - escaping the LIKE wildcards of the search query, so they are matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+/*
+* searchUsersRequest - type for searching the users (admins only)
+* Query: matched against the username, the full name and the email (case insensitive)
+ */
+type searchUsersRequest struct {
+	Query    string `form:"query" binding:"required,max=100"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// searchUsers - API endpoint for searching the users by their username, full name or email
+func (server *Server) searchUsers(ctx *gin.Context) {
+	var req searchUsersRequest
+	// validating the request params - on error: status 400(BadRequest)
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	authPayload, ok := adminPayload(ctx)
+	if !ok {
+		return
+	}
+
+	users, err := server.store.SearchUsers(ctx, db.SearchUsersParams{
+		Query:      likeEscaper.Replace(req.Query),
+		PageSize:   req.PageSize,
+		PageOffset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// recording the search - the response exposes the personal details of the users
+	err = server.store.RecordAuditEvent(ctx, db.RecordAuditEventParams{
+		AuditParams: auditParams(ctx, authPayload.Username),
+		Action:      db.AuditUserSearch,
+		Target:      "users",
+		After:       gin.H{"query": req.Query},
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	rsp := make([]userResponse, 0, len(users))
+	for _, user := range users {
+		rsp = append(rsp, newUserResponse(user))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// unlockUserRequest - the username of the user to unlock
+type unlockUserRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+/*
+* unlockUser - API endpoint for unlocking a user locked out by failed logins (admins only)
+* the failed login attempts of the user are forgotten - the throttles of the client ips are kept
+ */
+func (server *Server) unlockUser(ctx *gin.Context) {
+	var uri unlockUserRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	authPayload, ok := adminPayload(ctx)
+	if !ok {
+		return
+	}
+
+	// a user without failed attempts has nothing to unlock - code 404(NotFound)
+	throttle, err := server.store.UnlockUserTx(ctx, db.UnlockUserTxParams{
+		Username: uri.Username,
+		Audit:    auditParams(ctx, authPayload.Username),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, throttle)
+}
+
+// revokeUserTokensRequest - the username of the user whose tokens are revoked
+type revokeUserTokensRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// revokeUserTokensResponse - every token of the user issued before RevokedBefore is revoked
+type revokeUserTokensResponse struct {
+	Username      string    `json:"username"`
+	RevokedBefore time.Time `json:"revoked_before"`
+}
+
+/*
+* revokeUserTokens - API endpoint for revoking every token issued to the user so far and blocking the user sessions (admins only)
+* e.g. a stolen device - the user must log in again
+ */
+func (server *Server) revokeUserTokens(ctx *gin.Context) {
+	var uri revokeUserTokensRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	authPayload, ok := adminPayload(ctx)
+	if !ok {
+		return
+	}
+
+	// the user must exist - if not found: 404(NotFound) else 500(InternalServerError)
+	if _, err := server.store.GetUser(ctx, uri.Username); err != nil {
+		writeError(ctx, storeError(err, errUserNotFound))
+		return
+	}
+
+	// revoking the tokens + recording the revocation in the audit trail
+	revokedBefore := time.Now()
+	err := server.store.RevokeUserTokensTx(ctx, db.RevokeUserTokensTxParams{
+		Username:      uri.Username,
+		RevokedBefore: revokedBefore,
+		Audit:         auditParams(ctx, authPayload.Username),
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// the cached tokens of the user are checked again on their next use
+	server.revocations.ForgetUser(uri.Username)
+
+	ctx.JSON(http.StatusOK, revokeUserTokensResponse{
+		Username:      uri.Username,
+		RevokedBefore: revokedBefore,
+	})
+}
+
+// adminAccountResponse - any account with a page of its entries (newest first), NextCursor is omitted on the last page
+type adminAccountResponse struct {
+	Account    accountResponse            `json:"account"`
+	Entries    []db.ListAccountEntriesRow `json:"entries"`
+	NextCursor int64                      `json:"next_cursor,omitempty"`
+}
+
+// adminGetAccount - API endpoint for viewing any account with its entries (admins only)
+func (server *Server) adminGetAccount(ctx *gin.Context) {
+	account, req, valid := server.accountHistoryRequest(ctx)
+	if !valid {
+		return
+	}
+
+	authPayload, ok := adminPayload(ctx)
+	if !ok {
+		return
+	}
+
+	// getting the funds reserved by the active holds of the account
+	held, err := server.store.GetAccountHeldAmount(ctx, account.ID)
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	entries, err := server.store.ListAccountEntries(ctx, db.ListAccountEntriesParams{
+		AccountID: account.ID,
+		Cursor:    nullInt64(req.Cursor),
+		FromTime:  nullTime(req.FromTime),
+		ToTime:    nullTime(req.ToTime),
+		MinAmount: nullInt64(req.MinAmount),
+		MaxAmount: nullInt64(req.MaxAmount),
+		Direction: nullString(req.Direction),
+		PageSize:  req.PageSize,
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// recording the view of the account
+	err = server.store.RecordAuditEvent(ctx, db.RecordAuditEventParams{
+		AuditParams: auditParams(ctx, authPayload.Username),
+		Action:      db.AuditAccountView,
+		Target:      db.AccountTarget(account.ID),
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	rsp := adminAccountResponse{
+		Account: newAccountResponse(account, held),
+		Entries: entries,
+	}
+	// a full page may be followed by more entries
+	if len(entries) == int(req.PageSize) {
+		rsp.NextCursor = entries[len(entries)-1].ID
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// freezeAccount - API endpoint for freezing an account - its money can't be moved until it's unfrozen
+func (server *Server) freezeAccount(ctx *gin.Context) {
+	server.updateAccountStatus(ctx, db.AccountFrozen)
+}
+
+// unfreezeAccount - API endpoint for unfreezing a frozen account
+func (server *Server) unfreezeAccount(ctx *gin.Context) {
+	server.updateAccountStatus(ctx, db.AccountActive)
+}
+
+// closeAccount - API endpoint for closing an empty account for good
+func (server *Server) closeAccount(ctx *gin.Context) {
+	server.updateAccountStatus(ctx, db.AccountClosed)
+}
+
+// updateAccountStatus - changing the status of the account of the uri, on error writing the error response
+func (server *Server) updateAccountStatus(ctx *gin.Context, status string) {
+	var uri getAccountRequest
+	// extracting the account id - if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	authPayload, ok := adminPayload(ctx)
+	if !ok {
+		return
+	}
+
+	// changing the status and checking for errors
+	// if the account can't be changed to the status return code 409(Conflict)
+	account, err := server.store.UpdateAccountStatusTx(ctx, db.UpdateAccountStatusTxParams{
+		AccountID: uri.ID,
+		Status:    status,
+		Audit:     auditParams(ctx, authPayload.Username),
+	})
+	if err != nil {
+		writeError(ctx, storeError(err, errAccountNotFound))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, account)
+}
+
+// adminPayload - getting the payload of the admin access token, on error writing the error response
+func adminPayload(ctx *gin.Context) (*token.Payload, bool) {
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return nil, false
+	}
+	return authPayload, true
+}
+
+/*
+* getDBStats - API endpoint for monitoring the connection pool of the DB (admins only)
+* a growing empty acquire count or acquire duration means the requests wait for connections - the pool is too small
+ */
+func (server *Server) getDBStats(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, server.store.PoolStats())
+}
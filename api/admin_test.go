@@ -0,0 +1,616 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchUsersAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:  "OK",
+		query: url.Values{"query": {"100%_sure"}, "page_id": {"2"}, "page_size": {"5"}},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			// the wildcards of the query are matched literally
+			store.EXPECT().
+				SearchUsers(gomock.Any(), gomock.Eq(db.SearchUsersParams{
+					Query:      `100\%\_sure`,
+					PageSize:   5,
+					PageOffset: 5,
+				})).
+				Times(1).
+				Return([]db.User{user}, nil)
+
+			store.EXPECT().
+				RecordAuditEvent(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ any, arg db.RecordAuditEventParams) error {
+					require.Equal(t, admin.Username, arg.Actor)
+					require.Equal(t, db.AuditUserSearch, arg.Action)
+					return nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp []userResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Len(t, rsp, 1)
+			require.Equal(t, user.Username, rsp[0].Username)
+			require.NotContains(t, recorded.Body.String(), user.HashedPassword)
+		},
+	}, {
+		name:  "BankerForbidden",
+		query: url.Values{"query": {"bob"}, "page_id": {"1"}, "page_size": {"5"}},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, "banker", util.BankerRole, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				SearchUsers(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+		},
+	}, {
+		name:  "MissingQuery",
+		query: url.Values{"page_id": {"1"}, "page_size": {"5"}},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				SearchUsers(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name:  "AuditError",
+		query: url.Values{"query": {"bob"}, "page_id": {"1"}, "page_size": {"5"}},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				SearchUsers(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return([]db.User{user}, nil)
+
+			store.EXPECT().
+				RecordAuditEvent(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/users?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.token)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAdminGetAccountAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	entries := []db.ListAccountEntriesRow{{
+		ID:        util.RandomInt(1, 1000),
+		AccountID: account.ID,
+		Amount:    util.RandomMoney(),
+	}}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+	store.EXPECT().
+		GetAccountHeldAmount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(int64(0), nil)
+	store.EXPECT().
+		ListAccountEntries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(entries, nil)
+	store.EXPECT().
+		RecordAuditEvent(gomock.Any(), gomock.Eq(db.RecordAuditEventParams{
+			AuditParams: db.AuditParams{
+				Actor:     admin.Username,
+				RequestID: testRequestID,
+			},
+			Action: db.AuditAccountView,
+			Target: db.AccountTarget(account.ID),
+		})).
+		Times(1).
+		Return(nil)
+
+	server := NewTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	path := fmt.Sprintf("/admin/accounts/%d?page_size=5", account.ID)
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	require.NoError(t, err)
+	req.Header.Set(requestIDHeader, testRequestID)
+
+	addRoleAuthorization(t, req, server.token, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+	server.Router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp adminAccountResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, account, rsp.Account.Account)
+	require.Equal(t, entries[0].ID, rsp.Entries[0].ID)
+	require.Zero(t, rsp.NextCursor)
+}
+
+func TestUpdateAccountStatusAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		action        string
+		status        string
+		role          string
+		buildStubs    func(store *mockdb.MockStore, status string)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:   "Freeze",
+		action: "freeze",
+		status: db.AccountFrozen,
+		role:   util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore, status string) {
+			frozen := account
+			frozen.Status = status
+			store.EXPECT().
+				UpdateAccountStatusTx(gomock.Any(), gomock.Eq(db.UpdateAccountStatusTxParams{
+					AccountID: account.ID,
+					Status:    status,
+					Audit: db.AuditParams{
+						Actor:     admin.Username,
+						RequestID: testRequestID,
+					},
+				})).
+				Times(1).
+				Return(frozen, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp db.Account
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Equal(t, db.AccountFrozen, rsp.Status)
+		},
+	}, {
+		name:   "Unfreeze",
+		action: "unfreeze",
+		status: db.AccountActive,
+		role:   util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore, status string) {
+			store.EXPECT().
+				UpdateAccountStatusTx(gomock.Any(), gomock.Eq(db.UpdateAccountStatusTxParams{
+					AccountID: account.ID,
+					Status:    status,
+					Audit: db.AuditParams{
+						Actor:     admin.Username,
+						RequestID: testRequestID,
+					},
+				})).
+				Times(1).
+				Return(account, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name:   "CloseNotEmpty",
+		action: "close",
+		status: db.AccountClosed,
+		role:   util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore, status string) {
+			store.EXPECT().
+				UpdateAccountStatusTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.Account{}, fmt.Errorf("transaction error: %w", db.ErrAccountNotEmpty))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusConflict, recorded.Code)
+		},
+	}, {
+		name:   "ClosedAccount",
+		action: "unfreeze",
+		status: db.AccountActive,
+		role:   util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore, status string) {
+			store.EXPECT().
+				UpdateAccountStatusTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.Account{}, fmt.Errorf("transaction error: %w: account %d is closed", db.ErrAccountStatusChange, account.ID))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusConflict, recorded.Code)
+		},
+	}, {
+		name:   "AccountNotFound",
+		action: "freeze",
+		status: db.AccountFrozen,
+		role:   util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore, status string) {
+			store.EXPECT().
+				UpdateAccountStatusTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.Account{}, fmt.Errorf("transaction error: %w", db.ErrNotFound))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+		},
+	}, {
+		name:   "BankerForbidden",
+		action: "freeze",
+		status: db.AccountFrozen,
+		role:   util.BankerRole,
+		buildStubs: func(store *mockdb.MockStore, status string) {
+			store.EXPECT().
+				UpdateAccountStatusTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, tc.status)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			path := fmt.Sprintf("/admin/accounts/%d/%s", account.ID, tc.action)
+			req, err := http.NewRequest(http.MethodPost, path, nil)
+			require.NoError(t, err)
+			req.Header.Set(requestIDHeader, testRequestID)
+
+			addRoleAuthorization(t, req, server.token, authorizationTypeBearer, admin.Username, tc.role, time.Minute)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUnlockUserAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		role: util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				UnlockUserTx(gomock.Any(), gomock.Eq(db.UnlockUserTxParams{
+					Username: user.Username,
+					Audit: db.AuditParams{
+						Actor:     admin.Username,
+						RequestID: testRequestID,
+					},
+				})).
+				Times(1).
+				Return(db.LoginThrottle{
+					Key:            db.UserThrottleKey(user.Username),
+					FailedAttempts: 10,
+					LockedUntil:    time.Now().Add(time.Minute),
+				}, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp db.LoginThrottle
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Equal(t, int32(10), rsp.FailedAttempts)
+		},
+	}, {
+		name: "NotLocked",
+		role: util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				UnlockUserTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.LoginThrottle{}, fmt.Errorf("transaction error: %w", db.ErrLoginNotThrottled))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+		},
+	}, {
+		name: "InternalError",
+		role: util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				UnlockUserTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.LoginThrottle{}, sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}, {
+		name: "Banker",
+		role: util.BankerRole,
+		buildStubs: func(store *mockdb.MockStore) {
+			// only the admins can use the back-office
+			store.EXPECT().
+				UnlockUserTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			path := fmt.Sprintf("/admin/users/%s/unlock", user.Username)
+			req, err := http.NewRequest(http.MethodPost, path, nil)
+			require.NoError(t, err)
+			req.Header.Set(requestIDHeader, testRequestID)
+
+			addRoleAuthorization(t, req, server.token, authorizationTypeBearer, admin.Username, tc.role, time.Minute)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRevokeUserTokensAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		role: util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(user, nil)
+			store.EXPECT().
+				RevokeUserTokensTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.RevokeUserTokensTxParams) error {
+					require.Equal(t, user.Username, arg.Username)
+					require.WithinDuration(t, time.Now(), arg.RevokedBefore, time.Second)
+					// the revocation is recorded in the audit trail
+					require.Equal(t, db.AuditParams{Actor: admin.Username, RequestID: testRequestID}, arg.Audit)
+					return nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp revokeUserTokensResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Equal(t, user.Username, rsp.Username)
+			require.WithinDuration(t, time.Now(), rsp.RevokedBefore, time.Second)
+		},
+	}, {
+		name: "UserNotFound",
+		role: util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(db.User{}, db.ErrNotFound)
+			store.EXPECT().
+				RevokeUserTokensTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+		},
+	}, {
+		name: "InternalError",
+		role: util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(user, nil)
+			store.EXPECT().
+				RevokeUserTokensTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}, {
+		name: "Banker",
+		role: util.BankerRole,
+		buildStubs: func(store *mockdb.MockStore) {
+			// only the admins can use the back-office
+			store.EXPECT().
+				RevokeUserTokensTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			path := fmt.Sprintf("/admin/users/%s/revoke_tokens", user.Username)
+			req, err := http.NewRequest(http.MethodPost, path, nil)
+			require.NoError(t, err)
+			req.Header.Set(requestIDHeader, testRequestID)
+
+			addRoleAuthorization(t, req, server.token, authorizationTypeBearer, admin.Username, tc.role, time.Minute)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetDBStatsAPI(t *testing.T) {
+	stats := db.PoolStats{
+		TotalConns:        4,
+		AcquiredConns:     1,
+		IdleConns:         3,
+		MaxConns:          10,
+		AcquireCount:      100,
+		EmptyAcquireCount: 2,
+	}
+
+	testCases := []struct {
+		name          string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		role: util.AdminRole,
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				PoolStats().
+				Times(1).
+				Return(stats)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp db.PoolStats
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Equal(t, stats, rsp)
+		},
+	}, {
+		name: "Banker",
+		role: util.BankerRole,
+		buildStubs: func(store *mockdb.MockStore) {
+			// only the admins can use the back-office
+			store.EXPECT().
+				PoolStats().
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/db/stats", nil)
+			require.NoError(t, err)
+
+			addRoleAuthorization(t, req, server.token, authorizationTypeBearer, "admin", tc.role, time.Minute)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
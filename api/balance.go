@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+)
+
+// maxBalanceHistoryPeriods - the maximum number of periods returned by a single balance history request
+const maxBalanceHistoryPeriods = 366
+
+/*
+* getAccountBalanceRequest - type for extracting the point-in-time balance params
+* AsOf: optional - RFC3339 time, the balance includes the entries created before it (now when omitted)
+ */
+type getAccountBalanceRequest struct {
+	AsOf *time.Time `form:"as_of" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// accountBalanceResponse - the balance of the account at the given time
+type accountBalanceResponse struct {
+	AccountID int64     `json:"account_id"`
+	Currency  string    `json:"currency"`
+	Balance   int64     `json:"balance"`
+	AsOf      time.Time `json:"as_of"`
+}
+
+// getAccountBalance - API endpoint for getting the balance of an account at a point in time
+func (server *Server) getAccountBalance(ctx *gin.Context) {
+	var uri getAccountRequest
+	var req getAccountBalanceRequest
+	// extracting the account id + the time - if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	account, valid := server.readableAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	asOf := time.Now()
+	if req.AsOf != nil {
+		asOf = *req.AsOf
+	}
+
+	// reconstructing the balance from the account entries
+	balance, err := server.store.GetAccountBalanceAt(ctx, account.ID, asOf)
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accountBalanceResponse{
+		AccountID: account.ID,
+		Currency:  account.Currency,
+		Balance:   balance,
+		AsOf:      asOf,
+	})
+}
+
+/*
+* getBalanceHistoryRequest - type for extracting the balance history params
+* Interval: the length of a period - day, week or month (UTC aligned)
+* From/To: RFC3339 time range - every period containing a time of the range is returned
+ */
+type getBalanceHistoryRequest struct {
+	Interval string    `form:"interval" binding:"required,oneof=day week month"`
+	From     time.Time `form:"from" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       time.Time `form:"to" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// balanceHistoryResponse - the end-of-period balances of the account (oldest first)
+type balanceHistoryResponse struct {
+	AccountID int64             `json:"account_id"`
+	Currency  string            `json:"currency"`
+	Interval  string            `json:"interval"`
+	Balances  []db.BalancePoint `json:"balances"`
+}
+
+// getBalanceHistory - API endpoint for getting the end-of-period balances of an account
+func (server *Server) getBalanceHistory(ctx *gin.Context) {
+	var uri getAccountRequest
+	var req getBalanceHistoryRequest
+	// extracting the account id + the range - if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// validating the range - if invalid or too long return code 400(BadRequest)
+	if req.To.Before(req.From) {
+		writeError(ctx, invalidRequest("to", "must not be before the from time"))
+		return
+	}
+	if len(db.BalancePeriods(req.Interval, req.From, req.To)) > maxBalanceHistoryPeriods {
+		writeError(ctx, invalidRequest("to", fmt.Sprintf("the range may contain up to %d periods", maxBalanceHistoryPeriods)))
+		return
+	}
+
+	account, valid := server.readableAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	balances, err := server.store.AccountBalanceHistory(ctx, db.AccountBalanceHistoryParams{
+		AccountID: account.ID,
+		Interval:  req.Interval,
+		From:      req.From,
+		To:        req.To,
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, balanceHistoryResponse{
+		AccountID: account.ID,
+		Currency:  account.Currency,
+		Interval:  req.Interval,
+		Balances:  balances,
+	})
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountBalanceAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account := randomAccount(user1.Username)
+
+	asOf := time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:  "AsOf",
+		query: url.Values{"as_of": {asOf.Format(time.RFC3339)}},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				GetAccountBalanceAt(gomock.Any(), gomock.Eq(account.ID), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, _ int64, at time.Time) (int64, error) {
+					require.True(t, asOf.Equal(at))
+					return 42, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp accountBalanceResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Equal(t, account.ID, rsp.AccountID)
+			require.Equal(t, int64(42), rsp.Balance)
+			require.True(t, asOf.Equal(rsp.AsOf))
+		},
+	}, {
+		name:  "Now",
+		query: url.Values{},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				GetAccountBalanceAt(gomock.Any(), gomock.Eq(account.ID), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, _ int64, at time.Time) (int64, error) {
+					require.WithinDuration(t, time.Now(), at, time.Second)
+					return account.Balance, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name:  "InvalidAsOf",
+		query: url.Values{"as_of": {"yesterday"}},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccountBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name:  "UnauthorizedUser",
+		query: url.Values{},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				GetAccountBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name:  "InternalError",
+		query: url.Values{},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				GetAccountBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(int64(0), errors.New("connection refused"))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/accounts/%d/balance?%s", account.ID, tc.query.Encode())
+
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.token)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetBalanceHistoryAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	from := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, time.March, 3, 12, 0, 0, 0, time.UTC)
+	balances := []db.BalancePoint{
+		{PeriodStart: from, PeriodEnd: from.AddDate(0, 0, 1), Balance: 10},
+		{PeriodStart: from.AddDate(0, 0, 1), PeriodEnd: from.AddDate(0, 0, 2), Balance: 10},
+		{PeriodStart: from.AddDate(0, 0, 2), PeriodEnd: from.AddDate(0, 0, 3), Balance: 30},
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		query: url.Values{
+			"interval": {db.BalanceIntervalDay},
+			"from":     {from.Format(time.RFC3339)},
+			"to":       {to.Format(time.RFC3339)},
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				Return(account, nil)
+
+			store.EXPECT().
+				AccountBalanceHistory(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.AccountBalanceHistoryParams) ([]db.BalancePoint, error) {
+					require.Equal(t, account.ID, arg.AccountID)
+					require.Equal(t, db.BalanceIntervalDay, arg.Interval)
+					require.True(t, from.Equal(arg.From))
+					require.True(t, to.Equal(arg.To))
+					return balances, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp balanceHistoryResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Equal(t, db.BalanceIntervalDay, rsp.Interval)
+			require.Len(t, rsp.Balances, len(balances))
+			for i := range balances {
+				require.Equal(t, balances[i].Balance, rsp.Balances[i].Balance)
+				require.True(t, balances[i].PeriodEnd.Equal(rsp.Balances[i].PeriodEnd))
+			}
+		},
+	}, {
+		name: "InvalidInterval",
+		query: url.Values{
+			"interval": {"hour"},
+			"from":     {from.Format(time.RFC3339)},
+			"to":       {to.Format(time.RFC3339)},
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				AccountBalanceHistory(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "ToBeforeFrom",
+		query: url.Values{
+			"interval": {db.BalanceIntervalDay},
+			"from":     {to.Format(time.RFC3339)},
+			"to":       {from.Format(time.RFC3339)},
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				AccountBalanceHistory(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "TooManyPeriods",
+		query: url.Values{
+			"interval": {db.BalanceIntervalDay},
+			"from":     {from.Format(time.RFC3339)},
+			"to":       {from.AddDate(2, 0, 0).Format(time.RFC3339)},
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				AccountBalanceHistory(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/accounts/%d/balance-history?%s", account.ID, tc.query.Encode())
+
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, req, server.token, authorizationTypeBearer, user.Username, time.Minute)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
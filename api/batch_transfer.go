@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+)
+
+// batchTransferLegRequest - a single payment of the batch
+type batchTransferLegRequest struct {
+	ToAccountID int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount      int64  `json:"amount" binding:"required,gt=0"`
+	Reference   string `json:"reference" binding:"max=140"`
+}
+
+/*
+* batchTransferRequest - type for paying many accounts from a single account
+* 'dive': validator input - validating every leg of the batch
+* the to accounts must hold the given currency
+ */
+type batchTransferRequest struct {
+	FromAccountID int64                     `json:"from_account_id" binding:"required,min=1"`
+	Currency      string                    `json:"currency" binding:"required,currency"`
+	Legs          []batchTransferLegRequest `json:"legs" binding:"required,min=1,dive"`
+}
+
+// createBatchTransfer - API endpoint for executing many transfers atomically
+func (server *Server) createBatchTransfer(ctx *gin.Context) {
+	var req batchTransferRequest
+	// if one of the required fields is missed - then return code 400(BadRequest)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// the batch size is limited by the configurations
+	if len(req.Legs) > server.config.BatchTransferMaxLegs {
+		writeError(ctx, invalidRequest("legs", fmt.Sprintf("must have at most %d legs", server.config.BatchTransferMaxLegs)))
+		return
+	}
+
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// validating the from account id + currency
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	// checking if the authenticated account is authorized to make the transfer
+	if fromAccount.Owner != authPayload.Username {
+		writeError(ctx, errTransferAccessDenied)
+		return
+	}
+
+	// a large batch demands a fresh second factor - the total of the legs is checked
+	var total int64
+	for _, leg := range req.Legs {
+		total += leg.Amount
+	}
+	if !server.requireFreshMFA(ctx, authPayload.Username, total) {
+		return
+	}
+
+	arg := db.BatchTransferTxParams{
+		FromAccountID: req.FromAccountID,
+		Legs:          make([]db.BatchTransferLeg, len(req.Legs)),
+	}
+	for i, leg := range req.Legs {
+		arg.Legs[i] = db.BatchTransferLeg{
+			ToAccountID: leg.ToAccountID,
+			Amount:      leg.Amount,
+			Reference:   leg.Reference,
+		}
+	}
+
+	// executing the batch and checking for errors - a failed leg fails the whole batch
+	// if a to account doesn't exist return code 404(NotFound)
+	// if a to account currency mismatch or it's the from account return code 400(BadRequest)
+	// if the from account can't cover the total amount return code 422(UnprocessableEntity)
+	result, err := server.store.BatchTransferTx(ctx, arg)
+	if err != nil {
+		apiErr := storeError(err, errAccountNotFound)
+		// the failed leg is reported as the invalid field of the request
+		var legErr *db.BatchLegError
+		if errors.As(err, &legErr) && apiErr.Status < http.StatusInternalServerError {
+			apiErr.Fields = []FieldViolation{{Field: fmt.Sprintf("legs[%d]", legErr.Index), Message: apiErr.Message}}
+			apiErr.Message = fmt.Sprintf("leg %d: %s", legErr.Index, apiErr.Message)
+		}
+		writeError(ctx, apiErr)
+		return
+	}
+
+	// if all good returning the from account + the result of every leg and status OK
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBatchTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account3 := randomAccount(user2.Username)
+	account1.Currency = "ILS"
+
+	legs := []gin.H{
+		{"to_account_id": account2.ID, "amount": 10, "reference": "salary"},
+		{"to_account_id": account3.ID, "amount": 20, "reference": "bonus"},
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		body: gin.H{
+			"from_account_id": account1.ID,
+			"currency":        "ILS",
+			"legs":            legs,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			arg := db.BatchTransferTxParams{
+				FromAccountID: account1.ID,
+				Legs: []db.BatchTransferLeg{
+					{ToAccountID: account2.ID, Amount: 10, Reference: "salary"},
+					{ToAccountID: account3.ID, Amount: 20, Reference: "bonus"},
+				},
+			}
+			store.EXPECT().
+				BatchTransferTx(gomock.Any(), gomock.Eq(arg)).
+				Times(1).
+				Return(db.BatchTransferTxResult{
+					FromAccount: account1,
+					Legs:        make([]db.BatchTransferLegResult, 2),
+				}, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var result db.BatchTransferTxResult
+			err := json.Unmarshal(recorded.Body.Bytes(), &result)
+			require.NoError(t, err)
+			require.Len(t, result.Legs, 2)
+		},
+	}, {
+		name: "TooManyLegs",
+		body: gin.H{
+			"from_account_id": account1.ID,
+			"currency":        "ILS",
+			"legs":            append(legs, legs...),
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				BatchTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "InvalidLeg",
+		body: gin.H{
+			"from_account_id": account1.ID,
+			"currency":        "ILS",
+			"legs":            []gin.H{{"to_account_id": account2.ID, "amount": -1}},
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				BatchTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "UnauthorizedUser",
+		body: gin.H{
+			"from_account_id": account1.ID,
+			"currency":        "ILS",
+			"legs":            legs,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				BatchTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name: "LegAccountNotFound",
+		body: gin.H{
+			"from_account_id": account1.ID,
+			"currency":        "ILS",
+			"legs":            legs,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				BatchTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.BatchTransferTxResult{}, fmt.Errorf("transaction error: %w", &db.BatchLegError{Index: 1, Err: db.ErrNotFound}))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+			require.Contains(t, recorded.Body.String(), "leg 1")
+		},
+	}, {
+		name: "LegCurrencyMismatch",
+		body: gin.H{
+			"from_account_id": account1.ID,
+			"currency":        "ILS",
+			"legs":            legs,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				BatchTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.BatchTransferTxResult{}, fmt.Errorf("transaction error: %w", &db.BatchLegError{Index: 0, Err: db.ErrCurrencyMismatch}))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "InsufficientFunds",
+		body: gin.H{
+			"from_account_id": account1.ID,
+			"currency":        "ILS",
+			"legs":            legs,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				BatchTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.BatchTransferTxResult{}, fmt.Errorf("transaction error: %w", db.ErrInsufficientFunds))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.token)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,391 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/totp"
+)
+
+// problemContentType - the content type of the error responses (RFC 7807)
+const problemContentType = "application/problem+json"
+
+// the stable codes of the API errors - the clients switch on the code, the message is for humans only
+const (
+	CodeInvalidRequest                = "INVALID_REQUEST"
+	CodeValidationFailed              = "VALIDATION_FAILED"
+	CodeUnauthenticated               = "UNAUTHENTICATED"
+	CodeTokenExpired                  = "TOKEN_EXPIRED"
+	CodeTokenInvalid                  = "TOKEN_INVALID"
+	CodeTokenRevoked                  = "TOKEN_REVOKED"
+	CodeForbidden                     = "FORBIDDEN"
+	CodeNotFound                      = "NOT_FOUND"
+	CodeConflict                      = "CONFLICT"
+	CodeReferenceNotFound             = "REFERENCE_NOT_FOUND"
+	CodeConcurrentUpdate              = "CONCURRENT_UPDATE"
+	CodeInternal                      = "INTERNAL"
+	CodeUserNotFound                  = "USER_NOT_FOUND"
+	CodeUserAlreadyExists             = "USER_ALREADY_EXISTS"
+	CodeIncorrectPassword             = "INCORRECT_PASSWORD"
+	CodeIncorrectCredentials          = "INCORRECT_CREDENTIALS"
+	CodeLoginThrottled                = "LOGIN_THROTTLED"
+	CodeEmailNotVerified              = "EMAIL_NOT_VERIFIED"
+	CodeVerifyEmailUnavailable        = "VERIFY_EMAIL_UNAVAILABLE"
+	CodePasswordResetUnavailable      = "PASSWORD_RESET_UNAVAILABLE"
+	CodePasswordResetThrottled        = "PASSWORD_RESET_THROTTLED"
+	CodeMFARequired                   = "MFA_REQUIRED"
+	CodeMFANotEnabled                 = "MFA_NOT_ENABLED"
+	CodeMFAAlreadyEnabled             = "MFA_ALREADY_ENABLED"
+	CodeMFANotPending                 = "MFA_NOT_PENDING"
+	CodeInvalidMFACode                = "INVALID_MFA_CODE"
+	CodeSessionNotFound               = "SESSION_NOT_FOUND"
+	CodeSessionInvalid                = "SESSION_INVALID"
+	CodeAccountNotFound               = "ACCOUNT_NOT_FOUND"
+	CodeAccountAlreadyExists          = "ACCOUNT_ALREADY_EXISTS"
+	CodeAccountAccessDenied           = "ACCOUNT_ACCESS_DENIED"
+	CodeAccountInactive               = "ACCOUNT_INACTIVE"
+	CodeAccountStatusConflict         = "ACCOUNT_STATUS_CONFLICT"
+	CodeAccountNotEmpty               = "ACCOUNT_NOT_EMPTY"
+	CodeCurrencyMismatch              = "CURRENCY_MISMATCH"
+	CodeInsufficientFunds             = "INSUFFICIENT_FUNDS"
+	CodeSameAccount                   = "SAME_ACCOUNT"
+	CodeIdempotencyKeyMismatch        = "IDEMPOTENCY_KEY_MISMATCH"
+	CodeTransferAccessDenied          = "TRANSFER_ACCESS_DENIED"
+	CodeTransferNotFound              = "TRANSFER_NOT_FOUND"
+	CodeTransferAlreadyReversed       = "TRANSFER_ALREADY_REVERSED"
+	CodeTransferNotReversible         = "TRANSFER_NOT_REVERSIBLE"
+	CodeInvalidReversalAmount         = "INVALID_REVERSAL_AMOUNT"
+	CodeHoldAccessDenied              = "HOLD_ACCESS_DENIED"
+	CodeHoldNotFound                  = "HOLD_NOT_FOUND"
+	CodeHoldUnavailable               = "HOLD_UNAVAILABLE"
+	CodeInvalidCaptureAmount          = "INVALID_CAPTURE_AMOUNT"
+	CodeQuoteUnavailable              = "QUOTE_UNAVAILABLE"
+	CodeExchangeAmountTooSmall        = "EXCHANGE_AMOUNT_TOO_SMALL"
+	CodeRateUnavailable               = "RATE_UNAVAILABLE"
+	CodeScheduledTransferNotFound     = "SCHEDULED_TRANSFER_NOT_FOUND"
+	CodeScheduledTransferInactive     = "SCHEDULED_TRANSFER_INACTIVE"
+	CodeScheduledTransferAccessDenied = "SCHEDULED_TRANSFER_ACCESS_DENIED"
+	CodeLoginNotThrottled             = "LOGIN_NOT_THROTTLED"
+)
+
+// the messages of the generic API errors
+const (
+	internalErrorMessage       = "the request couldn't be completed, try again later"
+	validationFailedMessage    = "the request has invalid fields"
+	invalidRequestMessage      = "the request is malformed"
+	recordNotFoundMessage      = "the requested resource doesn't exist"
+	recordAlreadyExistsMessage = "the resource already exists"
+	referenceNotFoundMessage   = "the request references a resource that doesn't exist"
+	concurrentUpdateMessage    = "the resource was updated by a concurrent request, try again"
+)
+
+// FieldViolation - a field of the request that failed the validation
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+/*
+* APIError - a typed error of the API, written to the client as a problem details document (RFC 7807)
+* Status: the HTTP status of the response
+* Code: the stable code of the error - e.g. ACCOUNT_NOT_FOUND
+* Message: the human readable message
+* Fields: the fields of the request that failed the validation
+* the cause is never written to the client - the internal errors are logged with it
+ */
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  []FieldViolation
+	cause   error
+}
+
+// newAPIError - creating a new API error
+func newAPIError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+func (apiErr *APIError) Error() string {
+	return apiErr.Message
+}
+
+func (apiErr *APIError) Unwrap() error {
+	return apiErr.cause
+}
+
+// withCause - a copy of the API error caused by the given error
+func (apiErr *APIError) withCause(err error) *APIError {
+	copied := *apiErr
+	copied.cause = err
+	return &copied
+}
+
+/*
+* problemResponse - the problem details document of the error responses (RFC 7807)
+* the code, the field errors and the request id are extension members of the document
+ */
+type problemResponse struct {
+	Type      string           `json:"type"`
+	Title     string           `json:"title"`
+	Status    int              `json:"status"`
+	Detail    string           `json:"detail"`
+	Instance  string           `json:"instance"`
+	Code      string           `json:"code"`
+	Errors    []FieldViolation `json:"errors,omitempty"`
+	RequestID string           `json:"request_id,omitempty"`
+}
+
+/*
+* writeError - writing the error as a problem details document and aborting the request
+* the errors that are not API errors are mapped by storeError - the unknown errors are internal errors
+* the cause of an internal error is logged (through the gin errors), the client gets a generic message
+ */
+func writeError(ctx *gin.Context, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = storeError(err, nil)
+	}
+	if apiErr.Status >= http.StatusInternalServerError {
+		_ = ctx.Error(err)
+	}
+
+	ctx.Header("Content-Type", problemContentType)
+	ctx.AbortWithStatusJSON(apiErr.Status, problemResponse{
+		Type:      "about:blank",
+		Title:     http.StatusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Detail:    apiErr.Message,
+		Instance:  ctx.Request.URL.Path,
+		Code:      apiErr.Code,
+		Errors:    apiErr.Fields,
+		RequestID: ctx.GetString(requestIDKey),
+	})
+}
+
+// errorMappings - the API errors of the known errors of the store and the token packages
+var errorMappings = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{db.ErrInsufficientFunds, http.StatusUnprocessableEntity, CodeInsufficientFunds},
+	{db.ErrCurrencyMismatch, http.StatusBadRequest, CodeCurrencyMismatch},
+	{db.ErrQuoteUnavailable, http.StatusUnprocessableEntity, CodeQuoteUnavailable},
+	{db.ErrExchangeAmountTooSmall, http.StatusUnprocessableEntity, CodeExchangeAmountTooSmall},
+	{db.ErrTransferAlreadyReversed, http.StatusConflict, CodeTransferAlreadyReversed},
+	{db.ErrTransferNotReversible, http.StatusUnprocessableEntity, CodeTransferNotReversible},
+	{db.ErrInvalidReversalAmount, http.StatusUnprocessableEntity, CodeInvalidReversalAmount},
+	{db.ErrHoldUnavailable, http.StatusUnprocessableEntity, CodeHoldUnavailable},
+	{db.ErrInvalidCaptureAmount, http.StatusUnprocessableEntity, CodeInvalidCaptureAmount},
+	{db.ErrAccountInactive, http.StatusForbidden, CodeAccountInactive},
+	{db.ErrAccountStatusChange, http.StatusConflict, CodeAccountStatusConflict},
+	{db.ErrAccountNotEmpty, http.StatusConflict, CodeAccountNotEmpty},
+	{db.ErrVerifyEmailUnavailable, http.StatusUnprocessableEntity, CodeVerifyEmailUnavailable},
+	{db.ErrPasswordResetUnavailable, http.StatusUnprocessableEntity, CodePasswordResetUnavailable},
+	{db.ErrTotpAlreadyEnabled, http.StatusConflict, CodeMFAAlreadyEnabled},
+	{db.ErrTotpNotPending, http.StatusConflict, CodeMFANotPending},
+	{db.ErrLoginNotThrottled, http.StatusNotFound, CodeLoginNotThrottled},
+	{db.ErrSameAccount, http.StatusBadRequest, CodeSameAccount},
+	{db.ErrIdempotencyKeyMismatch, http.StatusUnprocessableEntity, CodeIdempotencyKeyMismatch},
+	{token.ErrRevokedToken, http.StatusUnauthorized, CodeTokenRevoked},
+	{totp.ErrInvalidCode, http.StatusUnprocessableEntity, CodeInvalidMFACode},
+}
+
+/*
+* storeError - mapping the error of the store to the API error, shared by all the handlers
+* notFound: the API error of a missing record (e.g. errAccountNotFound) - a generic NOT_FOUND error when nil
+* the messages of the known errors are written to the client, the unknown errors are internal errors
+ */
+func storeError(err error, notFound *APIError) *APIError {
+	if errors.Is(err, db.ErrNotFound) {
+		if notFound == nil {
+			notFound = newAPIError(http.StatusNotFound, CodeNotFound, recordNotFoundMessage)
+		}
+		return notFound.withCause(err)
+	}
+
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return newAPIError(mapping.status, mapping.code, mapping.err.Error()).withCause(err)
+		}
+	}
+
+	// the classified errors of the driver - the constraints are internal, the messages are generic
+	switch {
+	case errors.Is(err, &db.ErrDuplicate{}):
+		return newAPIError(http.StatusConflict, CodeConflict, recordAlreadyExistsMessage).withCause(err)
+	case errors.Is(err, &db.ErrForeignKey{}):
+		return newAPIError(http.StatusUnprocessableEntity, CodeReferenceNotFound, referenceNotFoundMessage).withCause(err)
+	case errors.Is(err, db.ErrSerialization):
+		return newAPIError(http.StatusConflict, CodeConcurrentUpdate, concurrentUpdateMessage).withCause(err)
+	}
+	return newAPIError(http.StatusInternalServerError, CodeInternal, internalErrorMessage).withCause(err)
+}
+
+// the API errors of the missing records
+var (
+	errAccountNotFound           = newAPIError(http.StatusNotFound, CodeAccountNotFound, "the account doesn't exist")
+	errTransferNotFound          = newAPIError(http.StatusNotFound, CodeTransferNotFound, "the transfer doesn't exist")
+	errScheduledTransferNotFound = newAPIError(http.StatusNotFound, CodeScheduledTransferNotFound, "the scheduled transfer doesn't exist")
+	errUserNotFound              = newAPIError(http.StatusNotFound, CodeUserNotFound, "the user doesn't exist")
+	errSessionNotFound           = newAPIError(http.StatusNotFound, CodeSessionNotFound, "the session doesn't exist")
+	errHoldNotFound              = newAPIError(http.StatusNotFound, CodeHoldNotFound, "the hold doesn't exist")
+)
+
+// internalError - the error of an unexpected failure of the server - the cause is logged, never written to the client
+func internalError(err error) *APIError {
+	return newAPIError(http.StatusInternalServerError, CodeInternal, internalErrorMessage).withCause(err)
+}
+
+// tokenError - the error of a token that couldn't be verified - code 401(Unauthorized)
+func tokenError(err error) *APIError {
+	if errors.Is(err, token.ErrExpiredToken) {
+		return newAPIError(http.StatusUnauthorized, CodeTokenExpired, token.ErrExpiredToken.Error()).withCause(err)
+	}
+	return newAPIError(http.StatusUnauthorized, CodeTokenInvalid, token.ErrInvalidToken.Error()).withCause(err)
+}
+
+// invalidRequest - the error of a request with an invalid field, found by the handler (not by the validator)
+func invalidRequest(field, message string) *APIError {
+	apiErr := newAPIError(http.StatusBadRequest, CodeValidationFailed, validationFailedMessage)
+	apiErr.Fields = []FieldViolation{{Field: field, Message: message}}
+	return apiErr
+}
+
+/*
+* bindingError - the error of a request that couldn't be bound - code 400(BadRequest)
+* the validator errors are translated to the field violations, the other errors get a generic message
+* (the raw errors name the go types of the requests)
+ */
+func bindingError(err error) *APIError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		apiErr := newAPIError(http.StatusBadRequest, CodeValidationFailed, validationFailedMessage).withCause(err)
+		for _, fieldErr := range validationErrs {
+			apiErr.Fields = append(apiErr.Fields, FieldViolation{
+				Field:   fieldName(fieldErr),
+				Message: fieldMessage(fieldErr),
+			})
+		}
+		return apiErr
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		apiErr := newAPIError(http.StatusBadRequest, CodeValidationFailed, validationFailedMessage).withCause(err)
+		apiErr.Fields = []FieldViolation{{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("must be a %s", jsonTypeName(typeErr.Type)),
+		}}
+		return apiErr
+	}
+	return newAPIError(http.StatusBadRequest, CodeInvalidRequest, invalidRequestMessage).withCause(err)
+}
+
+// fieldName - the name of the field in the request (e.g. transfers[0].amount) - without the name of the request type
+func fieldName(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fieldErr.Field()
+}
+
+// fieldMessage - the human readable message of the failed validation of the field
+func fieldMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	sized := fieldErr.Kind() == reflect.String || fieldErr.Kind() == reflect.Slice
+	switch fieldErr.Tag() {
+	case "required", "required_without":
+		return "is required"
+	case "min":
+		if sized {
+			return fmt.Sprintf("must have at least %s characters or items", param)
+		}
+		return fmt.Sprintf("must be at least %s", param)
+	case "max":
+		if sized {
+			return fmt.Sprintf("must have at most %s characters or items", param)
+		}
+		return fmt.Sprintf("must be at most %s", param)
+	case "len":
+		return fmt.Sprintf("must have exactly %s characters or items", param)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", param)
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(param, " ", ", "))
+	case "nefield":
+		return fmt.Sprintf("must be different from %s", snakeCase(param))
+	case "alpha":
+		return "must contain letters only"
+	case "alphanum":
+		return "must contain letters and digits only"
+	case "numeric":
+		return "must contain digits only"
+	case "email":
+		return "must be a valid email address"
+	case "uuid":
+		return "must be a valid uuid"
+	case "currency":
+		return "must be a supported currency"
+	case "recurrence":
+		return "must be a supported recurrence"
+	}
+	return fmt.Sprintf("failed the %s validation", fieldErr.Tag())
+}
+
+// snakeCase - the json name of a go field name (e.g. CurrentPassword - current_password)
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, c := range name {
+		if unicode.IsUpper(c) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// jsonTypeName - the json name of the go type of a field (e.g. number for int64)
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return "string"
+}
+
+// requestFieldName - the name of the field in the request (the json, uri or form tag) - used in the validator errors
+func requestFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "uri", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
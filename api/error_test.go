@@ -0,0 +1,262 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorResponse(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          interface{}
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "NotFound",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d/balance", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, db.ErrNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				problem := requireProblem(t, recorder)
+				require.Equal(t, CodeAccountNotFound, problem.Code)
+				// the raw error of the store is never written to the client
+				require.NotContains(t, recorder.Body.String(), db.ErrNotFound.Error())
+			},
+		},
+		{
+			name:   "InternalError",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d/balance", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				problem := requireProblem(t, recorder)
+				require.Equal(t, CodeInternal, problem.Code)
+				require.Equal(t, internalErrorMessage, problem.Detail)
+				require.NotContains(t, recorder.Body.String(), sql.ErrConnDone.Error())
+			},
+		},
+		{
+			name:   "ValidationFailed",
+			method: http.MethodPost,
+			url:    "/fx/transfers",
+			body: gin.H{
+				"from_account_id": account.ID,
+				"to_account_id":   0,
+				"amount":          -1,
+				"currency":        "invalid",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				problem := requireProblem(t, recorder)
+				require.Equal(t, CodeValidationFailed, problem.Code)
+
+				// every invalid field is reported by its json name
+				fields := map[string]string{}
+				for _, violation := range problem.Errors {
+					fields[violation.Field] = violation.Message
+				}
+				require.Len(t, fields, 4)
+				require.Contains(t, fields, "to_account_id")
+				require.Contains(t, fields, "amount")
+				require.Contains(t, fields, "currency")
+				require.Contains(t, fields, "quote_id")
+			},
+		},
+		{
+			name:   "InvalidFieldType",
+			method: http.MethodPost,
+			url:    "/fx/quotes",
+			body:   gin.H{"from_currency": 1},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateFxQuote(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				problem := requireProblem(t, recorder)
+				require.Equal(t, CodeValidationFailed, problem.Code)
+				require.Equal(t, []FieldViolation{{Field: "from_currency", Message: "must be a string"}}, problem.Errors)
+			},
+		},
+		{
+			name:   "InvalidJSON",
+			method: http.MethodPost,
+			url:    "/fx/quotes",
+			body:   "{",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateFxQuote(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				problem := requireProblem(t, recorder)
+				require.Equal(t, CodeInvalidRequest, problem.Code)
+				require.Empty(t, problem.Errors)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			// a string body is sent as is - e.g. a malformed json document
+			var body []byte
+			switch b := tc.body.(type) {
+			case nil:
+			case string:
+				body = []byte(b)
+			default:
+				data, err := json.Marshal(b)
+				require.NoError(t, err)
+				body = data
+			}
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(body))
+			require.NoError(t, err)
+			request.Header.Set(requestIDHeader, testRequestID)
+			addAuthorization(t, request, server.token, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.Router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestStoreError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		notFound *APIError
+		status   int
+		code     string
+	}{
+		{
+			name:     "NotFound",
+			err:      fmt.Errorf("get account: %w", db.ErrNotFound),
+			notFound: errAccountNotFound,
+			status:   http.StatusNotFound,
+			code:     CodeAccountNotFound,
+		},
+		{
+			name:   "GenericNotFound",
+			err:    db.ErrNotFound,
+			status: http.StatusNotFound,
+			code:   CodeNotFound,
+		},
+		{
+			name:   "InsufficientFunds",
+			err:    fmt.Errorf("transaction error: %w", db.ErrInsufficientFunds),
+			status: http.StatusUnprocessableEntity,
+			code:   CodeInsufficientFunds,
+		},
+		{
+			name:   "CurrencyMismatch",
+			err:    db.ErrCurrencyMismatch,
+			status: http.StatusBadRequest,
+			code:   CodeCurrencyMismatch,
+		},
+		{
+			name:   "RevokedToken",
+			err:    token.ErrRevokedToken,
+			status: http.StatusUnauthorized,
+			code:   CodeTokenRevoked,
+		},
+		{
+			name:   "Duplicate",
+			err:    fmt.Errorf("transaction error: %w", &db.ErrDuplicate{Constraint: "owner_currency_key"}),
+			status: http.StatusConflict,
+			code:   CodeConflict,
+		},
+		{
+			name:   "ForeignKey",
+			err:    &db.ErrForeignKey{Constraint: "accounts_owner_fkey"},
+			status: http.StatusUnprocessableEntity,
+			code:   CodeReferenceNotFound,
+		},
+		{
+			name:   "Serialization",
+			err:    fmt.Errorf("transaction error: %w", db.ErrSerialization),
+			status: http.StatusConflict,
+			code:   CodeConcurrentUpdate,
+		},
+		{
+			name:   "InternalError",
+			err:    sql.ErrConnDone,
+			status: http.StatusInternalServerError,
+			code:   CodeInternal,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			apiErr := storeError(tc.err, tc.notFound)
+			require.Equal(t, tc.status, apiErr.Status)
+			require.Equal(t, tc.code, apiErr.Code)
+			// the cause is kept for the logs
+			require.ErrorIs(t, apiErr, tc.err)
+		})
+	}
+}
+
+// requireProblem - checking the response is a problem details document of the request, returning the document
+func requireProblem(t *testing.T, recorder *httptest.ResponseRecorder) problemResponse {
+	require.Equal(t, problemContentType, recorder.Header().Get("Content-Type"))
+
+	var problem problemResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &problem)
+	require.NoError(t, err)
+	require.Equal(t, recorder.Code, problem.Status)
+	require.Equal(t, http.StatusText(recorder.Code), problem.Title)
+	require.Equal(t, testRequestID, problem.RequestID)
+	require.NotEmpty(t, problem.Detail)
+	return problem
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/fx"
+	"github.com/shimon-git/simple-bank/token"
+)
+
+// errRateUnavailable - the exchange rate of the currency pair is not known - code 422(UnprocessableEntity)
+var errRateUnavailable = newAPIError(http.StatusUnprocessableEntity, CodeRateUnavailable, "the exchange rate of the currencies is not available")
+
+// newRateProvider - creates the exchange rate provider, without a rates file only same currency rates are known
+func newRateProvider(ratesFile string) (fx.RateProvider, error) {
+	if ratesFile == "" {
+		return fx.NewStaticRateProvider(nil)
+	}
+	return fx.LoadStaticRateProvider(ratesFile)
+}
+
+/*
+* createFxQuoteRequest - type for creating a new exchange quote
+* 'nefield': the to currency must be different from the from currency
+ */
+type createFxQuoteRequest struct {
+	FromCurrency string `json:"from_currency" binding:"required,currency"`
+	ToCurrency   string `json:"to_currency" binding:"required,currency,nefield=FromCurrency"`
+}
+
+// createFxQuote - API endpoint for locking an exchange rate for a short time
+func (server *Server) createFxQuote(ctx *gin.Context) {
+	var req createFxQuoteRequest
+	// extracting the request into the req variable
+	// if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// getting the current rate - if the pair is not supported return code 422(UnprocessableEntity)
+	rate, err := server.rates.GetRate(ctx, req.FromCurrency, req.ToCurrency)
+	if err != nil {
+		if errors.Is(err, fx.ErrRateNotFound) {
+			writeError(ctx, errRateUnavailable.withCause(err))
+			return
+		}
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// the quote locks the rate until it expires
+	quote, err := server.store.CreateFxQuote(ctx, db.CreateFxQuoteParams{
+		ID:           uuid.New(),
+		Username:     authPayload.Username,
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+		Rate:         rate,
+		ExpiresAt:    time.Now().Add(server.config.FXQuoteDuration),
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// if all good returning the new quote and status OK
+	ctx.JSON(http.StatusOK, quote)
+}
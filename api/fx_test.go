@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/fx"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateFxQuoteAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		body: gin.H{
+			"from_currency": util.USD,
+			"to_currency":   util.ILS,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				CreateFxQuote(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.CreateFxQuoteParams) (db.FxQuote, error) {
+					// the quote must lock the rate of the provider for the configured duration
+					require.Equal(t, user.Username, arg.Username)
+					require.Equal(t, util.USD, arg.FromCurrency)
+					require.Equal(t, util.ILS, arg.ToCurrency)
+					require.Equal(t, 3.5, arg.Rate)
+					require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+
+					return db.FxQuote{
+						ID:           arg.ID,
+						Username:     arg.Username,
+						FromCurrency: arg.FromCurrency,
+						ToCurrency:   arg.ToCurrency,
+						Rate:         arg.Rate,
+						ExpiresAt:    arg.ExpiresAt,
+					}, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var quote db.FxQuote
+			err := json.Unmarshal(recorded.Body.Bytes(), &quote)
+			require.NoError(t, err)
+			require.NotZero(t, quote.ID)
+			require.Equal(t, 3.5, quote.Rate)
+		},
+	}, {
+		name: "UnsupportedPair",
+		body: gin.H{
+			"from_currency": util.USD,
+			"to_currency":   util.EUR,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				CreateFxQuote(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}, {
+		name: "SameCurrency",
+		body: gin.H{
+			"from_currency": util.USD,
+			"to_currency":   util.USD,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				CreateFxQuote(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "InternalError",
+		body: gin.H{
+			"from_currency": util.ILS,
+			"to_currency":   util.USD,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				CreateFxQuote(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.FxQuote{}, sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}, {
+		name: "NoAuthorization",
+		body: gin.H{
+			"from_currency": util.USD,
+			"to_currency":   util.ILS,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				CreateFxQuote(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			// using a known rate for the tests
+			rates, err := fx.NewStaticRateProvider(map[string]float64{"USD/ILS": 3.5})
+			require.NoError(t, err)
+			server.rates = rates
+
+			recorder := httptest.NewRecorder()
+			url := "/fx/quotes"
+
+			reqBody, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(reqBody))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.token)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+)
+
+/*
+* createHoldRequest - type for reserving funds on an account
+* ToAccountID: the account that gets the money when the hold is captured
+* Currency: must match the currency of both accounts
+ */
+type createHoldRequest struct {
+	ToAccountID int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount      int64  `json:"amount" binding:"required,gt=0"`
+	Currency    string `json:"currency" binding:"required,currency"`
+}
+
+// createHold - API endpoint for reserving funds on an account without moving them
+func (server *Server) createHold(ctx *gin.Context) {
+	var uri getAccountRequest
+	// extracting the account id - if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	var req createHoldRequest
+	// if one of the required fields is missed - then return code 400(BadRequest)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// validating the held account id + currency
+	account, valid := server.validAccount(ctx, uri.ID, req.Currency)
+	if !valid {
+		return
+	}
+
+	// only the account owner can reserve its funds
+	if account.Owner != authPayload.Username {
+		writeError(ctx, newAPIError(http.StatusUnauthorized, CodeAccountAccessDenied, "you are not authorized to create a hold on the account"))
+		return
+	}
+
+	// a captured hold moves the money - a large hold demands a fresh second factor like a transfer
+	if !server.requireFreshMFA(ctx, authPayload.Username, req.Amount) {
+		return
+	}
+
+	// validating the to account id + currency
+	if _, valid = server.validAccount(ctx, req.ToAccountID, req.Currency); !valid {
+		return
+	}
+
+	// creating the hold and checking for errors
+	// if the available balance can't cover the amount return code 422(UnprocessableEntity)
+	hold, err := server.store.CreateHoldTx(ctx, db.CreateHoldTxParams{
+		AccountID:   account.ID,
+		ToAccountID: req.ToAccountID,
+		Amount:      req.Amount,
+		ExpiresAt:   time.Now().Add(server.config.HoldDuration),
+		Audit:       auditParams(ctx, authPayload.Username),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	// if all good returning the hold and status OK
+	ctx.JSON(http.StatusOK, hold)
+}
+
+// holdURI - type for getting the hold id from the uri
+type holdURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+/*
+* captureHoldRequest - type for capturing a hold
+* Amount: optional - the amount transferred, the whole held amount when omitted
+ */
+type captureHoldRequest struct {
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+// captureHold - API endpoint for turning a hold into a transfer
+func (server *Server) captureHold(ctx *gin.Context) {
+	var uri holdURI
+	// extracting the hold id - if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// the body is optional - an empty body captures the whole held amount
+	var req captureHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// only the receiving side captures - the payer can't pull the held money into the merchant account
+	hold, valid := server.authorizedHold(ctx, uri.ID, authPayload.Username, true)
+	if !valid {
+		return
+	}
+
+	// capturing the hold and checking for errors
+	// if the hold can't be captured, the amount is too big or the balance can't cover it return code 422(UnprocessableEntity)
+	result, err := server.store.CaptureHoldTx(ctx, db.CaptureHoldTxParams{
+		HoldID: hold.ID,
+		Amount: req.Amount,
+		Audit:  auditParams(ctx, authPayload.Username),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	// if all good returning the hold + the transfer and status OK
+	ctx.JSON(http.StatusOK, result)
+}
+
+// voidHold - API endpoint for releasing the funds of a hold
+func (server *Server) voidHold(ctx *gin.Context) {
+	var uri holdURI
+	// extracting the hold id - if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	hold, valid := server.authorizedHold(ctx, uri.ID, authPayload.Username, false)
+	if !valid {
+		return
+	}
+
+	// voiding the hold - if the hold is not active anymore return code 422(UnprocessableEntity)
+	hold, err := server.store.VoidHoldTx(ctx, db.VoidHoldTxParams{
+		HoldID: hold.ID,
+		Audit:  auditParams(ctx, authPayload.Username),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	// if all good returning the hold and status OK
+	ctx.JSON(http.StatusOK, hold)
+}
+
+/*
+* authorizedHold - getting the hold by the given id, on error writing the error response
+* the owner of the to account is allowed to capture the hold, the owners of both accounts are allowed to void it
+ */
+func (server *Server) authorizedHold(ctx *gin.Context, holdID int64, username string, capture bool) (db.AccountHold, bool) {
+	// getting the hold - if not found: 404(NotFound) else 500(InternalServerError)
+	hold, err := server.store.GetAccountHold(ctx, holdID)
+	if err != nil {
+		writeError(ctx, storeError(err, errHoldNotFound))
+		return hold, false
+	}
+
+	managers := []int64{hold.AccountID, hold.ToAccountID}
+	if capture {
+		managers = []int64{hold.ToAccountID}
+	}
+
+	for _, accountID := range managers {
+		account, valid := server.existingAccount(ctx, accountID)
+		if !valid {
+			return hold, false
+		}
+		if account.Owner == username {
+			return hold, true
+		}
+	}
+
+	writeError(ctx, newAPIError(http.StatusUnauthorized, CodeHoldAccessDenied, "you are not authorized to manage the hold"))
+	return hold, false
+}
@@ -0,0 +1,573 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateHoldAPI(t *testing.T) {
+	// the customer holding the funds and the merchant
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = "ILS"
+	account2.Currency = "ILS"
+
+	amount := int64(10)
+	hold := db.AccountHold{
+		ID:          util.RandomInt(1, 1000),
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      amount,
+		Status:      "active",
+	}
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		body          gin.H
+		setupServer   func(server *Server)
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:      "OK",
+		accountID: account1.ID,
+		body: gin.H{
+			"to_account_id": account2.ID,
+			"amount":        amount,
+			"currency":      "ILS",
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				CreateHoldTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.CreateHoldTxParams) (db.AccountHold, error) {
+					require.Equal(t, account1.ID, arg.AccountID)
+					require.Equal(t, account2.ID, arg.ToAccountID)
+					require.Equal(t, amount, arg.Amount)
+					require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+					require.Equal(t, db.AuditParams{Actor: user1.Username, RequestID: testRequestID}, arg.Audit)
+					return hold, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var gotHold db.AccountHold
+			err := json.Unmarshal(recorded.Body.Bytes(), &gotHold)
+			require.NoError(t, err)
+			require.Equal(t, hold, gotHold)
+		},
+	}, {
+		name:      "MFARequired",
+		accountID: account1.ID,
+		body: gin.H{
+			"to_account_id": account2.ID,
+			"amount":        amount,
+			"currency":      "ILS",
+		},
+		setupServer: func(server *Server) {
+			// a large hold demands a fresh second factor like a transfer
+			server.config.MFATransferThreshold = amount - 1
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				CreateHoldTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+			require.Contains(t, recorded.Body.String(), CodeMFARequired)
+		},
+	}, {
+		name:      "EmailNotVerified",
+		accountID: account1.ID,
+		body: gin.H{
+			"to_account_id": account2.ID,
+			"amount":        amount,
+			"currency":      "ILS",
+		},
+		setupServer: func(server *Server) {
+			server.config.RequireVerifiedEmail = true
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			// a captured hold moves the money - the email must be verified like for a transfer
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user1.Username)).
+				Times(1).
+				Return(user1, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				CreateHoldTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+			require.Contains(t, recorded.Body.String(), CodeEmailNotVerified)
+		},
+	}, {
+		name:      "InsufficientFunds",
+		accountID: account1.ID,
+		body: gin.H{
+			"to_account_id": account2.ID,
+			"amount":        amount,
+			"currency":      "ILS",
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				CreateHoldTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.AccountHold{}, fmt.Errorf("transaction error: %w", db.ErrInsufficientFunds))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}, {
+		name:      "UnauthorizedUser",
+		accountID: account1.ID,
+		body: gin.H{
+			"to_account_id": account2.ID,
+			"amount":        amount,
+			"currency":      "ILS",
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				CreateHoldTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name:      "CurrencyMismatch",
+		accountID: account1.ID,
+		body: gin.H{
+			"to_account_id": account2.ID,
+			"amount":        amount,
+			"currency":      "USD",
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				CreateHoldTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			if tc.setupServer != nil {
+				tc.setupServer(server)
+			}
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/accounts/%d/holds", tc.accountID)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			req.Header.Set(requestIDHeader, testRequestID)
+
+			tc.setupAuth(t, req, server.token)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestManageHoldAPI(t *testing.T) {
+	// the customer holding the funds, the merchant and an unrelated user
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	user3, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+
+	hold := db.AccountHold{
+		ID:          util.RandomInt(1, 1000),
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      100,
+		Status:      "active",
+	}
+
+	testCases := []struct {
+		name          string
+		action        string
+		body          gin.H
+		setupServer   func(server *Server)
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:   "Capture",
+		action: "capture",
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccountHold(gomock.Any(), gomock.Eq(hold.ID)).
+				Times(1).
+				Return(hold, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				CaptureHoldTx(gomock.Any(), gomock.Eq(db.CaptureHoldTxParams{
+					HoldID: hold.ID,
+					Audit: db.AuditParams{
+						Actor:     user2.Username,
+						RequestID: testRequestID,
+					},
+				})).
+				Times(1).
+				Return(db.CaptureHoldTxResult{Hold: hold}, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name:   "PartialCaptureByMerchant",
+		action: "capture",
+		body:   gin.H{"amount": 60},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccountHold(gomock.Any(), gomock.Eq(hold.ID)).
+				Times(1).
+				Return(hold, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			arg := db.CaptureHoldTxParams{
+				HoldID: hold.ID,
+				Amount: 60,
+				Audit: db.AuditParams{
+					Actor:     user2.Username,
+					RequestID: testRequestID,
+				},
+			}
+			store.EXPECT().
+				CaptureHoldTx(gomock.Any(), gomock.Eq(arg)).
+				Times(1).
+				Return(db.CaptureHoldTxResult{Hold: hold}, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name:   "CaptureUnavailable",
+		action: "capture",
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccountHold(gomock.Any(), gomock.Eq(hold.ID)).
+				Times(1).
+				Return(hold, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				CaptureHoldTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.CaptureHoldTxResult{}, fmt.Errorf("transaction error: %w", db.ErrHoldUnavailable))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}, {
+		name:   "CaptureUnauthorizedUser",
+		action: "capture",
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user3.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccountHold(gomock.Any(), gomock.Eq(hold.ID)).
+				Times(1).
+				Return(hold, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				CaptureHoldTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name:   "CaptureByPayer",
+		action: "capture",
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccountHold(gomock.Any(), gomock.Eq(hold.ID)).
+				Times(1).
+				Return(hold, nil)
+
+			// the owner of the held account is not asked about - only the to account owner captures
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(0)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				CaptureHoldTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+			require.Contains(t, recorded.Body.String(), CodeHoldAccessDenied)
+		},
+	}, {
+		name:   "CaptureEmailNotVerified",
+		action: "capture",
+		setupServer: func(server *Server) {
+			server.config.RequireVerifiedEmail = true
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user2.Username)).
+				Times(1).
+				Return(user2, nil)
+
+			store.EXPECT().
+				GetAccountHold(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				CaptureHoldTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+			require.Contains(t, recorded.Body.String(), CodeEmailNotVerified)
+		},
+	}, {
+		name:   "HoldNotFound",
+		action: "capture",
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccountHold(gomock.Any(), gomock.Eq(hold.ID)).
+				Times(1).
+				Return(db.AccountHold{}, db.ErrNotFound)
+
+			store.EXPECT().
+				CaptureHoldTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+		},
+	}, {
+		name:   "Void",
+		action: "void",
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccountHold(gomock.Any(), gomock.Eq(hold.ID)).
+				Times(1).
+				Return(hold, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			voided := hold
+			voided.Status = "voided"
+			arg := db.VoidHoldTxParams{
+				HoldID: hold.ID,
+				Audit: db.AuditParams{
+					Actor:     user1.Username,
+					RequestID: testRequestID,
+				},
+			}
+			store.EXPECT().
+				VoidHoldTx(gomock.Any(), gomock.Eq(arg)).
+				Times(1).
+				Return(voided, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var gotHold db.AccountHold
+			err := json.Unmarshal(recorded.Body.Bytes(), &gotHold)
+			require.NoError(t, err)
+			require.Equal(t, "voided", gotHold.Status)
+		},
+	}, {
+		name:   "VoidUnavailable",
+		action: "void",
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccountHold(gomock.Any(), gomock.Eq(hold.ID)).
+				Times(1).
+				Return(hold, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				VoidHoldTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.AccountHold{}, fmt.Errorf("transaction error: %w", db.ErrHoldUnavailable))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			if tc.setupServer != nil {
+				tc.setupServer(server)
+			}
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/holds/%d/%s", hold.ID, tc.action)
+
+			// the body is optional
+			var body bytes.Buffer
+			if tc.body != nil {
+				err := json.NewEncoder(&body).Encode(tc.body)
+				require.NoError(t, err)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, url, &body)
+			require.NoError(t, err)
+			req.Header.Set(requestIDHeader, testRequestID)
+
+			tc.setupAuth(t, req, server.token)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
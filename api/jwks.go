@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shimon-git/simple-bank/token"
+)
+
+// jwksMaxAge - how long (in seconds) the key set can be cached by the verifiers
+const jwksMaxAge = "300"
+
+/*
+* getJWKS - API endpoint for the verification keys of the tokens (JSON Web Key Set)
+* other services verify the tokens with these keys - the kid of the token selects the key
+* the set is empty for the symmetric token types - their key is never published
+ */
+func (server *Server) getJWKS(ctx *gin.Context) {
+	jwks := token.JSONWebKeySet{Keys: []token.JSONWebKey{}}
+	if server.keys != nil {
+		jwks = server.keys.JWKS()
+	}
+
+	ctx.Header("Cache-Control", "public, max-age="+jwksMaxAge)
+	ctx.JSON(http.StatusOK, jwks)
+}
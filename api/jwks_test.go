@@ -0,0 +1,139 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyFile - generates a new ed25519 key and writes it as a PKCS #8 PEM file
+func writeKeyFile(t *testing.T) (string, crypto.PublicKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "private.pem")
+	err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+	return file, publicKey
+}
+
+func TestGetJWKSAPI(t *testing.T) {
+	signingKeyFile, signingKey := writeKeyFile(t)
+	rotatedKeyFile, rotatedKey := writeKeyFile(t)
+
+	testCases := []struct {
+		name          string
+		tokenType     string
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "EdDSA",
+			tokenType: token.JWTEdDSA,
+			checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorded.Code)
+				require.Equal(t, "public, max-age=300", recorded.Header().Get("Cache-Control"))
+
+				var rsp token.JSONWebKeySet
+				err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Keys, 2)
+
+				// the signing key is the first key of the set
+				signingKeyID, err := token.KeyID(signingKey)
+				require.NoError(t, err)
+				require.Equal(t, signingKeyID, rsp.Keys[0].KeyID)
+				rotatedKeyID, err := token.KeyID(rotatedKey)
+				require.NoError(t, err)
+				require.Equal(t, rotatedKeyID, rsp.Keys[1].KeyID)
+			},
+		},
+		{
+			name:      "PasetoPublic",
+			tokenType: token.PASETOPublic,
+			checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorded.Code)
+
+				var rsp token.JSONWebKeySet
+				err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Keys, 2)
+			},
+		},
+		{
+			name:      "SymmetricToken",
+			tokenType: util.RandomTokenType(),
+			checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorded.Code)
+
+				var rsp token.JSONWebKeySet
+				err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotNil(t, rsp.Keys)
+				require.Empty(t, rsp.Keys)
+			},
+		},
+	}
+
+	for testIDX := range testCases {
+		test := testCases[testIDX]
+
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server, err := NewServer(util.Config{
+				TokenSymmetricKey:         util.RandomString(32),
+				TokenType:                 test.tokenType,
+				TokenSigningKeyFile:       signingKeyFile,
+				TokenVerificationKeyFiles: []string{rotatedKeyFile},
+				AccessTokenDuration:       time.Minute,
+			}, store, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+			require.NoError(t, err)
+
+			server.Router.ServeHTTP(recorder, request)
+			test.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestNewServerInvalidKeyFile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, err := NewServer(util.Config{
+		TokenType:           token.JWTRS256,
+		TokenSigningKeyFile: filepath.Join(t.TempDir(), "missing.pem"),
+	}, mockdb.NewMockStore(ctrl), nil)
+	require.Error(t, err)
+
+	// an ed25519 key can't sign RS256 tokens
+	signingKeyFile, _ := writeKeyFile(t)
+	_, err = NewServer(util.Config{
+		TokenType:           token.JWTRS256,
+		TokenSigningKeyFile: signingKeyFile,
+	}, mockdb.NewMockStore(ctrl), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/rules"
+)
+
+// the errors returned by the throttled logins
+var (
+	ErrLoginThrottled = newAPIError(http.StatusTooManyRequests, CodeLoginThrottled, "too many failed login attempts, try again later")
+)
+
+// loginAttempt - the login attempt of the username by the client of the request
+func loginAttempt(ctx *gin.Context, username string) rules.LoginAttempt {
+	return rules.LoginAttempt{
+		Username:  username,
+		ClientIP:  ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+		Audit:     auditParams(ctx, username),
+	}
+}
+
+/*
+* countLoginAttempt - counting the login attempt of the username and the client ip, before the password (or the second factor) is verified
+* a throttled attempt is rejected - code 429(TooManyRequests), the Retry-After header tells the client when to try again
+* returns the throttle of the username, false when the login can't be made - the error response is already written
+ */
+func (server *Server) countLoginAttempt(ctx *gin.Context, username string) (db.LoginThrottle, bool) {
+	throttle, err := rules.CountLoginAttempt(ctx, server.store, server.config, loginAttempt(ctx, username))
+	if err != nil {
+		writeError(ctx, loginAttemptError(ctx, err))
+		return db.LoginThrottle{}, false
+	}
+	return throttle, true
+}
+
+// loginAttemptError - the API error of a login attempt that couldn't be made - a throttled attempt sets the Retry-After header
+func loginAttemptError(ctx *gin.Context, err error) *APIError {
+	var throttled *rules.ThrottledError
+	if errors.As(err, &throttled) {
+		setRetryAfter(ctx, throttled.LockedUntil)
+		return ErrLoginThrottled.withCause(err)
+	}
+	return internalError(err)
+}
+
+// setRetryAfter - the Retry-After header of a throttled request - the seconds until the lock is over
+func setRetryAfter(ctx *gin.Context, lockedUntil time.Time) {
+	retryAfter := time.Until(lockedUntil)
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+/*
+* recordFailedLogin - recording the failed login attempt in the audit trail - with the lockout of the username when the attempt locked it
+* throttle: the throttle of the username after the attempt was counted
+* returns false when the attempt couldn't be recorded - the error response is already written
+ */
+func (server *Server) recordFailedLogin(ctx *gin.Context, username string, throttle db.LoginThrottle, reason string) bool {
+	err := rules.RecordFailedLogin(ctx, server.store, server.config, loginAttempt(ctx, username), throttle, reason)
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return false
+	}
+	return true
+}
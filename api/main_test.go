@@ -6,21 +6,72 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
 	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
 	"github.com/shimon-git/simple-bank/util"
 	"github.com/stretchr/testify/require"
-
-	_ "github.com/lib/pq"
 )
 
+// testRequestID - the X-Request-ID of the test requests whose audit params are checked
+const testRequestID = "test-request-id"
+
 func NewTestServer(t *testing.T, store db.Store) *Server {
+	// the logins of the tests are not throttled - unless the test expects otherwise before creating the server
+	// every key has a single attempt
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().
+			ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			DoAndReturn(func(_ interface{}, arg db.ThrottleAttemptsTxParams) (db.ThrottleAttemptsTxResult, error) {
+				var result db.ThrottleAttemptsTxResult
+				for _, attempt := range arg.Attempts {
+					result.Throttles = append(result.Throttles, db.LoginThrottle{Key: attempt.Key, FailedAttempts: 1})
+				}
+				return result, nil
+			})
+		mockStore.EXPECT().
+			ForgetLoginAttemptTx(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(nil)
+	}
+
+	return newStrictTestServer(t, store)
+}
+
+// newStrictTestServer - a test server without the default login throttle stubs - the test expects every counted attempt
+func newStrictTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		TokenType:           util.RandomTokenType(),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:     util.RandomString(32),
+		TokenType:             util.RandomTokenType(),
+		AccessTokenDuration:   time.Minute,
+		RefreshTokenDuration:  time.Hour,
+		RevocationCacheSize:   100,
+		RevocationCacheTTL:    time.Minute,
+		FXQuoteDuration:       time.Minute,
+		HoldDuration:          time.Minute,
+		BatchTransferMaxLegs:  3,
+		VerifyEmailDuration:   time.Minute,
+		PasswordResetDuration: time.Minute,
+		LoginBackoffAfter:     3,
+		LoginBackoff:          time.Second,
+		LoginMaxAttempts:      10,
+		LoginIPMaxAttempts:    100,
+		LoginLockoutDuration:  time.Minute,
+		LoginAttemptWindow:    time.Minute,
+	}
+
+	// the tokens of the tests are not revoked - unless the test expects otherwise before creating the server
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().
+			IsTokenRevoked(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(false, nil)
 	}
 
-	server, err := NewServer(config, store)
+	revocations := token.NewRevocationList(db.NewTokenRevocations(store), config.RevocationCacheSize, config.RevocationCacheTTL)
+	server, err := NewServer(config, store, revocations)
 
 	require.NoError(t, err)
 	require.NotEmpty(t, server)
@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
 	"github.com/shimon-git/simple-bank/token"
 )
 
@@ -14,17 +16,60 @@ const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
 	authorizationPayloadKey = "authorization_payload"
+	requestIDHeader         = "X-Request-ID"
+	requestIDKey            = "request_id"
+	maxRequestIDLength      = 128
 )
 
-// authMiddleware - a middleware that check for access tokens and verifying them
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+/*
+* requestIDMiddleware - a middleware that identifies every request - the id is recorded with the audit events of the request
+* the X-Request-ID header of the client is kept when valid, otherwise a new id is generated
+* the id is returned in the X-Request-ID response header and set on the request - e.g. for the gRPC gateway
+ */
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		ctx.Set(requestIDKey, requestID)
+		ctx.Request.Header.Set(requestIDHeader, requestID)
+		ctx.Header(requestIDHeader, requestID)
+		// passing the request to the next handler
+		ctx.Next()
+	}
+}
+
+// validRequestID - checking the request id is not empty, not too long and made of printable ascii characters only
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// auditParams - the audit params of the request made by the given actor
+func auditParams(ctx *gin.Context, actor string) db.AuditParams {
+	return db.AuditParams{
+		Actor:     actor,
+		RequestID: ctx.GetString(requestIDKey),
+	}
+}
+
+// authMiddleware - a middleware that check for access tokens and verifying them (including the revocation list)
+// a token issued before the last password change of its user is rejected as revoked
+func authMiddleware(tokenMaker token.Maker, revocations *token.RevocationList) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// getting the authorization header
 		authorizationHeader := ctx.GetHeader("authorization")
 		// if the authorization header those not exist then return error message & code 401(Unauthorized)
 		if len(authorizationHeader) == 0 {
-			err := errors.New("authorization header has not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, newAPIError(http.StatusUnauthorized, CodeUnauthenticated, "authorization header has not provided"))
 			return
 		}
 		// split the authorization string into a slice
@@ -32,22 +77,35 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		// if the length of the authorization slice is less the 2 return error + code 401(Unauthorized)
 		// 2 fields must be: 1 for the authorization type, the second for the access token value
 		if len(fields) < 2 {
-			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, newAPIError(http.StatusUnauthorized, CodeUnauthenticated, "invalid authorization header format"))
 			return
 		}
 		// verifying the authorization type
 		authorizationType := strings.ToLower(fields[0])
 		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("unsupported authorization type: %s", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, newAPIError(http.StatusUnauthorized, CodeUnauthenticated, fmt.Sprintf("unsupported authorization type: %s", authorizationType)))
 			return
 		}
 		// verifying the access token
 		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		payload, err := tokenMaker.VerifyToken(accessToken, token.TokenTypeAccessToken)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, tokenError(err))
+			return
+		}
+		// a mfa pending token only completes the login - it can't authorize requests
+		if payload.HasScope(token.ScopeMFAVerify) {
+			writeError(ctx, newAPIError(http.StatusUnauthorized, CodeMFARequired, "the login must be completed with the second factor"))
+			return
+		}
+		// rejecting revoked tokens - if the check failed return code 500(InternalServerError)
+		revoked, err := revocations.IsRevoked(ctx, payload)
+		if err != nil {
+			writeError(ctx, internalError(err))
+			return
+		}
+		if revoked {
+			writeError(ctx, token.ErrRevokedToken)
 			return
 		}
 		// adding a new header,value for the token payload
@@ -56,3 +114,25 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// authorize - a middleware that allows the request only when the access token grants all the given scopes
+func authorize(scopes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// the payload is set by the auth middleware
+		payload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !ok {
+			err := errors.New(payloadRetrieveErr)
+			writeError(ctx, internalError(err))
+			return
+		}
+		// if one of the scopes is missing return code 403(Forbidden)
+		for _, scope := range scopes {
+			if !payload.HasScope(scope) {
+				writeError(ctx, newAPIError(http.StatusForbidden, CodeForbidden, fmt.Sprintf("the %s role doesn't grant the %s scope", payload.Role, scope)))
+				return
+			}
+		}
+		// passing the request to the next handler
+		ctx.Next()
+	}
+}
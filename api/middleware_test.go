@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,11 +9,15 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
 	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
 	"github.com/stretchr/testify/require"
 )
 
-// addAuthorization - adding the authorization header by the given params
+// addAuthorization - adding the authorization header of a customer by the given params
 func addAuthorization(
 	t *testing.T,
 	request *http.Request,
@@ -20,11 +25,25 @@ func addAuthorization(
 	authorizationType string,
 	username string,
 	duration time.Duration,
+) {
+	addRoleAuthorization(t, request, tokenMaker, authorizationType, username, util.CustomerRole, duration)
+}
+
+// addRoleAuthorization - adding the authorization header by the given params
+func addRoleAuthorization(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	username string,
+	role string,
+	duration time.Duration,
 ) {
 	// creating a new token
-	token, err := tokenMaker.CreateToken(username, duration)
+	token, payload, err := tokenMaker.CreateToken(username, role, duration, token.TokenTypeAccessToken)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 	// creating the authorization header value
 	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, token)
 	// setting the authorization header
@@ -35,6 +54,7 @@ func TestAuthMiddleware(t *testing.T) {
 	testCases := []struct {
 		name          string
 		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recoredr *httptest.ResponseRecorder)
 	}{{
 		name: "OK",
@@ -81,6 +101,57 @@ func TestAuthMiddleware(t *testing.T) {
 			checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorded.Code)
 			},
+		},
+		{
+			name: "MFAPendingToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addRoleAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", token.MFAPendingRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorded.Code)
+			},
+		},
+		{
+			name: "RefreshToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				// a refresh token can't be used as an access token
+				refreshToken, _, err := tokenMaker.CreateToken("user", util.CustomerRole, time.Minute, token.TokenTypeRefreshToken)
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, refreshToken))
+			},
+			checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorded.Code)
+			},
+		},
+		{
+			name: "RevokedToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					IsTokenRevoked(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(true, nil)
+			},
+			checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorded.Code)
+			},
+		},
+		{
+			name: "RevocationCheckError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					IsTokenRevoked(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(false, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorded.Code)
+			},
 		}}
 
 	// looping through the test cases
@@ -89,14 +160,20 @@ func TestAuthMiddleware(t *testing.T) {
 		test := testCases[testIDX]
 		// running sub tests in goroutines
 		t.Run(test.name, func(t *testing.T) {
-			// creating a new test server
-			server := NewTestServer(t, nil)
+			// creating a new test server - the revocation list is checked through the store
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			if test.buildStubs != nil {
+				test.buildStubs(store)
+			}
+			server := NewTestServer(t, store)
 			// the uri path
 			authPath := "/auth"
 			// creating a new endpoint handler
 			server.Router.GET(
 				authPath,
-				authMiddleware(server.token),
+				authMiddleware(server.token, server.revocations),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -115,3 +192,122 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthorizeMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		role          string
+		scopes        []string
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			role:   util.AdminRole,
+			scopes: []string{token.ScopeAccountsReadAll, token.ScopeBalancesAdjust},
+			checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorded.Code)
+			},
+		},
+		{
+			name:   "MissingScope",
+			role:   util.BankerRole,
+			scopes: []string{token.ScopeAccountsReadAll, token.ScopeBalancesAdjust},
+			checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorded.Code)
+			},
+		},
+		{
+			name:   "CustomerScope",
+			role:   util.CustomerRole,
+			scopes: []string{token.ScopeAccountsRead, token.ScopeAccountsWrite},
+			checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorded.Code)
+			},
+		},
+	}
+
+	for testIDX := range testCases {
+		test := testCases[testIDX]
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := NewTestServer(t, mockdb.NewMockStore(ctrl))
+			// the uri path
+			authPath := "/authorize"
+			// the endpoint requires all the scopes of the test
+			server.Router.GET(
+				authPath,
+				authMiddleware(server.token, server.revocations),
+				authorize(test.scopes...),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+			addRoleAuthorization(t, request, server.token, authorizationTypeBearer, "user", test.role, time.Minute)
+			server.Router.ServeHTTP(recorder, request)
+			test.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{
+			name:      "ClientRequestID",
+			requestID: "client-request-1",
+		},
+		{
+			name:      "MissingRequestID",
+			generated: true,
+		},
+		{
+			name:      "InvalidRequestID",
+			requestID: "not valid",
+			generated: true,
+		},
+		{
+			name:      "TooLongRequestID",
+			requestID: util.RandomString(maxRequestIDLength + 1),
+			generated: true,
+		},
+	}
+
+	for testIDX := range testCases {
+		test := testCases[testIDX]
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := NewTestServer(t, mockdb.NewMockStore(ctrl))
+			// the uri path
+			path := "/request-id"
+			// the endpoint returns the request id seen by the handlers
+			server.Router.GET(path, func(ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, gin.H{"request_id": auditParams(ctx, "user").RequestID})
+			})
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, path, nil)
+			require.NoError(t, err)
+			if test.requestID != "" {
+				request.Header.Set(requestIDHeader, test.requestID)
+			}
+			server.Router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			requestID := recorder.Header().Get(requestIDHeader)
+			require.Contains(t, recorder.Body.String(), requestID)
+			if test.generated {
+				_, err = uuid.Parse(requestID)
+				require.NoError(t, err)
+			} else {
+				require.Equal(t, test.requestID, requestID)
+			}
+		})
+	}
+}
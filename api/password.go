@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/mail"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+)
+
+// the length of the password reset tokens
+const passwordResetTokenLength = 32
+
+// errPasswordResetThrottled - too many password resets were requested for the email or from the client ip - code 429(TooManyRequests)
+var errPasswordResetThrottled = newAPIError(http.StatusTooManyRequests, CodePasswordResetThrottled, "too many password reset requests, try again later")
+
+// errIncorrectPassword - the current password given for the password change is wrong - code 401(Unauthorized)
+var errIncorrectPassword = newAPIError(http.StatusUnauthorized, CodeIncorrectPassword, "the current password is incorrect")
+
+/*
+* changePasswordRequest - type for changing the password of the logged in user
+* 'nefield': the new password must be different from the current password
+ */
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required,min=6"`
+	NewPassword     string `json:"new_password" binding:"required,min=6,nefield=CurrentPassword"`
+}
+
+/*
+* changePassword - API endpoint for changing the password of the logged in user
+* every token issued before the change is rejected afterwards (including the token of the request) - the user must login again
+ */
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	// extracting the request into the req variable
+	// if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// getting the user through the payload of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		writeError(ctx, storeError(err, errUserNotFound))
+		return
+	}
+	// verifying the current password is correct - if not return code 401(Unauthorized)
+	if err = util.CheckPassword(req.CurrentPassword, user.HashedPassword); err != nil {
+		writeError(ctx, errIncorrectPassword.withCause(err))
+		return
+	}
+
+	hashedPassword, err := util.HashedPassword(req.NewPassword)
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	user, err = server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+		Audit:          auditParams(ctx, user.Username),
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+	// the cached tokens of the user were issued before the change
+	server.revocations.ForgetUser(user.Username)
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// forgotPasswordRequest - type for requesting a password reset email
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+/*
+* forgotPassword - API endpoint for sending a password reset link to the email of the user
+* the requests are throttled per email and per client ip - code 429(TooManyRequests)
+* the email is sent in the background - the response is the same (and as fast) whether or not a user has the email
+* so the emails of the users can't be discovered
+ */
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	// extracting the request into the req variable
+	// if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// counting the request for the email and for the client ip - e.g. flooding the inbox of a user
+	result, err := server.store.ThrottleAttemptsTx(ctx, db.ThrottleAttemptsTxParams{
+		Attempts: []db.ThrottleAttempt{
+			{Key: db.PasswordResetEmailThrottleKey(req.Email), Policy: db.PasswordResetEmailPolicy(server.config)},
+			{Key: db.PasswordResetIPThrottleKey(ctx.ClientIP()), Policy: db.PasswordResetIPPolicy(server.config)},
+		},
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrThrottled) {
+			setRetryAfter(ctx, result.LockedUntil)
+			writeError(ctx, errPasswordResetThrottled.withCause(err))
+			return
+		}
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	server.runInBackground("send the password reset email", func(bgCtx context.Context) error {
+		return server.sendPasswordReset(bgCtx, req.Email)
+	})
+
+	ctx.JSON(http.StatusAccepted, gin.H{})
+}
+
+// sendPasswordReset - creating a password reset for the user of the email and sending its link, nothing is sent to an unknown email
+func (server *Server) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := server.store.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// the single-use reset token - only its hash is stored
+	resetToken, err := util.RandomSecret(passwordResetTokenLength)
+	if err != nil {
+		return err
+	}
+	_, err = server.store.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: util.HashSecret(resetToken),
+		ExpiredAt: time.Now().Add(server.config.PasswordResetDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := mail.PasswordResetLink(server.config.PasswordResetURL, resetToken)
+	content := mail.PasswordResetContent(user.FullName, link, server.config.PasswordResetDuration)
+	return server.mailer.SendEmail(mail.PasswordResetSubject, content, []string{user.Email})
+}
+
+// resetPasswordRequest - type for setting a new password with the token of the reset email
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required,len=32"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// resetPassword - API endpoint for setting a new password with a password reset token
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	// extracting the request into the req variable
+	// if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	hashedPassword, err := util.HashedPassword(req.NewPassword)
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	user, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:      util.HashSecret(req.Token),
+		HashedPassword: hashedPassword,
+		RequestID:      ctx.GetString(requestIDKey),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	// the cached tokens of the user were issued before the reset
+	server.revocations.ForgetUser(user.Username)
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
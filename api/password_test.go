@@ -0,0 +1,428 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/mail"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUser(t)
+	newPassword := util.RandomString(8)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		body: gin.H{
+			"current_password": password,
+			"new_password":     newPassword,
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(user, nil)
+
+			store.EXPECT().
+				ChangePasswordTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.ChangePasswordTxParams) (db.User, error) {
+					require.Equal(t, user.Username, arg.Username)
+					require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword))
+					// the change is recorded in the audit trail with the id of the request
+					require.Equal(t, db.AuditParams{Actor: user.Username, RequestID: testRequestID}, arg.Audit)
+
+					changed := user
+					changed.HashedPassword = arg.HashedPassword
+					changed.PasswordChangedAt = time.Now()
+					return changed, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp userResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Equal(t, user.Username, rsp.Username)
+			require.WithinDuration(t, time.Now(), rsp.PasswordChangedAt, time.Second)
+		},
+	}, {
+		name: "IncorrectPassword",
+		body: gin.H{
+			"current_password": "incorrect",
+			"new_password":     newPassword,
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(user, nil)
+
+			store.EXPECT().
+				ChangePasswordTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name: "SamePassword",
+		body: gin.H{
+			"current_password": password,
+			"new_password":     password,
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "TooShortPassword",
+		body: gin.H{
+			"current_password": password,
+			"new_password":     "123",
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "InternalError",
+		body: gin.H{
+			"current_password": password,
+			"new_password":     newPassword,
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(user, nil)
+
+			store.EXPECT().
+				ChangePasswordTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.User{}, sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			reqBody, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPatch, "/users/me/password", bytes.NewBuffer(reqBody))
+			require.NoError(t, err)
+			req.Header.Set(requestIDHeader, testRequestID)
+
+			addAuthorization(t, req, server.token, authorizationTypeBearer, user.Username, time.Minute)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	// the request is counted for the email and for the client ip
+	throttled := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ interface{}, arg db.ThrottleAttemptsTxParams) (db.ThrottleAttemptsTxResult, error) {
+				require.Len(t, arg.Attempts, 2)
+				require.Equal(t, db.PasswordResetEmailThrottleKey(user.Email), arg.Attempts[0].Key)
+				require.Equal(t, db.PasswordResetIPThrottleKey("192.0.2.1"), arg.Attempts[1].Key)
+				return db.ThrottleAttemptsTxResult{}, nil
+			})
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+		checkEmails   func(t *testing.T, emails []mail.Email)
+	}{{
+		name: "OK",
+		body: gin.H{"email": user.Email},
+		buildStubs: func(store *mockdb.MockStore) {
+			throttled(store)
+
+			store.EXPECT().
+				GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+				Times(1).
+				Return(user, nil)
+
+			store.EXPECT().
+				CreatePasswordReset(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.CreatePasswordResetParams) (db.PasswordReset, error) {
+					require.Equal(t, user.Username, arg.Username)
+					require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiredAt, time.Second)
+					return db.PasswordReset{Username: arg.Username, TokenHash: arg.TokenHash, ExpiredAt: arg.ExpiredAt}, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusAccepted, recorded.Code)
+		},
+		checkEmails: func(t *testing.T, emails []mail.Email) {
+			require.Len(t, emails, 1)
+			require.Equal(t, []string{user.Email}, emails[0].To)
+			require.Regexp(t, regexp.MustCompile(`\?token=\w{32}"`), emails[0].Content)
+		},
+	}, {
+		name: "UnknownEmail",
+		body: gin.H{"email": user.Email},
+		buildStubs: func(store *mockdb.MockStore) {
+			throttled(store)
+
+			store.EXPECT().
+				GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+				Times(1).
+				Return(db.User{}, db.ErrNotFound)
+
+			store.EXPECT().
+				CreatePasswordReset(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			// the same response as for a known email
+			require.Equal(t, http.StatusAccepted, recorded.Code)
+		},
+		checkEmails: func(t *testing.T, emails []mail.Email) {
+			require.Empty(t, emails)
+		},
+	}, {
+		name: "Throttled",
+		body: gin.H{"email": user.Email},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.ThrottleAttemptsTxResult{LockedUntil: time.Now().Add(time.Minute)}, db.ErrThrottled)
+
+			store.EXPECT().
+				GetUserByEmail(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusTooManyRequests, recorded.Code)
+			require.Equal(t, "60", recorded.Header().Get("Retry-After"))
+		},
+		checkEmails: func(t *testing.T, emails []mail.Email) {
+			require.Empty(t, emails)
+		},
+	}, {
+		name: "InvalidEmail",
+		body: gin.H{"email": "invalid-email"},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "ThrottleError",
+		body: gin.H{"email": user.Email},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.ThrottleAttemptsTxResult{}, sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}, {
+		name: "InternalError",
+		body: gin.H{"email": user.Email},
+		buildStubs: func(store *mockdb.MockStore) {
+			throttled(store)
+
+			store.EXPECT().
+				GetUserByEmail(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(user, nil)
+
+			store.EXPECT().
+				CreatePasswordReset(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.PasswordReset{}, sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			// the email is sent in the background - its errors are only logged
+			require.Equal(t, http.StatusAccepted, recorded.Code)
+		},
+		checkEmails: func(t *testing.T, emails []mail.Email) {
+			require.Empty(t, emails)
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			reqBody, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewBuffer(reqBody))
+			require.NoError(t, err)
+			req.RemoteAddr = "192.0.2.1:1234"
+
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+
+			// waiting for the email sent in the background
+			server.background.Wait()
+			if tc.checkEmails != nil {
+				tc.checkEmails(t, server.mailer.(*mail.MemorySender).Emails())
+			}
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	resetToken := util.RandomString(passwordResetTokenLength)
+	newPassword := util.RandomString(8)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		body: gin.H{
+			"token":        resetToken,
+			"new_password": newPassword,
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				ResetPasswordTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.ResetPasswordTxParams) (db.User, error) {
+					// only the hash of the token is compared
+					require.Equal(t, util.HashSecret(resetToken), arg.TokenHash)
+					require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword))
+					require.Equal(t, testRequestID, arg.RequestID)
+					return user, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+			requireBodyMatchUser(t, recorded.Body, user)
+		},
+	}, {
+		name: "UnavailableToken",
+		body: gin.H{
+			"token":        resetToken,
+			"new_password": newPassword,
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				ResetPasswordTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.User{}, db.ErrPasswordResetUnavailable)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}, {
+		name: "InvalidToken",
+		body: gin.H{
+			"token":        resetToken[:10],
+			"new_password": newPassword,
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				ResetPasswordTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "InternalError",
+		body: gin.H{
+			"token":        resetToken,
+			"new_password": newPassword,
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				ResetPasswordTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.User{}, sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			reqBody, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewBuffer(reqBody))
+			require.NoError(t, err)
+			req.Header.Set(requestIDHeader, testRequestID)
+
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+)
+
+// errScheduledTransferInactive - a completed, failed or cancelled transfer can't be changed - code 422(UnprocessableEntity)
+var errScheduledTransferInactive = newAPIError(http.StatusUnprocessableEntity, CodeScheduledTransferInactive, "the scheduled transfer is not active")
+
+/*
+* createScheduledTransferRequest - type for creating a one-off or a recurring transfer
+* RunAt: the time of the first (or only) transfer - must be in the future
+* Recurrence: optional - daily, weekly or monthly:<day>, a one-off transfer when omitted
+ */
+type createScheduledTransferRequest struct {
+	FromAccountID int64     `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64     `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64     `json:"amount" binding:"required,gt=0"`
+	Currency      string    `json:"currency" binding:"required,currency"`
+	RunAt         time.Time `json:"run_at" binding:"required"`
+	Recurrence    string    `json:"recurrence" binding:"omitempty,recurrence"`
+}
+
+// createScheduledTransfer - API endpoint for scheduling a transfer
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	// if one of the required fields is missed - then return code 400(BadRequest)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	if !req.RunAt.After(time.Now()) {
+		writeError(ctx, invalidRequest("run_at", "must be in the future"))
+		return
+	}
+
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// validating the from account id + currency
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	// checking if the authenticated account is authorized to make the transfer
+	if fromAccount.Owner != authPayload.Username {
+		writeError(ctx, errTransferAccessDenied)
+		return
+	}
+
+	// a large transfer demands a fresh second factor when it's scheduled
+	if !server.requireFreshMFA(ctx, authPayload.Username, req.Amount) {
+		return
+	}
+
+	// validating the to account id + currency
+	if _, valid = server.validAccount(ctx, req.ToAccountID, req.Currency); !valid {
+		return
+	}
+
+	// the funds are checked by the executor when the transfer runs
+	scheduled, err := server.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Recurrence:    req.Recurrence,
+		NextRunAt:     req.RunAt,
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// if all good returning the scheduled transfer and status OK
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+// scheduledTransferURI - type for getting the scheduled transfer id from the uri
+type scheduledTransferURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getScheduledTransfer - API endpoint for getting a scheduled transfer of the user
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	scheduled, valid := server.ownedScheduledTransfer(ctx)
+	if !valid {
+		return
+	}
+
+	// if all good returning the scheduled transfer and status OK
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+/*
+* listScheduledTransfersRequest - type for extracting the listScheduledTransfers request
+* PageID: ID to start from
+* PageSize: desired amount of rows
+ */
+type listScheduledTransfersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listScheduledTransfers - API endpoint for listing the scheduled transfers of the user
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	// validating the request params - on error: status 400(BadRequest)
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	scheduled, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// if all good return the scheduled transfers - status: 200(OK)
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+/*
+* updateScheduledTransferRequest - type for changing an active scheduled transfer
+* the failed attempts of the current occurrence are reset
+ */
+type updateScheduledTransferRequest struct {
+	Amount     int64     `json:"amount" binding:"required,gt=0"`
+	RunAt      time.Time `json:"run_at" binding:"required"`
+	Recurrence string    `json:"recurrence" binding:"omitempty,recurrence"`
+}
+
+// updateScheduledTransfer - API endpoint for changing the amount and the schedule of a scheduled transfer
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var req updateScheduledTransferRequest
+	// if one of the required fields is missed - then return code 400(BadRequest)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	if !req.RunAt.After(time.Now()) {
+		writeError(ctx, invalidRequest("run_at", "must be in the future"))
+		return
+	}
+
+	scheduled, valid := server.ownedScheduledTransfer(ctx)
+	if !valid {
+		return
+	}
+
+	// a large transfer demands a fresh second factor when it's scheduled
+	if !server.requireFreshMFA(ctx, scheduled.Owner, req.Amount) {
+		return
+	}
+
+	// a completed, failed or cancelled transfer can't be changed - 422(UnprocessableEntity)
+	scheduled, err := server.store.UpdateScheduledTransfer(ctx, db.UpdateScheduledTransferParams{
+		ID:         scheduled.ID,
+		Amount:     req.Amount,
+		Recurrence: req.Recurrence,
+		NextRunAt:  req.RunAt,
+	})
+	if err != nil {
+		writeError(ctx, storeError(err, errScheduledTransferInactive))
+		return
+	}
+
+	// if all good returning the scheduled transfer and status OK
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+// cancelScheduledTransfer - API endpoint for cancelling the future runs of a scheduled transfer
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	scheduled, valid := server.ownedScheduledTransfer(ctx)
+	if !valid {
+		return
+	}
+
+	// a completed, failed or cancelled transfer can't be cancelled - 422(UnprocessableEntity)
+	scheduled, err := server.store.CancelScheduledTransfer(ctx, scheduled.ID)
+	if err != nil {
+		writeError(ctx, storeError(err, errScheduledTransferInactive))
+		return
+	}
+
+	// if all good returning the cancelled transfer and status OK
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+// listScheduledTransferRuns - API endpoint for listing the execution history of a scheduled transfer (newest first)
+func (server *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	// validating the request params - on error: status 400(BadRequest)
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	scheduled, valid := server.ownedScheduledTransfer(ctx)
+	if !valid {
+		return
+	}
+
+	runs, err := server.store.ListScheduledTransferRuns(ctx, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               req.PageSize,
+		Offset:              (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// if all good return the runs - status: 200(OK)
+	ctx.JSON(http.StatusOK, runs)
+}
+
+// ownedScheduledTransfer - getting the scheduled transfer of the uri id, on error (or when not owned by the user) writing the error response
+func (server *Server) ownedScheduledTransfer(ctx *gin.Context) (db.ScheduledTransfer, bool) {
+	var uri scheduledTransferURI
+	// extracting the scheduled transfer id - if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return db.ScheduledTransfer{}, false
+	}
+
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return db.ScheduledTransfer{}, false
+	}
+
+	// getting the scheduled transfer - if not found: 404(NotFound) else 500(InternalServerError)
+	scheduled, err := server.store.GetScheduledTransfer(ctx, uri.ID)
+	if err != nil {
+		writeError(ctx, storeError(err, errScheduledTransferNotFound))
+		return scheduled, false
+	}
+
+	if scheduled.Owner != authPayload.Username {
+		writeError(ctx, newAPIError(http.StatusUnauthorized, CodeScheduledTransferAccessDenied, "you are not authorized to access the scheduled transfer"))
+		return scheduled, false
+	}
+	return scheduled, true
+}
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateScheduledTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = "ILS"
+	account2.Currency = "ILS"
+
+	runAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	scheduled := db.ScheduledTransfer{
+		ID:            util.RandomInt(1, 1000),
+		Owner:         user1.Username,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Recurrence:    "monthly:1",
+		Status:        db.ScheduledTransferActive,
+		NextRunAt:     runAt,
+		DueAt:         runAt,
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		body: gin.H{
+			"from_account_id": account1.ID,
+			"to_account_id":   account2.ID,
+			"amount":          10,
+			"currency":        "ILS",
+			"run_at":          runAt,
+			"recurrence":      "monthly:1",
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			arg := db.CreateScheduledTransferParams{
+				Owner:         user1.Username,
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        10,
+				Recurrence:    "monthly:1",
+				NextRunAt:     runAt,
+			}
+			store.EXPECT().
+				CreateScheduledTransfer(gomock.Any(), gomock.Eq(arg)).
+				Times(1).
+				Return(scheduled, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var got db.ScheduledTransfer
+			err := json.Unmarshal(recorded.Body.Bytes(), &got)
+			require.NoError(t, err)
+			require.Equal(t, scheduled.ID, got.ID)
+			require.Equal(t, scheduled.Recurrence, got.Recurrence)
+		},
+	}, {
+		name: "RunAtInThePast",
+		body: gin.H{
+			"from_account_id": account1.ID,
+			"to_account_id":   account2.ID,
+			"amount":          10,
+			"currency":        "ILS",
+			"run_at":          time.Now().Add(-time.Minute),
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "InvalidRecurrence",
+		body: gin.H{
+			"from_account_id": account1.ID,
+			"to_account_id":   account2.ID,
+			"amount":          10,
+			"currency":        "ILS",
+			"run_at":          runAt,
+			"recurrence":      "monthly:32",
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "UnauthorizedUser",
+		body: gin.H{
+			"from_account_id": account1.ID,
+			"to_account_id":   account2.ID,
+			"amount":          10,
+			"currency":        "ILS",
+			"run_at":          runAt,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/scheduled-transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.token)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCancelScheduledTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	scheduled := db.ScheduledTransfer{
+		ID:     util.RandomInt(1, 1000),
+		Owner:  user1.Username,
+		Amount: 10,
+		Status: db.ScheduledTransferActive,
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+				Times(1).
+				Return(scheduled, nil)
+
+			cancelled := scheduled
+			cancelled.Status = db.ScheduledTransferCancelled
+			store.EXPECT().
+				CancelScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+				Times(1).
+				Return(cancelled, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name: "NotActive",
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+				Times(1).
+				Return(scheduled, nil)
+
+			store.EXPECT().
+				CancelScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+				Times(1).
+				Return(db.ScheduledTransfer{}, db.ErrNotFound)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}, {
+		name: "UnauthorizedUser",
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+				Times(1).
+				Return(scheduled, nil)
+
+			store.EXPECT().
+				CancelScheduledTransfer(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name: "NotFound",
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+				Times(1).
+				Return(db.ScheduledTransfer{}, db.ErrNotFound)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/scheduled-transfers/%d", scheduled.ID)
+
+			req, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.token)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,42 +1,86 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/doc"
+	"github.com/shimon-git/simple-bank/fx"
+	"github.com/shimon-git/simple-bank/mail"
 	"github.com/shimon-git/simple-bank/token"
 	"github.com/shimon-git/simple-bank/util"
 )
 
 // Server serves HTTP requests for our banking service
 type Server struct {
-	config util.Config
-	store  db.Store
-	token  token.Maker
-	Router *gin.Engine
+	config      util.Config
+	store       db.Store
+	token       token.Maker
+	keys        *token.KeyRing
+	revocations *token.RevocationList
+	rates       fx.RateProvider
+	mailer      mail.EmailSender
+	Router      *gin.Engine
+	// the tasks running after their response was written (e.g. sending emails)
+	background sync.WaitGroup
 }
 
-// NewServer - creates a new HTTP server and setup routing
-func NewServer(config util.Config, store db.Store) (*Server, error) {
+// the timeout of the tasks running in the background
+const backgroundTaskTimeout = time.Minute
 
-	token, err := token.CreateNewToken(config.TokenType, config.TokenSymmetricKey)
+// NewServer - creates a new HTTP server and setup routing, the revoked tokens are checked by the given revocation list
+func NewServer(config util.Config, store db.Store, revocations *token.RevocationList) (*Server, error) {
+
+	tokenMaker, keys, err := token.NewMakerFromConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
+
+	rates, err := newRateProvider(config.FXRatesFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create exchange rate provider: %w", err)
+	}
+
+	mailer, err := mail.NewSenderFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create email sender: %w", err)
+	}
+	// the client ip (e.g. of the login throttle) is taken from X-Forwarded-For only behind the trusted proxies
+	// otherwise it's the remote address of the request
+	router := gin.Default()
+	if err = router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
 	// creating a new server object
 	server := &Server{
 		config: config,
 		store:  store,
-		Router: gin.Default(),
-		token:  token,
+		Router: router,
+		token:  tokenMaker,
+		keys:   keys,
+		rates:  rates,
+		mailer: mailer,
+		// the revoked tokens - checked by the auth middleware
+		revocations: revocations,
 	}
 
 	// registering a validator function named currency
+	// the validator errors name the fields like the requests (json, uri or form tag)
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(requestFieldName)
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("recurrence", validRecurrence)
 	}
 	// setting up the routs
 	server.setupRouter()
@@ -44,27 +88,141 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 	return server, nil
 }
 
+/*
+* runInBackground - running the task after the response is written, the request context is canceled by then
+* so the task gets its own context - its error is logged
+ */
+func (server *Server) runInBackground(name string, task func(ctx context.Context) error) {
+	server.background.Add(1)
+	go func() {
+		defer server.background.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), backgroundTaskTimeout)
+		defer cancel()
+		if err := task(ctx); err != nil {
+			log.Printf("failed to %s: %v", name, err)
+		}
+	}()
+}
+
 // setupRouter - setup the routes
 func (server *Server) setupRouter() {
-	server.Router.POST("/users", server.createUser)
-	server.Router.POST("/users/login", server.loginUser)
+	// identifying every request - for the audit trail
+	server.Router.Use(requestIDMiddleware())
+
+	server.Router.POST("/users/login/mfa", server.loginUserMFA)
+	server.Router.POST("/users/password/forgot", server.forgotPassword)
+	server.Router.POST("/users/password/reset", server.resetPassword)
+	server.Router.POST("/tokens/renew_access", server.renewAccessToken)
+	server.Router.GET("/verify_email", server.verifyEmail)
+	server.Router.GET("/.well-known/jwks.json", server.getJWKS)
+
+	// the Swagger UI of the OpenAPI spec generated from the proto definitions - embedded in the binary
+	swagger, err := fs.Sub(doc.Swagger, "swagger")
+	if err != nil {
+		panic(err)
+	}
+	server.Router.StaticFS("/swagger", http.FS(swagger))
 
 	// creating a new group and using the authMiddleWare
-	authRoutes := server.Router.Group("/").Use(authMiddleware(server.token))
+	authRoutes := server.Router.Group("/", authMiddleware(server.token, server.revocations))
+
+	authRoutes.POST("/users/logout", server.logoutUser)
+	authRoutes.PATCH("/users/me/password", server.changePassword)
+	authRoutes.POST("/users/me/mfa/totp", server.enrollTotp)
+	authRoutes.POST("/users/me/mfa/totp/confirm", server.confirmTotp)
+
+	authRoutes.GET("/sessions", server.listSessions)
+	authRoutes.DELETE("/sessions/:id", server.revokeSession)
 
-	authRoutes.POST("/accounts", server.createAccount)
-	authRoutes.GET("/accounts", server.listAccounts)
-	authRoutes.GET("/accounts/:id", server.getAccount)
+	// reading the accounts - the bankers and the admins can read any account
+	readRoutes := authRoutes.Group("/", authorize(token.ScopeAccountsRead))
 
-	authRoutes.POST("/transfers", server.createTransfer)
+	readRoutes.GET("/accounts/:id/entries", server.listAccountEntries)
+	readRoutes.GET("/accounts/:id/transfers", server.listAccountTransfers)
+	readRoutes.GET("/accounts/:id/balance", server.getAccountBalance)
+	readRoutes.GET("/accounts/:id/balance-history", server.getBalanceHistory)
+
+	readRoutes.GET("/scheduled-transfers", server.listScheduledTransfers)
+	readRoutes.GET("/scheduled-transfers/:id", server.getScheduledTransfer)
+	readRoutes.GET("/scheduled-transfers/:id/runs", server.listScheduledTransferRuns)
+
+	// creating accounts and moving the money of the user
+	writeRoutes := authRoutes.Group("/", authorize(token.ScopeAccountsWrite))
+
+	writeRoutes.POST("/accounts/:id/holds", server.requireVerifiedEmail(), server.createHold)
+
+	writeRoutes.POST("/holds/:id/capture", server.requireVerifiedEmail(), server.captureHold)
+	writeRoutes.POST("/holds/:id/void", server.voidHold)
+
+	writeRoutes.POST("/transfers/batch", server.requireVerifiedEmail(), server.createBatchTransfer)
+	writeRoutes.POST("/transfers/:id/reverse", server.reverseTransfer)
+
+	writeRoutes.POST("/fx/quotes", server.createFxQuote)
+	writeRoutes.POST("/fx/transfers", server.requireVerifiedEmail(), server.createExchangeTransfer)
+
+	writeRoutes.POST("/scheduled-transfers", server.requireVerifiedEmail(), server.createScheduledTransfer)
+	writeRoutes.PUT("/scheduled-transfers/:id", server.updateScheduledTransfer)
+	writeRoutes.DELETE("/scheduled-transfers/:id", server.cancelScheduledTransfer)
+
+	// the back-office routes - only the admins can use the back-office, every action is recorded in the audit trail
+	adminRoutes := authRoutes.Group("/admin", authorize(token.ScopeBackOffice))
+
+	adminRoutes.GET("/users", server.searchUsers)
+	adminRoutes.POST("/users/:username/unlock", server.unlockUser)
+	adminRoutes.POST("/users/:username/revoke_tokens", server.revokeUserTokens)
+	adminRoutes.GET("/accounts/:id", server.adminGetAccount)
+	adminRoutes.POST("/accounts/:id/freeze", server.freezeAccount)
+	adminRoutes.POST("/accounts/:id/unfreeze", server.unfreezeAccount)
+	adminRoutes.POST("/accounts/:id/close", server.closeAccount)
+	adminRoutes.POST("/accounts/:id/adjustments", authorize(token.ScopeBalancesAdjust), server.adjustAccountBalance)
+	adminRoutes.GET("/db/stats", server.getDBStats)
+}
+
+/*
+* gatewayAliases - the unversioned paths of the operations served by the gateway
+* the requests are served by the gateway under /v1, so the HTTP and the gRPC APIs have a single implementation
+* BREAKING: the responses are the responses of the gateway, not of the former HTTP handlers of these paths
+*   - the int64 fields (ids, balances, amounts) are JSON strings (protojson)
+*   - the resources are wrapped by their name - e.g. GET /accounts/:id returns {"account": {...}}
+*   - POST /transfers doesn't take a quote_id - the cross currency transfers moved to POST /fx/transfers
+* the clients should move to the /v1 paths - the aliases only keep the paths resolvable
+ */
+var gatewayAliases = []struct {
+	method string
+	path   string
+}{
+	{http.MethodPost, "/users"},
+	{http.MethodPost, "/users/login"},
+	{http.MethodPost, "/accounts"},
+	{http.MethodGet, "/accounts"},
+	{http.MethodGet, "/accounts/:id"},
+	{http.MethodPost, "/transfers"},
+}
+
+/*
+* MountGateway - serving the REST API of the gRPC service (grpc-gateway) under /v1 + its unversioned aliases
+* the gateway requests pass through the middlewares of the router - e.g. the request id
+* the gateway takes the client ip from the remote address - set to the client ip resolved by the router (through the trusted proxies)
+ */
+func (server *Server) MountGateway(gateway http.Handler) {
+	serveGateway := func(ctx *gin.Context) {
+		ctx.Request.RemoteAddr = net.JoinHostPort(ctx.ClientIP(), "0")
+		ctx.Request.Header.Del("X-Forwarded-For")
+		gateway.ServeHTTP(ctx.Writer, ctx.Request)
+	}
+
+	server.Router.Any("/v1/*path", serveGateway)
+	for _, alias := range gatewayAliases {
+		server.Router.Handle(alias.method, alias.path, func(ctx *gin.Context) {
+			ctx.Request.URL.Path = "/v1" + ctx.Request.URL.Path
+			ctx.Request.URL.RawPath = ""
+			serveGateway(ctx)
+		})
+	}
 }
 
 // start - starting the HTTP server on a specific address
 func (server *Server) Start(address string) error {
 	return server.Router.Run(address)
 }
-
-// errorResponse - return map[string]{} - we inside the map[string]VALUE the error response
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
-}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwaggerAPI(t *testing.T) {
+	testCases := []struct {
+		name        string
+		path        string
+		contentType string
+	}{
+		{
+			name:        "UI",
+			path:        "/swagger/",
+			contentType: "text/html",
+		},
+		{
+			name:        "Spec",
+			path:        "/swagger/simple_bank.swagger.json",
+			contentType: "application/json",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := NewTestServer(t, mockdb.NewMockStore(ctrl))
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			require.NoError(t, err)
+
+			server.Router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.Contains(t, recorder.Header().Get("Content-Type"), tc.contentType)
+		})
+	}
+}
+
+func TestMountGateway(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server := NewTestServer(t, mockdb.NewMockStore(ctrl))
+
+	// the gateway sees the request id of the request id middleware
+	var gatewayRequestID string
+	server.MountGateway(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gatewayRequestID = r.Header.Get(requestIDHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+	require.NoError(t, err)
+
+	server.Router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotEmpty(t, gatewayRequestID)
+	require.Equal(t, recorder.Header().Get(requestIDHeader), gatewayRequestID)
+}
+
+func TestMountGatewayAliases(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server := NewTestServer(t, mockdb.NewMockStore(ctrl))
+
+	// the gateway sees the versioned path + the client ip resolved by the router
+	var gatewayPath, gatewayRemoteAddr, gatewayForwardedFor string
+	server.MountGateway(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gatewayPath = r.URL.Path
+		gatewayRemoteAddr = r.RemoteAddr
+		gatewayForwardedFor = r.Header.Get("X-Forwarded-For")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testCases := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/users"},
+		{http.MethodPost, "/users/login"},
+		{http.MethodPost, "/accounts"},
+		{http.MethodGet, "/accounts"},
+		{http.MethodGet, "/accounts/1"},
+		{http.MethodPost, "/transfers"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method+tc.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(tc.method, tc.path, nil)
+			require.NoError(t, err)
+			// the forwarded ip of an untrusted client is ignored
+			request.RemoteAddr = "192.0.2.1:1234"
+			request.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+			server.Router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.Equal(t, "/v1"+tc.path, gatewayPath)
+			require.Equal(t, "192.0.2.1:0", gatewayRemoteAddr)
+			require.Empty(t, gatewayForwardedFor)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+)
+
+// sessionResponse - a session of the user, the refresh token itself is never returned
+type sessionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// newSessionResponse - create a new session response
+func newSessionResponse(session db.Session) sessionResponse {
+	return sessionResponse{
+		ID:        session.ID,
+		UserAgent: session.UserAgent,
+		ClientIp:  session.ClientIp,
+		ExpiresAt: session.ExpiresAt,
+		CreatedAt: session.CreatedAt,
+	}
+}
+
+// listSessions - API endpoint for listing the active (not blocked and not expired) sessions of the user
+func (server *Server) listSessions(ctx *gin.Context) {
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	sessions, err := server.store.ListActiveSessions(ctx, authPayload.Username)
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	rsp := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		rsp = append(rsp, newSessionResponse(session))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// revokeSessionRequest - type for getting the session id from the uri
+type revokeSessionRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// revokeSession - API endpoint for blocking a session of the user - its refresh token can no longer renew access tokens
+func (server *Server) revokeSession(ctx *gin.Context) {
+	var req revokeSessionRequest
+	// extracting the session id - if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// only a session of the user can be blocked - otherwise it's not found (404)
+	session, err := server.store.BlockSession(ctx, db.BlockSessionParams{
+		ID:       uuid.MustParse(req.ID),
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		writeError(ctx, storeError(err, errSessionNotFound))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newSessionResponse(session))
+}
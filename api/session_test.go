@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomSession(username string) db.Session {
+	return db.Session{
+		ID:               uuid.New(),
+		Username:         username,
+		RefreshTokenHash: util.HashSecret(util.RandomString(32)),
+		UserAgent:        "test-agent",
+		ClientIp:         "127.0.0.1",
+		ExpiresAt:        time.Now().Add(time.Hour).Truncate(time.Second),
+		CreatedAt:        time.Now().Truncate(time.Second),
+	}
+}
+
+func TestListSessionsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	sessions := []db.Session{randomSession(user.Username), randomSession(user.Username)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().
+		ListActiveSessions(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(sessions, nil)
+
+	server := NewTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "/sessions", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, req, server.token, authorizationTypeBearer, user.Username, time.Minute)
+	server.Router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// the refresh token hashes are never returned
+	require.NotContains(t, recorder.Body.String(), sessions[0].RefreshTokenHash)
+
+	var rsp []sessionResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Len(t, rsp, len(sessions))
+	for i := range sessions {
+		require.Equal(t, sessions[i].ID, rsp[i].ID)
+	}
+}
+
+func TestRevokeSessionAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	session := randomSession(user.Username)
+
+	testCases := []struct {
+		name          string
+		sessionID     string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:      "OK",
+		sessionID: session.ID.String(),
+		buildStubs: func(store *mockdb.MockStore) {
+			arg := db.BlockSessionParams{
+				ID:       session.ID,
+				Username: user.Username,
+			}
+			blocked := session
+			blocked.IsBlocked = true
+			store.EXPECT().
+				BlockSession(gomock.Any(), gomock.Eq(arg)).
+				Times(1).
+				Return(blocked, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp sessionResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Equal(t, session.ID, rsp.ID)
+		},
+	}, {
+		// a session of another user is not found
+		name:      "NotFound",
+		sessionID: session.ID.String(),
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				BlockSession(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.Session{}, db.ErrNotFound)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+		},
+	}, {
+		name:      "InvalidID",
+		sessionID: "not-a-uuid",
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				BlockSession(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/sessions/%s", tc.sessionID)
+
+			req, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, req, server.token, authorizationTypeBearer, user.Username, time.Minute)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+)
+
+// renewAccessTokenRequest - a type for renewing the access token with the refresh token of the session
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// renewAccessTokenResponse - a type for the renewed access token
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+// renewAccessToken - API endpoint for issuing a new access token for an active session
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	// extracting the request into the variable
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// verifying the refresh token - if invalid or expired return code 401(Unauthorized)
+	refreshPayload, err := server.token.VerifyToken(req.RefreshToken, token.TokenTypeRefreshToken)
+	if err != nil {
+		writeError(ctx, tokenError(err))
+		return
+	}
+
+	// a revoked refresh token (e.g. all the user tokens were revoked) can't renew the access token
+	revoked, err := server.revocations.IsRevoked(ctx, refreshPayload)
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+	if revoked {
+		writeError(ctx, token.ErrRevokedToken)
+		return
+	}
+
+	// getting the session of the refresh token
+	session, err := server.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		writeError(ctx, storeError(err, errSessionNotFound))
+		return
+	}
+
+	// the session must be active and belong to the same user as the refresh token
+	// otherwise return code 401(Unauthorized)
+	if session.IsBlocked {
+		writeError(ctx, newAPIError(http.StatusUnauthorized, CodeSessionInvalid, "blocked session"))
+		return
+	}
+	if session.Username != refreshPayload.Username {
+		writeError(ctx, newAPIError(http.StatusUnauthorized, CodeSessionInvalid, "incorrect session user"))
+		return
+	}
+	if session.RefreshTokenHash != util.HashSecret(req.RefreshToken) {
+		writeError(ctx, newAPIError(http.StatusUnauthorized, CodeSessionInvalid, "mismatched session token"))
+		return
+	}
+	if time.Now().After(session.ExpiresAt) {
+		writeError(ctx, newAPIError(http.StatusUnauthorized, CodeSessionInvalid, "expired session"))
+		return
+	}
+
+	// generating a new access token - with the role the user had at login
+	accessToken, accessPayload, err := server.token.CreateToken(session.Username, refreshPayload.Role, server.config.AccessTokenDuration, token.TokenTypeAccessToken)
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, renewAccessTokenResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	})
+}
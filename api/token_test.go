@@ -0,0 +1,248 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		username      string
+		duration      time.Duration
+		buildSession  func(session db.Session) db.Session
+		sessionErr    error
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:     "OK",
+		username: user.Username,
+		duration: time.Hour,
+		buildSession: func(session db.Session) db.Session {
+			return session
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp renewAccessTokenResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.NotEmpty(t, rsp.AccessToken)
+			require.WithinDuration(t, time.Now().Add(time.Minute), rsp.AccessTokenExpiresAt, time.Second)
+		},
+	}, {
+		name:     "ExpiredRefreshToken",
+		username: user.Username,
+		duration: -time.Second,
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name:       "SessionNotFound",
+		username:   user.Username,
+		duration:   time.Hour,
+		sessionErr: db.ErrNotFound,
+		buildSession: func(session db.Session) db.Session {
+			return db.Session{}
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+		},
+	}, {
+		name:     "BlockedSession",
+		username: user.Username,
+		duration: time.Hour,
+		buildSession: func(session db.Session) db.Session {
+			session.IsBlocked = true
+			return session
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name:     "IncorrectSessionUser",
+		username: user.Username,
+		duration: time.Hour,
+		buildSession: func(session db.Session) db.Session {
+			session.Username = "other"
+			return session
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name:     "MismatchedSessionToken",
+		username: user.Username,
+		duration: time.Hour,
+		buildSession: func(session db.Session) db.Session {
+			session.RefreshTokenHash = util.HashSecret("other")
+			return session
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name:     "ExpiredSession",
+		username: user.Username,
+		duration: time.Hour,
+		buildSession: func(session db.Session) db.Session {
+			session.ExpiresAt = time.Now().Add(-time.Second)
+			return session
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+
+			server := NewTestServer(t, store)
+			refreshToken, payload, err := server.token.CreateToken(tc.username, util.CustomerRole, tc.duration, token.TokenTypeRefreshToken)
+			require.NoError(t, err)
+
+			// the session as stored at login
+			if tc.buildSession != nil {
+				session := tc.buildSession(db.Session{
+					ID:               payload.ID,
+					Username:         payload.Username,
+					RefreshTokenHash: util.HashSecret(refreshToken),
+					ExpiresAt:        payload.ExpiredAt,
+				})
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, tc.sessionErr)
+			} else {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			}
+
+			reqBody, err := json.Marshal(renewAccessTokenRequest{RefreshToken: refreshToken})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewBuffer(reqBody))
+			require.NoError(t, err)
+
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// verifying the renewed access token authorizes requests as the session user
+func TestRenewedAccessToken(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	server := NewTestServer(t, store)
+	refreshToken, payload, err := server.token.CreateToken(user.Username, util.CustomerRole, time.Hour, token.TokenTypeRefreshToken)
+	require.NoError(t, err)
+
+	store.EXPECT().
+		GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+		Times(1).
+		Return(db.Session{
+			ID:               payload.ID,
+			Username:         user.Username,
+			RefreshTokenHash: util.HashSecret(refreshToken),
+			ExpiresAt:        payload.ExpiredAt,
+		}, nil)
+
+	reqBody, err := json.Marshal(renewAccessTokenRequest{RefreshToken: refreshToken})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewBuffer(reqBody))
+	require.NoError(t, err)
+	server.Router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp renewAccessTokenResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+
+	var accessPayload *token.Payload
+	accessPayload, err = server.token.VerifyToken(rsp.AccessToken, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, accessPayload.Username)
+	require.NotEqual(t, payload.ID, accessPayload.ID)
+}
+
+func TestRenewRevokedRefreshToken(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	// e.g. every token of the user was revoked
+	store.EXPECT().
+		IsTokenRevoked(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(true, nil)
+	store.EXPECT().
+		GetSession(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := NewTestServer(t, store)
+	refreshToken, _, err := server.token.CreateToken(user.Username, util.CustomerRole, time.Hour, token.TokenTypeRefreshToken)
+	require.NoError(t, err)
+
+	reqBody, err := json.Marshal(renewAccessTokenRequest{RefreshToken: refreshToken})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewBuffer(reqBody))
+	require.NoError(t, err)
+
+	server.Router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestRenewWithAccessToken(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	// an access token can't renew the access token - its session is never looked up
+	store.EXPECT().
+		GetSession(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := NewTestServer(t, store)
+	accessToken, _, err := server.token.CreateToken(user.Username, util.CustomerRole, time.Hour, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	reqBody, err := json.Marshal(renewAccessTokenRequest{RefreshToken: accessToken})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewBuffer(reqBody))
+	require.NoError(t, err)
+
+	server.Router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
@@ -1,44 +1,65 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/rules"
 	"github.com/shimon-git/simple-bank/token"
 )
 
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// errTransferAccessDenied - the from account doesn't belong to the authenticated user - code 401(Unauthorized)
+var errTransferAccessDenied = newAPIError(http.StatusUnauthorized, CodeTransferAccessDenied, "you are not authorized to make the transfer")
+
 /*
-* transferRequest -  type for creating a new account
+* exchangeTransferRequest - type for transferring money to an account of a different currency
 * 'binding': validator fields - build in the gin framework
-* 'oneof': validator input - the given input must be one of the 'oneof' values
+* QuoteID: the exchange quote (POST /fx/quotes) - locks the rate of the transfer
+* the transfers between accounts of the same currency are served by the gateway (POST /v1/transfers)
+* formerly POST /transfers with a quote_id - the gateway request has no quote id, so the cross currency transfers have their own route
  */
-type transferRequest struct {
+type exchangeTransferRequest struct {
 	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
 	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	QuoteID       string `json:"quote_id" binding:"required,uuid"`
 }
 
-// createAccount - API endpoint for creating a new bank account
-func (server *Server) createTransfer(ctx *gin.Context) {
-	var req transferRequest
+// createExchangeTransfer - API endpoint for transferring money by an exchange quote
+func (server *Server) createExchangeTransfer(ctx *gin.Context) {
+	var req exchangeTransferRequest
 	// if one of the required fields is missed - then return an error response
 	// also extracting the requests into the req variable
 	// if an error ocurred return code 400(BadRequest)
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, bindingError(err))
 		return
 	}
 
 	// getting the owner through the user name of the access token
 	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if !ok {
-		err := errors.New("failed to retrieve the payload data from the authorization token")
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// validating the idempotency key - must be checked before touching any account
+	idempotencyKey := ctx.GetHeader(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		writeError(ctx, invalidRequest(idempotencyKeyHeader, fmt.Sprintf("must have at most %d characters", maxIdempotencyKeyLength)))
 		return
 	}
 
@@ -50,57 +71,166 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 
 	// checking if the authenticated account is authorized to make the transfer
 	if fromAccount.Owner != authPayload.Username {
-		err := errors.New("you are not authorized to make the transfer")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		writeError(ctx, errTransferAccessDenied)
 		return
 	}
 
-	// validating the to account id + currency
-	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
-	if !valid {
+	// a large transfer demands a fresh second factor
+	if !server.requireFreshMFA(ctx, authPayload.Username, req.Amount) {
+		return
+	}
+
+	// the currency of the to account is validated against the quote by the store
+	if _, valid = server.existingAccount(ctx, req.ToAccountID); !valid {
 		return
 	}
 
-	// creating an account object for recording in the DB
 	arg := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
 		Amount:        req.Amount,
+		Audit:         auditParams(ctx, authPayload.Username),
+	}
+
+	// a retry with the same idempotency key returns the original transfer instead of a new one
+	if idempotencyKey != "" {
+		requestHash, err := rules.HashTransferRequest(rules.TransferRequest{
+			FromAccountID: req.FromAccountID,
+			ToAccountID:   req.ToAccountID,
+			Amount:        req.Amount,
+			Currency:      req.Currency,
+			QuoteID:       req.QuoteID,
+		})
+		if err != nil {
+			writeError(ctx, internalError(err))
+			return
+		}
+		arg.Idempotency = db.IdempotencyParams{
+			Username:    authPayload.Username,
+			Key:         idempotencyKey,
+			RequestHash: requestHash,
+		}
 	}
 
-	// inserting the account into the accounts table and checking for errors
+	// if the from account can't cover the amount return code 422(UnprocessableEntity)
+	// if the exchange quote can't be used return code 422(UnprocessableEntity)
+	// if the quote currencies don't match the accounts return code 400(BadRequest)
+	// if the idempotency key was used for a different request return code 422(UnprocessableEntity)
 	// if something goes wrong return code 500(InternalServerError)
-	result, err := server.store.TransferTx(ctx, arg)
+	result, err := server.store.ExchangeTransferTx(ctx, db.ExchangeTransferTxParams{
+		TransferTxParams: arg,
+		QuoteID:          uuid.MustParse(req.QuoteID),
+		Username:         authPayload.Username,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	// letting the client know the response is a replay of a previous request
+	if result.Replayed {
+		ctx.Header(idempotentReplayedHeader, "true")
+	}
+
+	// if all good returning the transfer and status OK
+	ctx.JSON(http.StatusOK, result)
+}
+
+// reverseTransferURI - type for getting the transfer id from the uri
+type reverseTransferURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+/*
+* reverseTransferRequest - type for reversing a transfer
+* Amount: optional - the amount returned to the sender, the whole remaining amount when omitted
+ */
+type reverseTransferRequest struct {
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+// reverseTransfer - API endpoint for refunding a transfer by its receiver
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var uri reverseTransferURI
+	// extracting the transfer id - if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// the body is optional - an empty body means a full reversal
+	var req reverseTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// getting the owner through the user name of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// getting the original transfer - if not found: 404(NotFound) else 500(InternalServerError)
+	transfer, err := server.store.GetTransfer(ctx, uri.ID)
+	if err != nil {
+		writeError(ctx, storeError(err, errTransferNotFound))
+		return
+	}
+
+	// only the receiver of the transfer can return the money
+	toAccount, valid := server.existingAccount(ctx, transfer.ToAccountID)
+	if !valid {
+		return
+	}
+	if toAccount.Owner != authPayload.Username {
+		writeError(ctx, newAPIError(http.StatusUnauthorized, CodeTransferAccessDenied, "you are not authorized to reverse the transfer"))
+		return
+	}
+
+	// reversing the transfer and checking for errors
+	// if the transfer was already fully reversed return code 409(Conflict)
+	// if the transfer is a reversal, the amount is too big or the balance can't cover it return code 422(UnprocessableEntity)
+	result, err := server.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{
+		TransferID: transfer.ID,
+		Amount:     req.Amount,
+		Audit:      auditParams(ctx, authPayload.Username),
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
-	// if all good returning the new account and status OK
+	// if all good returning the reversal and status OK
 	ctx.JSON(http.StatusOK, result)
 }
 
 // validAccount - validating the given account id + currency
 func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
 	// get the account by his details
-	account, err := server.store.GetAccount(ctx, accountID)
-	// if an error ocurred while trying to get the account
-	if err != nil {
-		// if the error was ocurred because the account wasn't found - status 400(StatusNotFound)
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return account, false
-		}
-		// if an error ocurred and it's not because the account wasn't found - status 500(StatusInternalServerError)
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	account, valid := server.existingAccount(ctx, accountID)
+	if !valid {
 		return account, false
 	}
 	// if the account currency is unmatched to the given currency - status 400(StatusBadRequest)
 	if account.Currency != currency {
-		err = fmt.Errorf("account [%d] currency mismatch: given currency is %s but expected currency is %s", accountID, currency, account.Currency)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		message := fmt.Sprintf("account [%d] currency mismatch: given currency is %s but expected currency is %s", accountID, currency, account.Currency)
+		writeError(ctx, newAPIError(http.StatusBadRequest, CodeCurrencyMismatch, message))
 		return account, false
 	}
 	// if the account is valid return true
 	return account, true
 }
+
+// existingAccount - getting the account by the given id, on error writing the error response
+func (server *Server) existingAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	// if an error ocurred while trying to get the account
+	if err != nil {
+		writeError(ctx, storeError(err, errAccountNotFound))
+		return account, false
+	}
+	return account, true
+}
@@ -2,64 +2,83 @@ package api
 
 import (
 	"bytes"
-	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	mockdb "github.com/shimon-git/simple-bank/db/mock"
 	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/rules"
 	"github.com/shimon-git/simple-bank/token"
 	"github.com/shimon-git/simple-bank/util"
 	"github.com/stretchr/testify/require"
 )
 
-func TestCreateTransferAPI(t *testing.T) {
+func TestCreateExchangeTransferAPI(t *testing.T) {
 	// creating random accounts for the transfer request
 	user1, _ := randomUser(t)
 	user2, _ := randomUser(t)
 	account1 := randomAccount(user1.Username)
 	account2 := randomAccount(user2.Username)
 
-	// setting the currency odf the random accounts to be equal
+	// the accounts hold different currencies - the rate is locked by the quote
 	account1.Currency = "ILS"
-	account2.Currency = "ILS"
+	account2.Currency = "USD"
+	quoteID := uuid.New()
 
 	// building the struct slices for testing case to be execute
 	testCases := []struct {
 		name          string
-		request       transferRequest
+		request       exchangeTransferRequest
 		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
 	}{{
 		name: "OK",
-		request: transferRequest{
+		request: exchangeTransferRequest{
 			FromAccountID: account1.ID,
 			ToAccountID:   account2.ID,
-			Amount:        util.RandomMoney(),
+			Amount:        100,
 			Currency:      account1.Currency,
+			QuoteID:       quoteID.String(),
 		},
 		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 		},
 		buildStubs: func(store *mockdb.MockStore) {
-			// building the expected function to be execute and the expected results
 			store.EXPECT().
 				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
 				Times(1).
 				Return(account1, nil)
 
+			// the to account currency is validated against the quote by the store
 			store.EXPECT().
 				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
 				Times(1).
 				Return(account2, nil)
 
+			arg := db.ExchangeTransferTxParams{
+				TransferTxParams: db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        100,
+					Audit: db.AuditParams{
+						Actor:     user1.Username,
+						RequestID: testRequestID,
+					},
+				},
+				QuoteID:  quoteID,
+				Username: user1.Username,
+			}
+
 			store.EXPECT().
-				TransferTx(gomock.Any(), gomock.Any()).
+				ExchangeTransferTx(gomock.Any(), gomock.Eq(arg)).
 				Times(1).
 				Return(db.TransferTxResult{
 					FromAccount: account1,
@@ -67,74 +86,241 @@ func TestCreateTransferAPI(t *testing.T) {
 				}, nil)
 		},
 		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
-			// check the http test response
 			require.Equal(t, http.StatusOK, recorded.Code)
 
-			// extracting the response in5o the result varia
+			// extracting the response into the result variable
 			var result db.TransferTxResult
 			err := json.Unmarshal(recorded.Body.Bytes(), &result)
 			require.NoError(t, err)
 			// comparing the results
 			require.Equal(t, account1, result.FromAccount)
 			require.Equal(t, account2, result.ToAccount)
+		},
+	}, {
+		name: "IdempotentReplay",
+		request: exchangeTransferRequest{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+			Currency:      account1.Currency,
+			QuoteID:       quoteID.String(),
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			request.Header.Set(idempotencyKeyHeader, "transfer-key")
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			// the request hash must be passed to the store together with the key
+			requestHash, err := rules.HashTransferRequest(rules.TransferRequest{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        10,
+				Currency:      account1.Currency,
+				QuoteID:       quoteID.String(),
+			})
+			require.NoError(t, err)
 
+			arg := db.ExchangeTransferTxParams{
+				TransferTxParams: db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        10,
+					Idempotency: db.IdempotencyParams{
+						Username:    user1.Username,
+						Key:         "transfer-key",
+						RequestHash: requestHash,
+					},
+					Audit: db.AuditParams{
+						Actor:     user1.Username,
+						RequestID: testRequestID,
+					},
+				},
+				QuoteID:  quoteID,
+				Username: user1.Username,
+			}
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Eq(arg)).
+				Times(1).
+				Return(db.TransferTxResult{
+					FromAccount: account1,
+					ToAccount:   account2,
+					Replayed:    true,
+				}, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+			require.Equal(t, "true", recorded.Header().Get(idempotentReplayedHeader))
 		},
 	}, {
-		name: "InvalidRequest",
-		request: transferRequest{
-			FromAccountID: 0, // invalid account ID
+		name: "IdempotencyKeyMismatch",
+		request: exchangeTransferRequest{
+			FromAccountID: account1.ID,
 			ToAccountID:   account2.ID,
 			Amount:        util.RandomMoney(),
-			Currency:      account2.Currency,
+			Currency:      account1.Currency,
+			QuoteID:       quoteID.String(),
 		},
 		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			request.Header.Set(idempotencyKeyHeader, "transfer-key")
 		},
 		buildStubs: func(store *mockdb.MockStore) {
 			store.EXPECT().
-				GetAccount(gomock.Any(), gomock.Eq(0)).
-				Times(0)
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
 
 			store.EXPECT().
 				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.TransferTxResult{}, db.ErrIdempotencyKeyMismatch)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}, {
+		name: "IdempotencyKeyTooLong",
+		request: exchangeTransferRequest{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        util.RandomMoney(),
+			Currency:      account1.Currency,
+			QuoteID:       quoteID.String(),
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			request.Header.Set(idempotencyKeyHeader, util.RandomString(maxIdempotencyKeyLength+1))
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
 				Times(0)
 		},
 		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
-			// expecting for status code 400(BadRequest)
 			require.Equal(t, http.StatusBadRequest, recorded.Code)
 		},
 	}, {
-		name: "AccountNotExist",
-		request: transferRequest{
-			FromAccountID: 9999,
+		name: "InsufficientFunds",
+		request: exchangeTransferRequest{
+			FromAccountID: account1.ID,
 			ToAccountID:   account2.ID,
-			Amount:        util.RandomMoney(),
-			Currency:      account2.Currency,
+			Amount:        account1.Balance + 1,
+			Currency:      account1.Currency,
+			QuoteID:       quoteID.String(),
 		},
 		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
 		},
 		buildStubs: func(store *mockdb.MockStore) {
 			store.EXPECT().
-				GetAccount(gomock.Any(), gomock.Eq(int64(9999))).
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
 				Times(1).
-				Return(db.Account{}, sql.ErrNoRows)
+				Return(account1, nil)
 
 			store.EXPECT().
 				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
 				Times(1).
 				Return(account2, nil)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.TransferTxResult{}, fmt.Errorf("transaction error: %w", db.ErrInsufficientFunds))
 		},
 		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
-			require.Equal(t, http.StatusNotFound, recorded.Code)
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
 		},
 	}, {
-		name: "UnMatchedCurrency",
-		request: transferRequest{
+		name: "QuoteUnavailable",
+		request: exchangeTransferRequest{
 			FromAccountID: account1.ID,
 			ToAccountID:   account2.ID,
-			Amount:        util.RandomMoney(),
-			Currency:      "USD",
+			Amount:        100,
+			Currency:      account1.Currency,
+			QuoteID:       quoteID.String(),
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.TransferTxResult{}, db.ErrQuoteUnavailable)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}, {
+		name: "ExchangeAmountTooSmall",
+		request: exchangeTransferRequest{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        1,
+			Currency:      account1.Currency,
+			QuoteID:       quoteID.String(),
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.TransferTxResult{}, fmt.Errorf("transaction error: %w", db.ErrExchangeAmountTooSmall))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+			require.Contains(t, recorded.Body.String(), CodeExchangeAmountTooSmall)
+		},
+	}, {
+		name: "QuoteCurrencyMismatch",
+		request: exchangeTransferRequest{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        100,
+			Currency:      account1.Currency,
+			QuoteID:       quoteID.String(),
 		},
 		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
@@ -149,6 +335,136 @@ func TestCreateTransferAPI(t *testing.T) {
 				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
 				Times(1).
 				Return(account2, nil)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.TransferTxResult{}, db.ErrCurrencyMismatch)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "InvalidQuoteID",
+		request: exchangeTransferRequest{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        100,
+			Currency:      account1.Currency,
+			QuoteID:       "invalid-quote",
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "MissingQuoteID",
+		request: exchangeTransferRequest{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        100,
+			Currency:      account1.Currency,
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			// the transfers of the same currency are served by the gateway
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name: "UnauthorizedUser",
+		request: exchangeTransferRequest{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        100,
+			Currency:      account1.Currency,
+			QuoteID:       quoteID.String(),
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name: "AccountNotExist",
+		request: exchangeTransferRequest{
+			FromAccountID: account1.ID,
+			ToAccountID:   9999,
+			Amount:        100,
+			Currency:      account1.Currency,
+			QuoteID:       quoteID.String(),
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(int64(9999))).
+				Times(1).
+				Return(db.Account{}, db.ErrNotFound)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+		},
+	}, {
+		name: "UnMatchedCurrency",
+		request: exchangeTransferRequest{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        100,
+			Currency:      account2.Currency,
+			QuoteID:       quoteID.String(),
+		},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			// the currency of the request must be the currency of the from account
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				Times(1).
+				Return(account1, nil)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
 		},
 		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
 			require.Equal(t, http.StatusBadRequest, recorded.Code)
@@ -158,12 +474,10 @@ func TestCreateTransferAPI(t *testing.T) {
 	// looping through the test cases
 	for testIDX := range testCases {
 		test := testCases[testIDX]
-		// run sub tests in parallel(goroutines)
 		t.Run(test.name, func(t *testing.T) {
 			// creating mock controller
 			ctrl := gomock.NewController(t)
-			// check the related methods of the controller was finished
-			ctrl.Finish()
+			defer ctrl.Finish()
 			// creating a mockdb controller
 			store := mockdb.NewMockStore(ctrl)
 
@@ -175,7 +489,7 @@ func TestCreateTransferAPI(t *testing.T) {
 			// creating a recorder
 			recorder := httptest.NewRecorder()
 			// specify the url path
-			url := "/transfers"
+			url := "/fx/transfers"
 
 			// creating transfer http request & checking for errors
 			reqBody, err := json.Marshal(test.request)
@@ -184,6 +498,7 @@ func TestCreateTransferAPI(t *testing.T) {
 
 			req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(reqBody))
 			require.NoError(t, err)
+			req.Header.Set(requestIDHeader, testRequestID)
 			// adding the authorization header
 			test.setupAuth(t, req, server.token)
 			// sending the request to the router
@@ -192,5 +507,234 @@ func TestCreateTransferAPI(t *testing.T) {
 			test.checkResponse(t, recorder)
 		})
 	}
+}
 
+func TestReverseTransferAPI(t *testing.T) {
+	// the sender and the receiver of the original transfer
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+
+	transfer := db.Transfer{
+		ID:            util.RandomInt(1, 1000),
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		ToAmount:      100,
+		ExchangeRate:  1,
+	}
+
+	testCases := []struct {
+		name          string
+		transferID    int64
+		body          interface{}
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:       "FullReversal",
+		transferID: transfer.ID,
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+				Times(1).
+				Return(transfer, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				ReverseTransferTx(gomock.Any(), gomock.Eq(db.ReverseTransferTxParams{
+					TransferID: transfer.ID,
+					Audit: db.AuditParams{
+						Actor:     user2.Username,
+						RequestID: testRequestID,
+					},
+				})).
+				Times(1).
+				Return(db.TransferTxResult{FromAccount: account2, ToAccount: account1}, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name:       "PartialReversal",
+		transferID: transfer.ID,
+		body:       gin.H{"amount": 40},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+				Times(1).
+				Return(transfer, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			arg := db.ReverseTransferTxParams{
+				TransferID: transfer.ID,
+				Amount:     40,
+				Audit: db.AuditParams{
+					Actor:     user2.Username,
+					RequestID: testRequestID,
+				},
+			}
+			store.EXPECT().
+				ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).
+				Times(1).
+				Return(db.TransferTxResult{FromAccount: account2, ToAccount: account1}, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name:       "AlreadyReversed",
+		transferID: transfer.ID,
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+				Times(1).
+				Return(transfer, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				ReverseTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.TransferTxResult{}, fmt.Errorf("transaction error: %w", db.ErrTransferAlreadyReversed))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusConflict, recorded.Code)
+		},
+	}, {
+		name:       "AmountTooBig",
+		transferID: transfer.ID,
+		body:       gin.H{"amount": 101},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+				Times(1).
+				Return(transfer, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				ReverseTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.TransferTxResult{}, fmt.Errorf("transaction error: %w", db.ErrInvalidReversalAmount))
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}, {
+		name:       "NotTheReceiver",
+		transferID: transfer.ID,
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+				Times(1).
+				Return(transfer, nil)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				Times(1).
+				Return(account2, nil)
+
+			store.EXPECT().
+				ReverseTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name:       "TransferNotFound",
+		transferID: transfer.ID,
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+				Times(1).
+				Return(db.Transfer{}, db.ErrNotFound)
+
+			store.EXPECT().
+				ReverseTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+		},
+	}, {
+		name:       "InvalidAmount",
+		transferID: transfer.ID,
+		body:       gin.H{"amount": -1},
+		setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetTransfer(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/transfers/%d/reverse", tc.transferID)
+
+			// the body is optional
+			var body bytes.Buffer
+			if tc.body != nil {
+				err := json.NewEncoder(&body).Encode(tc.body)
+				require.NoError(t, err)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, url, &body)
+			require.NoError(t, err)
+			req.Header.Set(requestIDHeader, testRequestID)
+
+			tc.setupAuth(t, req, server.token)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
 }
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/rules"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/totp"
+	"github.com/shimon-git/simple-bank/util"
+)
+
+const (
+	// mfaCodeHeader - the header of the fresh totp code demanded by the large transfers
+	mfaCodeHeader = "X-MFA-Code"
+	// the number + length of the recovery codes given when the two factor authentication is enabled
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+// different types of errors returned by the two factor authentication - shared with the gRPC server
+var (
+	ErrInvalidMFACode = rules.ErrInvalidMFACode
+	ErrMFANotEnabled  = rules.ErrMFANotEnabled
+)
+
+// errMFANotEnrolled - the enrollment of the user wasn't started - code 404(NotFound)
+var errMFANotEnrolled = newAPIError(http.StatusNotFound, CodeMFANotEnabled, "two factor authentication enrollment wasn't started")
+
+// mfaError - the API error of a failed second factor (ErrInvalidMFACode or ErrMFANotEnabled), with the status of the caller
+func mfaError(status int, err error) *APIError {
+	code := CodeInvalidMFACode
+	if errors.Is(err, ErrMFANotEnabled) {
+		code = CodeMFANotEnabled
+	}
+	return newAPIError(status, code, err.Error()).withCause(err)
+}
+
+/*
+* loginUserMFARequest - a type for completing the login with the second factor
+* Code: the current code of the authenticator app, or RecoveryCode: one of the recovery codes of the user
+ */
+type loginUserMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" binding:"omitempty,len=10,alpha"`
+}
+
+// loginUserMFA - API endpoint for exchanging a mfa pending token + a second factor for the tokens of the user
+func (server *Server) loginUserMFA(ctx *gin.Context) {
+	var req loginUserMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// verifying the mfa pending token - only the tokens issued by the login can be exchanged
+	payload, err := server.token.VerifyToken(req.MFAToken, token.TokenTypeMFAToken)
+	if err != nil {
+		writeError(ctx, tokenError(err))
+		return
+	}
+	if !payload.HasScope(token.ScopeMFAVerify) {
+		writeError(ctx, tokenError(token.ErrInvalidToken))
+		return
+	}
+	// rejecting a mfa token that was already exchanged
+	revoked, err := server.revocations.IsRevoked(ctx, payload)
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+	if revoked {
+		writeError(ctx, token.ErrRevokedToken)
+		return
+	}
+
+	// the second factor is throttled like the password - the attempt is counted before the code is verified
+	throttle, ok := server.countLoginAttempt(ctx, payload.Username)
+	if !ok {
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// verifying the second factor - a failure is recorded like a failed password
+	if req.Code != "" {
+		err = rules.UseTotpCode(ctx, server.store, user.Username, req.Code)
+	} else {
+		_, err = server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			Username: user.Username,
+			CodeHash: util.HashSecret(req.RecoveryCode),
+		})
+		if errors.Is(err, db.ErrNotFound) {
+			err = ErrInvalidMFACode
+		}
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) || errors.Is(err, ErrMFANotEnabled) {
+			if server.recordFailedLogin(ctx, user.Username, throttle, "incorrect second factor") {
+				writeError(ctx, mfaError(http.StatusUnauthorized, err))
+			}
+			return
+		}
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// the mfa token is single-use
+	if err = server.revocations.Revoke(ctx, payload); err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	server.startSession(ctx, user)
+}
+
+/*
+* requireFreshMFA - demanding a fresh second factor (the X-MFA-Code header) for a transfer above the configured threshold
+* the codes are throttled like the logins - a wrong code is recorded as a failed login, so the user is locked out after the max attempts
+* returns false when the transfer can't be made - the error response is already written
+ */
+func (server *Server) requireFreshMFA(ctx *gin.Context, username string, amount int64) bool {
+	if !rules.StepUpRequired(server.config, amount) {
+		return true
+	}
+
+	code := ctx.GetHeader(mfaCodeHeader)
+	if code == "" {
+		message := fmt.Sprintf("transfers above %d require a two factor code in the %s header", server.config.MFATransferThreshold, mfaCodeHeader)
+		writeError(ctx, newAPIError(http.StatusUnauthorized, CodeMFARequired, message))
+		return false
+	}
+
+	err := rules.VerifyStepUpCode(ctx, server.store, server.config, loginAttempt(ctx, username), code)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMFANotEnabled):
+			// the user must enable two factor authentication first - code 403(Forbidden)
+			writeError(ctx, mfaError(http.StatusForbidden, err))
+		case errors.Is(err, ErrInvalidMFACode):
+			writeError(ctx, mfaError(http.StatusUnauthorized, err))
+		default:
+			writeError(ctx, loginAttemptError(ctx, err))
+		}
+		return false
+	}
+	return true
+}
+
+/*
+* enrollTotpResponse - the totp secret of the user
+* OTPAuthURI: the secret as an otpauth URI - usually shown as a QR code for the authenticator app
+ */
+type enrollTotpResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+/*
+* enrollTotp - API endpoint for starting the two factor authentication enrollment of the user
+* the enrollment is pending until confirmed with a valid code (POST /users/me/mfa/totp/confirm)
+ */
+func (server *Server) enrollTotp(ctx *gin.Context) {
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	// a pending enrollment is restarted with the new secret, an enabled one can't be replaced - code 409(Conflict)
+	userTotp, err := server.store.UpsertUserTotp(ctx, db.UpsertUserTotpParams{
+		Username: authPayload.Username,
+		Secret:   secret,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			err = db.ErrTotpAlreadyEnabled
+		}
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTotpResponse{
+		Secret:     userTotp.Secret,
+		OTPAuthURI: totp.URI(server.config.MFAIssuer, userTotp.Username, userTotp.Secret),
+	})
+}
+
+// confirmTotpRequest - type for confirming the totp enrollment with the current code of the authenticator app
+type confirmTotpRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+/*
+* confirmTotpResponse - the recovery codes of the user
+* shown only once - each code can replace the authenticator app for a single login
+ */
+type confirmTotpResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTotp - API endpoint for enabling the two factor authentication of the user
+func (server *Server) confirmTotp(ctx *gin.Context) {
+	var req confirmTotpRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
+		return
+	}
+
+	userTotp, err := server.store.GetUserTotp(ctx, authPayload.Username)
+	if err != nil {
+		writeError(ctx, storeError(err, errMFANotEnrolled))
+		return
+	}
+	if userTotp.ConfirmedAt.Valid {
+		writeError(ctx, db.ErrTotpAlreadyEnabled)
+		return
+	}
+
+	// the code proves the authenticator app has the secret - if not return code 422(UnprocessableEntity)
+	step, err := totp.Validate(userTotp.Secret, req.Code, time.Now())
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	// the recovery codes - only their hashes are stored
+	recoveryCodes := make([]string, recoveryCodeCount)
+	recoveryCodeHashes := make([]string, recoveryCodeCount)
+	for i := range recoveryCodes {
+		recoveryCodes[i], err = util.RandomSecret(recoveryCodeLength)
+		if err != nil {
+			writeError(ctx, internalError(err))
+			return
+		}
+		recoveryCodeHashes[i] = util.HashSecret(recoveryCodes[i])
+	}
+
+	_, err = server.store.ConfirmTotpTx(ctx, db.ConfirmTotpTxParams{
+		Username:           authPayload.Username,
+		Step:               step,
+		RecoveryCodeHashes: recoveryCodeHashes,
+		Audit:              auditParams(ctx, authPayload.Username),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, confirmTotpResponse{RecoveryCodes: recoveryCodes})
+}
@@ -0,0 +1,720 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/totp"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// randomUserTotp - returning a random totp enrollment of the user
+func randomUserTotp(t *testing.T, username string, confirmed bool) db.UserTotp {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	userTotp := db.UserTotp{
+		Username:  username,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+	if confirmed {
+		userTotp.ConfirmedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	return userTotp
+}
+
+// currentTotpCode - the current code of the secret, as shown by the authenticator app
+func currentTotpCode(t *testing.T, secret string) string {
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func TestLoginUserMFAAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	userTotp := randomUserTotp(t, user.Username, true)
+	recoveryCode := "abcdefghij"
+
+	testCases := []struct {
+		name          string
+		role          string
+		body          func(mfaToken string) gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		role: token.MFAPendingRole,
+		body: func(mfaToken string) gin.H {
+			return gin.H{"mfa_token": mfaToken, "code": currentTotpCode(t, userTotp.Secret)}
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(user, nil)
+
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(userTotp, nil)
+
+			// the step of the code is marked as used
+			store.EXPECT().
+				UseTotpStep(gomock.Any(), gomock.Eq(db.UseTotpStepParams{
+					Username:     user.Username,
+					LastUsedStep: totp.Step(time.Now()),
+				})).
+				Times(1).
+				Return(userTotp, nil)
+
+			// the mfa token is single-use
+			store.EXPECT().
+				CreateRevokedToken(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(nil)
+
+			store.EXPECT().
+				CreateSessionTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.CreateSessionTxParams) (db.Session, error) {
+					return db.Session{ID: arg.ID, Username: arg.Username}, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp loginUserResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.NotEmpty(t, rsp.AccessToken)
+			require.NotEmpty(t, rsp.RefreshToken)
+			require.Equal(t, user.Username, rsp.User.Username)
+		},
+	}, {
+		name: "RecoveryCode",
+		role: token.MFAPendingRole,
+		body: func(mfaToken string) gin.H {
+			return gin.H{"mfa_token": mfaToken, "recovery_code": recoveryCode}
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(user, nil)
+
+			// only the hash of the recovery code is compared
+			store.EXPECT().
+				UseRecoveryCode(gomock.Any(), gomock.Eq(db.UseRecoveryCodeParams{
+					Username: user.Username,
+					CodeHash: util.HashSecret(recoveryCode),
+				})).
+				Times(1).
+				Return(db.RecoveryCode{}, nil)
+
+			store.EXPECT().
+				CreateRevokedToken(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(nil)
+
+			store.EXPECT().
+				CreateSessionTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.CreateSessionTxParams) (db.Session, error) {
+					return db.Session{ID: arg.ID, Username: arg.Username}, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name: "IncorrectCode",
+		role: token.MFAPendingRole,
+		body: func(mfaToken string) gin.H {
+			return gin.H{"mfa_token": mfaToken, "code": "000000"}
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(user, nil)
+
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(randomUserTotp(t, user.Username, true), nil)
+
+			store.EXPECT().
+				RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.RecordFailedLoginTxParams) (db.RecordFailedLoginTxResult, error) {
+					require.Equal(t, user.Username, arg.Username)
+					return db.RecordFailedLoginTxResult{}, nil
+				})
+
+			store.EXPECT().
+				CreateSessionTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name: "UsedCode",
+		role: token.MFAPendingRole,
+		body: func(mfaToken string) gin.H {
+			return gin.H{"mfa_token": mfaToken, "code": currentTotpCode(t, userTotp.Secret)}
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(user, nil)
+
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(userTotp, nil)
+
+			// the step of the code was already used
+			store.EXPECT().
+				UseTotpStep(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.UserTotp{}, db.ErrNotFound)
+
+			store.EXPECT().
+				RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.RecordFailedLoginTxResult{}, nil)
+
+			store.EXPECT().
+				CreateSessionTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name: "Throttled",
+		role: token.MFAPendingRole,
+		body: func(mfaToken string) gin.H {
+			return gin.H{"mfa_token": mfaToken, "code": currentTotpCode(t, userTotp.Secret)}
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			// the second factor can't be guessed while the user is locked out
+			store.EXPECT().
+				ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.ThrottleAttemptsTxResult{LockedUntil: time.Now().Add(time.Minute)}, db.ErrThrottled)
+
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusTooManyRequests, recorded.Code)
+		},
+	}, {
+		name: "AccessToken",
+		role: user.Role,
+		body: func(mfaToken string) gin.H {
+			return gin.H{"mfa_token": mfaToken, "code": currentTotpCode(t, userTotp.Secret)}
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			// only a mfa pending token can be exchanged
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name: "MissingCode",
+		role: token.MFAPendingRole,
+		body: func(mfaToken string) gin.H {
+			return gin.H{"mfa_token": mfaToken}
+		},
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			mfaToken, _, err := server.token.CreateToken(user.Username, tc.role, time.Minute, token.TokenTypeMFAToken)
+			require.NoError(t, err)
+
+			reqBody, err := json.Marshal(tc.body(mfaToken))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewBuffer(reqBody))
+			require.NoError(t, err)
+
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestEnrollTotpAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				UpsertUserTotp(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.UpsertUserTotpParams) (db.UserTotp, error) {
+					require.Equal(t, user.Username, arg.Username)
+					require.NotEmpty(t, arg.Secret)
+					return db.UserTotp{Username: arg.Username, Secret: arg.Secret}, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp enrollTotpResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+
+			uri, err := url.Parse(rsp.OTPAuthURI)
+			require.NoError(t, err)
+			require.Equal(t, "otpauth", uri.Scheme)
+			require.Equal(t, rsp.Secret, uri.Query().Get("secret"))
+		},
+	}, {
+		name: "AlreadyEnabled",
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				UpsertUserTotp(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.UserTotp{}, db.ErrNotFound)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusConflict, recorded.Code)
+		},
+	}, {
+		name: "InternalError",
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				UpsertUserTotp(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.UserTotp{}, sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodPost, "/users/me/mfa/totp", nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, req, server.token, authorizationTypeBearer, user.Username, time.Minute)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestConfirmTotpAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	userTotp := randomUserTotp(t, user.Username, false)
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name: "OK",
+		code: currentTotpCode(t, userTotp.Secret),
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(userTotp, nil)
+
+			store.EXPECT().
+				ConfirmTotpTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.ConfirmTotpTxParams) (db.UserTotp, error) {
+					require.Equal(t, user.Username, arg.Username)
+					require.Equal(t, totp.Step(time.Now()), arg.Step)
+					require.Len(t, arg.RecoveryCodeHashes, recoveryCodeCount)
+					require.Equal(t, db.AuditParams{Actor: user.Username, RequestID: testRequestID}, arg.Audit)
+					return userTotp, nil
+				})
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp confirmTotpResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.Len(t, rsp.RecoveryCodes, recoveryCodeCount)
+			for _, code := range rsp.RecoveryCodes {
+				require.Len(t, code, recoveryCodeLength)
+			}
+		},
+	}, {
+		name: "IncorrectCode",
+		code: "000000",
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(randomUserTotp(t, user.Username, false), nil)
+
+			store.EXPECT().
+				ConfirmTotpTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}, {
+		name: "NotEnrolled",
+		code: currentTotpCode(t, userTotp.Secret),
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(db.UserTotp{}, db.ErrNotFound)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusNotFound, recorded.Code)
+		},
+	}, {
+		name: "AlreadyEnabled",
+		code: currentTotpCode(t, userTotp.Secret),
+		buildStubs: func(store *mockdb.MockStore) {
+			confirmed := userTotp
+			confirmed.ConfirmedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(confirmed, nil)
+
+			store.EXPECT().
+				ConfirmTotpTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusConflict, recorded.Code)
+		},
+	}, {
+		name: "InvalidCode",
+		code: "abc",
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			reqBody, err := json.Marshal(confirmTotpRequest{Code: tc.code})
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/me/mfa/totp/confirm", bytes.NewBuffer(reqBody))
+			require.NoError(t, err)
+			req.Header.Set(requestIDHeader, testRequestID)
+
+			addAuthorization(t, req, server.token, authorizationTypeBearer, user.Username, time.Minute)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestTransferFreshMFA(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = util.ILS
+	account2.Currency = util.USD
+	userTotp := randomUserTotp(t, user1.Username, true)
+
+	const threshold = 100
+
+	testCases := []struct {
+		name          string
+		amount        int64
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:   "BelowThreshold",
+		amount: threshold,
+		buildStubs: func(store *mockdb.MockStore) {
+			// no code is verified - the attempt isn't counted
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.TransferTxResult{}, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name:   "FreshCode",
+		amount: threshold + 1,
+		code:   currentTotpCode(t, userTotp.Secret),
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Eq(user1.Username)).
+				Times(1).
+				Return(userTotp, nil)
+
+			expectCountedAttempt(t, store, user1.Username)
+
+			store.EXPECT().
+				UseTotpStep(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(userTotp, nil)
+
+			// the counted attempt of a correct code is forgotten
+			store.EXPECT().
+				ForgetLoginAttemptTx(gomock.Any(), gomock.Eq(db.ForgetLoginAttemptTxParams{
+					Username: user1.Username,
+					ClientIP: "192.0.2.1",
+				})).
+				Times(1).
+				Return(nil)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.TransferTxResult{}, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name:   "MissingCode",
+		amount: threshold + 1,
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name:   "IncorrectCode",
+		amount: threshold + 1,
+		code:   "000000",
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Eq(user1.Username)).
+				Times(1).
+				Return(randomUserTotp(t, user1.Username, true), nil)
+
+			expectCountedAttempt(t, store, user1.Username)
+
+			// a wrong code is counted like a failed login
+			store.EXPECT().
+				RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.RecordFailedLoginTxParams) (db.RecordFailedLoginTxResult, error) {
+					require.Equal(t, user1.Username, arg.Username)
+					return db.RecordFailedLoginTxResult{}, nil
+				})
+
+			store.EXPECT().
+				ForgetLoginAttemptTx(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnauthorized, recorded.Code)
+		},
+	}, {
+		name:   "Throttled",
+		amount: threshold + 1,
+		code:   currentTotpCode(t, userTotp.Secret),
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Eq(user1.Username)).
+				Times(1).
+				Return(userTotp, nil)
+
+			// the code can't be guessed while the user is locked out
+			store.EXPECT().
+				ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.ThrottleAttemptsTxResult{LockedUntil: time.Now().Add(time.Minute)}, db.ErrThrottled)
+
+			store.EXPECT().
+				UseTotpStep(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusTooManyRequests, recorded.Code)
+		},
+	}, {
+		name:   "MFANotEnabled",
+		amount: threshold + 1,
+		code:   "123456",
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUserTotp(gomock.Any(), gomock.Eq(user1.Username)).
+				Times(1).
+				Return(db.UserTotp{}, db.ErrNotFound)
+
+			// there is no code to guess - the retries of the user don't count towards its throttle
+			store.EXPECT().
+				ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				ForgetLoginAttemptTx(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			store.EXPECT().
+				ExchangeTransferTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+				AnyTimes().
+				Return(account1, nil)
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+				AnyTimes().
+				Return(account2, nil)
+			tc.buildStubs(store)
+
+			// the counted attempts are expected exactly - the default throttle stubs would hide a leaked attempt
+			server := newStrictTestServer(t, store)
+			server.config.MFATransferThreshold = threshold
+
+			reqBody, err := json.Marshal(exchangeTransferRequest{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        tc.amount,
+				Currency:      account1.Currency,
+				QuoteID:       uuid.New().String(),
+			})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodPost, "/fx/transfers", bytes.NewBuffer(reqBody))
+			require.NoError(t, err)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tc.code != "" {
+				req.Header.Set(mfaCodeHeader, tc.code)
+			}
+
+			addAuthorization(t, req, server.token, authorizationTypeBearer, user1.Username, time.Minute)
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// expectCountedAttempt - expecting a single counted (not throttled) attempt of the username and the client ip of the test requests
+func expectCountedAttempt(t *testing.T, store *mockdb.MockStore, username string) {
+	store.EXPECT().
+		ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.ThrottleAttemptsTxParams) (db.ThrottleAttemptsTxResult, error) {
+			require.Len(t, arg.Attempts, 2)
+			require.Equal(t, db.UserThrottleKey(username), arg.Attempts[0].Key)
+			require.Equal(t, db.IPThrottleKey("192.0.2.1"), arg.Attempts[1].Key)
+
+			var result db.ThrottleAttemptsTxResult
+			for _, attempt := range arg.Attempts {
+				result.Throttles = append(result.Throttles, db.LoginThrottle{Key: attempt.Key, FailedAttempts: 1})
+			}
+			return result, nil
+		})
+}
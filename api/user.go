@@ -1,33 +1,24 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/google/uuid"
 	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
 	"github.com/shimon-git/simple-bank/util"
 )
 
-/*
-* createAccountRequest -  type for creating a new account
-* 'binding': validator fields - build in the gin framework
-* 'oneof': validator input - the given input must be one of the 'oneof' values
- */
-type createUserRequest struct {
-	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
-	FullName string `json:"full_name" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
-}
-
 type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -38,106 +29,113 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
 }
 
-// createAccount - API endpoint for creating a new bank account
-func (server *Server) createUser(ctx *gin.Context) {
-	var req createUserRequest
-	// if one of the required fields is missed - then return an error response
-	// also extracting the requests into the req variable
-	// if an error ocurred return code 400(BadRequest)
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return
-	}
+/*
+* loginUserResponse - a type for user login response
+* AccessToken: the short-lived token authorizing the requests
+* RefreshToken: the long-lived token of the session, renews the access token (POST /tokens/renew_access)
+ */
+type loginUserResponse struct {
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
+}
 
-	hashedPassword, err := util.HashedPassword(req.Password)
+// startSession - issuing the tokens of the user and recording the session of the login, writes the login response
+func (server *Server) startSession(ctx *gin.Context, user db.User) {
+	// generating an access token
+	accessToken, accessPayload, err := server.token.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration, token.TokenTypeAccessToken)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, internalError(err))
+		return
 	}
-
-	// creating an account object for recording in the DB
-	arg := db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: hashedPassword,
-		FullName:       req.FullName,
-		Email:          req.Email,
+	// generating a refresh token - the id of its payload identifies the session
+	refreshToken, refreshPayload, err := server.token.CreateToken(user.Username, user.Role, server.config.RefreshTokenDuration, token.TokenTypeRefreshToken)
+	if err != nil {
+		writeError(ctx, internalError(err))
+		return
 	}
-
-	// inserting the account into the accounts table and checking for errors
-	// if something goes wrong return code 500(InternalServerError)
-	user, err := server.store.CreateUser(ctx, arg)
+	// recording the session of the refresh token (only its hash is stored) + the login in the audit trail
+	session, err := server.store.CreateSessionTx(ctx, db.CreateSessionTxParams{
+		CreateSessionParams: db.CreateSessionParams{
+			ID:               refreshPayload.ID,
+			Username:         user.Username,
+			RefreshTokenHash: util.HashSecret(refreshToken),
+			UserAgent:        ctx.Request.UserAgent(),
+			ClientIp:         ctx.ClientIP(),
+			IsBlocked:        false,
+			ExpiresAt:        refreshPayload.ExpiredAt,
+		},
+		Audit: auditParams(ctx, user.Username),
+	})
 	if err != nil {
-		// checking if the error related to a foreign key or unique error in DB - code 403(StatusForbidden)
-		if pqerr, ok := err.(*pq.Error); ok {
-			switch pqerr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, internalError(err))
 		return
 	}
-
-	// creating a response
-	rsp := newUserResponse(user)
-
-	// if all good returning the new account and status OK
-	ctx.JSON(http.StatusOK, rsp)
+	// creating the response
+	res := loginUserResponse{
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
+	}
+	// sending the response
+	ctx.JSON(http.StatusOK, res)
 }
 
-// loginUserRequest - a type for user login request
-type loginUserRequest struct {
-	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+/*
+* logoutUserRequest - a type for user logout request
+* SessionID: optional - the session of the client, blocked so its refresh token can't renew access tokens
+ */
+type logoutUserRequest struct {
+	SessionID string `json:"session_id" binding:"omitempty,uuid"`
 }
 
-// loginUserResponse - a type for user login response
-type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
-}
+// logoutUser - API endpoint for revoking the access token of the request (and optionally blocking its session)
+func (server *Server) logoutUser(ctx *gin.Context) {
+	// the body is optional - an empty body only revokes the access token
+	var req logoutUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(ctx, bindingError(err))
+		return
+	}
 
-// loginUser - API endpoint for users login
-func (server *Server) loginUser(ctx *gin.Context) {
-	// extracting the request into the variable
-	var req loginUserRequest
-	if err := ctx.BindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	// getting the current token through the payload of the access token
+	authPayload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !ok {
+		err := errors.New(payloadRetrieveErr)
+		writeError(ctx, internalError(err))
 		return
 	}
 
-	// querying the DB for the desired username
-	user, err := server.store.GetUser(ctx, req.Username)
-	// checking for errors
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+	// blocking the session - only a session of the user can be blocked
+	if req.SessionID != "" {
+		_, err := server.store.BlockSession(ctx, db.BlockSessionParams{
+			ID:       uuid.MustParse(req.SessionID),
+			Username: authPayload.Username,
+		})
+		if err != nil {
+			writeError(ctx, storeError(err, errSessionNotFound))
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
 	}
-	// verifying the password is correct
-	if err = util.CheckPassword(req.Password, user.HashedPassword); err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
-		return
-	}
-	// generating an access token
-	accessToken, err := server.token.CreateToken(user.Username, server.config.AccessTokenDuration)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+
+	// revoking the access token until its expiration
+	if err := server.revocations.Revoke(ctx, authPayload); err != nil {
+		writeError(ctx, internalError(err))
 		return
 	}
-	// creating the response
-	res := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
-	}
-	// sending the response
-	ctx.JSON(http.StatusOK, res)
+
+	ctx.JSON(http.StatusOK, gin.H{})
 }
@@ -5,61 +5,52 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
-	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
-	"github.com/lib/pq"
+	"github.com/google/uuid"
 	mockdb "github.com/shimon-git/simple-bank/db/mock"
 	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
 	"github.com/shimon-git/simple-bank/util"
 	"github.com/stretchr/testify/require"
 )
 
-/*
- * creating custom eqCreateUserParamsMatcher + receivers
- * this customization needed because each time we generating a new hash to the same password
- * this custom eqCreateUserParamsMatcher was created to handle with this 'problem'
- */
-
-// eqCreateUserParamsMatcher -  custom type for comparing between 2 users + passwords
-type eqCreateUserParamsMatcher struct {
-	arg      db.CreateUserParams
-	password string
-}
-
-// Matches - return true if the there is a match otherwise return false
-func (e eqCreateUserParamsMatcher) Matches(x interface{}) bool {
-	arg, ok := x.(db.CreateUserParams)
-	if !ok {
-		return false
-	}
+func randomUser(t *testing.T) (user db.User, password string) {
+	password = util.RandomString(6)
+	passwordHash, err := util.HashedPassword(password)
+	require.NoError(t, err)
 
-	if err := util.CheckPassword(e.password, arg.HashedPassword); err != nil {
-		return false
+	user = db.User{
+		Username:       util.RandomString(6),
+		HashedPassword: passwordHash,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+		Role:           util.CustomerRole,
 	}
-
-	e.arg.HashedPassword = arg.HashedPassword
-
-	return reflect.DeepEqual(e.arg, arg)
+	return user, password
 }
 
-// String - return a string log
-func (e eqCreateUserParamsMatcher) String() string {
-	return fmt.Sprintf("matches arg %v and password %v", e.arg, e.password)
-}
+// requireBodyMatchUser - comparing the user that we request to create and the response of this request
+func requireBodyMatchUser(t *testing.T, recordedBody *bytes.Buffer, user db.User) {
+	var res userResponse
+	err := json.NewDecoder(recordedBody).Decode(&res)
+	require.NoError(t, err)
 
-// EqreateUserParams - this function responsible
-// to call the eqCreateUserParamsMatcher with the given params and return Matcher  type
-func EqreateUserParams(arg db.CreateUserParams, password string) gomock.Matcher {
-	return eqCreateUserParamsMatcher{arg, password}
+	require.Equal(t, user.Username, res.Username)
+	require.Equal(t, user.Role, res.Role)
+	require.Equal(t, user.FullName, res.FullName)
+	require.Equal(t, user.Email, res.Email)
 }
 
-func TestCreateUser(t *testing.T) {
-	user, password := randomUser(t)
+func TestLogoutUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	sessionID := uuid.New()
 
 	testCases := []struct {
 		name          string
@@ -68,107 +59,82 @@ func TestCreateUser(t *testing.T) {
 		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
 	}{{
 		name: "OK",
-		body: gin.H{
-			"username":  user.Username,
-			"password":  password,
-			"full_name": user.FullName,
-			"email":     user.Email,
-		},
 		buildStubs: func(store *mockdb.MockStore) {
-			arg := db.CreateUserParams{
-				Username: user.Username,
-				FullName: user.FullName,
-				Email:    user.Email,
-			}
 			store.EXPECT().
-				CreateUser(gomock.Any(), EqreateUserParams(arg, password)).
-				Times(1).
-				Return(user, nil)
-		}, checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
-			require.Equal(t, http.StatusOK, recorded.Code)
-			requireBodyMatchUser(t, recorded.Body, user)
-		},
-	}, {
-		name: "InternalError",
-		body: gin.H{
-			"username":  user.Username,
-			"password":  password,
-			"full_name": user.FullName,
-			"email":     user.Email,
-		},
-		buildStubs: func(store *mockdb.MockStore) {
+				BlockSession(gomock.Any(), gomock.Any()).
+				Times(0)
+
 			store.EXPECT().
-				CreateUser(gomock.Any(), gomock.Any()).
+				CreateRevokedToken(gomock.Any(), gomock.Any()).
 				Times(1).
-				Return(db.User{}, sql.ErrConnDone)
+				DoAndReturn(func(_ interface{}, arg db.CreateRevokedTokenParams) error {
+					require.Equal(t, user.Username, arg.Username)
+					require.NotZero(t, arg.ID)
+					require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+					return nil
+				})
 		},
 		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
-			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+			require.Equal(t, http.StatusOK, recorded.Code)
 		},
 	}, {
-		name: "DuplicateUsername",
-		body: gin.H{
-			"username":  user.Username,
-			"password":  password,
-			"full_name": user.FullName,
-			"email":     user.Email,
-		},
+		name: "WithSession",
+		body: gin.H{"session_id": sessionID.String()},
 		buildStubs: func(store *mockdb.MockStore) {
+			arg := db.BlockSessionParams{
+				ID:       sessionID,
+				Username: user.Username,
+			}
+			store.EXPECT().
+				BlockSession(gomock.Any(), gomock.Eq(arg)).
+				Times(1).
+				Return(db.Session{ID: sessionID, Username: user.Username, IsBlocked: true}, nil)
+
 			store.EXPECT().
-				CreateUser(gomock.Any(), gomock.Any()).
+				CreateRevokedToken(gomock.Any(), gomock.Any()).
 				Times(1).
-				Return(db.User{}, &pq.Error{Code: "23505"})
+				Return(nil)
 		},
 		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
-			require.Equal(t, http.StatusForbidden, recorded.Code)
+			require.Equal(t, http.StatusOK, recorded.Code)
 		},
 	}, {
-		name: "InvalidUsername",
-		body: gin.H{
-			"username":  "invalid-user#",
-			"password":  password,
-			"full_name": user.FullName,
-			"email":     user.Email,
-		},
+		name: "SessionNotFound",
+		body: gin.H{"session_id": sessionID.String()},
 		buildStubs: func(store *mockdb.MockStore) {
 			store.EXPECT().
-				CreateUser(gomock.Any(), gomock.Any()).
+				BlockSession(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.Session{}, db.ErrNotFound)
+
+			store.EXPECT().
+				CreateRevokedToken(gomock.Any(), gomock.Any()).
 				Times(0)
 		},
 		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
-			require.Equal(t, http.StatusBadRequest, recorded.Code)
+			require.Equal(t, http.StatusNotFound, recorded.Code)
 		},
 	}, {
-		name: "InvalidEmail",
-		body: gin.H{
-			"username":  user.Username,
-			"password":  password,
-			"full_name": user.FullName,
-			"email":     "invalid-email",
-		},
+		name: "InvalidSessionID",
+		body: gin.H{"session_id": "not-a-uuid"},
 		buildStubs: func(store *mockdb.MockStore) {
 			store.EXPECT().
-				CreateUser(gomock.Any(), gomock.Any()).
+				CreateRevokedToken(gomock.Any(), gomock.Any()).
 				Times(0)
 		},
 		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
 			require.Equal(t, http.StatusBadRequest, recorded.Code)
 		},
 	}, {
-		name: "TooShortPassword",
-		body: gin.H{
-			"username":  user.Username,
-			"password":  "123",
-			"full_name": user.FullName,
-			"email":     "invalid-email",
-		},
+		name: "InternalError",
 		buildStubs: func(store *mockdb.MockStore) {
 			store.EXPECT().
-				CreateUser(gomock.Any(), gomock.Any()).
-				Times(0)
+				CreateRevokedToken(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(sql.ErrConnDone)
 		},
 		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
-			require.Equal(t, http.StatusBadRequest, recorded.Code)
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
 		},
 	}}
 
@@ -181,41 +147,60 @@ func TestCreateUser(t *testing.T) {
 
 			server := NewTestServer(t, store)
 			recorder := httptest.NewRecorder()
-			url := "/users"
 
-			reqBody, err := json.Marshal(tc.body)
-			require.NoError(t, err)
+			var body io.Reader = http.NoBody
+			if tc.body != nil {
+				reqBody, err := json.Marshal(tc.body)
+				require.NoError(t, err)
+				body = bytes.NewBuffer(reqBody)
+			}
 
-			req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(reqBody))
+			req, err := http.NewRequest(http.MethodPost, "/users/logout", body)
 			require.NoError(t, err)
 
+			addAuthorization(t, req, server.token, authorizationTypeBearer, user.Username, time.Minute)
 			server.Router.ServeHTTP(recorder, req)
 			tc.checkResponse(t, recorder)
 		})
 	}
 }
 
-func randomUser(t *testing.T) (user db.User, password string) {
-	password = util.RandomString(6)
-	passwordHash, err := util.HashedPassword(password)
+// the revoked token is rejected right after the logout - without checking the store again
+func TestLoggedOutTokenRejected(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().
+		IsTokenRevoked(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(false, nil)
+	store.EXPECT().
+		CreateRevokedToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil)
+	store.EXPECT().
+		ListActiveSessions(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := NewTestServer(t, store)
+	accessToken, _, err := server.token.CreateToken(user.Username, util.CustomerRole, time.Minute, token.TokenTypeAccessToken)
 	require.NoError(t, err)
+	authorizationHeader := fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken)
 
-	user = db.User{
-		Username:       util.RandomString(6),
-		HashedPassword: passwordHash,
-		FullName:       util.RandomOwner(),
-		Email:          util.RandomEmail(),
-	}
-	return user, password
-}
-
-// requireBodyMatchUser - comparing the user that we request to create and the response of this request
-func requireBodyMatchUser(t *testing.T, recordedBody *bytes.Buffer, user db.User) {
-	var res userResponse
-	err := json.NewDecoder(recordedBody).Decode(&res)
+	recorder := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/users/logout", http.NoBody)
 	require.NoError(t, err)
+	req.Header.Set(authorizationHeaderKey, authorizationHeader)
+	server.Router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
 
-	require.Equal(t, user.Username, res.Username)
-	require.Equal(t, user.FullName, res.FullName)
-	require.Equal(t, user.Email, res.Email)
+	recorder = httptest.NewRecorder()
+	req, err = http.NewRequest(http.MethodGet, "/sessions", nil)
+	require.NoError(t, err)
+	req.Header.Set(authorizationHeaderKey, authorizationHeader)
+	server.Router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
 }
@@ -14,3 +14,12 @@ var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 	}
 	return false
 }
+
+var validRecurrence validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	// extracting the field as a string and checking the recurrence rule is supported
+	recurrence, ok := fieldLevel.Field().Interface().(string)
+	if ok {
+		return util.IsSupportedRecurrence(recurrence)
+	}
+	return false
+}
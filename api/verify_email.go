@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/rules"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+)
+
+// ErrEmailNotVerified - the user must verify the email before moving money
+var ErrEmailNotVerified = newAPIError(http.StatusForbidden, CodeEmailNotVerified, rules.ErrEmailNotVerified.Error())
+
+/*
+* verifyEmailRequest - type for verifying the email of a user - the params of the link sent to the user
+* 'form': the params are read from the query string
+ */
+type verifyEmailRequest struct {
+	ID   int64  `form:"id" binding:"required,min=1"`
+	Code string `form:"code" binding:"required,len=32"`
+}
+
+// verifyEmailResponse - the result of the email verification
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+// verifyEmail - API endpoint for verifying the email of a user with the code sent by email
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	// extracting the query params into the req variable
+	// if an error ocurred return code 400(BadRequest)
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, bindingError(err))
+		return
+	}
+
+	// using the code + marking the email of the user as verified
+	result, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		ID:             req.ID,
+		SecretCodeHash: util.HashSecret(req.Code),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, verifyEmailResponse{IsVerified: result.User.IsEmailVerified})
+}
+
+/*
+* requireVerifiedEmail - a middleware that allows the request only when the email of the user is verified
+* the check is enabled by the REQUIRE_VERIFIED_EMAIL configuration
+ */
+func (server *Server) requireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// the payload is set by the auth middleware
+		payload, ok := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !ok {
+			err := errors.New(payloadRetrieveErr)
+			writeError(ctx, internalError(err))
+			return
+		}
+
+		// if the email is not verified return code 403(Forbidden)
+		err := rules.RequireVerifiedEmail(ctx, server.store, server.config, payload.Username)
+		if err != nil {
+			if errors.Is(err, rules.ErrEmailNotVerified) {
+				writeError(ctx, ErrEmailNotVerified.withCause(err))
+				return
+			}
+			writeError(ctx, internalError(err))
+			return
+		}
+		// passing the request to the next handler
+		ctx.Next()
+	}
+}
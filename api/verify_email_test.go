@@ -0,0 +1,207 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	user.IsEmailVerified = true
+	code := util.RandomString(32)
+	id := util.RandomInt(1, 1000)
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:  "OK",
+		query: fmt.Sprintf("id=%d&code=%s", id, code),
+		buildStubs: func(store *mockdb.MockStore) {
+			// only the hash of the code is compared
+			store.EXPECT().
+				VerifyEmailTx(gomock.Any(), gomock.Eq(db.VerifyEmailTxParams{
+					ID:             id,
+					SecretCodeHash: util.HashSecret(code),
+				})).
+				Times(1).
+				Return(db.VerifyEmailTxResult{User: user}, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+
+			var rsp verifyEmailResponse
+			err := json.Unmarshal(recorded.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.True(t, rsp.IsVerified)
+		},
+	}, {
+		name:  "UnavailableCode",
+		query: fmt.Sprintf("id=%d&code=%s", id, code),
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				VerifyEmailTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.VerifyEmailTxResult{}, db.ErrVerifyEmailUnavailable)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusUnprocessableEntity, recorded.Code)
+		},
+	}, {
+		name:  "InternalError",
+		query: fmt.Sprintf("id=%d&code=%s", id, code),
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				VerifyEmailTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.VerifyEmailTxResult{}, sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}, {
+		name:  "InvalidID",
+		query: fmt.Sprintf("id=0&code=%s", code),
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				VerifyEmailTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}, {
+		name:  "InvalidCode",
+		query: fmt.Sprintf("id=%d&code=%s", id, code[:10]),
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				VerifyEmailTx(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusBadRequest, recorded.Code)
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "/verify_email?"+tc.query, nil)
+			require.NoError(t, err)
+
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRequireVerifiedEmailMiddleware(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		required      bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorded *httptest.ResponseRecorder)
+	}{{
+		name:     "NotRequired",
+		required: false,
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				Times(0)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name:     "Verified",
+		required: true,
+		buildStubs: func(store *mockdb.MockStore) {
+			verified := user
+			verified.IsEmailVerified = true
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(verified, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorded.Code)
+		},
+	}, {
+		name:     "NotVerified",
+		required: true,
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(user, nil)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusForbidden, recorded.Code)
+		},
+	}, {
+		name:     "InternalError",
+		required: true,
+		buildStubs: func(store *mockdb.MockStore) {
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.User{}, sql.ErrConnDone)
+		},
+		checkResponse: func(t *testing.T, recorded *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusInternalServerError, recorded.Code)
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			server.config.RequireVerifiedEmail = tc.required
+			// the uri path
+			path := "/verified"
+			// the endpoint requires a verified email when configured
+			server.Router.GET(
+				path,
+				authMiddleware(server.token, server.revocations),
+				server.requireVerifiedEmail(),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+			require.NoError(t, err)
+			addAuthorization(t, req, server.token, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.Router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
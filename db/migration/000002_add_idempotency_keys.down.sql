@@ -0,0 +1 @@
+DROP Table IF EXISTS idempotency_keys;
\ No newline at end of file
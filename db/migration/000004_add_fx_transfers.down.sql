@@ -0,0 +1,3 @@
+DROP Table IF EXISTS fx_quotes;
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "exchange_rate";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "to_amount";
\ No newline at end of file
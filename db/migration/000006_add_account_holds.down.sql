@@ -0,0 +1 @@
+DROP Table IF EXISTS account_holds;
\ No newline at end of file
@@ -0,0 +1,25 @@
+CREATE TABLE "account_holds" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'active',
+  "transfer_id" bigint,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  CONSTRAINT "hold_amount_positive" CHECK ("amount" > 0)
+);
+
+CREATE INDEX ON "account_holds" ("account_id", "status");
+
+COMMENT ON COLUMN "account_holds"."amount" IS 'the reserved amount - must be positive';
+
+COMMENT ON COLUMN "account_holds"."status" IS 'active, captured or voided - an active hold stops reserving funds once expired';
+
+COMMENT ON COLUMN "account_holds"."transfer_id" IS 'the transfer created when the hold was captured';
+
+ALTER TABLE "account_holds" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "account_holds" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "account_holds" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,2 @@
+DROP Table IF EXISTS scheduled_transfer_runs;
+DROP Table IF EXISTS scheduled_transfers;
\ No newline at end of file
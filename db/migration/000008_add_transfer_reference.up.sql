@@ -0,0 +1,3 @@
+ALTER TABLE "transfers" ADD COLUMN "reference" varchar NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN "transfers"."reference" IS 'a free text reference of the payer - e.g. the payroll line of a batch transfer';
@@ -0,0 +1,20 @@
+CREATE TABLE "balance_snapshots" (
+  "account_id" bigint NOT NULL,
+  "entry_id" bigint NOT NULL,
+  "balance" bigint NOT NULL,
+  "covered_until" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("account_id", "entry_id")
+);
+
+CREATE INDEX ON "entries" ("account_id", "id");
+
+CREATE INDEX ON "entries" ("account_id", "created_at");
+
+COMMENT ON COLUMN "balance_snapshots"."entry_id" IS 'the last entry included - the snapshot covers the account entries up to this id';
+
+COMMENT ON COLUMN "balance_snapshots"."balance" IS 'the sum of the covered entries';
+
+COMMENT ON COLUMN "balance_snapshots"."covered_until" IS 'the creation time of the newest covered entry';
+
+ALTER TABLE "balance_snapshots" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
@@ -0,0 +1,28 @@
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'the transfer the entry belongs to - null for entries made without a transfer';
+
+-- linking the existing entries to their transfers
+-- the entries of a transfer are created in its transaction (same created_at as the transfer)
+-- identical transfers of the same transaction are paired with the entries in id order
+WITH sides AS (
+    SELECT id, from_account_id AS account_id, -amount AS amount, created_at,
+        row_number() OVER (PARTITION BY from_account_id, amount, created_at ORDER BY id) AS n
+    FROM transfers
+    UNION ALL
+    SELECT id, to_account_id AS account_id, to_amount AS amount, created_at,
+        row_number() OVER (PARTITION BY to_account_id, to_amount, created_at ORDER BY id) AS n
+    FROM transfers
+), ranked_entries AS (
+    SELECT id, account_id, amount, created_at,
+        row_number() OVER (PARTITION BY account_id, amount, created_at ORDER BY id) AS n
+    FROM entries
+)
+UPDATE entries SET transfer_id = sides.id
+FROM ranked_entries
+JOIN sides USING (account_id, amount, created_at, n)
+WHERE entries.id = ranked_entries.id;
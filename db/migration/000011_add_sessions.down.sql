@@ -0,0 +1 @@
+DROP Table IF EXISTS sessions;
@@ -0,0 +1,2 @@
+DROP Table IF EXISTS revoked_tokens;
+DROP Table IF EXISTS user_token_revocations;
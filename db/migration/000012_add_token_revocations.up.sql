@@ -0,0 +1,23 @@
+CREATE TABLE "revoked_tokens" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE "user_token_revocations" (
+  "username" varchar PRIMARY KEY,
+  "revoked_before" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "revoked_tokens" ("expires_at");
+
+COMMENT ON COLUMN "revoked_tokens"."id" IS 'the id of the token payload';
+
+COMMENT ON COLUMN "revoked_tokens"."expires_at" IS 'the token expiration - the row can be pruned afterwards';
+
+COMMENT ON COLUMN "user_token_revocations"."revoked_before" IS 'every token of the user issued before this time is revoked';
+
+ALTER TABLE "revoked_tokens" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "user_token_revocations" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,2 @@
+DROP Table IF EXISTS balance_adjustments;
+ALTER TABLE "users" DROP COLUMN IF EXISTS "role";
@@ -0,0 +1,27 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'customer';
+
+ALTER TABLE "users" ADD CONSTRAINT "users_role_check" CHECK ("role" IN ('customer', 'banker', 'admin'));
+
+COMMENT ON COLUMN "users"."role" IS 'customer, banker or admin - embedded in the tokens of the user';
+
+CREATE TABLE "balance_adjustments" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "entry_id" bigint UNIQUE NOT NULL,
+  "amount" bigint NOT NULL,
+  "reason" varchar NOT NULL,
+  "created_by" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ON "balance_adjustments" ("account_id");
+
+COMMENT ON COLUMN "balance_adjustments"."amount" IS 'can be negative or positive';
+
+COMMENT ON COLUMN "balance_adjustments"."created_by" IS 'the admin who adjusted the balance';
+
+ALTER TABLE "balance_adjustments" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "balance_adjustments" ADD FOREIGN KEY ("entry_id") REFERENCES "entries" ("id");
+
+ALTER TABLE "balance_adjustments" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
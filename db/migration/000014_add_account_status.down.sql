@@ -0,0 +1,2 @@
+DROP Table IF EXISTS audit_events;
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "status";
@@ -0,0 +1,25 @@
+ALTER TABLE "accounts" ADD COLUMN "status" varchar NOT NULL DEFAULT 'active';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_status_check" CHECK ("status" IN ('active', 'frozen', 'closed'));
+
+COMMENT ON COLUMN "accounts"."status" IS 'the money of frozen and closed accounts can''t be moved';
+
+CREATE TABLE "audit_events" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar NOT NULL,
+  "action" varchar NOT NULL,
+  "target" varchar NOT NULL,
+  "before" jsonb NOT NULL,
+  "after" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ON "audit_events" ("actor");
+
+CREATE INDEX ON "audit_events" ("target");
+
+COMMENT ON COLUMN "audit_events"."target" IS 'the changed (or viewed) object - e.g. account:42';
+
+COMMENT ON COLUMN "audit_events"."before" IS 'the target before the action - json null when there is nothing to record';
+
+ALTER TABLE "audit_events" ADD FOREIGN KEY ("actor") REFERENCES "users" ("username");
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS "audit_events_no_truncate" ON "audit_events";
+DROP TRIGGER IF EXISTS "audit_events_append_only" ON "audit_events";
+DROP FUNCTION IF EXISTS audit_events_append_only();
+ALTER TABLE "audit_events" DROP COLUMN IF EXISTS "hash", DROP COLUMN IF EXISTS "prev_hash", DROP COLUMN IF EXISTS "request_id";
+ALTER TABLE "audit_events" ALTER COLUMN "before" TYPE jsonb, ALTER COLUMN "after" TYPE jsonb;
+ALTER TABLE "audit_events" ADD FOREIGN KEY ("actor") REFERENCES "users" ("username");
@@ -0,0 +1,60 @@
+-- the audit trail records actions of unknown users (e.g. failed logins) and must outlive the users
+ALTER TABLE "audit_events" DROP CONSTRAINT IF EXISTS "audit_events_actor_fkey";
+
+-- json keeps the exact text of the values - the hash is computed over the text as it was written
+ALTER TABLE "audit_events" ALTER COLUMN "before" TYPE json, ALTER COLUMN "after" TYPE json;
+
+ALTER TABLE "audit_events" ADD COLUMN "request_id" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "audit_events" ADD COLUMN "prev_hash" bytea NOT NULL DEFAULT '';
+
+ALTER TABLE "audit_events" ADD COLUMN "hash" bytea NOT NULL DEFAULT '';
+
+-- chaining the events recorded so far - the same hash as computed by the application (db.AuditEventHash)
+CREATE FUNCTION pg_temp.audit_hash_field(value bytea) RETURNS bytea AS $$
+  SELECT int8send(octet_length(value)::bigint) || value
+$$ LANGUAGE sql IMMUTABLE;
+
+DO $$
+DECLARE
+  event record;
+  prev bytea := '';
+BEGIN
+  FOR event IN SELECT * FROM "audit_events" ORDER BY "id" LOOP
+    UPDATE "audit_events" SET
+      "prev_hash" = prev,
+      "hash" = sha256(
+        pg_temp.audit_hash_field(prev) ||
+        pg_temp.audit_hash_field(convert_to(event."actor", 'UTF8')) ||
+        pg_temp.audit_hash_field(convert_to(event."action", 'UTF8')) ||
+        pg_temp.audit_hash_field(convert_to(event."target", 'UTF8')) ||
+        pg_temp.audit_hash_field(convert_to(event."request_id", 'UTF8')) ||
+        pg_temp.audit_hash_field(convert_to(event."before"::text, 'UTF8')) ||
+        pg_temp.audit_hash_field(convert_to(event."after"::text, 'UTF8')) ||
+        pg_temp.audit_hash_field(convert_to(to_char(event."created_at" AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'), 'UTF8'))
+      )
+    WHERE "id" = event."id"
+    RETURNING "hash" INTO prev;
+  END LOOP;
+END $$;
+
+ALTER TABLE "audit_events" ALTER COLUMN "prev_hash" DROP DEFAULT, ALTER COLUMN "hash" DROP DEFAULT;
+
+COMMENT ON COLUMN "audit_events"."request_id" IS 'the X-Request-ID of the API request (or the job) which did the action';
+
+COMMENT ON COLUMN "audit_events"."prev_hash" IS 'the hash of the previous event - empty for the first event';
+
+COMMENT ON COLUMN "audit_events"."hash" IS 'sha256 of the previous hash and the event - editing or deleting an event breaks the chain';
+
+-- the audit trail is append-only
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit events are append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_events_append_only" BEFORE UPDATE OR DELETE ON "audit_events"
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER "audit_events_no_truncate" BEFORE TRUNCATE ON "audit_events"
+FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
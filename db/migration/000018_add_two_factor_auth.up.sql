@@ -0,0 +1,29 @@
+CREATE TABLE "user_totps" (
+  "username" varchar PRIMARY KEY,
+  "secret" varchar NOT NULL,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "confirmed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("username", "code_hash");
+
+COMMENT ON COLUMN "user_totps"."secret" IS 'the base32 totp secret shared with the authenticator app of the user';
+
+COMMENT ON COLUMN "user_totps"."last_used_step" IS 'the step of the last accepted code - a code can be used only once';
+
+COMMENT ON COLUMN "user_totps"."confirmed_at" IS 'null until the user confirmed the enrollment with a valid code';
+
+COMMENT ON COLUMN "recovery_codes"."code_hash" IS 'sha256 of the single-use recovery code - the code itself is never stored';
+
+ALTER TABLE "user_totps" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
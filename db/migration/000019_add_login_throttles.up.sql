@@ -0,0 +1,8 @@
+CREATE TABLE "login_throttles" (
+  "key" varchar PRIMARY KEY,
+  "failed_attempts" int NOT NULL DEFAULT 0,
+  "locked_until" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z',
+  "last_failed_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "login_throttles"."key" IS 'user:<username> or ip:<client ip> - the failed logins are counted per username and per client ip';
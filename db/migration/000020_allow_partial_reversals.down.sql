@@ -0,0 +1,5 @@
+-- the unique index can't be restored once a transfer has several partial reversals
+-- the plain index is kept - the older code checks for an existing reversal before adding one
+DROP INDEX IF EXISTS "transfers_reversal_of_idx";
+
+CREATE INDEX ON "transfers" ("reversal_of");
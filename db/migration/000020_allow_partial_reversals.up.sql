@@ -0,0 +1,4 @@
+-- a transfer can be refunded by several partial reversals up to its amount
+DROP INDEX IF EXISTS "transfers_reversal_of_idx";
+
+CREATE INDEX ON "transfers" ("reversal_of");
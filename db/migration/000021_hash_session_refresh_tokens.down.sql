@@ -0,0 +1,6 @@
+-- the refresh tokens can't be recovered from their hashes - the existing sessions are blocked
+UPDATE "sessions" SET "is_blocked" = true;
+
+COMMENT ON COLUMN "sessions"."refresh_token_hash" IS NULL;
+
+ALTER TABLE "sessions" RENAME COLUMN "refresh_token_hash" TO "refresh_token";
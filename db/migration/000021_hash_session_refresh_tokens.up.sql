@@ -0,0 +1,6 @@
+ALTER TABLE "sessions" RENAME COLUMN "refresh_token" TO "refresh_token_hash";
+
+-- hashing the refresh tokens of the existing sessions
+UPDATE "sessions" SET "refresh_token_hash" = encode(sha256(convert_to("refresh_token_hash", 'UTF8')), 'hex');
+
+COMMENT ON COLUMN "sessions"."refresh_token_hash" IS 'the sha256 (hex) of the refresh token - the token itself is never stored';
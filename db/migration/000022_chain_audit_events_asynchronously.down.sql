@@ -0,0 +1,18 @@
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit events are append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+-- the unchained events are kept with an empty hash - the verification reports them
+ALTER TABLE "audit_events" DISABLE TRIGGER "audit_events_append_only";
+UPDATE "audit_events" SET "prev_hash" = '', "hash" = '' WHERE "chain_seq" IS NULL;
+ALTER TABLE "audit_events" ENABLE TRIGGER "audit_events_append_only";
+
+ALTER TABLE "audit_events" ALTER COLUMN "prev_hash" SET NOT NULL, ALTER COLUMN "hash" SET NOT NULL;
+
+ALTER TABLE "audit_events" DROP COLUMN IF EXISTS "chain_seq";
+
+COMMENT ON COLUMN "audit_events"."prev_hash" IS 'the hash of the previous event - empty for the first event';
+
+COMMENT ON COLUMN "audit_events"."hash" IS 'sha256 of the previous hash and the event - editing or deleting an event breaks the chain';
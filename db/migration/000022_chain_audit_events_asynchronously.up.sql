@@ -0,0 +1,30 @@
+-- the events are chained by a single appender after their transactions commit - an event is unchained until then
+ALTER TABLE "audit_events" ADD COLUMN "chain_seq" bigint UNIQUE;
+
+ALTER TABLE "audit_events" ALTER COLUMN "prev_hash" DROP NOT NULL, ALTER COLUMN "hash" DROP NOT NULL;
+
+-- the events recorded so far were chained in the order of their ids
+ALTER TABLE "audit_events" DISABLE TRIGGER "audit_events_append_only";
+UPDATE "audit_events" SET "chain_seq" = "id";
+ALTER TABLE "audit_events" ENABLE TRIGGER "audit_events_append_only";
+
+CREATE INDEX ON "audit_events" ("id") WHERE "chain_seq" IS NULL;
+
+COMMENT ON COLUMN "audit_events"."chain_seq" IS 'the position of the event in the hash chain - null until the appender chains the event';
+
+COMMENT ON COLUMN "audit_events"."prev_hash" IS 'the hash of the previous event in the chain - empty for the first event, null until chained';
+
+COMMENT ON COLUMN "audit_events"."hash" IS 'sha256 of the previous hash and the event - editing or deleting an event breaks the chain, null until chained';
+
+-- the audit trail is append-only - the appender only sets the chain of an unchained event
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'UPDATE' AND OLD."chain_seq" IS NULL AND NEW."chain_seq" IS NOT NULL
+    AND (NEW."id", NEW."actor", NEW."action", NEW."target", NEW."request_id", NEW."before"::text, NEW."after"::text, NEW."created_at")
+      IS NOT DISTINCT FROM
+        (OLD."id", OLD."actor", OLD."action", OLD."target", OLD."request_id", OLD."before"::text, OLD."after"::text, OLD."created_at") THEN
+    RETURN NEW;
+  END IF;
+  RAISE EXCEPTION 'audit events are append-only';
+END;
+$$ LANGUAGE plpgsql;
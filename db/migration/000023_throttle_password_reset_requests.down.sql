@@ -0,0 +1,3 @@
+DELETE FROM "login_throttles" WHERE "key" LIKE 'reset\_%';
+
+COMMENT ON COLUMN "login_throttles"."key" IS 'user:<username> or ip:<client ip> - the failed logins are counted per username and per client ip';
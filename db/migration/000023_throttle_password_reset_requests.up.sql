@@ -0,0 +1 @@
+COMMENT ON COLUMN "login_throttles"."key" IS 'user:<username> or ip:<client ip> for the failed logins, reset_email:<email> or reset_ip:<client ip> for the password reset requests';
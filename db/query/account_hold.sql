@@ -0,0 +1,43 @@
+-- name: CreateAccountHold :one
+insert into account_holds (
+    account_id,
+    to_account_id,
+    amount,
+    expires_at
+)
+values (
+    $1, $2, $3, $4
+) RETURNING *;
+
+
+-- name: GetAccountHold :one
+SELECT * FROM account_holds
+WHERE id = $1 LIMIT 1;
+
+-- name: GetAccountHeldAmount :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS held_amount FROM account_holds
+WHERE account_id = $1
+AND status = 'active'
+AND expires_at > now();
+
+-- name: CaptureAccountHold :one
+UPDATE account_holds
+set status = 'captured'
+WHERE id = $1
+AND status = 'active'
+AND expires_at > now()
+RETURNING *;
+
+-- name: SetAccountHoldTransfer :one
+UPDATE account_holds
+set transfer_id = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: VoidAccountHold :one
+UPDATE account_holds
+set status = 'voided'
+WHERE id = $1
+AND status = 'active'
+AND expires_at > now()
+RETURNING *;
@@ -0,0 +1,51 @@
+-- name: CreateAuditEvent :one
+insert into audit_events (
+    actor,
+    action,
+    target,
+    request_id,
+    before,
+    after,
+    created_at
+)
+values (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE target = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: LockAuditLog :exec
+SELECT pg_advisory_xact_lock(hashtext('audit_events'));
+
+-- name: GetLastChainedAuditEvent :one
+SELECT * FROM audit_events
+WHERE chain_seq IS NOT NULL
+ORDER BY chain_seq DESC
+LIMIT 1;
+
+-- name: ListUnchainedAuditEvents :many
+SELECT * FROM audit_events
+WHERE chain_seq IS NULL
+ORDER BY id
+LIMIT $1;
+
+-- name: ChainAuditEvent :exec
+UPDATE audit_events
+SET chain_seq = $2, prev_hash = $3, hash = $4
+WHERE id = $1
+AND chain_seq IS NULL;
+
+-- name: CountUnchainedAuditEvents :one
+SELECT count(*) FROM audit_events
+WHERE chain_seq IS NULL;
+
+-- name: ListChainedAuditEvents :many
+SELECT * FROM audit_events
+WHERE chain_seq > sqlc.arg(after_seq)::bigint
+ORDER BY chain_seq
+LIMIT sqlc.arg(page_size);
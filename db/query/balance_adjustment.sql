@@ -0,0 +1,18 @@
+-- name: CreateBalanceAdjustment :one
+insert into balance_adjustments (
+    account_id,
+    entry_id,
+    amount,
+    reason,
+    created_by
+)
+values (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListBalanceAdjustments :many
+SELECT * FROM balance_adjustments
+WHERE account_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
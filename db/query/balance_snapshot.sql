@@ -0,0 +1,47 @@
+-- name: CreateBalanceSnapshots :execrows
+WITH latest AS (
+    SELECT DISTINCT ON (account_id) account_id, entry_id, balance, covered_until
+    FROM balance_snapshots
+    ORDER BY account_id, entry_id DESC
+), boundary AS (
+    SELECT COALESCE(MAX(id), 0)::bigint AS entry_id FROM entries
+    WHERE created_at < sqlc.arg(before)
+)
+INSERT INTO balance_snapshots (
+    account_id,
+    entry_id,
+    balance,
+    covered_until
+)
+SELECT e.account_id,
+    MAX(e.id),
+    COALESCE(MAX(l.balance), 0) + SUM(e.amount),
+    GREATEST(MAX(l.covered_until), MAX(e.created_at))
+FROM entries e
+LEFT JOIN latest l ON l.account_id = e.account_id
+WHERE e.id > COALESCE(l.entry_id, 0)
+AND e.id <= (SELECT entry_id FROM boundary)
+GROUP BY e.account_id;
+
+-- name: GetLatestBalanceSnapshot :one
+SELECT * FROM balance_snapshots
+WHERE account_id = $1
+AND covered_until < $2
+ORDER BY entry_id DESC
+LIMIT 1;
+
+-- name: SumAccountEntriesAfter :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS amount FROM entries
+WHERE account_id = $1
+AND id > $2
+AND created_at < $3;
+
+-- name: SumAccountEntriesByPeriod :many
+SELECT date_trunc(sqlc.arg(interval)::text, created_at AT TIME ZONE 'UTC')::timestamp AS period_start,
+    SUM(amount)::bigint AS amount
+FROM entries
+WHERE account_id = sqlc.arg(account_id)
+AND created_at >= sqlc.arg(from_time)
+AND created_at < sqlc.arg(to_time)
+GROUP BY period_start
+ORDER BY period_start;
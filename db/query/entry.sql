@@ -1,10 +1,11 @@
 -- name: CreateEntry :one
 insert into entries (
     account_id,
-    amount
+    amount,
+    transfer_id
 )
 values (
-    $1, $2
+    $1, $2, $3
 ) RETURNING *;
 
 
@@ -20,6 +21,25 @@ LIMIT $1
 OFFSET $2;
 
 
+-- name: ListAccountEntries :many
+SELECT id, account_id, amount, created_at, running_balance FROM (
+    SELECT id, account_id, amount, created_at,
+    SUM(amount) OVER (ORDER BY id)::bigint AS running_balance
+    FROM entries
+    WHERE account_id = sqlc.arg(account_id)
+) AS account_entries
+WHERE (sqlc.narg(cursor)::bigint IS NULL OR id < sqlc.narg(cursor))
+AND (sqlc.narg(from_time)::timestamptz IS NULL OR created_at >= sqlc.narg(from_time))
+AND (sqlc.narg(to_time)::timestamptz IS NULL OR created_at < sqlc.narg(to_time))
+AND (sqlc.narg(min_amount)::bigint IS NULL OR abs(amount) >= sqlc.narg(min_amount))
+AND (sqlc.narg(max_amount)::bigint IS NULL OR abs(amount) <= sqlc.narg(max_amount))
+AND (sqlc.narg(direction)::text IS NULL
+    OR (sqlc.narg(direction) = 'credit' AND amount > 0)
+    OR (sqlc.narg(direction) = 'debit' AND amount < 0))
+ORDER BY id DESC
+LIMIT sqlc.arg(page_size);
+
+
 -- name: UpdateEntry :one
 UPDATE entries
 set amount = $2
@@ -0,0 +1,26 @@
+-- name: CreateFxQuote :one
+insert into fx_quotes (
+    id,
+    username,
+    from_currency,
+    to_currency,
+    rate,
+    expires_at
+)
+values (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+
+-- name: GetFxQuote :one
+SELECT * FROM fx_quotes
+WHERE id = $1 LIMIT 1;
+
+-- name: UseFxQuote :one
+UPDATE fx_quotes
+set is_used = true
+WHERE id = $1
+AND username = $2
+AND is_used = false
+AND expires_at > now()
+RETURNING *;
@@ -0,0 +1,18 @@
+-- name: CreateIdempotencyKey :one
+insert into idempotency_keys (
+    username,
+    key,
+    request_hash,
+    response
+)
+values (
+    $1, $2, $3, $4
+) RETURNING *;
+
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND key = $2 LIMIT 1;
+
+-- name: LockIdempotencyKey :exec
+SELECT pg_advisory_xact_lock(hashtext(@username::text || '/' || @key::text));
@@ -0,0 +1,39 @@
+-- name: RecordLoginFailure :one
+insert into login_throttles (
+    key,
+    failed_attempts,
+    last_failed_at
+)
+values (
+    sqlc.arg(key), 1, now()
+) ON CONFLICT (key) DO UPDATE
+set failed_attempts = CASE
+    WHEN login_throttles.last_failed_at < sqlc.arg(window_start) THEN 1
+    ELSE login_throttles.failed_attempts + 1
+END,
+last_failed_at = now()
+RETURNING *;
+
+-- name: LockLoginThrottle :one
+UPDATE login_throttles
+set locked_until = GREATEST(locked_until, $2)
+WHERE key = $1
+RETURNING *;
+
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE key = $1 LIMIT 1;
+
+-- name: ForgetLoginAttempt :exec
+UPDATE login_throttles
+set failed_attempts = failed_attempts - 1
+WHERE key = $1 AND failed_attempts > 0;
+
+-- name: DeleteLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE key = $1;
+
+-- name: DeleteStaleLoginThrottles :execrows
+DELETE FROM login_throttles
+WHERE last_failed_at < $1
+AND locked_until < now();
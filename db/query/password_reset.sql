@@ -0,0 +1,23 @@
+-- name: CreatePasswordReset :one
+insert into password_resets (
+    username,
+    token_hash,
+    expired_at
+)
+values (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: UsePasswordReset :one
+UPDATE password_resets
+set is_used = true
+WHERE token_hash = $1
+AND is_used = false
+AND expired_at > now()
+RETURNING *;
+
+-- name: ExpireUserPasswordResets :exec
+UPDATE password_resets
+set is_used = true
+WHERE username = $1
+AND is_used = false;
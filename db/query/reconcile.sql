@@ -0,0 +1,38 @@
+-- name: ListAccountBalanceMismatches :many
+SELECT a.id AS account_id,
+    a.currency,
+    a.balance,
+    COALESCE(SUM(e.amount), 0)::bigint AS entries_balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+GROUP BY a.id
+HAVING a.balance <> COALESCE(SUM(e.amount), 0)
+ORDER BY a.id;
+
+-- name: ListTransferEntryMismatches :many
+SELECT t.id AS transfer_id,
+    COUNT(e.id)::int AS entry_count,
+    COUNT(e.id) FILTER (WHERE e.account_id = t.from_account_id AND e.amount = -t.amount)::int AS matching_debits,
+    COUNT(e.id) FILTER (WHERE e.account_id = t.to_account_id AND e.amount = t.to_amount)::int AS matching_credits
+FROM transfers t
+LEFT JOIN entries e ON e.transfer_id = t.id
+GROUP BY t.id
+HAVING COUNT(e.id) <> 2
+OR COUNT(e.id) FILTER (WHERE e.account_id = t.from_account_id AND e.amount = -t.amount) <> 1
+OR COUNT(e.id) FILTER (WHERE e.account_id = t.to_account_id AND e.amount = t.to_amount) <> 1
+ORDER BY t.id;
+
+-- name: ListCurrencyImbalances :many
+SELECT a.currency,
+    SUM(e.amount)::bigint AS total
+FROM entries e
+JOIN accounts a ON a.id = e.account_id
+LEFT JOIN transfers t ON t.id = e.transfer_id
+LEFT JOIN accounts from_account ON from_account.id = t.from_account_id
+LEFT JOIN accounts to_account ON to_account.id = t.to_account_id
+LEFT JOIN balance_adjustments adj ON adj.entry_id = e.id
+WHERE adj.id IS NULL
+AND (t.id IS NULL OR from_account.currency = to_account.currency)
+GROUP BY a.currency
+HAVING SUM(e.amount) <> 0
+ORDER BY a.currency;
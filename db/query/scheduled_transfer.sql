@@ -0,0 +1,85 @@
+-- name: CreateScheduledTransfer :one
+insert into scheduled_transfers (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    recurrence,
+    next_run_at,
+    due_at
+)
+values (
+    $1, $2, $3, $4, $5, $6, $6
+) RETURNING *;
+
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+set amount = $2,
+    recurrence = $3,
+    next_run_at = $4,
+    due_at = $4,
+    attempts = 0
+WHERE id = $1
+AND status = 'active'
+RETURNING *;
+
+-- name: CancelScheduledTransfer :one
+UPDATE scheduled_transfers
+set status = 'cancelled'
+WHERE id = $1
+AND status = 'active'
+RETURNING *;
+
+-- name: ClaimDueScheduledTransfers :many
+UPDATE scheduled_transfers
+set due_at = sqlc.arg(lease_until)
+WHERE id IN (
+    SELECT id FROM scheduled_transfers
+    WHERE status = 'active'
+    AND due_at <= now()
+    ORDER BY due_at
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RescheduleScheduledTransfer :one
+UPDATE scheduled_transfers
+set status = $2,
+    next_run_at = $3,
+    due_at = $4,
+    attempts = $5
+WHERE id = $1
+AND status = 'active'
+RETURNING *;
+
+-- name: CreateScheduledTransferRun :one
+insert into scheduled_transfer_runs (
+    scheduled_transfer_id,
+    scheduled_for,
+    attempt,
+    transfer_id,
+    error
+)
+values (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListScheduledTransferRuns :many
+SELECT * FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
@@ -0,0 +1,41 @@
+-- name: CreateSession :one
+insert into sessions (
+    id,
+    username,
+    refresh_token_hash,
+    user_agent,
+    client_ip,
+    is_blocked,
+    expires_at
+)
+values (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE id = $1 LIMIT 1;
+
+
+-- name: ListActiveSessions :many
+SELECT * FROM sessions
+WHERE username = $1
+AND is_blocked = false
+AND expires_at > now()
+ORDER BY created_at DESC;
+
+
+-- name: BlockSession :one
+UPDATE sessions
+set is_blocked = true
+WHERE id = $1
+AND username = $2
+RETURNING *;
+
+
+-- name: BlockUserSessions :exec
+UPDATE sessions
+set is_blocked = true
+WHERE username = $1
+AND is_blocked = false;
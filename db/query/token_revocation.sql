@@ -0,0 +1,45 @@
+-- name: CreateRevokedToken :exec
+insert into revoked_tokens (
+    id,
+    username,
+    expires_at
+)
+values (
+    $1, $2, $3
+) ON CONFLICT (id) DO NOTHING;
+
+
+-- name: RevokeUserTokens :exec
+insert into user_token_revocations (
+    username,
+    revoked_before
+)
+values (
+    $1, $2
+) ON CONFLICT (username) DO UPDATE
+set revoked_before = GREATEST(user_token_revocations.revoked_before, EXCLUDED.revoked_before);
+
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS (
+    SELECT 1 FROM revoked_tokens
+    WHERE id = sqlc.arg(id)
+) OR EXISTS (
+    SELECT 1 FROM user_token_revocations
+    WHERE username = sqlc.arg(username)
+    AND revoked_before > sqlc.arg(issued_at)
+) OR EXISTS (
+    SELECT 1 FROM users
+    WHERE username = sqlc.arg(username)
+    AND password_changed_at > sqlc.arg(issued_at)
+) AS revoked;
+
+
+-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at < $1;
+
+
+-- name: DeleteUserTokenRevocations :execrows
+DELETE FROM user_token_revocations
+WHERE revoked_before < $1;
@@ -2,10 +2,14 @@
 insert into transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    to_amount,
+    exchange_rate,
+    reversal_of,
+    reference
 )
 values (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6, $7
 ) RETURNING *;
 
 
@@ -13,12 +17,38 @@ values (
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetTransferReversedAmounts :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS debited, COALESCE(SUM(to_amount), 0)::bigint AS refunded
+FROM transfers
+WHERE reversal_of = $1;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: ListAccountTransfers :many
+SELECT * FROM transfers
+WHERE (from_account_id = sqlc.arg(account_id) OR to_account_id = sqlc.arg(account_id))
+AND (sqlc.narg(cursor)::bigint IS NULL OR id < sqlc.narg(cursor))
+AND (sqlc.narg(from_time)::timestamptz IS NULL OR created_at >= sqlc.narg(from_time))
+AND (sqlc.narg(to_time)::timestamptz IS NULL OR created_at < sqlc.narg(to_time))
+AND (sqlc.narg(min_amount)::bigint IS NULL
+    OR (CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END) >= sqlc.narg(min_amount))
+AND (sqlc.narg(max_amount)::bigint IS NULL
+    OR (CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END) <= sqlc.narg(max_amount))
+AND (sqlc.narg(direction)::text IS NULL
+    OR (sqlc.narg(direction) = 'credit' AND to_account_id = sqlc.arg(account_id))
+    OR (sqlc.narg(direction) = 'debit' AND from_account_id = sqlc.arg(account_id)))
+ORDER BY id DESC
+LIMIT sqlc.arg(page_size);
+
 -- name: UpdateTransfer :one
 UPDATE transfers
 set amount = $2
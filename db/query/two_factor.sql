@@ -0,0 +1,55 @@
+-- name: UpsertUserTotp :one
+insert into user_totps (
+    username,
+    secret
+)
+values (
+    $1, $2
+) ON CONFLICT (username) DO UPDATE
+set secret = EXCLUDED.secret,
+last_used_step = 0,
+created_at = now()
+WHERE user_totps.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetUserTotp :one
+SELECT * FROM user_totps
+WHERE username = $1 LIMIT 1;
+
+-- name: ConfirmUserTotp :one
+UPDATE user_totps
+set confirmed_at = now(),
+last_used_step = $2
+WHERE username = $1
+AND confirmed_at IS NULL
+AND last_used_step < $2
+RETURNING *;
+
+-- name: UseTotpStep :one
+UPDATE user_totps
+set last_used_step = $2
+WHERE username = $1
+AND confirmed_at IS NOT NULL
+AND last_used_step < $2
+RETURNING *;
+
+-- name: CreateRecoveryCode :one
+insert into recovery_codes (
+    username,
+    code_hash
+)
+values (
+    $1, $2
+) RETURNING *;
+
+-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+set used_at = now()
+WHERE username = $1
+AND code_hash = $2
+AND used_at IS NULL
+RETURNING *;
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
@@ -12,4 +12,35 @@ values (
 
 -- name: GetUser :one
 SELECT * FROM users
-WHERE username = $1 LIMIT 1;
\ No newline at end of file
+WHERE username = $1 LIMIT 1;
+-- name: UpdateUserRole :one
+UPDATE users
+set role = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: SearchUsers :many
+SELECT * FROM users
+WHERE username ILIKE '%' || sqlc.arg(query)::text || '%'
+OR full_name ILIKE '%' || sqlc.arg(query)::text || '%'
+OR email ILIKE '%' || sqlc.arg(query)::text || '%'
+ORDER BY username
+LIMIT sqlc.arg(page_size)
+OFFSET sqlc.arg(page_offset);
+
+-- name: VerifyUserEmail :one
+UPDATE users
+set is_email_verified = true
+WHERE username = $1 AND email = $2
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+set hashed_password = $2,
+password_changed_at = now()
+WHERE username = $1
+RETURNING *;
@@ -0,0 +1,19 @@
+-- name: CreateVerifyEmail :one
+insert into verify_emails (
+    username,
+    email,
+    secret_code_hash,
+    expired_at
+)
+values (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: UseVerifyEmail :one
+UPDATE verify_emails
+set is_used = true
+WHERE id = @id
+AND secret_code_hash = @secret_code_hash
+AND is_used = false
+AND expired_at > now()
+RETURNING *;
@@ -0,0 +1,26 @@
+package db
+
+import "context"
+
+// CreateAccountTxParams - contains the input parameters of the create account transaction
+type CreateAccountTxParams struct {
+	CreateAccountParams
+	Audit AuditParams `json:"audit"`
+}
+
+// CreateAccountTx - creates the account and records it in the audit trail within a single database transaction
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		account, err = q.CreateAccount(ctx, arg.CreateAccountParams)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, q, arg.Audit, AuditAccountCreate, AccountTarget(account.ID), nil, account)
+	})
+
+	return account, err
+}
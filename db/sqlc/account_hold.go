@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+/*
+* CreateHoldTxParams - contains the input parameters of the create hold transaction
+* Amount is reserved on the account until the hold is captured, voided or expired
+ */
+type CreateHoldTxParams struct {
+	AccountID   int64       `json:"account_id"`
+	ToAccountID int64       `json:"to_account_id"`
+	Amount      int64       `json:"amount"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+	Audit       AuditParams `json:"audit"`
+}
+
+/*
+* CreateHoldTx - reserves funds on the account without moving them
+* I) locks the account - so holds and transfers of the account are serialized
+* II) checks the account is active and its available balance covers the held amount
+* III) creates the hold and records it in the audit trail
+* within a single database transaction
+ */
+func (store *SQLStore) CreateHoldTx(ctx context.Context, arg CreateHoldTxParams) (AccountHold, error) {
+	var hold AccountHold
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if err = checkAccountActive(account); err != nil {
+			return err
+		}
+
+		if err = checkAvailableBalance(ctx, q, account, arg.Amount); err != nil {
+			return err
+		}
+
+		hold, err = q.CreateAccountHold(ctx, CreateAccountHoldParams{
+			AccountID:   arg.AccountID,
+			ToAccountID: arg.ToAccountID,
+			Amount:      arg.Amount,
+			ExpiresAt:   arg.ExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, q, arg.Audit, AuditHoldCreate, HoldTarget(hold.ID), nil, hold)
+	})
+
+	return hold, err
+}
+
+// CaptureHoldTxParams - contains the input parameters of the capture hold transaction
+type CaptureHoldTxParams struct {
+	HoldID int64 `json:"hold_id"`
+	// Amount - the amount transferred to the to account, zero for capturing the whole held amount
+	Amount int64       `json:"amount"`
+	Audit  AuditParams `json:"audit"`
+}
+
+// CaptureHoldTxResult - contains the output of the capture hold transaction
+type CaptureHoldTxResult struct {
+	Hold AccountHold `json:"hold"`
+	TransferTxResult
+}
+
+/*
+* CaptureHoldTx - turns an active hold into a real transfer
+* I) marks the hold as captured - releasing the reserved funds
+* II) transfers up to the held amount from the hold account to the hold to account
+* III) links the transfer to the hold and records the capture in the audit trail
+* within a single database transaction
+ */
+func (store *SQLStore) CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	var result CaptureHoldTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// capturing the hold - an expired, captured or voided hold can't be captured
+		hold, err := q.CaptureAccountHold(ctx, arg.HoldID)
+		if err != nil {
+			if isNotFound(err) {
+				return ErrHoldUnavailable
+			}
+			return err
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		if amount < 0 || amount > hold.Amount {
+			return ErrInvalidCaptureAmount
+		}
+
+		result.TransferTxResult, err = transfer(ctx, q, CreateTransferParams{
+			FromAccountID: hold.AccountID,
+			ToAccountID:   hold.ToAccountID,
+			Amount:        amount,
+			ToAmount:      amount,
+			ExchangeRate:  1,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.SetAccountHoldTransfer(ctx, SetAccountHoldTransferParams{
+			ID:         hold.ID,
+			TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, q, arg.Audit, AuditHoldCapture, HoldTarget(hold.ID), nil, result)
+	})
+
+	return result, err
+}
+
+// VoidHoldTxParams - contains the input parameters of the void hold transaction
+type VoidHoldTxParams struct {
+	HoldID int64       `json:"hold_id"`
+	Audit  AuditParams `json:"audit"`
+}
+
+/*
+* VoidHoldTx - releases the funds of an active hold
+* I) marks the hold as voided - an expired, captured or voided hold can't be voided
+* II) records the void in the audit trail
+* within a single database transaction
+ */
+func (store *SQLStore) VoidHoldTx(ctx context.Context, arg VoidHoldTxParams) (AccountHold, error) {
+	var hold AccountHold
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		hold, err = q.VoidAccountHold(ctx, arg.HoldID)
+		if err != nil {
+			if isNotFound(err) {
+				return ErrHoldUnavailable
+			}
+			return err
+		}
+
+		return recordAuditEvent(ctx, q, arg.Audit, AuditHoldVoid, HoldTarget(hold.ID), nil, hold)
+	})
+
+	return hold, err
+}
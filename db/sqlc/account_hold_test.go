@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateHoldTx(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := setAccountBalance(t, createRandomAccount(t), 100)
+	account2 := createRandomAccount(t)
+
+	hold, err := store.CreateHoldTx(context.Background(), CreateHoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      70,
+		ExpiresAt:   time.Now().Add(time.Minute),
+		Audit:       AuditParams{Actor: account1.Owner},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, hold.ID)
+	require.Equal(t, "active", hold.Status)
+	require.False(t, hold.TransferID.Valid)
+	requireHoldAuditEvent(t, hold.ID, AuditHoldCreate, account1.Owner)
+
+	held, err := testQueries.GetAccountHeldAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(70), held)
+
+	// the held funds can't be used by another hold or a transfer
+	_, err = store.CreateHoldTx(context.Background(), CreateHoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      31,
+		ExpiresAt:   time.Now().Add(time.Minute),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        31,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// voiding the hold releases the funds
+	voided, err := store.VoidHoldTx(context.Background(), VoidHoldTxParams{
+		HoldID: hold.ID,
+		Audit:  AuditParams{Actor: account2.Owner},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "voided", voided.Status)
+	requireHoldAuditEvent(t, hold.ID, AuditHoldVoid, account2.Owner)
+
+	// a voided hold can't be voided again
+	_, err = store.VoidHoldTx(context.Background(), VoidHoldTxParams{HoldID: hold.ID})
+	require.ErrorIs(t, err, ErrHoldUnavailable)
+
+	held, err = testQueries.GetAccountHeldAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, held)
+}
+
+func TestExpiredHold(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := setAccountBalance(t, createRandomAccount(t), 100)
+	account2 := createRandomAccount(t)
+
+	hold, err := store.CreateHoldTx(context.Background(), CreateHoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      100,
+		ExpiresAt:   time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+
+	// an expired hold doesn't reserve funds and can't be captured
+	held, err := testQueries.GetAccountHeldAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, held)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID})
+	require.ErrorIs(t, err, ErrHoldUnavailable)
+}
+
+func TestCaptureHoldTx(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := setAccountBalance(t, createRandomAccount(t), 100)
+	account2 := setAccountBalance(t, createRandomAccount(t), 0)
+
+	hold, err := store.CreateHoldTx(context.Background(), CreateHoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      100,
+		ExpiresAt:   time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	// the capture amount can't be bigger than the held amount
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID: hold.ID,
+		Amount: 101,
+	})
+	require.ErrorIs(t, err, ErrInvalidCaptureAmount)
+
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID: hold.ID,
+		Amount: 60,
+		Audit:  AuditParams{Actor: account2.Owner},
+	})
+	require.NoError(t, err)
+	requireHoldAuditEvent(t, hold.ID, AuditHoldCapture, account2.Owner)
+	require.Equal(t, "captured", result.Hold.Status)
+	require.True(t, result.Hold.TransferID.Valid)
+	require.Equal(t, result.Transfer.ID, result.Hold.TransferID.Int64)
+	require.Equal(t, int64(60), result.Transfer.Amount)
+	require.Equal(t, int64(40), result.FromAccount.Balance)
+	require.Equal(t, int64(60), result.ToAccount.Balance)
+
+	// the rest of the held amount is released
+	held, err := testQueries.GetAccountHeldAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, held)
+
+	// a hold can be captured once
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID})
+	require.ErrorIs(t, err, ErrHoldUnavailable)
+}
+
+// requireHoldAuditEvent - checking the last audit event of the hold
+func requireHoldAuditEvent(t *testing.T, holdID int64, action, actor string) {
+	events, err := testQueries.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		Target: HoldTarget(holdID),
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	require.Equal(t, action, events[0].Action)
+	require.Equal(t, actor, events[0].Actor)
+}
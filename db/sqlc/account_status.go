@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// the statuses of an account
+const (
+	// AccountActive - the money of the account can be moved
+	AccountActive = "active"
+	// AccountFrozen - the money of the account can't be moved until the account is unfrozen
+	AccountFrozen = "frozen"
+	// AccountClosed - the account is closed for good
+	AccountClosed = "closed"
+)
+
+/*
+* UpdateAccountStatusTxParams - contains the input parameters of the account status transaction
+* Audit: the admin who changes the status - recorded in the audit trail
+ */
+type UpdateAccountStatusTxParams struct {
+	AccountID int64       `json:"account_id"`
+	Status    string      `json:"status"`
+	Audit     AuditParams `json:"audit"`
+}
+
+/*
+* UpdateAccountStatusTx - freezes, unfreezes or closes the account
+* The transaction steps are:
+* 1. locking the account - so the status change is serialized with the transfers of the account
+* 2. checking the change is allowed - a closed account can't be changed and only an empty account can be closed
+* 3. updating the status
+* 4. recording the change in the audit trail
+ */
+func (store *SQLStore) UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if before.Status == AccountClosed || before.Status == arg.Status {
+			return fmt.Errorf("%w: account %d is %s", ErrAccountStatusChange, before.ID, before.Status)
+		}
+
+		if arg.Status == AccountClosed {
+			held, err := q.GetAccountHeldAmount(ctx, before.ID)
+			if err != nil {
+				return err
+			}
+			if before.Balance != 0 || held != 0 {
+				return ErrAccountNotEmpty
+			}
+		}
+
+		account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:     arg.AccountID,
+			Status: arg.Status,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, q, arg.Audit, accountStatusAction(arg.Status), AccountTarget(account.ID), before, account)
+	})
+
+	return account, err
+}
+
+// checkAccountActive - checking the money of the account can be moved
+func checkAccountActive(account Account) error {
+	if account.Status != AccountActive {
+		return fmt.Errorf("%w: account %d is %s", ErrAccountInactive, account.ID, account.Status)
+	}
+	return nil
+}
+
+// accountStatusAction - the audit action of changing the account to the given status
+func accountStatusAction(status string) string {
+	switch status {
+	case AccountFrozen:
+		return AuditAccountFreeze
+	case AccountClosed:
+		return AuditAccountClose
+	default:
+		return AuditAccountUnfreeze
+	}
+}
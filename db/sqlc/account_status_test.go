@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateAccountStatusTx(t *testing.T) {
+	store := NewStore(testPool)
+	admin := createRandomUser(t)
+	account := createRandomAccount(t)
+	other := createRandomAccount(t)
+	require.Equal(t, AccountActive, account.Status)
+
+	frozen, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountFrozen,
+		Audit:     AuditParams{Actor: admin.Username},
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountFrozen, frozen.Status)
+
+	// the money of a frozen account can't be moved - in both directions
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   other.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrAccountInactive)
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: other.ID,
+		ToAccountID:   account.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrAccountInactive)
+
+	// freezing a frozen account isn't a change
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountFrozen,
+		Audit:     AuditParams{Actor: admin.Username},
+	})
+	require.ErrorIs(t, err, ErrAccountStatusChange)
+
+	// an account with money can't be closed
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountClosed,
+		Audit:     AuditParams{Actor: admin.Username},
+	})
+	require.ErrorIs(t, err, ErrAccountNotEmpty)
+
+	events, err := testQueries.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		Target: AccountTarget(account.ID),
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, admin.Username, events[0].Actor)
+	require.Equal(t, AuditAccountFreeze, events[0].Action)
+	require.Contains(t, string(events[0].Before), `"status":"active"`)
+	require.Contains(t, string(events[0].After), `"status":"frozen"`)
+}
+
+func TestCloseEmptyAccount(t *testing.T) {
+	store := NewStore(testPool)
+	admin := createRandomUser(t)
+	account := createRandomAccount(t)
+
+	_, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: 0,
+	})
+	require.NoError(t, err)
+
+	closed, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountClosed,
+		Audit:     AuditParams{Actor: admin.Username},
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountClosed, closed.Status)
+
+	// a closed account is closed for good
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountActive,
+		Audit:     AuditParams{Actor: admin.Username},
+	})
+	require.ErrorIs(t, err, ErrAccountStatusChange)
+}
@@ -2,10 +2,10 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/shimon-git/simple-bank/util"
 	"github.com/stretchr/testify/require"
 )
@@ -88,7 +88,7 @@ func TestDelete(t *testing.T) {
 	account2, err := testQueries.GetAccount(context.Background(), account1.ID)
 
 	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.EqualError(t, err, pgx.ErrNoRows.Error())
 	require.Empty(t, account2)
 }
 
@@ -0,0 +1,282 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// the actions recorded in the audit trail
+const (
+	AuditAccountCreate        = "account.create"
+	AuditAccountAdjustBalance = "account.adjust_balance"
+	AuditAccountFreeze        = "account.freeze"
+	AuditAccountUnfreeze      = "account.unfreeze"
+	AuditAccountClose         = "account.close"
+	AuditAccountView          = "account.view"
+	AuditTransferCreate       = "transfer.create"
+	AuditTransferReverse      = "transfer.reverse"
+	AuditHoldCreate           = "hold.create"
+	AuditHoldCapture          = "hold.capture"
+	AuditHoldVoid             = "hold.void"
+	AuditUserLogin            = "user.login"
+	AuditUserLoginFailed      = "user.login_failed"
+	AuditUserLockout          = "user.lockout"
+	AuditUserUnlock           = "user.unlock"
+	AuditUserSearch           = "user.search"
+	AuditUserPasswordChange   = "user.password_change"
+	AuditUserPasswordReset    = "user.password_reset"
+	AuditUserMFAEnable        = "user.mfa_enable"
+	AuditUserRevokeTokens     = "user.revoke_tokens"
+)
+
+// auditTimeFormat - the created at time as hashed - the database keeps microseconds
+const auditTimeFormat = "2006-01-02T15:04:05.000000Z"
+
+// verifyAuditPageSize - the number of events read at once by the audit log verification
+const verifyAuditPageSize = 1000
+
+// AccountTarget - the audit target of the account
+func AccountTarget(accountID int64) string {
+	return fmt.Sprintf("account:%d", accountID)
+}
+
+// TransferTarget - the audit target of the transfer
+func TransferTarget(transferID int64) string {
+	return fmt.Sprintf("transfer:%d", transferID)
+}
+
+// HoldTarget - the audit target of the hold
+func HoldTarget(holdID int64) string {
+	return fmt.Sprintf("hold:%d", holdID)
+}
+
+// UserTarget - the audit target of the user
+func UserTarget(username string) string {
+	return fmt.Sprintf("user:%s", username)
+}
+
+/*
+* AuditParams - who did the action and within which request
+* recorded with the audit events of the transaction
+ */
+type AuditParams struct {
+	Actor     string `json:"actor"`
+	RequestID string `json:"request_id"`
+}
+
+/*
+* RecordAuditEventParams - contains the input parameters of an audit event
+* Before/After: the target before and after the action - nil when there is nothing to record (e.g. viewing)
+ */
+type RecordAuditEventParams struct {
+	AuditParams
+	Action string `json:"action"`
+	Target string `json:"target"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// RecordAuditEvent - records an action which doesn't change the data (e.g. viewing an account) in the audit trail
+func (store *SQLStore) RecordAuditEvent(ctx context.Context, arg RecordAuditEventParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		return recordAuditEvent(ctx, q, arg.AuditParams, arg.Action, arg.Target, arg.Before, arg.After)
+	})
+}
+
+/*
+* recordAuditEvent - appends the action to the audit trail using the given queries object
+* so a change and its audit event are committed (or rolled back) together
+* the event is recorded unchained - no lock is taken, it's chained by the appender (ChainAuditEvents) after the commit
+ */
+func recordAuditEvent(ctx context.Context, q *Queries, audit AuditParams, action, target string, before, after any) error {
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateAuditEvent(ctx, CreateAuditEventParams{
+		Actor:     audit.Actor,
+		Action:    action,
+		Target:    target,
+		RequestID: audit.RequestID,
+		Before:    beforeJSON,
+		After:     afterJSON,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	})
+	return err
+}
+
+/*
+* ChainAuditEvents - the single appender of the hash chain, chains up to limit committed events which weren't chained yet
+* I) locks the audit log - the appenders of all the instances chain one at a time, the business transactions never wait for it
+* II) chains the events in the order of their ids after the last chained event - an event committed late is chained later
+* within a single database transaction
+* returns the number of the chained events
+ */
+func (store *SQLStore) ChainAuditEvents(ctx context.Context, limit int32) (int, error) {
+	var chained int
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		chained = 0
+		if err := q.LockAuditLog(ctx); err != nil {
+			return err
+		}
+
+		// the first event is chained to an empty hash
+		var seq int64
+		prevHash := []byte{}
+		last, err := q.GetLastChainedAuditEvent(ctx)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		if err == nil {
+			seq = last.ChainSeq.Int64
+			prevHash = last.Hash
+		}
+
+		events, err := q.ListUnchainedAuditEvents(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			seq++
+			event.PrevHash = prevHash
+			event.Hash = AuditEventHash(event)
+			err = q.ChainAuditEvent(ctx, ChainAuditEventParams{
+				ID:       event.ID,
+				ChainSeq: sql.NullInt64{Int64: seq, Valid: true},
+				PrevHash: event.PrevHash,
+				Hash:     event.Hash,
+			})
+			if err != nil {
+				return err
+			}
+			prevHash = event.Hash
+			chained++
+		}
+		return nil
+	})
+
+	return chained, err
+}
+
+/*
+* AuditEventHash - the sha256 hash of the event chained to the previous event
+* every field is prefixed by its length, so moving bytes between the fields changes the hash
+* the migration chaining the events recorded before the hash chain computes the same hash
+ */
+func AuditEventHash(event AuditEvent) []byte {
+	h := sha256.New()
+	writeHashField(h, event.PrevHash)
+	writeHashField(h, []byte(event.Actor))
+	writeHashField(h, []byte(event.Action))
+	writeHashField(h, []byte(event.Target))
+	writeHashField(h, []byte(event.RequestID))
+	writeHashField(h, event.Before)
+	writeHashField(h, event.After)
+	writeHashField(h, []byte(event.CreatedAt.UTC().Format(auditTimeFormat)))
+	return h.Sum(nil)
+}
+
+// writeHashField - writing the length of the field (8 bytes big endian) and the field into the hash
+func writeHashField(h hash.Hash, field []byte) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(field)))
+	h.Write(length[:])
+	h.Write(field)
+}
+
+// AuditChainBreak - an event which doesn't match the chain
+type AuditChainBreak struct {
+	EventID int64  `json:"event_id"`
+	Reason  string `json:"reason"`
+}
+
+/*
+* AuditLogReport - the result of the audit log verification
+* Head: the hash of the last event - keeping it allows detecting events deleted from the end of the log later
+* Pending: the events not chained yet by the appender - they are verified once chained
+ */
+type AuditLogReport struct {
+	Events     int64             `json:"events"`
+	Pending    int64             `json:"pending"`
+	Head       []byte            `json:"head"`
+	AnchorSeen bool              `json:"anchor_seen"`
+	Breaks     []AuditChainBreak `json:"breaks"`
+}
+
+/*
+* VerifyAuditLog - recomputes the hash chain of the whole audit log (the chained events in the order of the chain)
+* an edited event doesn't match its hash, a deleted event breaks the link of the event after it
+* anchor: optional - the head of a previous verification, must still be found in the chain
+* all the events are read within a single repeatable read (read only) transaction
+ */
+func (store *SQLStore) VerifyAuditLog(ctx context.Context, anchor []byte) (AuditLogReport, error) {
+	report := AuditLogReport{Breaks: []AuditChainBreak{}}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		if report.Pending, err = q.CountUnchainedAuditEvents(ctx); err != nil {
+			return err
+		}
+
+		var lastSeq int64
+		for {
+			events, err := q.ListChainedAuditEvents(ctx, ListChainedAuditEventsParams{
+				AfterSeq: lastSeq,
+				PageSize: verifyAuditPageSize,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, event := range events {
+				report.Breaks = append(report.Breaks, verifyAuditEvent(report.Head, event)...)
+				report.Head = event.Hash
+				report.Events++
+				if anchor != nil && bytes.Equal(anchor, event.Hash) {
+					report.AnchorSeen = true
+				}
+			}
+
+			if len(events) < verifyAuditPageSize {
+				return nil
+			}
+			lastSeq = events[len(events)-1].ChainSeq.Int64
+		}
+	}, withIsolation(pgx.RepeatableRead), readOnly())
+
+	return report, err
+}
+
+// verifyAuditEvent - checking the event is linked to the previous hash and matches its own hash
+func verifyAuditEvent(prevHash []byte, event AuditEvent) []AuditChainBreak {
+	var breaks []AuditChainBreak
+	if !bytes.Equal(prevHash, event.PrevHash) {
+		breaks = append(breaks, AuditChainBreak{
+			EventID: event.ID,
+			Reason:  "the previous hash doesn't match the event before - an event was deleted or reordered",
+		})
+	}
+	if !bytes.Equal(AuditEventHash(event), event.Hash) {
+		breaks = append(breaks, AuditChainBreak{
+			EventID: event.ID,
+			Reason:  "the hash doesn't match the event - the event was edited",
+		})
+	}
+	return breaks
+}
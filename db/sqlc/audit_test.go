@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAuditEvent(t *testing.T) {
+	store := NewStore(testPool)
+	account := createRandomAccount(t)
+
+	arg := RecordAuditEventParams{
+		AuditParams: AuditParams{
+			Actor:     util.RandomOwner(),
+			RequestID: util.RandomString(16),
+		},
+		Action: AuditAccountView,
+		Target: AccountTarget(account.ID),
+	}
+	for i := 0; i < 2; i++ {
+		err := store.RecordAuditEvent(context.Background(), arg)
+		require.NoError(t, err)
+	}
+
+	// the events are recorded unchained - until the appender chains them
+	events, err := testQueries.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		Target: arg.Target,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	for _, event := range events {
+		require.False(t, event.ChainSeq.Valid)
+		require.Empty(t, event.Hash)
+	}
+
+	chained, err := store.ChainAuditEvents(context.Background(), 1000)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, chained, 2)
+
+	events, err = testQueries.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		Target: arg.Target,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	// the newest event is chained to the event before it
+	require.Equal(t, events[1].Hash, events[0].PrevHash)
+	require.Equal(t, events[1].ChainSeq.Int64+1, events[0].ChainSeq.Int64)
+	for _, event := range events {
+		require.Equal(t, arg.Actor, event.Actor)
+		require.Equal(t, arg.RequestID, event.RequestID)
+		require.JSONEq(t, "null", string(event.Before))
+		require.Equal(t, AuditEventHash(event), event.Hash)
+	}
+
+	// the audit events can't be edited, chained again or deleted
+	_, err = testPool.Exec(context.Background(), "UPDATE audit_events SET actor = 'someone' WHERE id = $1", events[0].ID)
+	require.Error(t, err)
+	_, err = testPool.Exec(context.Background(), "UPDATE audit_events SET hash = '' WHERE id = $1", events[0].ID)
+	require.Error(t, err)
+	_, err = testPool.Exec(context.Background(), "DELETE FROM audit_events WHERE id = $1", events[0].ID)
+	require.Error(t, err)
+
+	report, err := store.VerifyAuditLog(context.Background(), events[1].Hash)
+	require.NoError(t, err)
+	require.Empty(t, report.Breaks)
+	require.True(t, report.AnchorSeen)
+	require.GreaterOrEqual(t, report.Events, int64(2))
+	require.Zero(t, report.Pending)
+}
+
+func TestVerifyAuditEvent(t *testing.T) {
+	first := AuditEvent{
+		ID:     1,
+		Actor:  util.RandomOwner(),
+		Action: AuditAccountCreate,
+		Target: AccountTarget(1),
+		Before: []byte("null"),
+		After:  []byte(`{"id":1}`),
+	}
+	first.Hash = AuditEventHash(first)
+
+	second := AuditEvent{
+		ID:       2,
+		Actor:    first.Actor,
+		Action:   AuditAccountFreeze,
+		Target:   first.Target,
+		Before:   []byte(`{"status":"active"}`),
+		After:    []byte(`{"status":"frozen"}`),
+		PrevHash: first.Hash,
+	}
+	second.Hash = AuditEventHash(second)
+
+	require.Empty(t, verifyAuditEvent(nil, first))
+	require.Empty(t, verifyAuditEvent(first.Hash, second))
+
+	// an edited event doesn't match its hash
+	edited := second
+	edited.After = []byte(`{"status":"active"}`)
+	require.Len(t, verifyAuditEvent(first.Hash, edited), 1)
+
+	// the event after a deleted event isn't linked to the event before the deleted one
+	require.Len(t, verifyAuditEvent(nil, second), 1)
+
+	// moving bytes between the fields changes the hash
+	moved := first
+	moved.Actor, moved.Action = first.Actor+first.Action[:1], first.Action[1:]
+	require.NotEqual(t, first.Hash, AuditEventHash(moved))
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// the supported periods of the balance history - periods are aligned to UTC
+const (
+	BalanceIntervalDay   = "day"
+	BalanceIntervalWeek  = "week"
+	BalanceIntervalMonth = "month"
+)
+
+/*
+* GetAccountBalanceAt - reconstructs the account balance at the given time from its entries
+* the balance is the sum of the entries created before the given time
+* the newest balance snapshot covering that time is used as a starting point
+* so only the entries created after the snapshot are scanned
+ */
+func (store *SQLStore) GetAccountBalanceAt(ctx context.Context, accountID int64, at time.Time) (int64, error) {
+	return accountBalanceAt(ctx, store.Queries, accountID, at)
+}
+
+// accountBalanceAt - the latest snapshot before the given time + the entries the snapshot doesn't cover
+func accountBalanceAt(ctx context.Context, q *Queries, accountID int64, at time.Time) (int64, error) {
+	// every entry covered by the snapshot was created before the given time
+	snapshot, err := q.GetLatestBalanceSnapshot(ctx, GetLatestBalanceSnapshotParams{
+		AccountID:    accountID,
+		CoveredUntil: at,
+	})
+	// no snapshot yet - starting from the first entry of the account
+	if err != nil && !isNotFound(err) {
+		return 0, err
+	}
+
+	// entries are not created in id order under concurrency, so newer entries are filtered by time
+	amount, err := q.SumAccountEntriesAfter(ctx, SumAccountEntriesAfterParams{
+		AccountID: accountID,
+		ID:        snapshot.EntryID,
+		CreatedAt: at,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return snapshot.Balance + amount, nil
+}
+
+// AccountBalanceHistoryParams - contains the input parameters of the balance history
+type AccountBalanceHistoryParams struct {
+	AccountID int64     `json:"account_id"`
+	Interval  string    `json:"interval"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+}
+
+// BalancePoint - the account balance at the end of a period
+type BalancePoint struct {
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Balance     int64     `json:"balance"`
+}
+
+/*
+* AccountBalanceHistory - returns the end-of-period balances of every period between From and To
+* I) gets the balance at the start of the first period
+* II) sums the entries of each period with a single query
+* III) accumulates the sums - periods without entries keep the previous balance
+ */
+func (store *SQLStore) AccountBalanceHistory(ctx context.Context, arg AccountBalanceHistoryParams) ([]BalancePoint, error) {
+	periods := BalancePeriods(arg.Interval, arg.From, arg.To)
+	if len(periods) == 0 {
+		return []BalancePoint{}, nil
+	}
+	start := periods[0]
+	end := NextBalancePeriod(arg.Interval, periods[len(periods)-1])
+
+	balance, err := store.GetAccountBalanceAt(ctx, arg.AccountID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	sums, err := store.SumAccountEntriesByPeriod(ctx, SumAccountEntriesByPeriodParams{
+		Interval:  arg.Interval,
+		AccountID: arg.AccountID,
+		FromTime:  start,
+		ToTime:    end,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	amounts := make(map[time.Time]int64, len(sums))
+	for _, sum := range sums {
+		amounts[sum.PeriodStart.UTC()] = sum.Amount
+	}
+
+	history := make([]BalancePoint, 0, len(periods))
+	for _, period := range periods {
+		balance += amounts[period]
+		history = append(history, BalancePoint{
+			PeriodStart: period,
+			PeriodEnd:   NextBalancePeriod(arg.Interval, period),
+			Balance:     balance,
+		})
+	}
+
+	return history, nil
+}
+
+/*
+* BalancePeriods - returns the start of every period between from and to (UTC)
+* the first period is the one containing from, the last is the one containing to
+* returns nil for an unsupported interval or when to is before from
+ */
+func BalancePeriods(interval string, from, to time.Time) []time.Time {
+	if !IsSupportedBalanceInterval(interval) || to.Before(from) {
+		return nil
+	}
+
+	var periods []time.Time
+	for period := truncateBalancePeriod(interval, from); !period.After(to); period = NextBalancePeriod(interval, period) {
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+// NextBalancePeriod - returns the start of the period following the given period start
+func NextBalancePeriod(interval string, period time.Time) time.Time {
+	switch interval {
+	case BalanceIntervalWeek:
+		return period.AddDate(0, 0, 7)
+	case BalanceIntervalMonth:
+		return period.AddDate(0, 1, 0)
+	default:
+		return period.AddDate(0, 0, 1)
+	}
+}
+
+// IsSupportedBalanceInterval - returns true if the balance history supports the given interval
+func IsSupportedBalanceInterval(interval string) bool {
+	switch interval {
+	case BalanceIntervalDay, BalanceIntervalWeek, BalanceIntervalMonth:
+		return true
+	}
+	return false
+}
+
+// truncateBalancePeriod - returns the start of the period containing t, same as postgres date_trunc (weeks start on monday)
+func truncateBalancePeriod(interval string, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case BalanceIntervalWeek:
+		// time.Weekday starts on sunday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case BalanceIntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+/*
+* AdjustBalanceTxParams - contains the input parameters of the balance adjustment transaction
+* Amount: added to the balance - negative for debiting the account
+* Audit: the admin who adjusts the balance - recorded with the adjustment and in the audit trail
+ */
+type AdjustBalanceTxParams struct {
+	AccountID int64       `json:"account_id"`
+	Amount    int64       `json:"amount"`
+	Reason    string      `json:"reason"`
+	Audit     AuditParams `json:"audit"`
+}
+
+// AdjustBalanceTxResult - the result of the balance adjustment transaction
+type AdjustBalanceTxResult struct {
+	Adjustment BalanceAdjustment `json:"adjustment"`
+	Account    Account           `json:"account"`
+	Entry      Entry             `json:"entry"`
+}
+
+/*
+* AdjustBalanceTx - posts a manual adjustment entry to the account
+* The transaction steps are:
+* 1. locking the account - the account before the adjustment is recorded in the audit trail
+* 2. creating the entry - it doesn't belong to any transfer
+* 3. updating the account balance - a debit may not take the account below its overdraft limit
+* 4. recording the adjustment with its reason + the audit event
+ */
+func (store *SQLStore) AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error) {
+	var result AdjustBalanceTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:  arg.AccountID,
+			Amount:     arg.Amount,
+			TransferID: sql.NullInt64{},
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = updateAccountBalance(ctx, q, arg.AccountID, arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		result.Adjustment, err = q.CreateBalanceAdjustment(ctx, CreateBalanceAdjustmentParams{
+			AccountID: arg.AccountID,
+			EntryID:   result.Entry.ID,
+			Amount:    arg.Amount,
+			Reason:    arg.Reason,
+			CreatedBy: arg.Audit.Actor,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, q, arg.Audit, AuditAccountAdjustBalance, AccountTarget(arg.AccountID), before, result)
+	})
+
+	return result, err
+}
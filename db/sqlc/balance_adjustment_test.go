@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustBalanceTx(t *testing.T) {
+	store := NewStore(testPool)
+	admin := createRandomUser(t)
+	account := createRandomAccount(t)
+
+	arg := AdjustBalanceTxParams{
+		AccountID: account.ID,
+		Amount:    25,
+		Reason:    "refunding a duplicated card fee",
+		Audit:     AuditParams{Actor: admin.Username},
+	}
+
+	result, err := store.AdjustBalanceTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, account.Balance+arg.Amount, result.Account.Balance)
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, arg.Amount, result.Entry.Amount)
+	require.False(t, result.Entry.TransferID.Valid)
+
+	require.NotZero(t, result.Adjustment.ID)
+	require.Equal(t, result.Entry.ID, result.Adjustment.EntryID)
+	require.Equal(t, arg.Reason, result.Adjustment.Reason)
+	require.Equal(t, admin.Username, result.Adjustment.CreatedBy)
+
+	adjustments, err := testQueries.ListBalanceAdjustments(context.Background(), ListBalanceAdjustmentsParams{
+		AccountID: account.ID,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, adjustments, 1)
+	require.Equal(t, result.Adjustment.ID, adjustments[0].ID)
+
+	// the adjustment is recorded in the audit trail
+	events, err := testQueries.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		Target: AccountTarget(account.ID),
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, AuditAccountAdjustBalance, events[0].Action)
+	require.Equal(t, admin.Username, events[0].Actor)
+
+	// a debit can't take the account below its overdraft limit
+	_, err = store.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID: account.ID,
+		Amount:    -(result.Account.Balance + result.Account.OverdraftLimit + 1),
+		Reason:    arg.Reason,
+		Audit:     AuditParams{Actor: admin.Username},
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
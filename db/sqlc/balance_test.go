@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountBalanceAt(t *testing.T) {
+	store := NewStore(testPool)
+	account := createRandomAccount(t)
+	before := time.Now().Add(-time.Second)
+
+	for _, amount := range []int64{10, -3} {
+		_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    amount,
+		})
+		require.NoError(t, err)
+	}
+
+	// no entries before the account was created
+	balance, err := store.GetAccountBalanceAt(context.Background(), account.ID, before)
+	require.NoError(t, err)
+	require.Zero(t, balance)
+
+	balance, err = store.GetAccountBalanceAt(context.Background(), account.ID, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, int64(7), balance)
+
+	// snapshotting the entries and adding a new one - the snapshot + the newer entries are summed
+	created, err := testQueries.CreateBalanceSnapshots(context.Background(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.NotZero(t, created)
+
+	_, err = testQueries.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID: account.ID,
+		Amount:    5,
+	})
+	require.NoError(t, err)
+
+	balance, err = store.GetAccountBalanceAt(context.Background(), account.ID, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, int64(12), balance)
+
+	// a snapshot is never used for a time before its entries
+	balance, err = store.GetAccountBalanceAt(context.Background(), account.ID, before)
+	require.NoError(t, err)
+	require.Zero(t, balance)
+}
+
+func TestCreateBalanceSnapshots(t *testing.T) {
+	account := createRandomAccount(t)
+
+	for _, amount := range []int64{20, -5} {
+		_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    amount,
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := testQueries.CreateBalanceSnapshots(context.Background(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+
+	snapshot, err := testQueries.GetLatestBalanceSnapshot(context.Background(), GetLatestBalanceSnapshotParams{
+		AccountID:    account.ID,
+		CoveredUntil: time.Now().Add(time.Second),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(15), snapshot.Balance)
+
+	// the next snapshot continues the previous one
+	_, err = testQueries.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID: account.ID,
+		Amount:    -10,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.CreateBalanceSnapshots(context.Background(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+
+	next, err := testQueries.GetLatestBalanceSnapshot(context.Background(), GetLatestBalanceSnapshotParams{
+		AccountID:    account.ID,
+		CoveredUntil: time.Now().Add(time.Second),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), next.Balance)
+	require.Greater(t, next.EntryID, snapshot.EntryID)
+}
+
+func TestAccountBalanceHistory(t *testing.T) {
+	store := NewStore(testPool)
+	account := createRandomAccount(t)
+
+	for _, amount := range []int64{30, -10} {
+		_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    amount,
+		})
+		require.NoError(t, err)
+	}
+
+	now := time.Now()
+	history, err := store.AccountBalanceHistory(context.Background(), AccountBalanceHistoryParams{
+		AccountID: account.ID,
+		Interval:  BalanceIntervalDay,
+		From:      now.AddDate(0, 0, -2),
+		To:        now,
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+
+	// the entries were created today
+	require.Zero(t, history[0].Balance)
+	require.Zero(t, history[1].Balance)
+	require.Equal(t, int64(20), history[2].Balance)
+	require.Equal(t, history[1].PeriodEnd, history[2].PeriodStart)
+}
+
+func TestBalancePeriods(t *testing.T) {
+	// a wednesday
+	from := time.Date(2023, time.March, 29, 15, 0, 0, 0, time.UTC)
+
+	days := BalancePeriods(BalanceIntervalDay, from, from.AddDate(0, 0, 3))
+	require.Len(t, days, 4)
+	require.Equal(t, time.Date(2023, time.March, 29, 0, 0, 0, 0, time.UTC), days[0])
+	require.Equal(t, time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC), days[3])
+
+	weeks := BalancePeriods(BalanceIntervalWeek, from, from.AddDate(0, 0, 7))
+	require.Len(t, weeks, 2)
+	require.Equal(t, time.Monday, weeks[0].Weekday())
+	require.Equal(t, time.Date(2023, time.March, 27, 0, 0, 0, 0, time.UTC), weeks[0])
+
+	months := BalancePeriods(BalanceIntervalMonth, from, from.AddDate(0, 1, 0))
+	require.Len(t, months, 2)
+	require.Equal(t, time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC), months[1])
+
+	require.Nil(t, BalancePeriods("hour", from, from.AddDate(0, 0, 1)))
+	require.Nil(t, BalancePeriods(BalanceIntervalDay, from, from.AddDate(0, 0, -1)))
+}
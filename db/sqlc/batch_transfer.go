@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// BatchTransferLeg - a single payment of a batch transfer
+type BatchTransferLeg struct {
+	ToAccountID int64  `json:"to_account_id"`
+	Amount      int64  `json:"amount"`
+	Reference   string `json:"reference"`
+}
+
+// BatchTransferTxParams - contains the input parameters of the batch transfer transaction
+type BatchTransferTxParams struct {
+	FromAccountID int64              `json:"from_account_id"`
+	Legs          []BatchTransferLeg `json:"legs"`
+}
+
+// BatchTransferLegResult - contains the output of a single leg - in the order of the legs
+type BatchTransferLegResult struct {
+	Transfer  Transfer `json:"transfer"`
+	ToAccount Account  `json:"to_account"`
+	FromEntry Entry    `json:"from_entry"`
+	ToEntry   Entry    `json:"to_entry"`
+}
+
+// BatchTransferTxResult - contains the output of the batch transfer transaction
+type BatchTransferTxResult struct {
+	FromAccount Account                  `json:"from_account"`
+	Legs        []BatchTransferLegResult `json:"legs"`
+}
+
+// BatchLegError - the error of a single leg, failing the whole batch
+type BatchLegError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchLegError) Error() string {
+	return fmt.Sprintf("leg %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchLegError) Unwrap() error {
+	return e.Err
+}
+
+/*
+* BatchTransferTx - preforms many transfers from a single account - all or nothing
+* I) locks all the accounts once - always in ascending id order for avoiding deadlocks
+* II) validates the legs and checks the from account can cover the total amount
+* III) creates a transfer + entries for every leg and updates the to accounts balances
+* IV) updates the from account balance once with the total amount
+* within a single database transaction
+ */
+func (store *SQLStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		accounts, err := lockBatchAccounts(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
+		fromAccount := accounts[arg.FromAccountID]
+		if err = checkAccountActive(fromAccount); err != nil {
+			return err
+		}
+		var total int64
+		for i, leg := range arg.Legs {
+			if leg.ToAccountID == arg.FromAccountID {
+				return &BatchLegError{Index: i, Err: ErrSameAccount}
+			}
+			if accounts[leg.ToAccountID].Currency != fromAccount.Currency {
+				return &BatchLegError{Index: i, Err: ErrCurrencyMismatch}
+			}
+			if err = checkAccountActive(accounts[leg.ToAccountID]); err != nil {
+				return &BatchLegError{Index: i, Err: err}
+			}
+			total += leg.Amount
+		}
+
+		if err = checkAvailableBalance(ctx, q, fromAccount, total); err != nil {
+			return err
+		}
+
+		result.Legs = make([]BatchTransferLegResult, len(arg.Legs))
+		for i, leg := range arg.Legs {
+			legResult := &result.Legs[i]
+			legResult.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+				FromAccountID: arg.FromAccountID,
+				ToAccountID:   leg.ToAccountID,
+				Amount:        leg.Amount,
+				ToAmount:      leg.Amount,
+				ExchangeRate:  1,
+				Reference:     leg.Reference,
+			})
+			if err != nil {
+				return err
+			}
+
+			legResult.FromEntry, legResult.ToEntry, err = makeEntry(ctx, q, legResult.Transfer.ID, arg.FromAccountID, leg.ToAccountID, leg.Amount, leg.Amount)
+			if err != nil {
+				return err
+			}
+
+			// the to account is already locked - updating the balance directly
+			legResult.ToAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
+				ID:      leg.ToAccountID,
+				Balance: accounts[leg.ToAccountID].Balance + leg.Amount,
+			})
+			if err != nil {
+				return err
+			}
+			accounts[leg.ToAccountID] = legResult.ToAccount
+		}
+
+		result.FromAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
+			ID:      arg.FromAccountID,
+			Balance: fromAccount.Balance - total,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// lockBatchAccounts - locking the from account and the to accounts of the batch, every account is locked once
+func lockBatchAccounts(ctx context.Context, q *Queries, arg BatchTransferTxParams) (map[int64]Account, error) {
+	// the first leg of every to account - for reporting a missing account
+	legIndex := make(map[int64]int)
+	ids := []int64{arg.FromAccountID}
+	for i, leg := range arg.Legs {
+		if _, ok := legIndex[leg.ToAccountID]; !ok && leg.ToAccountID != arg.FromAccountID {
+			legIndex[leg.ToAccountID] = i
+			ids = append(ids, leg.ToAccountID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			if i, ok := legIndex[id]; ok {
+				return nil, &BatchLegError{Index: i, Err: err}
+			}
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
+}
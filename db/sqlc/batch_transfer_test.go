@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchTransferTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := createRandomUser(t)
+	from := createAccountWithCurrency(t, user.Username, "ILS", 100)
+	to1 := createAccountWithCurrency(t, user.Username, "ILS", 0)
+	to2 := createAccountWithCurrency(t, user.Username, "ILS", 0)
+
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		FromAccountID: from.ID,
+		Legs: []BatchTransferLeg{
+			{ToAccountID: to1.ID, Amount: 10, Reference: "salary"},
+			{ToAccountID: to2.ID, Amount: 20, Reference: "salary"},
+			{ToAccountID: to1.ID, Amount: 30, Reference: "bonus"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(40), result.FromAccount.Balance)
+	require.Len(t, result.Legs, 3)
+
+	// the legs are returned in the request order
+	require.Equal(t, "salary", result.Legs[0].Transfer.Reference)
+	require.Equal(t, int64(10), result.Legs[0].ToAccount.Balance)
+	require.Equal(t, int64(20), result.Legs[1].ToAccount.Balance)
+	require.Equal(t, "bonus", result.Legs[2].Transfer.Reference)
+	require.Equal(t, int64(40), result.Legs[2].ToAccount.Balance)
+
+	for _, leg := range result.Legs {
+		require.Equal(t, from.ID, leg.Transfer.FromAccountID)
+		require.Equal(t, -leg.Transfer.Amount, leg.FromEntry.Amount)
+		require.Equal(t, leg.Transfer.Amount, leg.ToEntry.Amount)
+	}
+}
+
+func TestBatchTransferTxAllOrNothing(t *testing.T) {
+	store := NewStore(testPool)
+	user := createRandomUser(t)
+	from := createAccountWithCurrency(t, user.Username, "ILS", 100)
+	to := createAccountWithCurrency(t, user.Username, "ILS", 0)
+	usd := createAccountWithCurrency(t, user.Username, "USD", 0)
+
+	testCases := []struct {
+		name  string
+		legs  []BatchTransferLeg
+		check func(t *testing.T, err error)
+	}{{
+		name: "InsufficientFunds",
+		legs: []BatchTransferLeg{{ToAccountID: to.ID, Amount: 60}, {ToAccountID: to.ID, Amount: 41}},
+		check: func(t *testing.T, err error) {
+			require.ErrorIs(t, err, ErrInsufficientFunds)
+		},
+	}, {
+		name: "CurrencyMismatch",
+		legs: []BatchTransferLeg{{ToAccountID: to.ID, Amount: 10}, {ToAccountID: usd.ID, Amount: 10}},
+		check: func(t *testing.T, err error) {
+			require.ErrorIs(t, err, ErrCurrencyMismatch)
+		},
+	}, {
+		name: "AccountNotFound",
+		legs: []BatchTransferLeg{{ToAccountID: to.ID, Amount: 10}, {ToAccountID: to.ID + 1000000, Amount: 10}},
+		check: func(t *testing.T, err error) {
+			require.ErrorIs(t, err, ErrNotFound)
+
+			var legErr *BatchLegError
+			require.ErrorAs(t, err, &legErr)
+			require.Equal(t, 1, legErr.Index)
+		},
+	}}
+
+	for _, tc := range testCases {
+		_, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+			FromAccountID: from.ID,
+			Legs:          tc.legs,
+		})
+		tc.check(t, err)
+	}
+
+	// nothing was moved
+	account, err := testQueries.GetAccount(context.Background(), to.ID)
+	require.NoError(t, err)
+	require.Zero(t, account.Balance)
+
+	account, err = testQueries.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), account.Balance)
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+/*
+* classifiedDB - the DBTX of the queries of the store (the pool or a transaction)
+* every query returns the errors of the store (e.g. ErrNotFound, *ErrDuplicate) instead of the errors of the driver
+* so a new query is classified without any code of its own
+ */
+type classifiedDB struct {
+	db DBTX
+}
+
+func (c classifiedDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := c.db.Exec(ctx, sql, args...)
+	return tag, storeError(err)
+}
+
+func (c classifiedDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := c.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return classifiedRows{Rows: rows}, nil
+}
+
+func (c classifiedDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return classifiedRow{row: c.db.QueryRow(ctx, sql, args...)}
+}
+
+// classifiedRow - the row of a :one query - the no rows error is returned by Scan
+type classifiedRow struct {
+	row pgx.Row
+}
+
+func (r classifiedRow) Scan(dest ...interface{}) error {
+	return storeError(r.row.Scan(dest...))
+}
+
+// classifiedRows - the rows of a :many query - the errors of the query are returned by Scan and Err
+type classifiedRows struct {
+	pgx.Rows
+}
+
+func (r classifiedRows) Scan(dest ...interface{}) error {
+	return storeError(r.Rows.Scan(dest...))
+}
+
+func (r classifiedRows) Err() error {
+	return storeError(r.Rows.Err())
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/shimon-git/simple-bank/util"
 	"github.com/stretchr/testify/require"
 )
@@ -71,6 +72,57 @@ func TestListEntries(t *testing.T) {
 	}
 }
 
+func TestListAccountEntries(t *testing.T) {
+	account := createRandomAccount(t)
+	amounts := []int64{100, -30, 50, -20, 10}
+	for _, amount := range amounts {
+		_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    amount,
+		})
+		require.NoError(t, err)
+	}
+
+	// the newest entries first - each with the sum of the entries up to it
+	page1, err := testQueries.ListAccountEntries(context.Background(), ListAccountEntriesParams{
+		AccountID: account.ID,
+		PageSize:  3,
+	})
+	require.NoError(t, err)
+	require.Len(t, page1, 3)
+	require.Equal(t, []int64{110, 100, 120}, []int64{page1[0].RunningBalance, page1[1].RunningBalance, page1[2].RunningBalance})
+
+	// the next page starts after the cursor - the running balance still includes the newer pages history
+	page2, err := testQueries.ListAccountEntries(context.Background(), ListAccountEntriesParams{
+		AccountID: account.ID,
+		Cursor:    sql.NullInt64{Int64: page1[2].ID, Valid: true},
+		PageSize:  3,
+	})
+	require.NoError(t, err)
+	require.Len(t, page2, 2)
+	require.Equal(t, int64(70), page2[0].RunningBalance)
+	require.Equal(t, int64(100), page2[1].RunningBalance)
+
+	// filters
+	debits, err := testQueries.ListAccountEntries(context.Background(), ListAccountEntriesParams{
+		AccountID: account.ID,
+		Direction: sql.NullString{String: "debit", Valid: true},
+		MinAmount: sql.NullInt64{Int64: 25, Valid: true},
+		PageSize:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, debits, 1)
+	require.Equal(t, int64(-30), debits[0].Amount)
+
+	future, err := testQueries.ListAccountEntries(context.Background(), ListAccountEntriesParams{
+		AccountID: account.ID,
+		FromTime:  sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true},
+		PageSize:  10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, future)
+}
+
 func TestUpdateEntry(t *testing.T) {
 	entry1 := createRandomEntry(t)
 
@@ -99,7 +151,7 @@ func TestDeleteEntry(t *testing.T) {
 	entry2, err := testQueries.GetEntry(context.Background(), entry1.ID)
 
 	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.EqualError(t, err, pgx.ErrNoRows.Error())
 
 	require.Empty(t, entry2)
 }
@@ -1,27 +1,167 @@
 package db
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
 )
 
+// the postgres error codes classified by the store
 const (
-	ForeignKeyViolation = "23503"
-	UniqueViolation     = "23505"
+	ForeignKeyViolation  = "23503"
+	UniqueViolation      = "23505"
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
 )
 
-var ErrRecordNotFound = pgx.ErrNoRows
+// ErrNotFound - the record doesn't exist
+var ErrNotFound = errors.New("record not found")
 
-var ErrUniqueViolation = &pgconn.PgError{
-	Code: UniqueViolation,
+// ErrSerialization - the transaction conflicted with a concurrent transaction (serialization failure or deadlock), it can be retried
+var ErrSerialization = errors.New("the transaction conflicted with a concurrent transaction")
+
+// ErrDuplicate - the record violates the unique constraint
+type ErrDuplicate struct {
+	Constraint string
+	cause      error
+}
+
+func (err *ErrDuplicate) Error() string {
+	return fmt.Sprintf("duplicate record: violates the unique constraint %q", err.Constraint)
+}
+
+func (err *ErrDuplicate) Unwrap() error {
+	return err.cause
+}
+
+// Is - matching a duplicate record error of the same constraint (of any constraint when the constraint of the target is empty)
+func (err *ErrDuplicate) Is(target error) bool {
+	t, ok := target.(*ErrDuplicate)
+	return ok && (t.Constraint == "" || t.Constraint == err.Constraint)
+}
+
+// ErrForeignKey - the record references a missing record (or a referenced record is deleted)
+type ErrForeignKey struct {
+	Constraint string
+	cause      error
+}
+
+func (err *ErrForeignKey) Error() string {
+	return fmt.Sprintf("missing referenced record: violates the foreign key constraint %q", err.Constraint)
+}
+
+func (err *ErrForeignKey) Unwrap() error {
+	return err.cause
+}
+
+// Is - matching a foreign key error of the same constraint (of any constraint when the constraint of the target is empty)
+func (err *ErrForeignKey) Is(target error) bool {
+	t, ok := target.(*ErrForeignKey)
+	return ok && (t.Constraint == "" || t.Constraint == err.Constraint)
+}
+
+// ErrInsufficientFunds - the account balance (including its overdraft limit) doesn't cover the withdrawal
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrCurrencyMismatch - the account currency doesn't match the currency of the operation
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// ErrQuoteUnavailable - the exchange quote doesn't exist, expired or was already used
+var ErrQuoteUnavailable = errors.New("exchange quote is expired, used or doesn't exist")
+
+// ErrExchangeAmountTooSmall - the amount converted by the quote rate rounds to nothing
+var ErrExchangeAmountTooSmall = errors.New("the amount is too small to be converted by the quote rate")
+
+// different types of errors returned by the transfer reversal
+var (
+	ErrTransferAlreadyReversed = errors.New("transfer was already fully reversed")
+	ErrTransferNotReversible   = errors.New("a reversal can't be reversed")
+	ErrInvalidReversalAmount   = errors.New("reversal amount must be positive and up to the remaining amount of the transfer")
+)
+
+// different types of errors returned by the account holds
+var (
+	ErrHoldUnavailable      = errors.New("hold is expired, captured, voided or doesn't exist")
+	ErrInvalidCaptureAmount = errors.New("capture amount must be positive and up to the held amount")
+)
+
+// different types of errors returned by the account status changes
+var (
+	ErrAccountInactive     = errors.New("the account is not active")
+	ErrAccountStatusChange = errors.New("the account status can't be changed")
+	ErrAccountNotEmpty     = errors.New("an account with money or active holds can't be closed")
+)
+
+// ErrVerifyEmailUnavailable - the verify email code is wrong, expired or was already used
+var ErrVerifyEmailUnavailable = errors.New("verify email code is invalid, expired or was already used")
+
+// ErrPasswordResetUnavailable - the password reset token is wrong, expired or was already used
+var ErrPasswordResetUnavailable = errors.New("password reset token is invalid, expired or was already used")
+
+// different types of errors returned by the two factor authentication
+var (
+	ErrTotpAlreadyEnabled = errors.New("two factor authentication is already enabled")
+	ErrTotpNotPending     = errors.New("two factor authentication enrollment is not pending or the code was already used")
+)
+
+// ErrLoginNotThrottled - the username has no failed login attempts to forget
+var ErrLoginNotThrottled = errors.New("the user has no failed login attempts")
+
+// ErrThrottled - too many attempts were made for one of the throttle keys (e.g. password reset requests)
+var ErrThrottled = errors.New("too many attempts, try again later")
+
+// ErrSameAccount - the money is transferred to the account it's taken from
+var ErrSameAccount = errors.New("the to account must be different from the from account")
+
+// ErrIdempotencyKeyMismatch - the idempotency key was already used with a different request
+var ErrIdempotencyKeyMismatch = errors.New("idempotency key was already used with a different request")
+
+/*
+* storeError - classifying the error of the driver (lib/pq or pgx) as the error of the store
+* no rows - ErrNotFound, unique violation - *ErrDuplicate, foreign key violation - *ErrForeignKey
+* serialization failure or deadlock - ErrSerialization, the other errors are returned as is
+* the error of the driver is kept in the chain of the classified errors (e.g. for the logs)
+ */
+func storeError(err error) error {
+	// the errors of the store are already classified
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrSerialization) ||
+		errors.Is(err, &ErrDuplicate{}) || errors.Is(err, &ErrForeignKey{}) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	code, constraint := pgErrorCode(err)
+	switch code {
+	case UniqueViolation:
+		return &ErrDuplicate{Constraint: constraint, cause: err}
+	case ForeignKeyViolation:
+		return &ErrForeignKey{Constraint: constraint, cause: err}
+	case SerializationFailure, DeadlockDetected:
+		return fmt.Errorf("%w: %w", ErrSerialization, err)
+	}
+	return err
 }
 
-func ErrorCode(err error) string {
+// isNotFound - checking the error is a missing record - of the store or of any of the drivers
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows)
+}
+
+// pgErrorCode - the postgres error code + constraint of the error of the driver (lib/pq or pgx), empty for the other errors
+func pgErrorCode(err error) (code, constraint string) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code), pqErr.Constraint
+	}
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
-		return pgErr.Code
+		return pgErr.Code, pgErr.ConstraintName
 	}
-	return ""
+	return "", ""
 }
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreError(t *testing.T) {
+	// the same postgres errors returned by each of the drivers
+	drivers := []struct {
+		name    string
+		noRows  error
+		pgError func(code, constraint string) error
+	}{
+		{
+			name:   "pq",
+			noRows: sql.ErrNoRows,
+			pgError: func(code, constraint string) error {
+				return &pq.Error{Code: pq.ErrorCode(code), Constraint: constraint}
+			},
+		},
+		{
+			name:   "pgx",
+			noRows: pgx.ErrNoRows,
+			pgError: func(code, constraint string) error {
+				return &pgconn.PgError{Code: code, ConstraintName: constraint}
+			},
+		},
+	}
+
+	for i := range drivers {
+		driver := drivers[i]
+
+		t.Run(driver.name, func(t *testing.T) {
+			// no rows - ErrNotFound
+			err := storeError(fmt.Errorf("get account: %w", driver.noRows))
+			require.ErrorIs(t, err, ErrNotFound)
+
+			// unique violation - ErrDuplicate of the constraint
+			err = storeError(driver.pgError(UniqueViolation, "owner_currency_key"))
+			require.ErrorIs(t, err, &ErrDuplicate{})
+			require.ErrorIs(t, err, &ErrDuplicate{Constraint: "owner_currency_key"})
+			require.NotErrorIs(t, err, &ErrDuplicate{Constraint: "users_pkey"})
+			var duplicate *ErrDuplicate
+			require.True(t, errors.As(err, &duplicate))
+			require.Equal(t, "owner_currency_key", duplicate.Constraint)
+
+			// foreign key violation - ErrForeignKey of the constraint
+			err = storeError(driver.pgError(ForeignKeyViolation, "accounts_owner_fkey"))
+			require.ErrorIs(t, err, &ErrForeignKey{Constraint: "accounts_owner_fkey"})
+			require.NotErrorIs(t, err, &ErrDuplicate{})
+
+			// serialization failure and deadlock - ErrSerialization
+			err = storeError(driver.pgError(SerializationFailure, ""))
+			require.ErrorIs(t, err, ErrSerialization)
+			err = storeError(driver.pgError(DeadlockDetected, ""))
+			require.ErrorIs(t, err, ErrSerialization)
+
+			// the other errors of the driver are returned as is
+			pgErr := driver.pgError("23514", "accounts_status_check")
+			require.Equal(t, pgErr, storeError(pgErr))
+		})
+	}
+}
+
+func TestStoreErrorClassified(t *testing.T) {
+	require.NoError(t, storeError(nil))
+
+	// an error is classified once - e.g. the error of a query of a failed transaction
+	duplicate := storeError(&pq.Error{Code: UniqueViolation, Constraint: "users_pkey"})
+	err := storeError(fmt.Errorf("transaction error: %w", duplicate))
+	require.ErrorIs(t, err, duplicate)
+
+	// the errors of the store are returned as is
+	require.Equal(t, ErrInsufficientFunds, storeError(ErrInsufficientFunds))
+	require.Equal(t, ErrNotFound, storeError(ErrNotFound))
+
+	// the wrapping errors are kept in the chain (e.g. the failed leg of a batch transfer)
+	err = storeError(fmt.Errorf("transaction error: %w", &BatchLegError{Index: 1, Err: sql.ErrNoRows}))
+	require.ErrorIs(t, err, ErrNotFound)
+	var legErr *BatchLegError
+	require.True(t, errors.As(err, &legErr))
+	require.Equal(t, 1, legErr.Index)
+}
+
+// stubDBTX - a DBTX returning the same error of the driver for every query
+type stubDBTX struct {
+	err error
+}
+
+func (s stubDBTX) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, s.err
+}
+
+func (s stubDBTX) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, s.err
+}
+
+func (s stubDBTX) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return stubRow{err: s.err}
+}
+
+type stubRow struct {
+	err error
+}
+
+func (r stubRow) Scan(...interface{}) error {
+	return r.err
+}
+
+func TestClassifiedDB(t *testing.T) {
+	ctx := context.Background()
+
+	// :one query - the no rows error of Scan
+	q := New(classifiedDB{db: stubDBTX{err: pgx.ErrNoRows}})
+	_, err := q.GetAccount(ctx, 1)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// :one, :exec and :many queries - the postgres error of the driver
+	q = New(classifiedDB{db: stubDBTX{err: &pgconn.PgError{Code: UniqueViolation, ConstraintName: "owner_currency_key"}}})
+	_, err = q.CreateAccount(ctx, CreateAccountParams{})
+	require.ErrorIs(t, err, &ErrDuplicate{Constraint: "owner_currency_key"})
+	err = q.DeleteAccount(ctx, 1)
+	require.ErrorIs(t, err, &ErrDuplicate{Constraint: "owner_currency_key"})
+	_, err = q.ListAccounts(ctx, ListAccountsParams{})
+	require.ErrorIs(t, err, &ErrDuplicate{Constraint: "owner_currency_key"})
+
+	// the queries without an error
+	q = New(classifiedDB{db: stubDBTX{}})
+	require.NoError(t, q.DeleteAccount(ctx, 1))
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shimon-git/simple-bank/fx"
+)
+
+/*
+* ExchangeTransferTxParams - contains the input parameters of a cross currency transfer transaction
+* Amount is debited from the from account (in its currency)
+* the to account is credited with the amount converted by the quote rate
+ */
+type ExchangeTransferTxParams struct {
+	TransferTxParams
+	QuoteID  uuid.UUID `json:"quote_id"`
+	Username string    `json:"username"`
+}
+
+/*
+* ExchangeTransferTx - preforms a money transfer between accounts of different currencies
+* I) consumes the exchange quote (must be owned by the user, unused and not expired)
+* II) validates the quote currencies against the accounts currencies and the converted amount
+* III) transfers the money - recording the applied rate and both amounts on the transfer
+* within a single database transaction
+ */
+func (store *SQLStore) ExchangeTransferTx(ctx context.Context, arg ExchangeTransferTxParams) (TransferTxResult, error) {
+	return store.idempotentTransferTx(ctx, arg.Idempotency, arg.Audit, func(q *Queries) (TransferTxResult, error) {
+		// marking the quote as used - a quote can lock the rate for a single transfer only
+		quote, err := q.UseFxQuote(ctx, UseFxQuoteParams{
+			ID:       arg.QuoteID,
+			Username: arg.Username,
+		})
+		if err != nil {
+			if isNotFound(err) {
+				return TransferTxResult{}, ErrQuoteUnavailable
+			}
+			return TransferTxResult{}, err
+		}
+
+		// the quote must convert between the currencies of the accounts
+		if err = validQuoteAccount(ctx, q, arg.FromAccountID, quote.FromCurrency); err != nil {
+			return TransferTxResult{}, err
+		}
+		if err = validQuoteAccount(ctx, q, arg.ToAccountID, quote.ToCurrency); err != nil {
+			return TransferTxResult{}, err
+		}
+
+		// a tiny amount at a small rate rounds to nothing - the from account would pay for no credit
+		toAmount := fx.Convert(arg.Amount, quote.Rate)
+		if toAmount <= 0 {
+			return TransferTxResult{}, ErrExchangeAmountTooSmall
+		}
+
+		return transfer(ctx, q, CreateTransferParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			ToAmount:      toAmount,
+			ExchangeRate:  quote.Rate,
+		})
+	})
+}
+
+// validQuoteAccount - checking the account currency is the currency expected by the quote
+func validQuoteAccount(ctx context.Context, q *Queries, accountID int64, currency string) error {
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	if account.Currency != currency {
+		return ErrCurrencyMismatch
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomFxQuote(t *testing.T, username, fromCurrency, toCurrency string, duration time.Duration) FxQuote {
+	arg := CreateFxQuoteParams{
+		ID:           uuid.New(),
+		Username:     username,
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		Rate:         3.5,
+		ExpiresAt:    time.Now().Add(duration),
+	}
+
+	quote, err := testQueries.CreateFxQuote(context.Background(), arg)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, quote)
+
+	require.Equal(t, arg.ID, quote.ID)
+	require.Equal(t, arg.Username, quote.Username)
+	require.Equal(t, arg.FromCurrency, quote.FromCurrency)
+	require.Equal(t, arg.ToCurrency, quote.ToCurrency)
+	require.Equal(t, arg.Rate, quote.Rate)
+	require.False(t, quote.IsUsed)
+	require.WithinDuration(t, arg.ExpiresAt, quote.ExpiresAt, time.Second)
+	require.NotZero(t, quote.CreatedAt)
+
+	return quote
+}
+
+func TestCreateFxQuote(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomFxQuote(t, user.Username, util.USD, util.ILS, time.Minute)
+}
+
+func TestUseFxQuote(t *testing.T) {
+	user := createRandomUser(t)
+	quote1 := createRandomFxQuote(t, user.Username, util.USD, util.ILS, time.Minute)
+
+	arg := UseFxQuoteParams{
+		ID:       quote1.ID,
+		Username: user.Username,
+	}
+
+	// the first use marks the quote as used
+	quote2, err := testQueries.UseFxQuote(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, quote1.ID, quote2.ID)
+	require.True(t, quote2.IsUsed)
+
+	// the quote can't be used twice
+	_, err = testQueries.UseFxQuote(context.Background(), arg)
+	require.EqualError(t, err, pgx.ErrNoRows.Error())
+
+	// an expired quote can't be used
+	quote3 := createRandomFxQuote(t, user.Username, util.USD, util.ILS, -time.Minute)
+	_, err = testQueries.UseFxQuote(context.Background(), UseFxQuoteParams{
+		ID:       quote3.ID,
+		Username: user.Username,
+	})
+	require.EqualError(t, err, pgx.ErrNoRows.Error())
+}
+
+func TestExchangeTransferTx(t *testing.T) {
+	store := NewStore(testPool)
+
+	user := createRandomUser(t)
+	fromAccount := createAccountWithCurrency(t, user.Username, util.USD, 1000)
+	toAccount := createAccountWithCurrency(t, createRandomUser(t).Username, util.ILS, 0)
+	quote := createRandomFxQuote(t, user.Username, util.USD, util.ILS, time.Minute)
+
+	arg := ExchangeTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: fromAccount.ID,
+			ToAccountID:   toAccount.ID,
+			Amount:        100,
+		},
+		QuoteID:  quote.ID,
+		Username: user.Username,
+	}
+
+	result, err := store.ExchangeTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// the transfer records the applied rate and both amounts
+	require.Equal(t, int64(100), result.Transfer.Amount)
+	require.Equal(t, int64(350), result.Transfer.ToAmount)
+	require.Equal(t, quote.Rate, result.Transfer.ExchangeRate)
+
+	// each account moved by its own currency amount
+	require.Equal(t, int64(-100), result.FromEntry.Amount)
+	require.Equal(t, int64(350), result.ToEntry.Amount)
+	require.Equal(t, int64(900), result.FromAccount.Balance)
+	require.Equal(t, int64(350), result.ToAccount.Balance)
+
+	// the quote was consumed by the transfer
+	_, err = store.ExchangeTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrQuoteUnavailable)
+
+	// the quote currencies must match the accounts currencies
+	quote = createRandomFxQuote(t, user.Username, util.EUR, util.ILS, time.Minute)
+	arg.QuoteID = quote.ID
+	_, err = store.ExchangeTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	// a tiny amount at a small rate rounds to nothing - the transfer is rejected and the quote is kept
+	quote, err = testQueries.CreateFxQuote(context.Background(), CreateFxQuoteParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		FromCurrency: util.USD,
+		ToCurrency:   util.ILS,
+		Rate:         0.004,
+		ExpiresAt:    time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	arg.QuoteID = quote.ID
+	arg.Amount = 1
+	_, err = store.ExchangeTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrExchangeAmountTooSmall)
+
+	arg.Amount = 500
+	result, err = store.ExchangeTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Transfer.ToAmount)
+}
+
+// createAccountWithCurrency - creates an account of the given owner, currency and balance
+func createAccountWithCurrency(t *testing.T, owner, currency string, balance int64) Account {
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    owner,
+		Balance:  balance,
+		Currency: currency,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, account)
+
+	return account
+}
@@ -0,0 +1,316 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shimon-git/simple-bank/util"
+)
+
+// UserThrottleKey - the login throttle key of the username
+func UserThrottleKey(username string) string {
+	return fmt.Sprintf("user:%s", username)
+}
+
+// IPThrottleKey - the login throttle key of the client ip
+func IPThrottleKey(clientIP string) string {
+	return fmt.Sprintf("ip:%s", clientIP)
+}
+
+// PasswordResetEmailThrottleKey - the throttle key of the password reset requests of the email
+func PasswordResetEmailThrottleKey(email string) string {
+	return fmt.Sprintf("reset_email:%s", strings.ToLower(email))
+}
+
+// PasswordResetIPThrottleKey - the throttle key of the password reset requests of the client ip
+func PasswordResetIPThrottleKey(clientIP string) string {
+	return fmt.Sprintf("reset_ip:%s", clientIP)
+}
+
+/*
+* LoginThrottlePolicy - how the failed logins of a throttle key are slowed down
+* BackoffAfter: the failed attempts allowed before the back-off starts
+* Backoff: the first back-off - doubled by every further failed attempt (up to the lockout duration)
+* MaxAttempts: the failed attempts locking out the key for the lockout duration
+* Window: the failed attempts older than the window are forgotten
+ */
+type LoginThrottlePolicy struct {
+	BackoffAfter    int32         `json:"backoff_after"`
+	Backoff         time.Duration `json:"backoff"`
+	MaxAttempts     int32         `json:"max_attempts"`
+	LockoutDuration time.Duration `json:"lockout_duration"`
+	Window          time.Duration `json:"window"`
+}
+
+// UserLoginPolicy - the configured throttling of the failed logins of a username
+func UserLoginPolicy(config util.Config) LoginThrottlePolicy {
+	return LoginThrottlePolicy{
+		BackoffAfter:    config.LoginBackoffAfter,
+		Backoff:         config.LoginBackoff,
+		MaxAttempts:     config.LoginMaxAttempts,
+		LockoutDuration: config.LoginLockoutDuration,
+		Window:          config.LoginAttemptWindow,
+	}
+}
+
+// IPLoginPolicy - the configured throttling of the failed logins of a client ip - it's locked out without a back-off (e.g. password spraying)
+func IPLoginPolicy(config util.Config) LoginThrottlePolicy {
+	return LoginThrottlePolicy{
+		BackoffAfter:    config.LoginIPMaxAttempts,
+		MaxAttempts:     config.LoginIPMaxAttempts,
+		LockoutDuration: config.LoginLockoutDuration,
+		Window:          config.LoginAttemptWindow,
+	}
+}
+
+// PasswordResetEmailPolicy - the configured throttling of the password reset requests of an email - every request is counted
+func PasswordResetEmailPolicy(config util.Config) LoginThrottlePolicy {
+	return LoginThrottlePolicy{
+		MaxAttempts:     config.PasswordResetMaxRequests,
+		LockoutDuration: config.PasswordResetRequestWindow,
+		Window:          config.PasswordResetRequestWindow,
+	}
+}
+
+// PasswordResetIPPolicy - the configured throttling of the password reset requests of a client ip - every request is counted
+func PasswordResetIPPolicy(config util.Config) LoginThrottlePolicy {
+	return LoginThrottlePolicy{
+		MaxAttempts:     config.PasswordResetIPMaxRequests,
+		LockoutDuration: config.PasswordResetRequestWindow,
+		Window:          config.PasswordResetRequestWindow,
+	}
+}
+
+// LockedUntil - until when the key is throttled after the given number of failed attempts (the zero time when it isn't)
+func (policy LoginThrottlePolicy) LockedUntil(failedAttempts int32, now time.Time) time.Time {
+	if policy.MaxAttempts > 0 && failedAttempts >= policy.MaxAttempts {
+		return now.Add(policy.LockoutDuration)
+	}
+	if policy.Backoff <= 0 || failedAttempts < policy.BackoffAfter {
+		return time.Time{}
+	}
+
+	// the back-off is doubled by every failed attempt - capped so the shift can't overflow
+	backoff := policy.LockoutDuration
+	if shift := failedAttempts - policy.BackoffAfter; shift < 32 {
+		if doubled := policy.Backoff << shift; doubled < backoff || backoff <= 0 {
+			backoff = doubled
+		}
+	}
+	return now.Add(backoff)
+}
+
+// LoginAttemptsParams - a login attempt counted for the username and for the client ip, before the password (or the second factor) is verified
+func LoginAttemptsParams(config util.Config, username, clientIP string) ThrottleAttemptsTxParams {
+	return ThrottleAttemptsTxParams{
+		Attempts: []ThrottleAttempt{
+			{Key: UserThrottleKey(username), Policy: UserLoginPolicy(config)},
+			{Key: IPThrottleKey(clientIP), Policy: IPLoginPolicy(config)},
+		},
+	}
+}
+
+/*
+* RecordFailedLoginTxParams - contains the input parameters of the record failed login transaction
+* UserThrottle: the throttle of the username after the attempt was counted (by ThrottleAttemptsTx)
+* Details: recorded with the failed login audit event (e.g. the reason and the user agent)
+ */
+type RecordFailedLoginTxParams struct {
+	Username     string              `json:"username"`
+	ClientIP     string              `json:"client_ip"`
+	UserThrottle LoginThrottle       `json:"user_throttle"`
+	UserPolicy   LoginThrottlePolicy `json:"user_policy"`
+	Details      any                 `json:"details"`
+	Audit        AuditParams         `json:"audit"`
+}
+
+/*
+* RecordFailedLoginTxResult - the result of the record failed login transaction
+* Locked: the username was locked out by this attempt
+ */
+type RecordFailedLoginTxResult struct {
+	Locked bool `json:"locked"`
+}
+
+/*
+ * RecordFailedLoginTx - records a failed login attempt - the attempt was already counted before the password was verified
+ * I) records the failed login in the audit trail
+ * II) records the lockout of the username in the audit trail - so the admins can unlock it
+ * within a single database transaction
+ */
+func (store *SQLStore) RecordFailedLoginTx(ctx context.Context, arg RecordFailedLoginTxParams) (RecordFailedLoginTxResult, error) {
+	var result RecordFailedLoginTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		err := recordAuditEvent(ctx, q, arg.Audit, AuditUserLoginFailed, UserTarget(arg.Username), nil, arg.Details)
+		if err != nil {
+			return err
+		}
+
+		// every failed attempt from the max attempts on locks the username out again
+		if arg.UserPolicy.MaxAttempts <= 0 || arg.UserThrottle.FailedAttempts < arg.UserPolicy.MaxAttempts {
+			return nil
+		}
+		result.Locked = true
+		return recordAuditEvent(ctx, q, arg.Audit, AuditUserLockout, UserTarget(arg.Username), nil, lockoutAuditEvent{
+			FailedAttempts: arg.UserThrottle.FailedAttempts,
+			LockedUntil:    arg.UserThrottle.LockedUntil,
+			ClientIP:       arg.ClientIP,
+		})
+	})
+
+	return result, err
+}
+
+// ForgetLoginAttemptTxParams - contains the input parameters of the forget login attempt transaction
+type ForgetLoginAttemptTxParams struct {
+	Username string `json:"username"`
+	ClientIP string `json:"client_ip"`
+}
+
+/*
+ * ForgetLoginAttemptTx - forgets the counted attempt of a successful second factor (e.g. a valid step-up code) within a single database transaction
+ * the attempts of the username are forgotten, the attempt is taken back from the client ip - its failed attempts are kept
+ */
+func (store *SQLStore) ForgetLoginAttemptTx(ctx context.Context, arg ForgetLoginAttemptTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		return forgetLoginAttempts(ctx, q, arg.Username, arg.ClientIP)
+	})
+}
+
+// forgetLoginAttempts - forgets the attempts of the username and takes the successful attempt back from the client ip within the given transaction
+func forgetLoginAttempts(ctx context.Context, q *Queries, username, clientIP string) error {
+	if err := q.DeleteLoginThrottle(ctx, UserThrottleKey(username)); err != nil {
+		return err
+	}
+	return q.ForgetLoginAttempt(ctx, IPThrottleKey(clientIP))
+}
+
+// ThrottleAttempt - an attempt counted for the throttle key by its policy
+type ThrottleAttempt struct {
+	Key    string              `json:"key"`
+	Policy LoginThrottlePolicy `json:"policy"`
+}
+
+// ThrottleAttemptsTxParams - contains the input parameters of the throttle attempts transaction
+type ThrottleAttemptsTxParams struct {
+	Attempts []ThrottleAttempt `json:"attempts"`
+}
+
+/*
+* ThrottleAttemptsTxResult - the result of the throttle attempts transaction
+* Throttles: the throttles of the keys after the attempt
+* LockedUntil: until when the attempt is rejected - set with ErrThrottled
+ */
+type ThrottleAttemptsTxResult struct {
+	Throttles   []LoginThrottle `json:"throttles"`
+	LockedUntil time.Time       `json:"locked_until"`
+}
+
+// Throttle - the throttle of the key after the attempt (the zero throttle when the key wasn't counted)
+func (result ThrottleAttemptsTxResult) Throttle(key string) LoginThrottle {
+	for _, throttle := range result.Throttles {
+		if throttle.Key == key {
+			return throttle
+		}
+	}
+	return LoginThrottle{}
+}
+
+/*
+ * ThrottleAttemptsTx - counts an attempt for every key and checks it within a single database transaction
+ * the counting locks the row of the key until the commit - so the concurrent attempts are counted and checked one at a time
+ * returns ErrThrottled when one of the keys is locked - the attempt is rolled back and not counted
+ */
+func (store *SQLStore) ThrottleAttemptsTx(ctx context.Context, arg ThrottleAttemptsTxParams) (ThrottleAttemptsTxResult, error) {
+	var result ThrottleAttemptsTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		now := time.Now()
+
+		for _, attempt := range arg.Attempts {
+			throttle, err := throttleAttempt(ctx, q, attempt, now)
+			if err != nil {
+				if errors.Is(err, ErrThrottled) {
+					result.LockedUntil = throttle.LockedUntil
+				}
+				return err
+			}
+			result.Throttles = append(result.Throttles, throttle)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+/*
+* throttleAttempt - counts the attempt of the key and locks it by the policy within the given transaction
+* returns ErrThrottled (with the throttle) when the key was already locked
+ */
+func throttleAttempt(ctx context.Context, q *Queries, attempt ThrottleAttempt, now time.Time) (LoginThrottle, error) {
+	throttle, err := q.RecordLoginFailure(ctx, RecordLoginFailureParams{
+		Key:         attempt.Key,
+		WindowStart: now.Add(-attempt.Policy.Window),
+	})
+	if err != nil {
+		return LoginThrottle{}, err
+	}
+	if throttle.LockedUntil.After(now) {
+		return throttle, ErrThrottled
+	}
+
+	lockedUntil := attempt.Policy.LockedUntil(throttle.FailedAttempts, now)
+	if lockedUntil.IsZero() {
+		return throttle, nil
+	}
+	return q.LockLoginThrottle(ctx, LockLoginThrottleParams{
+		Key:         attempt.Key,
+		LockedUntil: lockedUntil,
+	})
+}
+
+// lockoutAuditEvent - the lockout of a username as recorded in the audit trail
+type lockoutAuditEvent struct {
+	FailedAttempts int32     `json:"failed_attempts"`
+	LockedUntil    time.Time `json:"locked_until"`
+	ClientIP       string    `json:"client_ip"`
+}
+
+// UnlockUserTxParams - contains the input parameters of the unlock user transaction
+type UnlockUserTxParams struct {
+	Username string      `json:"username"`
+	Audit    AuditParams `json:"audit"`
+}
+
+/*
+ * UnlockUserTx - forgets the failed login attempts of the username (e.g. an admin unlocking a locked out user)
+ * and records the unlock in the audit trail
+ * returns ErrLoginNotThrottled when the username has no failed attempts
+ */
+func (store *SQLStore) UnlockUserTx(ctx context.Context, arg UnlockUserTxParams) (LoginThrottle, error) {
+	var throttle LoginThrottle
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		key := UserThrottleKey(arg.Username)
+
+		throttle, err = q.GetLoginThrottle(ctx, key)
+		if isNotFound(err) {
+			return ErrLoginNotThrottled
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = q.DeleteLoginThrottle(ctx, key); err != nil {
+			return err
+		}
+		return recordAuditEvent(ctx, q, arg.Audit, AuditUserUnlock, UserTarget(arg.Username), throttle, nil)
+	})
+
+	return throttle, err
+}
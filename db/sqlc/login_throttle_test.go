@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginThrottlePolicyLockedUntil(t *testing.T) {
+	policy := LoginThrottlePolicy{
+		BackoffAfter:    3,
+		Backoff:         time.Second,
+		MaxAttempts:     10,
+		LockoutDuration: 15 * time.Minute,
+	}
+	now := time.Now()
+
+	// the first attempts are free
+	require.True(t, policy.LockedUntil(2, now).IsZero())
+	// the back-off is doubled by every failed attempt
+	require.Equal(t, now.Add(time.Second), policy.LockedUntil(3, now))
+	require.Equal(t, now.Add(4*time.Second), policy.LockedUntil(5, now))
+	// the back-off never exceeds the lockout
+	policy.Backoff = time.Minute
+	require.Equal(t, now.Add(15*time.Minute), policy.LockedUntil(9, now))
+	// the max attempts lock the key out
+	require.Equal(t, now.Add(15*time.Minute), policy.LockedUntil(10, now))
+	require.Equal(t, now.Add(15*time.Minute), policy.LockedUntil(1000, now))
+}
+
+func TestRecordFailedLoginTx(t *testing.T) {
+	store := NewStore(testPool)
+	username := util.RandomOwner()
+	clientIP := util.RandomString(10)
+
+	userPolicy := LoginThrottlePolicy{
+		BackoffAfter:    100,
+		MaxAttempts:     3,
+		LockoutDuration: time.Minute,
+		Window:          time.Minute,
+	}
+	attempts := ThrottleAttemptsTxParams{
+		Attempts: []ThrottleAttempt{
+			{Key: UserThrottleKey(username), Policy: userPolicy},
+			{Key: IPThrottleKey(clientIP), Policy: LoginThrottlePolicy{
+				BackoffAfter:    100,
+				MaxAttempts:     100,
+				LockoutDuration: time.Minute,
+				Window:          time.Minute,
+			}},
+		},
+	}
+
+	// every attempt is counted before the password is verified - the failure is recorded after it
+	var result RecordFailedLoginTxResult
+	for i := int32(1); i <= userPolicy.MaxAttempts; i++ {
+		throttles, err := store.ThrottleAttemptsTx(context.Background(), attempts)
+		require.NoError(t, err)
+		userThrottle := throttles.Throttle(UserThrottleKey(username))
+		require.Equal(t, i, userThrottle.FailedAttempts)
+		require.Equal(t, i, throttles.Throttle(IPThrottleKey(clientIP)).FailedAttempts)
+
+		result, err = store.RecordFailedLoginTx(context.Background(), RecordFailedLoginTxParams{
+			Username:     username,
+			ClientIP:     clientIP,
+			UserThrottle: userThrottle,
+			UserPolicy:   userPolicy,
+			Details:      map[string]string{"reason": "incorrect password"},
+			Audit:        AuditParams{Actor: username, RequestID: util.RandomString(10)},
+		})
+		require.NoError(t, err)
+	}
+	// the max attempts lock the username out
+	require.True(t, result.Locked)
+
+	// the next attempt is rejected without being counted
+	throttles, err := store.ThrottleAttemptsTx(context.Background(), attempts)
+	require.ErrorIs(t, err, ErrThrottled)
+	require.WithinDuration(t, time.Now().Add(time.Minute), throttles.LockedUntil, time.Second)
+
+	// the admin unlocks the user - the throttle of the client ip is kept
+	throttle, err := store.UnlockUserTx(context.Background(), UnlockUserTxParams{
+		Username: username,
+		Audit:    AuditParams{Actor: util.RandomOwner(), RequestID: util.RandomString(10)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, userPolicy.MaxAttempts, throttle.FailedAttempts)
+
+	_, err = store.UnlockUserTx(context.Background(), UnlockUserTxParams{Username: username})
+	require.ErrorIs(t, err, ErrLoginNotThrottled)
+
+	ipThrottle, err := testQueries.GetLoginThrottle(context.Background(), IPThrottleKey(clientIP))
+	require.NoError(t, err)
+	require.Equal(t, userPolicy.MaxAttempts, ipThrottle.FailedAttempts)
+}
+
+func TestForgetLoginAttemptTx(t *testing.T) {
+	store := NewStore(testPool)
+	username := util.RandomOwner()
+	clientIP := util.RandomString(10)
+	policy := LoginThrottlePolicy{BackoffAfter: 100, MaxAttempts: 100, Window: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		_, err := store.ThrottleAttemptsTx(context.Background(), ThrottleAttemptsTxParams{
+			Attempts: []ThrottleAttempt{
+				{Key: UserThrottleKey(username), Policy: policy},
+				{Key: IPThrottleKey(clientIP), Policy: policy},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	// a successful attempt forgets the username and is taken back from the client ip
+	err := store.ForgetLoginAttemptTx(context.Background(), ForgetLoginAttemptTxParams{
+		Username: username,
+		ClientIP: clientIP,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.GetLoginThrottle(context.Background(), UserThrottleKey(username))
+	require.EqualError(t, err, pgx.ErrNoRows.Error())
+
+	ipThrottle, err := testQueries.GetLoginThrottle(context.Background(), IPThrottleKey(clientIP))
+	require.NoError(t, err)
+	require.Equal(t, int32(1), ipThrottle.FailedAttempts)
+}
+
+func TestRecordLoginFailureWindow(t *testing.T) {
+	key := IPThrottleKey(util.RandomString(10))
+
+	throttle, err := testQueries.RecordLoginFailure(context.Background(), RecordLoginFailureParams{
+		Key:         key,
+		WindowStart: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), throttle.FailedAttempts)
+
+	// the failed attempts before the window are forgotten
+	throttle, err = testQueries.RecordLoginFailure(context.Background(), RecordLoginFailureParams{
+		Key:         key,
+		WindowStart: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), throttle.FailedAttempts)
+}
+
+func TestThrottleAttemptsTx(t *testing.T) {
+	store := NewStore(testPool)
+	policy := LoginThrottlePolicy{
+		MaxAttempts:     2,
+		LockoutDuration: time.Minute,
+		Window:          time.Minute,
+	}
+	emailKey := PasswordResetEmailThrottleKey(util.RandomEmail())
+	ipKey := PasswordResetIPThrottleKey(util.RandomString(10))
+	arg := ThrottleAttemptsTxParams{
+		Attempts: []ThrottleAttempt{
+			{Key: emailKey, Policy: policy},
+			{Key: ipKey, Policy: LoginThrottlePolicy{MaxAttempts: 100, LockoutDuration: time.Minute, Window: time.Minute}},
+		},
+	}
+
+	result, err := store.ThrottleAttemptsTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, result.Throttles, 2)
+	require.Equal(t, int32(1), result.Throttles[0].FailedAttempts)
+
+	// the max attempts are allowed - and lock the key
+	result, err = store.ThrottleAttemptsTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), result.Throttles[0].FailedAttempts)
+	require.WithinDuration(t, time.Now().Add(time.Minute), result.Throttles[0].LockedUntil, time.Second)
+
+	// the locked key rejects the attempt - which isn't counted
+	result, err = store.ThrottleAttemptsTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrThrottled)
+	require.WithinDuration(t, time.Now().Add(time.Minute), result.LockedUntil, time.Second)
+
+	throttle, err := testQueries.GetLoginThrottle(context.Background(), emailKey)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), throttle.FailedAttempts)
+	throttle, err = testQueries.GetLoginThrottle(context.Background(), ipKey)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), throttle.FailedAttempts)
+}
@@ -1,12 +1,12 @@
 package db
 
 import (
-	"database/sql"
+	"context"
 	"log"
 	"os"
 	"testing"
 
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/shimon-git/simple-bank/util"
 )
 
@@ -15,7 +15,7 @@ const (
 )
 
 var testQueries *Queries
-var testDB *sql.DB
+var testPool *pgxpool.Pool
 
 func TestMain(m *testing.M) {
 	// load the config file from the given folder
@@ -24,12 +24,12 @@ func TestMain(m *testing.M) {
 		log.Fatalf("failed to load configurations: %v", err)
 	}
 
-	testDB, err = sql.Open(config.DBDriver, config.DBSource)
+	testPool, err = NewConnPool(context.Background(), config)
 	if err != nil {
 		log.Fatal("cannot connect to db:", err)
 	}
 
-	testQueries = New(testDB)
+	testQueries = New(testPool)
 
 	os.Exit(m.Run())
 }
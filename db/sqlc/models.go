@@ -5,7 +5,11 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -14,6 +18,67 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// how far below zero the balance is allowed to go
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	// the money of frozen and closed accounts can't be moved
+	Status string `json:"status"`
+}
+
+type AccountHold struct {
+	ID          int64 `json:"id"`
+	AccountID   int64 `json:"account_id"`
+	ToAccountID int64 `json:"to_account_id"`
+	// the reserved amount - must be positive
+	Amount int64 `json:"amount"`
+	// active, captured or voided - an active hold stops reserving funds once expired
+	Status string `json:"status"`
+	// the transfer created when the hold was captured
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type AuditEvent struct {
+	ID     int64  `json:"id"`
+	Actor  string `json:"actor"`
+	Action string `json:"action"`
+	// the changed (or viewed) object - e.g. account:42
+	Target string `json:"target"`
+	// the target before the action - json null when there is nothing to record
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"created_at"`
+	// the X-Request-ID of the API request (or the job) which did the action
+	RequestID string `json:"request_id"`
+	// the hash of the previous event in the chain - empty for the first event, null until chained
+	PrevHash []byte `json:"prev_hash"`
+	// sha256 of the previous hash and the event - editing or deleting an event breaks the chain, null until chained
+	Hash []byte `json:"hash"`
+	// the position of the event in the hash chain - null until the appender chains the event
+	ChainSeq sql.NullInt64 `json:"chain_seq"`
+}
+
+type BalanceAdjustment struct {
+	ID        int64 `json:"id"`
+	AccountID int64 `json:"account_id"`
+	EntryID   int64 `json:"entry_id"`
+	// can be negative or positive
+	Amount int64  `json:"amount"`
+	Reason string `json:"reason"`
+	// the admin who adjusted the balance
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BalanceSnapshot struct {
+	AccountID int64 `json:"account_id"`
+	// the last entry included - the snapshot covers the account entries up to this id
+	EntryID int64 `json:"entry_id"`
+	// the sum of the covered entries
+	Balance int64 `json:"balance"`
+	// the creation time of the newest covered entry
+	CoveredUntil time.Time `json:"covered_until"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -22,6 +87,109 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// the transfer the entry belongs to - null for entries made without a transfer
+	TransferID sql.NullInt64 `json:"transfer_id"`
+}
+
+type FxQuote struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         float64   `json:"rate"`
+	IsUsed       bool      `json:"is_used"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type IdempotencyKey struct {
+	Username string `json:"username"`
+	Key      string `json:"key"`
+	// sha256 of the original request body
+	RequestHash string `json:"request_hash"`
+	// the stored result returned on replay
+	Response  json.RawMessage `json:"response"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	// user:<username> or ip:<client ip> for the failed logins, reset_email:<email> or reset_ip:<client ip> for the password reset requests
+	Key            string    `json:"key"`
+	FailedAttempts int32     `json:"failed_attempts"`
+	LockedUntil    time.Time `json:"locked_until"`
+	LastFailedAt   time.Time `json:"last_failed_at"`
+}
+
+type PasswordReset struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the reset token sent by email - the token itself is never stored
+	TokenHash string    `json:"token_hash"`
+	IsUsed    bool      `json:"is_used"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+type RecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the single-use recovery code - the code itself is never stored
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RevokedToken struct {
+	// the id of the token payload
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	// the token expiration - the row can be pruned afterwards
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	// empty for a one-off transfer, else daily, weekly or monthly:<day>
+	Recurrence string `json:"recurrence"`
+	// active, completed, failed or cancelled
+	Status string `json:"status"`
+	// the time of the next occurrence
+	NextRunAt time.Time `json:"next_run_at"`
+	// when the executor picks the transfer - moved forward while running and between retries
+	DueAt time.Time `json:"due_at"`
+	// failed attempts of the next occurrence
+	Attempts  int32     `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64         `json:"id"`
+	ScheduledTransferID int64         `json:"scheduled_transfer_id"`
+	ScheduledFor        time.Time     `json:"scheduled_for"`
+	Attempt             int32         `json:"attempt"`
+	TransferID          sql.NullInt64 `json:"transfer_id"`
+	// empty when the run succeeded
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Session struct {
+	// the id of the refresh token payload
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	// the sha256 (hex) of the refresh token - the token itself is never stored
+	RefreshTokenHash string `json:"refresh_token_hash"`
+	UserAgent        string `json:"user_agent"`
+	ClientIp         string `json:"client_ip"`
+	// a blocked session can no longer renew access tokens
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Transfer struct {
@@ -31,6 +199,14 @@ type Transfer struct {
 	// must be positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// the amount credited to the to account - in its currency
+	ToAmount int64 `json:"to_amount"`
+	// the rate applied between the accounts currencies
+	ExchangeRate float64 `json:"exchange_rate"`
+	// the original transfer when this transfer is a reversal
+	ReversalOf sql.NullInt64 `json:"reversal_of"`
+	// a free text reference of the payer - e.g. the payroll line of a batch transfer
+	Reference string `json:"reference"`
 }
 
 type User struct {
@@ -40,4 +216,35 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	// customer, banker or admin - embedded in the tokens of the user
+	Role            string `json:"role"`
+	IsEmailVerified bool   `json:"is_email_verified"`
+}
+
+type UserTokenRevocation struct {
+	Username string `json:"username"`
+	// every token of the user issued before this time is revoked
+	RevokedBefore time.Time `json:"revoked_before"`
+}
+
+type UserTotp struct {
+	Username string `json:"username"`
+	// the base32 totp secret shared with the authenticator app of the user
+	Secret string `json:"secret"`
+	// the step of the last accepted code - a code can be used only once
+	LastUsedStep int64 `json:"last_used_step"`
+	// null until the user confirmed the enrollment with a valid code
+	ConfirmedAt sql.NullTime `json:"confirmed_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// sha256 of the code sent by email - the code itself is never stored
+	SecretCodeHash string    `json:"secret_code_hash"`
+	IsUsed         bool      `json:"is_used"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiredAt      time.Time `json:"expired_at"`
 }
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// ChangePasswordTxParams - contains the input parameters of the change password transaction
+type ChangePasswordTxParams struct {
+	Username       string      `json:"username"`
+	HashedPassword string      `json:"hashed_password"`
+	Audit          AuditParams `json:"audit"`
+}
+
+// ChangePasswordTx - changes the password of the user (the current password is checked by the caller)
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = setUserPassword(ctx, q, arg.Username, arg.HashedPassword, arg.Audit, AuditUserPasswordChange)
+		return err
+	})
+
+	return user, err
+}
+
+// ResetPasswordTxParams - contains the input parameters of the reset password transaction
+type ResetPasswordTxParams struct {
+	// the sha256 of the reset token sent to the user
+	TokenHash      string `json:"token_hash"`
+	HashedPassword string `json:"hashed_password"`
+	// the request of the reset - the actor is the user of the reset token
+	RequestID string `json:"request_id"`
+}
+
+/*
+ * ResetPasswordTx - uses the reset token and sets the new password of its user
+ * returns ErrPasswordResetUnavailable when the token is wrong, expired or was already used
+ */
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		reset, err := q.UsePasswordReset(ctx, arg.TokenHash)
+		if isNotFound(err) {
+			return ErrPasswordResetUnavailable
+		}
+		if err != nil {
+			return err
+		}
+
+		audit := AuditParams{Actor: reset.Username, RequestID: arg.RequestID}
+		user, err = setUserPassword(ctx, q, reset.Username, arg.HashedPassword, audit, AuditUserPasswordReset)
+		return err
+	})
+
+	return user, err
+}
+
+/*
+ * setUserPassword - sets the new password of the user within the given transaction
+ * I) updates the password - the tokens issued before the password change are rejected (see IsTokenRevoked)
+ * II) blocks the sessions of the user - so their refresh tokens can't renew access tokens
+ * III) expires the pending reset tokens of the user
+ * IV) records the change in the audit trail
+ */
+func setUserPassword(ctx context.Context, q *Queries, username, hashedPassword string, audit AuditParams, action string) (User, error) {
+	user, err := q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		Username:       username,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return User{}, err
+	}
+
+	if err = q.BlockUserSessions(ctx, username); err != nil {
+		return User{}, err
+	}
+
+	if err = q.ExpireUserPasswordResets(ctx, username); err != nil {
+		return User{}, err
+	}
+
+	// the password hash is never recorded
+	err = recordAuditEvent(ctx, q, audit, action, UserTarget(username), nil, passwordAuditEvent{
+		PasswordChangedAt: user.PasswordChangedAt,
+	})
+	return user, err
+}
+
+// passwordAuditEvent - the password change as recorded in the audit trail
+type passwordAuditEvent struct {
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasswordReset(t *testing.T, username string, duration time.Duration) (PasswordReset, string) {
+	tokenHash := util.RandomString(64)
+	reset, err := testQueries.CreatePasswordReset(context.Background(), CreatePasswordResetParams{
+		Username:  username,
+		TokenHash: tokenHash,
+		ExpiredAt: time.Now().Add(duration),
+	})
+	require.NoError(t, err)
+	require.Equal(t, username, reset.Username)
+	require.False(t, reset.IsUsed)
+	return reset, tokenHash
+}
+
+func TestChangePasswordTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := createRandomUser(t)
+	session := createRandomSession(t, user.Username)
+	_, tokenHash := createRandomPasswordReset(t, user.Username, time.Minute)
+
+	issuedAt := time.Now()
+	hashedPassword, err := util.HashedPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	changed, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+		Audit:          AuditParams{Actor: user.Username, RequestID: util.RandomString(10)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, changed.HashedPassword)
+	require.WithinDuration(t, time.Now(), changed.PasswordChangedAt, time.Second)
+
+	// the tokens issued before the change are rejected, the tokens issued afterwards are not
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: issuedAt,
+	})
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// the sessions of the user are blocked
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	// the pending reset tokens can't be used anymore
+	_, err = testQueries.UsePasswordReset(context.Background(), tokenHash)
+	require.Error(t, err)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := createRandomUser(t)
+	_, tokenHash := createRandomPasswordReset(t, user.Username, time.Minute)
+
+	hashedPassword, err := util.HashedPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	arg := ResetPasswordTxParams{
+		TokenHash:      tokenHash,
+		HashedPassword: hashedPassword,
+		RequestID:      util.RandomString(10),
+	}
+	changed, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, changed.Username)
+	require.Equal(t, hashedPassword, changed.HashedPassword)
+
+	// the reset token is single-use
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrPasswordResetUnavailable)
+}
+
+func TestResetPasswordTxExpired(t *testing.T) {
+	store := NewStore(testPool)
+	user := createRandomUser(t)
+	_, tokenHash := createRandomPasswordReset(t, user.Username, -time.Minute)
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      tokenHash,
+		HashedPassword: util.RandomString(32),
+	})
+	require.ErrorIs(t, err, ErrPasswordResetUnavailable)
+
+	user2, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.HashedPassword, user2.HashedPassword)
+}
@@ -6,27 +6,108 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
+	BlockSession(ctx context.Context, arg BlockSessionParams) (Session, error)
+	BlockUserSessions(ctx context.Context, username string) error
+	CancelScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	CaptureAccountHold(ctx context.Context, id int64) (AccountHold, error)
+	ChainAuditEvent(ctx context.Context, arg ChainAuditEventParams) error
+	ClaimDueScheduledTransfers(ctx context.Context, arg ClaimDueScheduledTransfersParams) ([]ScheduledTransfer, error)
+	ConfirmUserTotp(ctx context.Context, arg ConfirmUserTotpParams) (UserTotp, error)
+	CountUnchainedAuditEvents(ctx context.Context) (int64, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAccountHold(ctx context.Context, arg CreateAccountHoldParams) (AccountHold, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
+	CreateBalanceAdjustment(ctx context.Context, arg CreateBalanceAdjustmentParams) (BalanceAdjustment, error)
+	CreateBalanceSnapshots(ctx context.Context, before time.Time) (int64, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateFxQuote(ctx context.Context, arg CreateFxQuoteParams) (FxQuote, error)
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) error
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
 	DeleteAccount(ctx context.Context, id int64) error
 	DeleteEntry(ctx context.Context, id int64) error
+	DeleteExpiredRevokedTokens(ctx context.Context, expiresAt time.Time) (int64, error)
+	DeleteLoginThrottle(ctx context.Context, key string) error
+	DeleteStaleLoginThrottles(ctx context.Context, lastFailedAt time.Time) (int64, error)
 	DeleteTransfer(ctx context.Context, id int64) error
+	DeleteUserRecoveryCodes(ctx context.Context, username string) error
+	DeleteUserTokenRevocations(ctx context.Context, revokedBefore time.Time) (int64, error)
+	ExpireUserPasswordResets(ctx context.Context, username string) error
+	ForgetLoginAttempt(ctx context.Context, key string) error
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountHeldAmount(ctx context.Context, accountID int64) (int64, error)
+	GetAccountHold(ctx context.Context, id int64) (AccountHold, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetFxQuote(ctx context.Context, id uuid.UUID) (FxQuote, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetLastChainedAuditEvent(ctx context.Context) (AuditEvent, error)
+	GetLatestBalanceSnapshot(ctx context.Context, arg GetLatestBalanceSnapshotParams) (BalanceSnapshot, error)
+	GetLoginThrottle(ctx context.Context, key string) (LoginThrottle, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
+	GetTransferReversedAmounts(ctx context.Context, reversalOf sql.NullInt64) (GetTransferReversedAmountsRow, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserTotp(ctx context.Context, username string) (UserTotp, error)
+	IsTokenRevoked(ctx context.Context, arg IsTokenRevokedParams) (bool, error)
+	ListAccountBalanceMismatches(ctx context.Context) ([]ListAccountBalanceMismatchesRow, error)
+	ListAccountEntries(ctx context.Context, arg ListAccountEntriesParams) ([]ListAccountEntriesRow, error)
+	ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListActiveSessions(ctx context.Context, username string) ([]Session, error)
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error)
+	ListChainedAuditEvents(ctx context.Context, arg ListChainedAuditEventsParams) ([]AuditEvent, error)
+	ListUnchainedAuditEvents(ctx context.Context, limit int32) ([]AuditEvent, error)
+	ListBalanceAdjustments(ctx context.Context, arg ListBalanceAdjustmentsParams) ([]BalanceAdjustment, error)
+	ListCurrencyImbalances(ctx context.Context) ([]ListCurrencyImbalancesRow, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error)
+	ListTransferEntryMismatches(ctx context.Context) ([]ListTransferEntryMismatchesRow, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	LockAuditLog(ctx context.Context) error
+	LockIdempotencyKey(ctx context.Context, arg LockIdempotencyKeyParams) error
+	LockLoginThrottle(ctx context.Context, arg LockLoginThrottleParams) (LoginThrottle, error)
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error)
+	RescheduleScheduledTransfer(ctx context.Context, arg RescheduleScheduledTransferParams) (ScheduledTransfer, error)
+	RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) error
+	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error)
+	SetAccountHoldTransfer(ctx context.Context, arg SetAccountHoldTransferParams) (AccountHold, error)
+	SumAccountEntriesAfter(ctx context.Context, arg SumAccountEntriesAfterParams) (int64, error)
+	SumAccountEntriesByPeriod(ctx context.Context, arg SumAccountEntriesByPeriodParams) ([]SumAccountEntriesByPeriodRow, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error)
+	UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
 	UpdateEntry(ctx context.Context, arg UpdateEntryParams) (Entry, error)
+	UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
 	UpdateTransfer(ctx context.Context, arg UpdateTransferParams) (Transfer, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpsertUserTotp(ctx context.Context, arg UpsertUserTotpParams) (UserTotp, error)
+	UseFxQuote(ctx context.Context, arg UseFxQuoteParams) (FxQuote, error)
+	UsePasswordReset(ctx context.Context, tokenHash string) (PasswordReset, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	UseTotpStep(ctx context.Context, arg UseTotpStepParams) (UserTotp, error)
+	UseVerifyEmail(ctx context.Context, arg UseVerifyEmailParams) (VerifyEmail, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
+	VoidAccountHold(ctx context.Context, id int64) (AccountHold, error)
 }
 
 var _ Querier = (*Queries)(nil)
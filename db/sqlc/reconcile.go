@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+/*
+* ReconcileReport - the discrepancies found by the ledger integrity checks
+* AccountBalanceMismatches: accounts whose balance isn't the sum of their entries
+* TransferEntryMismatches: transfers without exactly one matching debit entry and one matching credit entry
+* CurrencyImbalances: currencies whose entries don't sum to zero (the balance adjustments of the admins are left out)
+* (the entries of cross-currency transfers are left out - the money is converted, not moved)
+ */
+type ReconcileReport struct {
+	CheckedAt                time.Time                         `json:"checked_at"`
+	AccountBalanceMismatches []ListAccountBalanceMismatchesRow `json:"account_balance_mismatches"`
+	TransferEntryMismatches  []ListTransferEntryMismatchesRow  `json:"transfer_entry_mismatches"`
+	CurrencyImbalances       []ListCurrencyImbalancesRow       `json:"currency_imbalances"`
+}
+
+// Discrepancies - returns the number of discrepancies in the report
+func (report ReconcileReport) Discrepancies() int {
+	return len(report.AccountBalanceMismatches) + len(report.TransferEntryMismatches) + len(report.CurrencyImbalances)
+}
+
+/*
+* Reconcile - verifies the ledger integrity
+* I) every account balance equals the sum of its entries
+* II) every transfer has exactly two matching entries
+* III) the entries of every currency sum to zero
+* the checks run in a single read only transaction - so they see the same snapshot of the ledger
+ */
+func (store *SQLStore) Reconcile(ctx context.Context) (ReconcileReport, error) {
+	report := ReconcileReport{CheckedAt: time.Now()}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		report.AccountBalanceMismatches, err = q.ListAccountBalanceMismatches(ctx)
+		if err != nil {
+			return err
+		}
+
+		report.TransferEntryMismatches, err = q.ListTransferEntryMismatches(ctx)
+		if err != nil {
+			return err
+		}
+
+		report.CurrencyImbalances, err = q.ListCurrencyImbalances(ctx)
+		return err
+	}, withIsolation(pgx.RepeatableRead), readOnly())
+
+	return report, err
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	// making sure the from account covers the transfer
+	account1, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account1.ID,
+		Balance: 100,
+	})
+	require.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// breaking the ledger - changing an entry without its transfer and the balance
+	_, err = testQueries.UpdateEntry(context.Background(), UpdateEntryParams{
+		ID:     result.ToEntry.ID,
+		Amount: 15,
+	})
+	require.NoError(t, err)
+
+	report, err := store.Reconcile(context.Background())
+	require.NoError(t, err)
+	require.NotZero(t, report.Discrepancies())
+
+	var accountFound bool
+	for _, mismatch := range report.AccountBalanceMismatches {
+		if mismatch.AccountID == account2.ID {
+			accountFound = true
+			require.Equal(t, account2.Balance+10, mismatch.Balance)
+			require.Equal(t, int64(15), mismatch.EntriesBalance)
+		}
+	}
+	require.True(t, accountFound)
+
+	var transferFound bool
+	for _, mismatch := range report.TransferEntryMismatches {
+		if mismatch.TransferID == result.Transfer.ID {
+			transferFound = true
+			require.Equal(t, int32(2), mismatch.EntryCount)
+			require.Equal(t, int32(1), mismatch.MatchingDebits)
+			require.Zero(t, mismatch.MatchingCredits)
+		}
+	}
+	require.True(t, transferFound)
+
+	// the entries of a cross-currency transfer are left out of the currency totals
+	if account1.Currency == account2.Currency {
+		var currencyFound bool
+		for _, imbalance := range report.CurrencyImbalances {
+			if imbalance.Currency == account2.Currency {
+				currencyFound = true
+			}
+		}
+		require.True(t, currencyFound)
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ReverseTransferTxParams - contains the input parameters of the reverse transfer transaction
+type ReverseTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	// Amount - the amount returned to the original sender (in the original amount currency), zero for the whole remaining amount
+	Amount int64       `json:"amount"`
+	Audit  AuditParams `json:"audit"`
+}
+
+/*
+* ReverseTransferTx - refunds a transfer fully or partially - a transfer can be refunded several times up to its amount
+* I) locks the original transfer and checks the reversals so far don't exceed its amount
+* II) creates a compensating transfer (linked to the original) from the original to account back to the original from account
+* III) add account entries + update account's balance
+* IV) records the reversal in the audit trail
+* within a single database transaction
+ */
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// locking the original transfer - concurrent reversals of the same transfer are serialized
+		original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		if original.ReversalOf.Valid {
+			return ErrTransferNotReversible
+		}
+
+		// summing the reversals of the transfer so far - the original transfer is locked so no reversal is added meanwhile
+		reversed, err := q.GetTransferReversedAmounts(ctx, sql.NullInt64{Int64: original.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+		remaining := original.Amount - reversed.Refunded
+		if remaining <= 0 {
+			return ErrTransferAlreadyReversed
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount < 0 || amount > remaining {
+			return ErrInvalidReversalAmount
+		}
+
+		// the original to account returns its proportional part of the credited amount
+		// the last reversal returns the rest of the credited amount - the rounding of the partial reversals is never lost
+		debit := original.ToAmount * amount / original.Amount
+		if amount == remaining {
+			debit = original.ToAmount - reversed.Debited
+		}
+
+		result, err = transfer(ctx, q, CreateTransferParams{
+			FromAccountID: original.ToAccountID,
+			ToAccountID:   original.FromAccountID,
+			Amount:        debit,
+			ToAmount:      amount,
+			ExchangeRate:  1 / original.ExchangeRate,
+			ReversalOf:    sql.NullInt64{Int64: original.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, q, arg.Audit, AuditTransferReverse, TransferTarget(original.ID), nil, result)
+	})
+
+	return result, err
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := setAccountBalance(t, createRandomAccount(t), 100)
+	account2 := setAccountBalance(t, createRandomAccount(t), 0)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+
+	// the reversal amount can't be bigger than the original amount
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     101,
+	})
+	require.ErrorIs(t, err, ErrInvalidReversalAmount)
+
+	// a partial refund moves the money back from the receiver to the sender
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     40,
+		Audit:      AuditParams{Actor: account2.Owner},
+	})
+	require.NoError(t, err)
+
+	// the reversal is recorded in the audit trail of the original transfer
+	events, err := testQueries.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		Target: TransferTarget(original.Transfer.ID),
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	require.Equal(t, AuditTransferReverse, events[0].Action)
+	require.Equal(t, account2.Owner, events[0].Actor)
+
+	reversal := result.Transfer
+	require.True(t, reversal.ReversalOf.Valid)
+	require.Equal(t, original.Transfer.ID, reversal.ReversalOf.Int64)
+	require.Equal(t, account2.ID, reversal.FromAccountID)
+	require.Equal(t, account1.ID, reversal.ToAccountID)
+	require.Equal(t, int64(40), reversal.Amount)
+
+	// the compensating entries
+	require.Equal(t, account2.ID, result.FromEntry.AccountID)
+	require.Equal(t, int64(-40), result.FromEntry.Amount)
+	require.Equal(t, account1.ID, result.ToEntry.AccountID)
+	require.Equal(t, int64(40), result.ToEntry.Amount)
+
+	require.Equal(t, int64(40), result.ToAccount.Balance)
+	require.Equal(t, int64(60), result.FromAccount.Balance)
+
+	// the reversals can't exceed the original amount
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     61,
+	})
+	require.ErrorIs(t, err, ErrInvalidReversalAmount)
+
+	// a further partial refund of the same transfer
+	result, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     20,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(20), result.Transfer.Amount)
+	require.Equal(t, int64(60), result.ToAccount.Balance)
+	require.Equal(t, int64(40), result.FromAccount.Balance)
+
+	// no amount - refunding the whole remaining amount
+	result, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(40), result.Transfer.Amount)
+	require.Equal(t, int64(100), result.ToAccount.Balance)
+	require.Equal(t, int64(0), result.FromAccount.Balance)
+
+	// the transfer was fully reversed
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     1,
+	})
+	require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+
+	// a reversal can't be reversed
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: reversal.ID,
+	})
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+}
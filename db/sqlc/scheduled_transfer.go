@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+)
+
+// different statuses of a scheduled transfer
+const (
+	ScheduledTransferActive    = "active"
+	ScheduledTransferCompleted = "completed"
+	ScheduledTransferFailed    = "failed"
+	ScheduledTransferCancelled = "cancelled"
+)
+
+/*
+* RecordScheduledTransferRunTxParams - contains the input parameters of the record scheduled transfer run transaction
+* Run: the history record of the execution attempt
+* Reschedule: the state of the scheduled transfer after the attempt
+ */
+type RecordScheduledTransferRunTxParams struct {
+	Run        CreateScheduledTransferRunParams  `json:"run"`
+	Reschedule RescheduleScheduledTransferParams `json:"reschedule"`
+}
+
+// RecordScheduledTransferRunTxResult - contains the output of the record scheduled transfer run transaction
+type RecordScheduledTransferRunTxResult struct {
+	Run               ScheduledTransferRun `json:"run"`
+	ScheduledTransfer ScheduledTransfer    `json:"scheduled_transfer"`
+}
+
+/*
+* RecordScheduledTransferRunTx - records an execution attempt of a scheduled transfer
+* I) adds the attempt to the run history
+* II) moves the scheduled transfer to its next state - unless it was cancelled while running
+* within a single database transaction
+ */
+func (store *SQLStore) RecordScheduledTransferRunTx(ctx context.Context, arg RecordScheduledTransferRunTxParams) (RecordScheduledTransferRunTxResult, error) {
+	var result RecordScheduledTransferRunTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.Run, err = q.CreateScheduledTransferRun(ctx, arg.Run)
+		if err != nil {
+			return err
+		}
+
+		result.ScheduledTransfer, err = q.RescheduleScheduledTransfer(ctx, arg.Reschedule)
+		if isNotFound(err) {
+			// the scheduled transfer isn't active anymore - keeping its current state
+			result.ScheduledTransfer, err = q.GetScheduledTransfer(ctx, arg.Reschedule.ID)
+		}
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, runAt time.Time) ScheduledTransfer {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	arg := CreateScheduledTransferParams{
+		Owner:         account1.Owner,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Recurrence:    "monthly:1",
+		NextRunAt:     runAt,
+	}
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, scheduled.Owner)
+	require.Equal(t, arg.Recurrence, scheduled.Recurrence)
+	require.Equal(t, ScheduledTransferActive, scheduled.Status)
+	require.WithinDuration(t, runAt, scheduled.NextRunAt, time.Second)
+	require.WithinDuration(t, runAt, scheduled.DueAt, time.Second)
+	require.Zero(t, scheduled.Attempts)
+
+	return scheduled
+}
+
+func TestClaimDueScheduledTransfers(t *testing.T) {
+	due := createRandomScheduledTransfer(t, time.Now().Add(-time.Minute))
+	future := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+
+	lease := time.Now().Add(time.Minute)
+	claimed, err := testQueries.ClaimDueScheduledTransfers(context.Background(), ClaimDueScheduledTransfersParams{
+		LeaseUntil: lease,
+		BatchSize:  1000,
+	})
+	require.NoError(t, err)
+
+	claimedIDs := make(map[int64]ScheduledTransfer)
+	for _, s := range claimed {
+		claimedIDs[s.ID] = s
+	}
+	require.Contains(t, claimedIDs, due.ID)
+	require.NotContains(t, claimedIDs, future.ID)
+
+	// the claimed transfer is leased - it's hidden until the lease ends
+	require.WithinDuration(t, lease, claimedIDs[due.ID].DueAt, time.Second)
+
+	claimed, err = testQueries.ClaimDueScheduledTransfers(context.Background(), ClaimDueScheduledTransfersParams{
+		LeaseUntil: lease,
+		BatchSize:  1000,
+	})
+	require.NoError(t, err)
+	for _, s := range claimed {
+		require.NotEqual(t, due.ID, s.ID)
+	}
+}
+
+func TestRecordScheduledTransferRunTx(t *testing.T) {
+	store := NewStore(testPool)
+	scheduled := createRandomScheduledTransfer(t, time.Now().Add(-time.Minute))
+
+	nextRun := time.Now().Add(time.Hour)
+	result, err := store.RecordScheduledTransferRunTx(context.Background(), RecordScheduledTransferRunTxParams{
+		Run: CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			ScheduledFor:        scheduled.NextRunAt,
+			Attempt:             1,
+			Error:               ErrInsufficientFunds.Error(),
+		},
+		Reschedule: RescheduleScheduledTransferParams{
+			ID:        scheduled.ID,
+			Status:    ScheduledTransferActive,
+			NextRunAt: scheduled.NextRunAt,
+			DueAt:     nextRun,
+			Attempts:  1,
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, ErrInsufficientFunds.Error(), result.Run.Error)
+	require.False(t, result.Run.TransferID.Valid)
+	require.Equal(t, int32(1), result.ScheduledTransfer.Attempts)
+	require.WithinDuration(t, nextRun, result.ScheduledTransfer.DueAt, time.Second)
+
+	// a cancelled transfer keeps its state
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+
+	result, err = store.RecordScheduledTransferRunTx(context.Background(), RecordScheduledTransferRunTxParams{
+		Run: CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			ScheduledFor:        scheduled.NextRunAt,
+			Attempt:             2,
+		},
+		Reschedule: RescheduleScheduledTransferParams{
+			ID:        scheduled.ID,
+			Status:    ScheduledTransferCompleted,
+			NextRunAt: scheduled.NextRunAt,
+			DueAt:     scheduled.NextRunAt,
+			Attempts:  0,
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferCancelled, result.ScheduledTransfer.Status)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               5,
+		Offset:              0,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	// newest first
+	require.Equal(t, int32(2), runs[0].Attempt)
+
+	_, err = testQueries.UpdateScheduledTransfer(context.Background(), UpdateScheduledTransferParams{
+		ID:        scheduled.ID,
+		Amount:    20,
+		NextRunAt: nextRun,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
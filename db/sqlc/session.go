@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// CreateSessionTxParams - contains the input parameters of the create session transaction (a successful login)
+type CreateSessionTxParams struct {
+	CreateSessionParams
+	Audit AuditParams `json:"audit"`
+}
+
+// loginAuditEvent - the session details recorded in the audit trail - without the refresh token
+type loginAuditEvent struct {
+	SessionID uuid.UUID `json:"session_id"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+}
+
+/*
+ * CreateSessionTx - creates the session of the login within a single database transaction
+ * I) creates the session
+ * II) forgets the login attempts of the username - and takes the attempt of the login back from the client ip
+ * III) records the login in the audit trail
+ */
+func (store *SQLStore) CreateSessionTx(ctx context.Context, arg CreateSessionTxParams) (Session, error) {
+	var session Session
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		session, err = q.CreateSession(ctx, arg.CreateSessionParams)
+		if err != nil {
+			return err
+		}
+
+		// the failed attempts of the client ip are kept - a valid login doesn't excuse a password spraying client
+		if err = forgetLoginAttempts(ctx, q, session.Username, session.ClientIp); err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, q, arg.Audit, AuditUserLogin, UserTarget(session.Username), nil, loginAuditEvent{
+			SessionID: session.ID,
+			UserAgent: session.UserAgent,
+			ClientIp:  session.ClientIp,
+		})
+	})
+
+	return session, err
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T, username string) Session {
+	arg := CreateSessionParams{
+		ID:               uuid.New(),
+		Username:         username,
+		RefreshTokenHash: util.HashSecret(util.RandomString(32)),
+		UserAgent:        "test-agent",
+		ClientIp:         "127.0.0.1",
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.RefreshTokenHash, session.RefreshTokenHash)
+	require.False(t, session.IsBlocked)
+	require.WithinDuration(t, arg.ExpiresAt, session.ExpiresAt, time.Second)
+	require.NotZero(t, session.CreatedAt)
+
+	return session
+}
+
+func TestGetSession(t *testing.T) {
+	user := createRandomUser(t)
+	session1 := createRandomSession(t, user.Username)
+
+	session2, err := testQueries.GetSession(context.Background(), session1.ID)
+	require.NoError(t, err)
+	require.Equal(t, session1.ID, session2.ID)
+	require.Equal(t, session1.RefreshTokenHash, session2.RefreshTokenHash)
+}
+
+func TestListActiveSessions(t *testing.T) {
+	user := createRandomUser(t)
+	session1 := createRandomSession(t, user.Username)
+	session2 := createRandomSession(t, user.Username)
+
+	_, err := testQueries.BlockSession(context.Background(), BlockSessionParams{
+		ID:       session1.ID,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+
+	// the blocked session is left out
+	sessions, err := testQueries.ListActiveSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, session2.ID, sessions[0].ID)
+}
+
+func TestBlockSession(t *testing.T) {
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+	session := createRandomSession(t, user1.Username)
+
+	// a session can be blocked only by its user
+	_, err := testQueries.BlockSession(context.Background(), BlockSessionParams{
+		ID:       session.ID,
+		Username: user2.Username,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	blocked, err := testQueries.BlockSession(context.Background(), BlockSessionParams{
+		ID:       session.ID,
+		Username: user1.Username,
+	})
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+}
@@ -3,26 +3,76 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // store provides all functions to execute db queries and transactions
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ExchangeTransferTx(ctx context.Context, arg ExchangeTransferTxParams) (TransferTxResult, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error)
+	BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error)
+	CreateHoldTx(ctx context.Context, arg CreateHoldTxParams) (AccountHold, error)
+	CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error)
+	VoidHoldTx(ctx context.Context, arg VoidHoldTxParams) (AccountHold, error)
+	RecordScheduledTransferRunTx(ctx context.Context, arg RecordScheduledTransferRunTxParams) (RecordScheduledTransferRunTxResult, error)
+	GetAccountBalanceAt(ctx context.Context, accountID int64, at time.Time) (int64, error)
+	AccountBalanceHistory(ctx context.Context, arg AccountBalanceHistoryParams) ([]BalancePoint, error)
+	Reconcile(ctx context.Context) (ReconcileReport, error)
+	RevokeUserTokensTx(ctx context.Context, arg RevokeUserTokensTxParams) error
+	AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (Account, error)
+	CreateSessionTx(ctx context.Context, arg CreateSessionTxParams) (Session, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	ConfirmTotpTx(ctx context.Context, arg ConfirmTotpTxParams) (UserTotp, error)
+	RecordFailedLoginTx(ctx context.Context, arg RecordFailedLoginTxParams) (RecordFailedLoginTxResult, error)
+	ThrottleAttemptsTx(ctx context.Context, arg ThrottleAttemptsTxParams) (ThrottleAttemptsTxResult, error)
+	ForgetLoginAttemptTx(ctx context.Context, arg ForgetLoginAttemptTxParams) error
+	UnlockUserTx(ctx context.Context, arg UnlockUserTxParams) (LoginThrottle, error)
+	UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (Account, error)
+	RecordAuditEvent(ctx context.Context, arg RecordAuditEventParams) error
+	ChainAuditEvents(ctx context.Context, limit int32) (int, error)
+	VerifyAuditLog(ctx context.Context, anchor []byte) (AuditLogReport, error)
+	PoolStats() PoolStats
 }
 
 // * Store provides all functions to execute db queries and transactions
 type SQLStore struct {
 	*Queries
-	db *sql.DB
+	connPool *pgxpool.Pool
 }
 
-// * NewStore - create a new store
-func NewStore(db *sql.DB) Store {
+// * NewStore - create a new store - the queries and the transactions are executed by the connections of the pool
+func NewStore(connPool *pgxpool.Pool) Store {
 	return &SQLStore{
-		db:      db,
-		Queries: New(db),
+		connPool: connPool,
+		Queries:  New(classifiedDB{db: connPool}),
+	}
+}
+
+// txOption - an option of the transaction executed by execTx
+type txOption func(opts *pgx.TxOptions)
+
+// withIsolation - executing the transaction with the given isolation level (read committed by default)
+func withIsolation(level pgx.TxIsoLevel) txOption {
+	return func(opts *pgx.TxOptions) {
+		opts.IsoLevel = level
+	}
+}
+
+// readOnly - executing the transaction in the read only mode - the writes of the transaction fail
+func readOnly() txOption {
+	return func(opts *pgx.TxOptions) {
+		opts.AccessMode = pgx.ReadOnly
 	}
 }
 
@@ -31,36 +81,55 @@ func NewStore(db *sql.DB) Store {
  * params:
  * ctx - context
  * fn - the given function where the transaction done(multiply queries)
+ * opts - the options of the transaction (e.g. withIsolation, readOnly), the defaults of the database when empty
+ * the errors of the driver are returned as the errors of the store (see storeError)
  */
-func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
+func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error, opts ...txOption) error {
+	var txOptions pgx.TxOptions
+	for _, opt := range opts {
+		opt(&txOptions)
+	}
+
 	// creating transaction object
-	tx, err := store.db.BeginTx(ctx, nil)
+	tx, err := store.connPool.BeginTx(ctx, txOptions)
 	if err != nil {
-		return err
+		return storeError(err)
 	}
 
 	// creating new queries - transaction object
-	q := New(tx)
+	q := New(classifiedDB{db: tx})
 	// passing the queries transaction params into the given function
 	err = fn(q)
 	// checking for errors in the transaction
 	if err != nil {
 		// rolling back - and checking for errors while rolling back
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("transaction error: %v, rollback error: %v", err, rbErr)
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("transaction error: %w, rollback error: %v", storeError(err), rbErr)
 		}
-		return fmt.Errorf("transaction error: %v", err)
+		return fmt.Errorf("transaction error: %w", storeError(err))
 	}
 
 	// if transaction was done successfully commit the transaction and return commits errors
-	return tx.Commit()
+	return storeError(tx.Commit(ctx))
 }
 
 // * TransferTxParams - contains the input parameters of the transfer transaction
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64             `json:"from_account_id"`
+	ToAccountID   int64             `json:"to_account_id"`
+	Amount        int64             `json:"amount"`
+	Idempotency   IdempotencyParams `json:"idempotency"`
+	Audit         AuditParams       `json:"audit"`
+}
+
+/*
+* IdempotencyParams - identifies a retryable request
+* when Key is empty the transfer is executed without idempotency
+ */
+type IdempotencyParams struct {
+	Username    string `json:"username"`
+	Key         string `json:"key"`
+	RequestHash string `json:"request_hash"`
 }
 
 // * TransferTxResults - contains the output of the transfer transaction
@@ -70,6 +139,8 @@ type TransferTxResult struct {
 	ToAccount   Account  `json:"to_account"`
 	FromEntry   Entry    `json:"from_entry"`
 	ToEntry     Entry    `json:"to_entry"`
+	// Replayed - true when the result was loaded from a previous request with the same idempotency key
+	Replayed bool `json:"-"`
 }
 
 /*
@@ -77,76 +148,178 @@ type TransferTxResult struct {
 * I) creates a transfer record
 * II) add account entries,
 * III) update account's balance
+* IV) records the transfer in the audit trail
+* V) stores the result under the idempotency key (if given)
 * within a single database transaction
  */
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	return store.idempotentTransferTx(ctx, arg.Idempotency, arg.Audit, func(q *Queries) (TransferTxResult, error) {
+		// same currency transfer - the to account is credited with the same amount
+		return transfer(ctx, q, CreateTransferParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			ToAmount:      arg.Amount,
+			ExchangeRate:  1,
+		})
+	})
+}
+
+/*
+* idempotentTransferTx - runs the given transfer function within a database transaction
+* when an idempotency key is given:
+* a retry returns the stored result instead of running the transfer again
+* a new request stores its result under the key within the same transaction
+* a new transfer is recorded in the audit trail, a replayed one isn't recorded again
+ */
+func (store *SQLStore) idempotentTransferTx(ctx context.Context, idempotency IdempotencyParams, audit AuditParams, fn func(*Queries) (TransferTxResult, error)) (TransferTxResult, error) {
 	// creating the result object of the transaction
 	var result TransferTxResult
-	/*
-	* Running the transaction
-	* The transaction steps are:
-	* 1. creating transfer
-	* 2. creating entries - (2 entries one from account and the other for to account)
-	 */
+
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
-		result.Transfer, err = makeTransfer(ctx, q, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+		// returning the stored result if the request was already executed
+		if idempotency.Key != "" {
+			result, err = replayTransfer(ctx, q, idempotency)
+			if err != nil || result.Replayed {
+				return err
+			}
+		}
+
+		result, err = fn(q)
 		if err != nil {
 			return err
 		}
 
-		result.FromEntry, result.ToEntry, err = makeEntry(ctx, q, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+		err = recordAuditEvent(ctx, q, audit, AuditTransferCreate, TransferTarget(result.Transfer.ID), nil, result)
 		if err != nil {
 			return err
 		}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			// update balance account's
-			result.FromAccount, err = updateAccountBalance(ctx, q, arg.FromAccountID, (arg.Amount * -1))
-			if err != nil {
-				return err
-			}
+		// storing the result for future retries of the same request
+		if idempotency.Key != "" {
+			return saveTransfer(ctx, q, idempotency, result)
+		}
+		return nil
+	})
 
-			result.ToAccount, err = updateAccountBalance(ctx, q, arg.ToAccountID, arg.Amount)
-			return err
+	// returning the transaction result + thr transaction error
+	return result, err
+}
 
-		} else {
-			result.ToAccount, err = updateAccountBalance(ctx, q, arg.ToAccountID, arg.Amount)
-			if err != nil {
-				return err
-			}
+/*
+* transfer - moves the money between the accounts using the given queries object
+* the from account is debited with arg.Amount and the to account is credited with arg.ToAmount
+* The transaction steps are:
+* 1. creating transfer
+* 2. creating entries - (2 entries one from account and the other for to account)
+* 3. updating the balances - always in the same accounts order for avoiding deadlocks
+ */
+func transfer(ctx context.Context, q *Queries, arg CreateTransferParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	var err error
 
-			result.FromAccount, err = updateAccountBalance(ctx, q, arg.FromAccountID, (arg.Amount * -1))
-			return err
+	result.Transfer, err = q.CreateTransfer(ctx, arg)
+	if err != nil {
+		return result, err
+	}
+
+	result.FromEntry, result.ToEntry, err = makeEntry(ctx, q, result.Transfer.ID, arg.FromAccountID, arg.ToAccountID, arg.Amount, arg.ToAmount)
+	if err != nil {
+		return result, err
+	}
+
+	if arg.FromAccountID < arg.ToAccountID {
+		// update balance account's
+		result.FromAccount, err = updateAccountBalance(ctx, q, arg.FromAccountID, (arg.Amount * -1))
+		if err != nil {
+			return result, err
 		}
-	})
 
-	// returning the transaction result + thr transaction error
+		result.ToAccount, err = updateAccountBalance(ctx, q, arg.ToAccountID, arg.ToAmount)
+		return result, err
+	}
+
+	result.ToAccount, err = updateAccountBalance(ctx, q, arg.ToAccountID, arg.ToAmount)
+	if err != nil {
+		return result, err
+	}
+
+	result.FromAccount, err = updateAccountBalance(ctx, q, arg.FromAccountID, (arg.Amount * -1))
 	return result, err
 }
 
-// makeTransfer - creating a transfer
-func makeTransfer(ctx context.Context, q *Queries, fromAccountID, toAccountID, amount int64) (Transfer, error) {
-	return q.CreateTransfer(ctx, CreateTransferParams{
-		FromAccountID: fromAccountID,
-		ToAccountID:   toAccountID,
-		Amount:        amount,
+/*
+* replayTransfer - looking for a previous result of the same idempotency key
+* the key is locked until the end of the transaction, so concurrent retries are serialized
+* returns ErrIdempotencyKeyMismatch when the key was used for a different request
+ */
+func replayTransfer(ctx context.Context, q *Queries, arg IdempotencyParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := q.LockIdempotencyKey(ctx, LockIdempotencyKeyParams{
+		Username: arg.Username,
+		Key:      arg.Key,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	key, err := q.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{
+		Username: arg.Username,
+		Key:      arg.Key,
+	})
+	if err != nil {
+		// first time we see this key - nothing to replay
+		if isNotFound(err) {
+			return result, nil
+		}
+		return result, err
+	}
+
+	if key.RequestHash != arg.RequestHash {
+		return result, ErrIdempotencyKeyMismatch
+	}
+
+	if err = json.Unmarshal(key.Response, &result); err != nil {
+		return result, err
+	}
+	result.Replayed = true
+
+	return result, nil
+}
+
+// saveTransfer - storing the transfer result under the idempotency key
+func saveTransfer(ctx context.Context, q *Queries, arg IdempotencyParams, result TransferTxResult) error {
+	response, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+		Username:    arg.Username,
+		Key:         arg.Key,
+		RequestHash: arg.RequestHash,
+		Response:    response,
 	})
+	return err
 }
 
-// makeTransfer - creating entries for from account + to account
-func makeEntry(ctx context.Context, q *Queries, fromAccountID, toAccountID, amount int64) (Entry, Entry, error) {
+// makeEntry - creating entries for from account + to account, both linked to the given transfer
+func makeEntry(ctx context.Context, q *Queries, transferID, fromAccountID, toAccountID, fromAmount, toAmount int64) (Entry, Entry, error) {
 	fromEntryResult, err := q.CreateEntry(ctx, CreateEntryParams{
-		AccountID: fromAccountID,
-		Amount:    (amount * -1),
+		AccountID:  fromAccountID,
+		Amount:     (fromAmount * -1),
+		TransferID: sql.NullInt64{Int64: transferID, Valid: true},
 	})
 	if err != nil {
 		return Entry{}, Entry{}, err
 	}
 
 	toEntryResult, err := q.CreateEntry(ctx, CreateEntryParams{
-		AccountID: toAccountID,
-		Amount:    amount,
+		AccountID:  toAccountID,
+		Amount:     toAmount,
+		TransferID: sql.NullInt64{Int64: transferID, Valid: true},
 	})
 
 	return fromEntryResult, toEntryResult, err
@@ -162,9 +335,35 @@ func updateAccountBalance(ctx context.Context, q *Queries, accountID, addedBalan
 		return Account{}, err
 	}
 
+	// the money of a frozen or closed account can't be moved - in both directions
+	if err = checkAccountActive(account); err != nil {
+		return Account{}, err
+	}
+
+	// a withdrawal may not take the available balance (the balance minus the active holds) below the account overdraft limit
+	if addedBalance < 0 {
+		if err = checkAvailableBalance(ctx, q, account, -addedBalance); err != nil {
+			return Account{}, err
+		}
+	}
+
 	// returning the update details account + errors
 	return q.UpdateAccount(ctx, UpdateAccountParams{
 		ID:      accountID,
 		Balance: account.Balance + addedBalance,
 	})
 }
+
+// checkAvailableBalance - checking the account can cover the given amount on top of its active holds
+// the account must be locked by the caller
+func checkAvailableBalance(ctx context.Context, q *Queries, account Account, amount int64) error {
+	held, err := q.GetAccountHeldAmount(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+
+	if account.Balance-held-amount < -account.OverdraftLimit {
+		return ErrInsufficientFunds
+	}
+	return nil
+}
@@ -10,14 +10,16 @@ import (
 
 func TestTransferTx(t *testing.T) {
 	// creating store object
-	store := NewStore(testDB)
+	store := NewStore(testPool)
 	// crating 2 accounts for the transfer transaction
 	account1 := createRandomAccount(t)
 	account2 := createRandomAccount(t)
 
 	// run n concurrent transfer transactions
 	n := int(util.RandomInt(4, 6))
-	amount := util.RandomMoney()
+	amount := util.RandomInt(1, 1000)
+	// making sure the from account can cover all the transfers
+	account1 = setAccountBalance(t, account1, amount*int64(n))
 
 	// crating 2 chanel's - (one for errors and the other for the results)
 	errs := make(chan error)
@@ -74,6 +76,10 @@ func TestTransferTx(t *testing.T) {
 		require.Equal(t, toEntry.AccountID, account2.ID)
 		require.Equal(t, toEntry.Amount, amount)
 
+		// both entries are linked to the transfer
+		require.Equal(t, transfer.ID, fromEntry.TransferID.Int64)
+		require.Equal(t, transfer.ID, toEntry.TransferID.Int64)
+
 		require.NotZero(t, fromEntry.ID)
 		require.NotZero(t, fromEntry.CreatedAt)
 
@@ -116,3 +122,97 @@ func TestTransferTx(t *testing.T) {
 	require.Equal(t, updateAccount1.Balance, account1.Balance-(amount*int64(n)))
 	require.Equal(t, updateAccount2.Balance, account2.Balance+(amount*int64(n)))
 }
+
+func TestTransferTxIdempotency(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := setAccountBalance(t, createRandomAccount(t), 100)
+	account2 := createRandomAccount(t)
+
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Idempotency: IdempotencyParams{
+			Username:    account1.Owner,
+			Key:         util.RandomString(12),
+			RequestHash: util.RandomString(32),
+		},
+	}
+
+	// the first request executes the transfer
+	result1, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, result1.Replayed)
+
+	// a retry returns the original transfer without moving the money again
+	result2, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result2.Replayed)
+	require.Equal(t, result1.Transfer.ID, result2.Transfer.ID)
+	require.Equal(t, result1.FromEntry.ID, result2.FromEntry.ID)
+	require.Equal(t, result1.ToEntry.ID, result2.ToEntry.ID)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-arg.Amount, updatedAccount1.Balance)
+
+	// the same key with a different request is rejected
+	arg.Amount = 20
+	arg.Idempotency.RequestHash = util.RandomString(32)
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyMismatch)
+}
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := setAccountBalance(t, createRandomAccount(t), 100)
+	account2 := createRandomAccount(t)
+
+	// the balance doesn't cover the amount
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        101,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// nothing was moved
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	// the overdraft limit allows the balance to go below zero
+	_, err = store.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 50,
+	})
+	require.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        150,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-50), result.FromAccount.Balance)
+
+	// but not below the overdraft limit
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+// setAccountBalance - sets the balance of the given account
+func setAccountBalance(t *testing.T, account Account, balance int64) Account {
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: balance,
+	})
+	require.NoError(t, err)
+	require.Equal(t, balance, account.Balance)
+
+	return account
+}
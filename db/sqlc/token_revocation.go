@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/*
+* RevokeUserTokensTxParams - contains the input parameters of the revoke user tokens transaction
+* RevokedBefore: every token of the user issued before this time is revoked
+* Audit: the revocation is recorded in the audit trail when it has an actor (e.g. an admin)
+ */
+type RevokeUserTokensTxParams struct {
+	Username      string      `json:"username"`
+	RevokedBefore time.Time   `json:"revoked_before"`
+	Audit         AuditParams `json:"audit"`
+}
+
+/*
+* RevokeUserTokensTx - revokes every token of the user
+* I) revokes the tokens issued before the given time
+* II) blocks the sessions of the user - so their refresh tokens can't renew access tokens
+* III) records the revocation in the audit trail (when made by an actor)
+* within a single database transaction
+ */
+func (store *SQLStore) RevokeUserTokensTx(ctx context.Context, arg RevokeUserTokensTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		err := q.RevokeUserTokens(ctx, RevokeUserTokensParams{
+			Username:      arg.Username,
+			RevokedBefore: arg.RevokedBefore,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err = q.BlockUserSessions(ctx, arg.Username); err != nil {
+			return err
+		}
+
+		if arg.Audit.Actor == "" {
+			return nil
+		}
+		return recordAuditEvent(ctx, q, arg.Audit, AuditUserRevokeTokens, UserTarget(arg.Username), nil, revokeTokensAuditEvent{
+			RevokedBefore: arg.RevokedBefore,
+		})
+	})
+}
+
+// revokeTokensAuditEvent - the revocation of the user tokens as recorded in the audit trail
+type revokeTokensAuditEvent struct {
+	RevokedBefore time.Time `json:"revoked_before"`
+}
+
+// TokenRevocations - the revoked tokens of the store - the token revocation store (token.RevocationStore) of the servers
+type TokenRevocations struct {
+	store Store
+}
+
+// NewTokenRevocations - creates the token revocation store of the given store
+func NewTokenRevocations(store Store) *TokenRevocations {
+	return &TokenRevocations{store: store}
+}
+
+// IsRevoked - checks if the token was revoked - by its id, by revoking the tokens of its user or by a password change
+func (revocations *TokenRevocations) IsRevoked(ctx context.Context, id uuid.UUID, username string, issuedAt time.Time) (bool, error) {
+	return revocations.store.IsTokenRevoked(ctx, IsTokenRevokedParams{
+		ID:       id,
+		Username: username,
+		IssuedAt: issuedAt,
+	})
+}
+
+// Revoke - revokes the token until its expiration
+func (revocations *TokenRevocations) Revoke(ctx context.Context, id uuid.UUID, username string, expiresAt time.Time) error {
+	return revocations.store.CreateRevokedToken(ctx, CreateRevokedTokenParams{
+		ID:        id,
+		Username:  username,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// RevokeUser - revokes every token of the user issued before the given time and blocks the user sessions
+func (revocations *TokenRevocations) RevokeUser(ctx context.Context, username string, revokedBefore time.Time) error {
+	return revocations.store.RevokeUserTokensTx(ctx, RevokeUserTokensTxParams{
+		Username:      username,
+		RevokedBefore: revokedBefore,
+	})
+}
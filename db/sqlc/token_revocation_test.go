@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokedToken(t *testing.T) {
+	user := createRandomUser(t)
+	id := uuid.New()
+	issuedAt := time.Now()
+
+	arg := IsTokenRevokedParams{
+		ID:       id,
+		Username: user.Username,
+		IssuedAt: issuedAt,
+	}
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = testQueries.CreateRevokedToken(context.Background(), CreateRevokedTokenParams{
+		ID:        id,
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// revoking twice is a no-op
+	err = testQueries.CreateRevokedToken(context.Background(), CreateRevokedTokenParams{
+		ID:        id,
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+}
+
+func TestRevokeUserTokensTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := createRandomUser(t)
+	session := createRandomSession(t, user.Username)
+
+	issuedAt := time.Now().Add(-time.Minute)
+	err := store.RevokeUserTokensTx(context.Background(), RevokeUserTokensTxParams{
+		Username:      user.Username,
+		RevokedBefore: time.Now(),
+	})
+	require.NoError(t, err)
+
+	// a token issued before the revocation is revoked
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: issuedAt,
+	})
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// a token issued after the revocation is valid
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// the sessions of the user are blocked
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+}
+
+func TestPruneTokenRevocations(t *testing.T) {
+	user := createRandomUser(t)
+	id := uuid.New()
+
+	err := testQueries.CreateRevokedToken(context.Background(), CreateRevokedTokenParams{
+		ID:        id,
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	err = testQueries.RevokeUserTokens(context.Background(), RevokeUserTokensParams{
+		Username:      user.Username,
+		RevokedBefore: time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	deleted, err := testQueries.DeleteExpiredRevokedTokens(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+
+	deleted, err = testQueries.DeleteUserTokenRevocations(context.Background(), time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+
+	// nothing left for the user
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       id,
+		Username: user.Username,
+		IssuedAt: time.Now().Add(-2 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/shimon-git/simple-bank/util"
 	"github.com/stretchr/testify/require"
 )
@@ -14,10 +15,13 @@ func createRandomTransfer(t *testing.T) Transfer {
 	account1 := createRandomAccount(t)
 	account2 := createRandomAccount(t)
 
+	amount := util.RandomMoney()
 	arg := CreateTransferParams{
 		FromAccountID: account1.ID,
 		ToAccountID:   account2.ID,
-		Amount:        util.RandomMoney(),
+		Amount:        amount,
+		ToAmount:      amount,
+		ExchangeRate:  1,
 	}
 
 	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
@@ -28,6 +32,8 @@ func createRandomTransfer(t *testing.T) Transfer {
 	require.Equal(t, arg.FromAccountID, transfer.FromAccountID)
 	require.Equal(t, arg.ToAccountID, transfer.ToAccountID)
 	require.Equal(t, arg.Amount, transfer.Amount)
+	require.Equal(t, arg.ToAmount, transfer.ToAmount)
+	require.Equal(t, arg.ExchangeRate, transfer.ExchangeRate)
 
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
@@ -76,6 +82,58 @@ func TestListTransfers(t *testing.T) {
 	}
 }
 
+func TestListAccountTransfers(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	// two outgoing transfers and one incoming transfer
+	for _, arg := range []CreateTransferParams{
+		{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, ToAmount: 10, ExchangeRate: 1},
+		{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 20, ToAmount: 20, ExchangeRate: 1},
+		{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 30, ToAmount: 30, ExchangeRate: 1},
+	} {
+		_, err := testQueries.CreateTransfer(context.Background(), arg)
+		require.NoError(t, err)
+	}
+
+	transfers, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		PageSize:  2,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	require.Equal(t, int64(30), transfers[0].Amount)
+	require.Equal(t, int64(20), transfers[1].Amount)
+
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		Cursor:    sql.NullInt64{Int64: transfers[1].ID, Valid: true},
+		PageSize:  2,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, int64(10), transfers[0].Amount)
+
+	credits, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		Direction: sql.NullString{String: "credit", Valid: true},
+		PageSize:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, credits, 1)
+	require.Equal(t, account1.ID, credits[0].ToAccountID)
+
+	debits, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		Direction: sql.NullString{String: "debit", Valid: true},
+		MaxAmount: sql.NullInt64{Int64: 15, Valid: true},
+		PageSize:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, debits, 1)
+	require.Equal(t, int64(10), debits[0].Amount)
+}
+
 func TestUpdateTransfer(t *testing.T) {
 	transfer1 := createRandomTransfer(t)
 
@@ -108,6 +166,6 @@ func TestDeleteTransfer(t *testing.T) {
 
 	require.Error(t, err)
 
-	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.EqualError(t, err, pgx.ErrNoRows.Error())
 	require.Empty(t, transfer2)
 }
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+)
+
+/*
+* ConfirmTotpTxParams - contains the input parameters of the confirm totp transaction
+* Step: the step of the valid code given by the user - it can't be used again
+* RecoveryCodeHashes: the sha256 of the new recovery codes of the user
+ */
+type ConfirmTotpTxParams struct {
+	Username           string      `json:"username"`
+	Step               int64       `json:"step"`
+	RecoveryCodeHashes []string    `json:"-"`
+	Audit              AuditParams `json:"audit"`
+}
+
+/*
+ * ConfirmTotpTx - enables the two factor authentication of the user
+ * I) confirms the pending totp enrollment
+ * II) replaces the recovery codes of the user
+ * III) records the change in the audit trail
+ * within a single database transaction
+ * returns ErrTotpNotPending when there is no pending enrollment or the code was already used
+ */
+func (store *SQLStore) ConfirmTotpTx(ctx context.Context, arg ConfirmTotpTxParams) (UserTotp, error) {
+	var userTotp UserTotp
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		userTotp, err = q.ConfirmUserTotp(ctx, ConfirmUserTotpParams{
+			Username:     arg.Username,
+			LastUsedStep: arg.Step,
+		})
+		if isNotFound(err) {
+			return ErrTotpNotPending
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = q.DeleteUserRecoveryCodes(ctx, arg.Username); err != nil {
+			return err
+		}
+		for _, codeHash := range arg.RecoveryCodeHashes {
+			_, err = q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: codeHash,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// the secret is never recorded
+		return recordAuditEvent(ctx, q, arg.Audit, AuditUserMFAEnable, UserTarget(arg.Username), nil, mfaAuditEvent{
+			Method:        "totp",
+			RecoveryCodes: len(arg.RecoveryCodeHashes),
+		})
+	})
+
+	return userTotp, err
+}
+
+// mfaAuditEvent - the enabled second factor as recorded in the audit trail
+type mfaAuditEvent struct {
+	Method        string `json:"method"`
+	RecoveryCodes int    `json:"recovery_codes"`
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomUserTotp(t *testing.T, username string) UserTotp {
+	arg := UpsertUserTotpParams{
+		Username: username,
+		Secret:   util.RandomString(32),
+	}
+	userTotp, err := testQueries.UpsertUserTotp(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, userTotp.Username)
+	require.Equal(t, arg.Secret, userTotp.Secret)
+	require.False(t, userTotp.ConfirmedAt.Valid)
+	return userTotp
+}
+
+func TestConfirmTotpTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := createRandomUser(t)
+	createRandomUserTotp(t, user.Username)
+
+	codeHash := util.RandomString(64)
+	arg := ConfirmTotpTxParams{
+		Username:           user.Username,
+		Step:               100,
+		RecoveryCodeHashes: []string{codeHash, util.RandomString(64)},
+		Audit:              AuditParams{Actor: user.Username, RequestID: util.RandomString(10)},
+	}
+	userTotp, err := store.ConfirmTotpTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, userTotp.ConfirmedAt.Valid)
+	require.Equal(t, arg.Step, userTotp.LastUsedStep)
+
+	// the enrollment can't be confirmed again or replaced
+	_, err = store.ConfirmTotpTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrTotpNotPending)
+
+	_, err = testQueries.UpsertUserTotp(context.Background(), UpsertUserTotpParams{
+		Username: user.Username,
+		Secret:   util.RandomString(32),
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	// the recovery codes are single-use
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: codeHash,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: codeHash,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestUseTotpStep(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomUserTotp(t, user.Username)
+
+	// a pending enrollment doesn't accept codes
+	_, err := testQueries.UseTotpStep(context.Background(), UseTotpStepParams{
+		Username:     user.Username,
+		LastUsedStep: 100,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	_, err = testQueries.ConfirmUserTotp(context.Background(), ConfirmUserTotpParams{
+		Username:     user.Username,
+		LastUsedStep: 100,
+	})
+	require.NoError(t, err)
+
+	userTotp, err := testQueries.UseTotpStep(context.Background(), UseTotpStepParams{
+		Username:     user.Username,
+		LastUsedStep: 101,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(101), userTotp.LastUsedStep)
+
+	// the code of a used step can't be replayed
+	_, err = testQueries.UseTotpStep(context.Background(), UseTotpStepParams{
+		Username:     user.Username,
+		LastUsedStep: 101,
+	})
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// CreateUserTxParams - contains the input parameters of the create user transaction
+type CreateUserTxParams struct {
+	CreateUserParams
+	// the sha256 of the code sent to the user for verifying the email
+	SecretCodeHash string `json:"secret_code_hash"`
+	// how long the code of the verify email is valid
+	VerifyEmailDuration time.Duration `json:"verify_email_duration"`
+}
+
+// CreateUserTxResult - the result of the create user transaction
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+/*
+ * CreateUserTx - creates the user and its verify email within a single database transaction
+ * the verify email is sent by the caller once the transaction is committed - never for a rolled back user
+ */
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:       result.User.Username,
+			Email:          result.User.Email,
+			SecretCodeHash: arg.SecretCodeHash,
+			ExpiredAt:      time.Now().Add(arg.VerifyEmailDuration),
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// VerifyEmailTxParams - contains the input parameters of the verify email transaction
+type VerifyEmailTxParams struct {
+	ID             int64  `json:"id"`
+	SecretCodeHash string `json:"secret_code_hash"`
+}
+
+// VerifyEmailTxResult - the result of the verify email transaction
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+/*
+ * VerifyEmailTx - uses the code of the verify email and marks the email of the user as verified within a single database transaction
+ * returns ErrVerifyEmailUnavailable when the code is wrong, expired or was already used
+ * or when the user changed the email since the code was sent
+ */
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.VerifyEmail, err = q.UseVerifyEmail(ctx, UseVerifyEmailParams{
+			ID:             arg.ID,
+			SecretCodeHash: arg.SecretCodeHash,
+		})
+		if isNotFound(err) {
+			return ErrVerifyEmailUnavailable
+		}
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: result.VerifyEmail.Username,
+			Email:    result.VerifyEmail.Email,
+		})
+		if isNotFound(err) {
+			return ErrVerifyEmailUnavailable
+		}
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// createRandomUserTx - creating a user with its verify email through the create user transaction
+func createRandomUserTx(t *testing.T, store Store, secretCodeHash string, duration time.Duration) (User, VerifyEmail) {
+	hashedPassword, err := util.HashedPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	result, err := store.CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomOwner(),
+			HashedPassword: hashedPassword,
+			FullName:       util.RandomOwner(),
+			Email:          util.RandomEmail(),
+		},
+		SecretCodeHash:      secretCodeHash,
+		VerifyEmailDuration: duration,
+	})
+	require.NoError(t, err)
+	user, verifyEmail := result.User, result.VerifyEmail
+	require.False(t, user.IsEmailVerified)
+
+	require.Equal(t, user.Username, verifyEmail.Username)
+	require.Equal(t, user.Email, verifyEmail.Email)
+	require.Equal(t, secretCodeHash, verifyEmail.SecretCodeHash)
+	require.False(t, verifyEmail.IsUsed)
+	require.WithinDuration(t, time.Now().Add(duration), verifyEmail.ExpiredAt, time.Second)
+	return user, verifyEmail
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testPool)
+	secretCodeHash := util.RandomString(64)
+	user, verifyEmail := createRandomUserTx(t, store, secretCodeHash, time.Minute)
+
+	// a wrong code doesn't verify the email
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             verifyEmail.ID,
+		SecretCodeHash: util.RandomString(64),
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailUnavailable)
+
+	result, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             verifyEmail.ID,
+		SecretCodeHash: secretCodeHash,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, result.User.Username)
+	require.True(t, result.User.IsEmailVerified)
+	require.True(t, result.VerifyEmail.IsUsed)
+
+	// the code can be used only once
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             verifyEmail.ID,
+		SecretCodeHash: secretCodeHash,
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailUnavailable)
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testPool)
+	secretCodeHash := util.RandomString(64)
+	user, verifyEmail := createRandomUserTx(t, store, secretCodeHash, -time.Minute)
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             verifyEmail.ID,
+		SecretCodeHash: secretCodeHash,
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailUnavailable)
+
+	user, err = testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.False(t, user.IsEmailVerified)
+}
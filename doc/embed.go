@@ -0,0 +1,10 @@
+package doc
+
+import "embed"
+
+/*
+* Swagger - the swagger ui of the REST API + its openapi spec (simple_bank.swagger.json)
+* the spec is generated from the proto files by make proto - embedded so the binary serves the docs of its own API
+ */
+//go:embed swagger
+var Swagger embed.FS
@@ -0,0 +1,30 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// different types of errors returned by the rate providers
+var (
+	ErrRateNotFound = errors.New("exchange rate not found")
+	ErrInvalidRate  = errors.New("exchange rate must be positive")
+)
+
+// RateProvider is an interface for getting exchange rates
+type RateProvider interface {
+	// GetRate - returns how many units of the 'to' currency are given for one unit of the 'from' currency
+	GetRate(ctx context.Context, from, to string) (float64, error)
+}
+
+// Convert - converts the amount by the given rate, rounded to the nearest unit
+func Convert(amount int64, rate float64) int64 {
+	return int64(math.Round(float64(amount) * rate))
+}
+
+// pair - returns the key of the currencies pair, for example: USD/ILS
+func pair(from, to string) string {
+	return fmt.Sprintf("%s/%s", from, to)
+}
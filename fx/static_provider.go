@@ -0,0 +1,64 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// StaticRateProvider is an in memory rate provider - rates are loaded once and never change
+type StaticRateProvider struct {
+	rates map[string]float64
+}
+
+/*
+* NewStaticRateProvider - creates a new StaticRateProvider
+* rates: map of currencies pair (for example: "USD/ILS") to rate
+ */
+func NewStaticRateProvider(rates map[string]float64) (RateProvider, error) {
+	provider := &StaticRateProvider{
+		rates: make(map[string]float64, len(rates)),
+	}
+
+	for currencies, rate := range rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("%s: %w", currencies, ErrInvalidRate)
+		}
+		provider.rates[currencies] = rate
+	}
+
+	return provider, nil
+}
+
+// LoadStaticRateProvider - creates a new StaticRateProvider from a json file of pairs and rates
+func LoadStaticRateProvider(path string) (RateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the rates file: %w", err)
+	}
+
+	var rates map[string]float64
+	if err = json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("failed to parse the rates file: %w", err)
+	}
+
+	return NewStaticRateProvider(rates)
+}
+
+// GetRate - returns the rate of the pair, if only the opposite pair is known its inverse rate is returned
+func (provider *StaticRateProvider) GetRate(ctx context.Context, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	if rate, ok := provider.rates[pair(from, to)]; ok {
+		return rate, nil
+	}
+
+	if rate, ok := provider.rates[pair(to, from)]; ok {
+		return 1 / rate, nil
+	}
+
+	return 0, ErrRateNotFound
+}
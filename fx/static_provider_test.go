@@ -0,0 +1,70 @@
+package fx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticRateProvider(t *testing.T) {
+	provider, err := NewStaticRateProvider(map[string]float64{
+		"USD/ILS": 4,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, provider)
+
+	// the known pair
+	rate, err := provider.GetRate(context.Background(), util.USD, util.ILS)
+	require.NoError(t, err)
+	require.Equal(t, 4.0, rate)
+
+	// the opposite pair
+	rate, err = provider.GetRate(context.Background(), util.ILS, util.USD)
+	require.NoError(t, err)
+	require.Equal(t, 0.25, rate)
+
+	// the same currency
+	rate, err = provider.GetRate(context.Background(), util.EUR, util.EUR)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, rate)
+
+	// unknown pair
+	rate, err = provider.GetRate(context.Background(), util.USD, util.EUR)
+	require.ErrorIs(t, err, ErrRateNotFound)
+	require.Zero(t, rate)
+}
+
+func TestInvalidStaticRate(t *testing.T) {
+	provider, err := NewStaticRateProvider(map[string]float64{
+		"USD/ILS": 0,
+	})
+	require.ErrorIs(t, err, ErrInvalidRate)
+	require.Nil(t, provider)
+}
+
+func TestLoadStaticRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"EUR/USD": 1.1}`), 0600)
+	require.NoError(t, err)
+
+	provider, err := LoadStaticRateProvider(path)
+	require.NoError(t, err)
+
+	rate, err := provider.GetRate(context.Background(), util.EUR, util.USD)
+	require.NoError(t, err)
+	require.Equal(t, 1.1, rate)
+
+	// missing file
+	_, err = LoadStaticRateProvider(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	require.Equal(t, int64(400), Convert(100, 4))
+	require.Equal(t, int64(33), Convert(100, 0.333))
+	require.Equal(t, int64(67), Convert(100, 0.666))
+}
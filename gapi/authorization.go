@@ -0,0 +1,111 @@
+package gapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/shimon-git/simple-bank/api"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// payloadKey - the context key of the payload of the verified access token
+type payloadKey struct{}
+
+// publicMethods - the methods that don't require an access token (the sign up and the login)
+var publicMethods = map[string]bool{
+	pb.SimpleBank_CreateUser_FullMethodName: true,
+	pb.SimpleBank_LoginUser_FullMethodName:  true,
+}
+
+// methodScopes - the scopes required by every method of the service that isn't public
+var methodScopes = map[string][]string{
+	pb.SimpleBank_GetAccount_FullMethodName:     {token.ScopeAccountsRead},
+	pb.SimpleBank_ListAccounts_FullMethodName:   {token.ScopeAccountsRead},
+	pb.SimpleBank_CreateAccount_FullMethodName:  {token.ScopeAccountsWrite},
+	pb.SimpleBank_CreateTransfer_FullMethodName: {token.ScopeAccountsWrite},
+}
+
+// simpleBankServicePrefix - the prefix of the full names of the methods of the service
+var simpleBankServicePrefix = "/" + pb.SimpleBank_ServiceDesc.ServiceName + "/"
+
+/*
+* authInterceptor - an interceptor that checks the access token of the authorization metadata ("bearer <token>")
+* the token is verified (including the revocation list) and must grant all the scopes of the method
+* the payload of the token is passed to the method through the context
+* a method of the service that is neither public nor in methodScopes is denied - a new method must be given its scopes explicitly
+ */
+func (server *Server) authInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	// the public methods + the methods of other services (e.g. the reflection service)
+	if publicMethods[info.FullMethod] || !strings.HasPrefix(info.FullMethod, simpleBankServicePrefix) {
+		return handler(ctx, req)
+	}
+	scopes, known := methodScopes[info.FullMethod]
+	if !known {
+		return nil, status.Errorf(codes.PermissionDenied, "the method %s has no authorization policy", info.FullMethod)
+	}
+
+	payload, err := server.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// if one of the scopes is missing return code PermissionDenied
+	for _, scope := range scopes {
+		if !payload.HasScope(scope) {
+			return nil, status.Errorf(codes.PermissionDenied, "the %s role doesn't grant the %s scope", payload.Role, scope)
+		}
+	}
+
+	return handler(context.WithValue(ctx, payloadKey{}, payload), req)
+}
+
+// authenticate - verifying the bearer access token of the request, returns its payload
+func (server *Server) authenticate(ctx context.Context) (*token.Payload, error) {
+	authorization := metadataValue(ctx, authorizationHeader)
+	if authorization == "" {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata has not provided")
+	}
+	// 2 fields must be: 1 for the authorization type, the second for the access token value
+	fields := strings.Fields(authorization)
+	if len(fields) < 2 {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+	}
+	if authorizationType := strings.ToLower(fields[0]); authorizationType != authorizationBearer {
+		return nil, status.Errorf(codes.Unauthenticated, "unsupported authorization type: %s", authorizationType)
+	}
+
+	payload, err := server.token.VerifyToken(fields[1], token.TokenTypeAccessToken)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+	// a mfa pending token only completes the login - it can't authorize requests
+	if payload.HasScope(token.ScopeMFAVerify) {
+		return nil, reasonError(codes.Unauthenticated, api.CodeMFARequired, "the login must be completed with the second factor")
+	}
+	// rejecting revoked tokens - a token issued before the last password change of its user is revoked too
+	revoked, err := server.revocations.IsRevoked(ctx, payload)
+	if err != nil {
+		return nil, internalError("failed to check the revocation list", err)
+	}
+	if revoked {
+		return nil, reasonError(codes.Unauthenticated, api.CodeTokenRevoked, token.ErrRevokedToken.Error())
+	}
+	return payload, nil
+}
+
+// authPayload - the payload of the access token of the request, set by the auth interceptor
+func authPayload(ctx context.Context) (*token.Payload, error) {
+	payload, ok := ctx.Value(payloadKey{}).(*token.Payload)
+	if !ok {
+		return nil, status.Error(codes.Internal, "failed to retrieve the payload data from the authorization token")
+	}
+	return payload, nil
+}
@@ -0,0 +1,281 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name       string
+		setupAuth  func(t *testing.T, tokenMaker token.Maker) context.Context
+		buildStubs func(store *mockdb.MockStore)
+		code       codes.Code
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetAccountHeldAmount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "UnsupportedAuthorization",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				accessToken, _, err := tokenMaker.CreateToken(user.Username, util.CustomerRole, time.Minute, token.TokenTypeAccessToken)
+				require.NoError(t, err)
+				md := metadata.Pairs(authorizationHeader, fmt.Sprintf("basic %s", accessToken))
+				return metadata.NewOutgoingContext(context.Background(), md)
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "InvalidAuthorizationFormat",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				md := metadata.Pairs(authorizationHeader, authorizationBearer)
+				return metadata.NewOutgoingContext(context.Background(), md)
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "ExpiredToken",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, util.CustomerRole, -time.Minute)
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "MFAPendingToken",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, token.MFAPendingRole, time.Minute)
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "RefreshToken",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				// a refresh token can't be used as an access token
+				refreshToken, _, err := tokenMaker.CreateToken(user.Username, util.CustomerRole, time.Minute, token.TokenTypeRefreshToken)
+				require.NoError(t, err)
+				md := metadata.Pairs(authorizationHeader, fmt.Sprintf("%s %s", authorizationBearer, refreshToken))
+				return metadata.NewOutgoingContext(context.Background(), md)
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "RevokedToken",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					IsTokenRevoked(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(true, nil)
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "RevocationCheckError",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					IsTokenRevoked(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(false, sql.ErrConnDone)
+			},
+			code: codes.Internal,
+		},
+	}
+
+	for testIDX := range testCases {
+		test := testCases[testIDX]
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			if test.buildStubs != nil {
+				test.buildStubs(store)
+			} else {
+				// the request must not reach the method
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			}
+
+			server, client := newTestServer(t, store)
+			_, err := client.GetAccount(test.setupAuth(t, server.token), &pb.GetAccountRequest{Id: account.ID})
+			if test.code == codes.OK {
+				require.NoError(t, err)
+				return
+			}
+			requireStatusCode(t, err, test.code)
+		})
+	}
+}
+
+func TestAuthInterceptorMethodPolicy(t *testing.T) {
+	testCases := []struct {
+		name       string
+		fullMethod string
+		code       codes.Code
+	}{
+		{
+			name:       "PublicMethod",
+			fullMethod: pb.SimpleBank_LoginUser_FullMethodName,
+			code:       codes.OK,
+		},
+		{
+			name:       "OtherService",
+			fullMethod: "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+			code:       codes.OK,
+		},
+		{
+			// a method of the service without an authorization policy is denied - even before the authentication
+			name:       "UnlistedMethod",
+			fullMethod: simpleBankServicePrefix + "UnlistedMethod",
+			code:       codes.PermissionDenied,
+		},
+		{
+			name:       "ProtectedMethod",
+			fullMethod: pb.SimpleBank_GetAccount_FullMethodName,
+			code:       codes.Unauthenticated,
+		},
+	}
+
+	for testIDX := range testCases {
+		test := testCases[testIDX]
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server, _ := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+			handled := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				handled = true
+				return nil, nil
+			}
+			_, err := server.authInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: test.fullMethod}, handler)
+			if test.code == codes.OK {
+				require.NoError(t, err)
+				require.True(t, handled)
+				return
+			}
+			requireStatusCode(t, err, test.code)
+			require.False(t, handled)
+		})
+	}
+
+	// every method of the service is either public or has its scopes
+	for _, method := range pb.SimpleBank_ServiceDesc.Methods {
+		fullMethod := simpleBankServicePrefix + method.MethodName
+		_, scoped := methodScopes[fullMethod]
+		require.True(t, publicMethods[fullMethod] || scoped, fullMethod)
+	}
+}
+
+func TestRequestIDInterceptor(t *testing.T) {
+	testCases := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{
+			name:      "ClientRequestID",
+			requestID: "client-request-1",
+		},
+		{
+			name:      "MissingRequestID",
+			generated: true,
+		},
+		{
+			name:      "InvalidRequestID",
+			requestID: "not valid",
+			generated: true,
+		},
+	}
+
+	for testIDX := range testCases {
+		test := testCases[testIDX]
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+
+			user, _ := randomUser(t)
+			account := randomAccount(user.Username)
+			// the request id of the request is recorded with the audit event of the new account
+			var auditedRequestID string
+			store.EXPECT().
+				CreateAccountTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.CreateAccountTxParams) (db.Account, error) {
+					auditedRequestID = arg.Audit.RequestID
+					return account, nil
+				})
+
+			server, client := newTestServer(t, store)
+			accessToken, _, err := server.token.CreateToken(user.Username, util.CustomerRole, time.Minute, token.TokenTypeAccessToken)
+			require.NoError(t, err)
+			md := metadata.Pairs(authorizationHeader, fmt.Sprintf("%s %s", authorizationBearer, accessToken))
+			if test.requestID != "" {
+				md.Append(requestIDHeader, test.requestID)
+			}
+
+			var header metadata.MD
+			_, err = client.CreateAccount(
+				metadata.NewOutgoingContext(context.Background(), md),
+				&pb.CreateAccountRequest{Currency: account.Currency},
+				grpc.Header(&header),
+			)
+			require.NoError(t, err)
+
+			requestID := header.Get(requestIDHeader)
+			require.Len(t, requestID, 1)
+			require.Equal(t, requestID[0], auditedRequestID)
+			if test.generated {
+				_, err = uuid.Parse(requestID[0])
+				require.NoError(t, err)
+			} else {
+				require.Equal(t, test.requestID, requestID[0])
+			}
+		})
+	}
+}
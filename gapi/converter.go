@@ -0,0 +1,58 @@
+package gapi
+
+import (
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// convertUser - the user returned to the client (without the hashed password)
+func convertUser(user db.User) *pb.User {
+	return &pb.User{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
+		PasswordChangedAt: timestamppb.New(user.PasswordChangedAt),
+		CreatedAt:         timestamppb.New(user.CreatedAt),
+	}
+}
+
+// convertAccount - the account returned to the client, the held amount is the funds reserved by the active holds
+func convertAccount(account db.Account, held int64) *pb.Account {
+	return &pb.Account{
+		Id:               account.ID,
+		Owner:            account.Owner,
+		Balance:          account.Balance,
+		Currency:         account.Currency,
+		OverdraftLimit:   account.OverdraftLimit,
+		Status:           account.Status,
+		AvailableBalance: account.Balance - held,
+		CreatedAt:        timestamppb.New(account.CreatedAt),
+	}
+}
+
+// convertTransfer - the transfer returned to the client
+func convertTransfer(transfer db.Transfer) *pb.Transfer {
+	return &pb.Transfer{
+		Id:            transfer.ID,
+		FromAccountId: transfer.FromAccountID,
+		ToAccountId:   transfer.ToAccountID,
+		Amount:        transfer.Amount,
+		ToAmount:      transfer.ToAmount,
+		ExchangeRate:  transfer.ExchangeRate,
+		Reference:     transfer.Reference,
+		CreatedAt:     timestamppb.New(transfer.CreatedAt),
+	}
+}
+
+// convertEntry - the entry returned to the client
+func convertEntry(entry db.Entry) *pb.Entry {
+	return &pb.Entry{
+		Id:        entry.ID,
+		AccountId: entry.AccountID,
+		Amount:    entry.Amount,
+		CreatedAt: timestamppb.New(entry.CreatedAt),
+	}
+}
@@ -0,0 +1,65 @@
+package gapi
+
+import (
+	"errors"
+	"log"
+
+	"github.com/shimon-git/simple-bank/api"
+	"github.com/shimon-git/simple-bank/token"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain - the domain of the ErrorInfo details of the statuses
+const errorDomain = "simple-bank"
+
+/*
+* reasonError - the status of the given code with its stable reason in the ErrorInfo details
+* the reasons are the stable codes of the HTTP API (e.g. ACCOUNT_NOT_FOUND) - the clients switch on the reason, the message is for humans only
+ */
+func reasonError(code codes.Code, reason, message string) error {
+	st := status.New(code, message)
+	statusDetails, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain})
+	if err != nil {
+		return st.Err()
+	}
+	return statusDetails.Err()
+}
+
+// fieldViolation - a validation error of a field of the request
+func fieldViolation(field string, err error) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: err.Error(),
+	}
+}
+
+// invalidArgumentError - the InvalidArgument status of the request, with the invalid fields in its details
+func invalidArgumentError(violations []*errdetails.BadRequest_FieldViolation) error {
+	badRequest := &errdetails.BadRequest{FieldViolations: violations}
+	statusInvalid := status.New(codes.InvalidArgument, "invalid parameters")
+
+	statusDetails, err := statusInvalid.WithDetails(
+		badRequest,
+		&errdetails.ErrorInfo{Reason: api.CodeValidationFailed, Domain: errorDomain},
+	)
+	if err != nil {
+		return statusInvalid.Err()
+	}
+	return statusDetails.Err()
+}
+
+// internalError - the Internal status of a failed request - the cause is only logged, the raw error (e.g. of the database) never reaches the client
+func internalError(message string, err error) error {
+	log.Printf("%s: %v", message, err)
+	return status.Error(codes.Internal, message)
+}
+
+// tokenError - the Unauthenticated status of a token that couldn't be verified
+func tokenError(err error) error {
+	if errors.Is(err, token.ErrExpiredToken) {
+		return reasonError(codes.Unauthenticated, api.CodeTokenExpired, token.ErrExpiredToken.Error())
+	}
+	return reasonError(codes.Unauthenticated, api.CodeTokenInvalid, token.ErrInvalidToken.Error())
+}
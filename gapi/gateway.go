@@ -0,0 +1,219 @@
+package gapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/shimon-git/simple-bank/api"
+	"github.com/shimon-git/simple-bank/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// problemContentType - the content type of the error responses of the gateway (RFC 7807)
+const problemContentType = "application/problem+json"
+
+/*
+* NewGatewayHandler - the HTTP handler of the REST API, translated by grpc-gateway from the gRPC service definitions
+* the methods of the server are called in-process - through the same interceptors as the gRPC requests
+ */
+func (server *Server) NewGatewayHandler(ctx context.Context) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		// the json fields are named like the proto fields (snake case), like the rest of the HTTP API
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}),
+		runtime.WithIncomingHeaderMatcher(gatewayIncomingHeader),
+		runtime.WithOutgoingHeaderMatcher(gatewayOutgoingHeader),
+		runtime.WithErrorHandler(gatewayErrorHandler),
+	)
+
+	if err := pb.RegisterSimpleBankHandlerServer(ctx, mux, gatewayServer{server: server}); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+/*
+* gatewayIncomingHeader - the HTTP headers passed to the methods as metadata
+* only the known headers are passed - the client can't set any other metadata (e.g. x-forwarded-for)
+* the authorization header is always passed by the gateway
+ */
+func gatewayIncomingHeader(key string) (string, bool) {
+	switch key = strings.ToLower(key); key {
+	case requestIDHeader, idempotencyKeyHeader, mfaCodeHeader:
+		return key, true
+	case userAgentHeader:
+		return gatewayUserAgentHeader, true
+	}
+	return "", false
+}
+
+/*
+* gatewayOutgoingHeader - the metadata of the response written as HTTP headers
+* the request id isn't written - the X-Request-ID header is set by the HTTP server
+ */
+func gatewayOutgoingHeader(key string) (string, bool) {
+	switch key = strings.ToLower(key); key {
+	case idempotentReplayedHeader, retryAfterHeader:
+		return key, true
+	}
+	return "", false
+}
+
+/*
+* problemResponse - the problem details document of the error responses of the gateway (RFC 7807) - like the HTTP API
+* the code is the stable code of the HTTP API (the reason of the status), the field errors are the BadRequest details of the status
+ */
+type problemResponse struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail"`
+	Instance  string         `json:"instance"`
+	Code      string         `json:"code"`
+	Errors    []problemField `json:"errors,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+// problemField - a field of the request that failed the validation
+type problemField struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// internalErrorMessage - the detail of the internal errors - the message of the status (e.g. a database error) is never written
+const internalErrorMessage = "the request couldn't be completed, try again later"
+
+// statusCodes - the stable codes of the statuses without a reason (e.g. the errors of the gateway itself)
+var statusCodes = map[codes.Code]string{
+	codes.InvalidArgument:  api.CodeInvalidRequest,
+	codes.Unauthenticated:  api.CodeUnauthenticated,
+	codes.PermissionDenied: api.CodeForbidden,
+	codes.NotFound:         api.CodeNotFound,
+	codes.AlreadyExists:    api.CodeConflict,
+	codes.Aborted:          api.CodeConcurrentUpdate,
+}
+
+/*
+* gatewayErrorHandler - writing the status of a failed call as a problem details document (application/problem+json)
+* the code is the reason of the status (its ErrorInfo details) - the statuses without a reason are mapped by their gRPC code
+* the server errors (e.g. Internal, Unknown) get a generic detail and the INTERNAL code
+ */
+func gatewayErrorHandler(ctx context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+	httpStatus := runtime.HTTPStatusFromCode(st.Code())
+
+	problem := problemResponse{
+		Type:      "about:blank",
+		Title:     http.StatusText(httpStatus),
+		Status:    httpStatus,
+		Detail:    st.Message(),
+		Instance:  r.URL.Path,
+		Code:      statusCodes[st.Code()],
+		RequestID: r.Header.Get(requestIDHeader),
+	}
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			problem.Code = detail.GetReason()
+		case *errdetails.BadRequest:
+			for _, violation := range detail.GetFieldViolations() {
+				problem.Errors = append(problem.Errors, problemField{
+					Field:   violation.GetField(),
+					Message: violation.GetDescription(),
+				})
+			}
+		}
+	}
+	if httpStatus >= http.StatusInternalServerError {
+		problem.Code = api.CodeInternal
+		problem.Detail = internalErrorMessage
+		problem.Errors = nil
+	} else if problem.Code == "" {
+		problem.Code = api.CodeInvalidRequest
+	}
+
+	// the headers of the response (e.g. retry-after) are written like the headers of a successful call
+	if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+		for key, values := range md.HeaderMD {
+			if name, ok := gatewayOutgoingHeader(key); ok {
+				for _, value := range values {
+					w.Header().Add(name, value)
+				}
+			}
+		}
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(httpStatus)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+// gatewayServer - the server called by the gateway, every method is called through the interceptors of the server
+type gatewayServer struct {
+	pb.UnimplementedSimpleBankServer
+	server *Server
+}
+
+func (gateway gatewayServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	return intercept(ctx, gateway.server, pb.SimpleBank_CreateUser_FullMethodName, req, gateway.server.CreateUser)
+}
+
+func (gateway gatewayServer) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	return intercept(ctx, gateway.server, pb.SimpleBank_LoginUser_FullMethodName, req, gateway.server.LoginUser)
+}
+
+func (gateway gatewayServer) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	return intercept(ctx, gateway.server, pb.SimpleBank_CreateAccount_FullMethodName, req, gateway.server.CreateAccount)
+}
+
+func (gateway gatewayServer) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	return intercept(ctx, gateway.server, pb.SimpleBank_GetAccount_FullMethodName, req, gateway.server.GetAccount)
+}
+
+func (gateway gatewayServer) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	return intercept(ctx, gateway.server, pb.SimpleBank_ListAccounts_FullMethodName, req, gateway.server.ListAccounts)
+}
+
+func (gateway gatewayServer) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	return intercept(ctx, gateway.server, pb.SimpleBank_CreateTransfer_FullMethodName, req, gateway.server.CreateTransfer)
+}
+
+// intercept - calling the method through the interceptors of the server, in the order of the gRPC server
+func intercept[Req, Rsp any](
+	ctx context.Context,
+	server *Server,
+	fullMethod string,
+	req Req,
+	method func(context.Context, Req) (Rsp, error),
+) (Rsp, error) {
+	info := &grpc.UnaryServerInfo{Server: server, FullMethod: fullMethod}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return method(ctx, req.(Req))
+	}
+	for i := len(server.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := server.interceptors[i], handler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+
+	rsp, err := handler(ctx, req)
+	if err != nil {
+		var empty Rsp
+		return empty, err
+	}
+	return rsp.(Rsp), nil
+}
@@ -0,0 +1,243 @@
+package gapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shimon-git/simple-bank/api"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGateway(t *testing.T) {
+	user, password := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		body          interface{}
+		setupRequest  func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "GetAccount",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/v1/accounts/%d", account.ID),
+			setupRequest: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetAccountHeldAmount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				// the fields are named like the proto fields - like the rest of the HTTP API
+				var rsp struct {
+					Account map[string]interface{} `json:"account"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, account.Owner, rsp.Account["owner"])
+				require.Equal(t, account.Currency, rsp.Account["currency"])
+				require.Contains(t, rsp.Account, "available_balance")
+			},
+		},
+		{
+			name:   "NoAuthorization",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/v1/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				problem := requireProblem(t, recorder)
+				require.Equal(t, api.CodeUnauthenticated, problem.Code)
+			},
+		},
+		{
+			name:   "AccountNotFound",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/v1/accounts/%d", account.ID),
+			setupRequest: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, db.ErrNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				// the code is the stable code of the HTTP API - not the name of the gRPC code
+				problem := requireProblem(t, recorder)
+				require.Equal(t, api.CodeAccountNotFound, problem.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/v1/accounts/%d", account.ID),
+			setupRequest: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				problem := requireProblem(t, recorder)
+				require.Equal(t, api.CodeInternal, problem.Code)
+				require.Equal(t, internalErrorMessage, problem.Detail)
+				require.NotContains(t, recorder.Body.String(), sql.ErrConnDone.Error())
+			},
+		},
+		{
+			name:   "InvalidID",
+			method: http.MethodGet,
+			path:   "/v1/accounts/0",
+			setupRequest: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				problem := requireProblem(t, recorder)
+				require.Equal(t, api.CodeValidationFailed, problem.Code)
+				require.Len(t, problem.Errors, 1)
+				require.Equal(t, "id", problem.Errors[0].Field)
+			},
+		},
+		{
+			name:   "ThrottledLogin",
+			method: http.MethodPost,
+			path:   "/v1/users/login",
+			body:   map[string]string{"username": user.Username, "password": password},
+			setupRequest: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				// the client can't choose the throttled ip - only the remote address is used
+				request.Header.Set("X-Forwarded-For", "10.0.0.1")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ThrottleAttemptsTxParams) (db.ThrottleAttemptsTxResult, error) {
+						require.Len(t, arg.Attempts, 2)
+						require.Equal(t, db.UserThrottleKey(user.Username), arg.Attempts[0].Key)
+						require.Equal(t, db.IPThrottleKey("192.0.2.1"), arg.Attempts[1].Key)
+						return db.ThrottleAttemptsTxResult{LockedUntil: time.Now().Add(90 * time.Second)}, db.ErrThrottled
+					})
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "90", recorder.Header().Get("Retry-After"))
+				problem := requireProblem(t, recorder)
+				require.Equal(t, api.CodeLoginThrottled, problem.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, _ := newTestServer(t, store)
+			gateway, err := server.NewGatewayHandler(context.Background())
+			require.NoError(t, err)
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				err = json.NewEncoder(&body).Encode(tc.body)
+				require.NoError(t, err)
+			}
+			// the remote address of the test requests is 192.0.2.1:1234
+			request := httptest.NewRequest(tc.method, tc.path, &body)
+			request.Header.Set("X-Request-ID", testRequestID)
+			if tc.setupRequest != nil {
+				tc.setupRequest(t, request, server.token)
+			}
+
+			recorder := httptest.NewRecorder()
+			gateway.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGatewayErrorHandlerUnknown(t *testing.T) {
+	// the message of an unknown error (e.g. a raw error returned by a method) is never written
+	err := status.Error(codes.Unknown, "pq: relation \"users\" does not exist")
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+	request.Header.Set("X-Request-ID", testRequestID)
+	recorder := httptest.NewRecorder()
+	gatewayErrorHandler(context.Background(), nil, nil, recorder, request, err)
+
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	problem := requireProblem(t, recorder)
+	require.Equal(t, api.CodeInternal, problem.Code)
+	require.Equal(t, internalErrorMessage, problem.Detail)
+	require.NotContains(t, recorder.Body.String(), "pq:")
+}
+
+// addAuthorization - adding the authorization header of a new access token of the customer
+func addAuthorization(t *testing.T, request *http.Request, tokenMaker token.Maker, username string) {
+	accessToken, _, err := tokenMaker.CreateToken(username, util.CustomerRole, time.Minute, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+	request.Header.Set("Authorization", fmt.Sprintf("%s %s", authorizationBearer, accessToken))
+}
+
+// requireProblem - checking the response is a problem details document of the request, returning the document
+func requireProblem(t *testing.T, recorder *httptest.ResponseRecorder) problemResponse {
+	require.Equal(t, problemContentType, recorder.Header().Get("Content-Type"))
+
+	var problem problemResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &problem)
+	require.NoError(t, err)
+	require.Equal(t, recorder.Code, problem.Status)
+	require.Equal(t, testRequestID, problem.RequestID)
+	require.NotEmpty(t, problem.Detail)
+	return problem
+}
@@ -0,0 +1,86 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/shimon-git/simple-bank/api"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/rules"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	retryAfterHeader = "retry-after"
+	// incorrectCredentialsErr - returned for an unknown user and for an incorrect password alike, so the usernames can't be enumerated
+	incorrectCredentialsErr = "incorrect username or password"
+	loginThrottledErr       = "too many failed login attempts, try again later"
+)
+
+// loginAttempt - the login attempt of the username by the client of the request
+func loginAttempt(ctx context.Context, username string, mtdt Metadata) rules.LoginAttempt {
+	return rules.LoginAttempt{
+		Username:  username,
+		ClientIP:  mtdt.ClientIP,
+		UserAgent: mtdt.UserAgent,
+		Audit:     auditParams(ctx, username),
+	}
+}
+
+/*
+* countLoginAttempt - counting the login attempt of the username and the client ip, before the password (or the second factor) is verified
+* a throttled attempt is rejected - code ResourceExhausted, the retry-after header (and the RetryInfo of the status) tells the client when to try again
+* returns the throttle of the username
+ */
+func (server *Server) countLoginAttempt(ctx context.Context, username string, mtdt Metadata) (db.LoginThrottle, error) {
+	throttle, err := rules.CountLoginAttempt(ctx, server.store, server.config, loginAttempt(ctx, username, mtdt))
+	if err != nil {
+		return db.LoginThrottle{}, loginAttemptError(ctx, err)
+	}
+	return throttle, nil
+}
+
+// loginAttemptError - the status of a login attempt that couldn't be made
+func loginAttemptError(ctx context.Context, err error) error {
+	var throttled *rules.ThrottledError
+	if errors.As(err, &throttled) {
+		return throttledError(ctx, throttled.LockedUntil)
+	}
+	return internalError("failed to verify the login attempt", err)
+}
+
+// throttledError - the status of a throttled login, with the retry-after header
+func throttledError(ctx context.Context, lockedUntil time.Time) error {
+	seconds := math.Ceil(time.Until(lockedUntil).Seconds())
+	_ = grpc.SetHeader(ctx, metadata.Pairs(retryAfterHeader, strconv.Itoa(int(seconds))))
+
+	statusThrottled := status.New(codes.ResourceExhausted, loginThrottledErr)
+	statusDetails, err := statusThrottled.WithDetails(
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Duration(seconds) * time.Second)},
+		&errdetails.ErrorInfo{Reason: api.CodeLoginThrottled, Domain: errorDomain},
+	)
+	if err != nil {
+		return statusThrottled.Err()
+	}
+	return statusDetails.Err()
+}
+
+/*
+* recordFailedLogin - recording the failed login attempt in the audit trail - with the lockout of the username when the attempt locked it
+* throttle: the throttle of the username after the attempt was counted
+ */
+func (server *Server) recordFailedLogin(ctx context.Context, username string, throttle db.LoginThrottle, reason string, mtdt Metadata) error {
+	err := rules.RecordFailedLogin(ctx, server.store, server.config, loginAttempt(ctx, username, mtdt), throttle, reason)
+	if err != nil {
+		return internalError("failed to record the failed login", err)
+	}
+	return nil
+}
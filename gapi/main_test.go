@@ -0,0 +1,146 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testRequestID - the x-request-id of the test requests whose audit params are checked
+const testRequestID = "test-request-id"
+
+// newTestServer - creating a new gRPC test server, served over an in-memory connection
+func newTestServer(t *testing.T, store db.Store) (*Server, pb.SimpleBankClient) {
+	// the logins of the tests are not throttled - unless the test expects otherwise before creating the server
+	// every key has a single attempt
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().
+			ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			DoAndReturn(func(_ context.Context, arg db.ThrottleAttemptsTxParams) (db.ThrottleAttemptsTxResult, error) {
+				var result db.ThrottleAttemptsTxResult
+				for _, attempt := range arg.Attempts {
+					result.Throttles = append(result.Throttles, db.LoginThrottle{Key: attempt.Key, FailedAttempts: 1})
+				}
+				return result, nil
+			})
+		mockStore.EXPECT().
+			ForgetLoginAttemptTx(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(nil)
+	}
+
+	return newStrictTestServer(t, store)
+}
+
+// newStrictTestServer - a gRPC test server without the default login throttle stubs - the test expects every counted attempt
+func newStrictTestServer(t *testing.T, store db.Store) (*Server, pb.SimpleBankClient) {
+	config := util.Config{
+		TokenSymmetricKey:    util.RandomString(32),
+		TokenType:            util.RandomTokenType(),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		RevocationCacheSize:  100,
+		RevocationCacheTTL:   time.Minute,
+		VerifyEmailDuration:  time.Minute,
+		MFATokenDuration:     time.Minute,
+		LoginBackoffAfter:    3,
+		LoginBackoff:         time.Second,
+		LoginMaxAttempts:     10,
+		LoginIPMaxAttempts:   100,
+		LoginLockoutDuration: time.Minute,
+		LoginAttemptWindow:   time.Minute,
+	}
+
+	// the tokens of the tests are not revoked - unless the test expects otherwise before creating the server
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().
+			IsTokenRevoked(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(false, nil)
+	}
+
+	revocations := token.NewRevocationList(db.NewTokenRevocations(store), config.RevocationCacheSize, config.RevocationCacheTTL)
+	server, err := NewServer(config, store, revocations)
+	require.NoError(t, err)
+
+	// serving the server over an in-memory listener
+	listener := bufconn.Listen(1024 * 1024)
+	go server.grpc.Serve(listener)
+	t.Cleanup(server.grpc.Stop)
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return server, pb.NewSimpleBankClient(conn)
+}
+
+// newContextWithBearerToken - the context of a request authorized by a new access token of the given user + role
+func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username, role string, duration time.Duration) context.Context {
+	accessToken, _, err := tokenMaker.CreateToken(username, role, duration, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	md := metadata.Pairs(
+		authorizationHeader, fmt.Sprintf("%s %s", authorizationBearer, accessToken),
+		requestIDHeader, testRequestID,
+	)
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+// requireStatusCode - checking the error of the call is a status with the given code
+func requireStatusCode(t *testing.T, err error, code codes.Code) {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, code, st.Code(), st.Message())
+}
+
+// randomUser - returning a random user + its password
+func randomUser(t *testing.T) (user db.User, password string) {
+	password = util.RandomString(6)
+	passwordHash, err := util.HashedPassword(password)
+	require.NoError(t, err)
+
+	user = db.User{
+		Username:       util.RandomString(6),
+		HashedPassword: passwordHash,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+		Role:           util.CustomerRole,
+	}
+	return user, password
+}
+
+// randomAccount - returning a random account of the owner
+func randomAccount(owner string) db.Account {
+	return db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    owner,
+		Balance:  util.RandomMoney(),
+		Currency: util.RandomCurrency(),
+		Status:   db.AccountActive,
+	}
+}
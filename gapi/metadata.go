@@ -0,0 +1,124 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// the metadata keys read and written by the server - the gRPC metadata keys are lowercase
+const (
+	authorizationHeader      = "authorization"
+	authorizationBearer      = "bearer"
+	requestIDHeader          = "x-request-id"
+	userAgentHeader          = "user-agent"
+	gatewayUserAgentHeader   = "grpcgateway-user-agent"
+	xForwardedForHeader      = "x-forwarded-for"
+	mfaCodeHeader            = "x-mfa-code"
+	idempotencyKeyHeader     = "idempotency-key"
+	idempotentReplayedHeader = "idempotent-replayed"
+	maxRequestIDLength       = 128
+)
+
+// requestIDKey - the context key of the request id
+type requestIDKey struct{}
+
+// Metadata - the details of the client of the request
+type Metadata struct {
+	UserAgent string
+	ClientIP  string
+}
+
+// metadataValue - the first value of the given metadata key of the request (empty when missing)
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+/*
+* extractMetadata - the user agent of the request and the ip address of the client
+* a request of the gateway has no peer - the gateway adds the remote address of the HTTP request to x-forwarded-for
+ */
+func extractMetadata(ctx context.Context) Metadata {
+	mtdt := Metadata{UserAgent: metadataValue(ctx, userAgentHeader)}
+	if userAgent := metadataValue(ctx, gatewayUserAgentHeader); userAgent != "" {
+		mtdt.UserAgent = userAgent
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		mtdt.ClientIP = clientIP(p)
+	} else if md, ok := metadata.FromIncomingContext(ctx); ok {
+		// only the last address is added by the gateway - the ones before it are sent by the client
+		if forwardedFor := md.Get(xForwardedForHeader); len(forwardedFor) > 0 {
+			addresses := strings.Split(forwardedFor[len(forwardedFor)-1], ",")
+			mtdt.ClientIP = strings.TrimSpace(addresses[len(addresses)-1])
+		}
+	}
+	return mtdt
+}
+
+// clientIP - the ip address of the peer (without the port)
+func clientIP(p *peer.Peer) string {
+	if p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+/*
+* requestIDInterceptor - an interceptor that identifies every request - the id is recorded with the audit events of the request
+* the x-request-id metadata of the client is kept when valid, otherwise a new id is generated
+* the id is returned in the x-request-id header of the response
+ */
+func requestIDInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	requestID := metadataValue(ctx, requestIDHeader)
+	if !validRequestID(requestID) {
+		requestID = uuid.NewString()
+	}
+	// the header is best effort - the request is served even when it can't be sent
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, requestID))
+	return handler(context.WithValue(ctx, requestIDKey{}, requestID), req)
+}
+
+// validRequestID - checking the request id is not empty, not too long and made of printable ascii characters only
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// auditParams - the audit params of the request made by the given actor
+func auditParams(ctx context.Context, actor string) db.AuditParams {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return db.AuditParams{
+		Actor:     actor,
+		RequestID: requestID,
+	}
+}
@@ -0,0 +1,52 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shimon-git/simple-bank/api"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+// CreateAccount - creating a new bank account of the user
+func (server *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	if violations := validateCreateAccountRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	payload, err := authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// inserting the account (+ the audit trail)
+	// a user can hold a single account of every currency - on a duplicate return code AlreadyExists
+	account, err := server.store.CreateAccountTx(ctx, db.CreateAccountTxParams{
+		CreateAccountParams: db.CreateAccountParams{
+			Owner:    payload.Username,
+			Currency: req.GetCurrency(),
+			Balance:  0,
+		},
+		Audit: auditParams(ctx, payload.Username),
+	})
+	if err != nil {
+		if errors.Is(err, &db.ErrDuplicate{}) || errors.Is(err, &db.ErrForeignKey{}) {
+			return nil, reasonError(codes.AlreadyExists, api.CodeAccountAlreadyExists, "the user already has an account in this currency")
+		}
+		return nil, internalError("failed to create the account", err)
+	}
+
+	// a new account has no holds
+	return &pb.CreateAccountResponse{Account: convertAccount(account, 0)}, nil
+}
+
+// validateCreateAccountRequest - the invalid fields of the request (nil when the request is valid)
+func validateCreateAccountRequest(req *pb.CreateAccountRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateCurrency(req.GetCurrency()); err != nil {
+		violations = append(violations, fieldViolation("currency", err))
+	}
+	return violations
+}
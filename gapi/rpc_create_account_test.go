@@ -0,0 +1,111 @@
+package gapi
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCreateAccountRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	account.Balance = 0
+
+	testCases := []struct {
+		name          string
+		currency      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.CreateAccountResponse, err error)
+	}{
+		{
+			name:     "OK",
+			currency: account.Currency,
+			buildStubs: func(store *mockdb.MockStore) {
+				// the account is created together with its audit event
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Eq(db.CreateAccountTxParams{
+						CreateAccountParams: db.CreateAccountParams{
+							Owner:    user.Username,
+							Currency: account.Currency,
+							Balance:  0,
+						},
+						Audit: db.AuditParams{
+							Actor:     user.Username,
+							RequestID: testRequestID,
+						},
+					})).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateAccountResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account.ID, rsp.GetAccount().GetId())
+				require.Equal(t, account.Currency, rsp.GetAccount().GetCurrency())
+				require.Zero(t, rsp.GetAccount().GetAvailableBalance())
+			},
+		},
+		{
+			name:     "DuplicateCurrency",
+			currency: account.Currency,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, fmt.Errorf("transaction error: %w", &db.ErrDuplicate{Constraint: "owner_currency_key"}))
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateAccountResponse, err error) {
+				requireStatusCode(t, err, codes.AlreadyExists)
+			},
+		},
+		{
+			name:     "InvalidCurrency",
+			currency: "invalid",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateAccountResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name:     "InternalError",
+			currency: account.Currency,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateAccountResponse, err error) {
+				requireStatusCode(t, err, codes.Internal)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, client := newTestServer(t, store)
+			ctx := newContextWithBearerToken(t, server.token, user.Username, util.CustomerRole, time.Minute)
+			rsp, err := client.CreateAccount(ctx, &pb.CreateAccountRequest{Currency: tc.currency})
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
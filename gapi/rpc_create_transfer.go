@@ -0,0 +1,158 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shimon-git/simple-bank/api"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/rules"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const maxIdempotencyKeyLength = 255
+
+/*
+* CreateTransfer - transferring money from an account of the user to another account of the same currency
+* a retry with the same idempotency key (the idempotency-key metadata) returns the original transfer
+* a transfer above the configured threshold demands a fresh second factor (the x-mfa-code metadata)
+ */
+func (server *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	if violations := validateCreateTransferRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	payload, err := authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// validating the idempotency key - must be checked before touching any account
+	idempotencyKey := metadataValue(ctx, idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return nil, status.Error(codes.InvalidArgument, "the idempotency key is too long")
+	}
+
+	// validating the from account - only the owner can move its money
+	fromAccount, err := server.validAccount(ctx, req.GetFromAccountId(), req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+	if fromAccount.Owner != payload.Username {
+		return nil, reasonError(codes.PermissionDenied, api.CodeTransferAccessDenied, "you are not authorized to make the transfer")
+	}
+
+	// blocking the transfers of the users until their email is verified
+	if err = rules.RequireVerifiedEmail(ctx, server.store, server.config, payload.Username); err != nil {
+		if errors.Is(err, rules.ErrEmailNotVerified) {
+			return nil, reasonError(codes.PermissionDenied, api.CodeEmailNotVerified, err.Error())
+		}
+		return nil, internalError("failed to check the email of the user", err)
+	}
+
+	// a large transfer demands a fresh second factor
+	if err = server.requireFreshMFA(ctx, payload.Username, req.GetAmount()); err != nil {
+		return nil, err
+	}
+
+	if _, err = server.validAccount(ctx, req.GetToAccountId(), req.GetCurrency()); err != nil {
+		return nil, err
+	}
+
+	arg := db.TransferTxParams{
+		FromAccountID: req.GetFromAccountId(),
+		ToAccountID:   req.GetToAccountId(),
+		Amount:        req.GetAmount(),
+		Audit:         auditParams(ctx, payload.Username),
+	}
+	if idempotencyKey != "" {
+		requestHash, err := rules.HashTransferRequest(rules.TransferRequest{
+			FromAccountID: req.GetFromAccountId(),
+			ToAccountID:   req.GetToAccountId(),
+			Amount:        req.GetAmount(),
+			Currency:      req.GetCurrency(),
+		})
+		if err != nil {
+			return nil, internalError("failed to hash the request", err)
+		}
+		arg.Idempotency = db.IdempotencyParams{
+			Username:    payload.Username,
+			Key:         idempotencyKey,
+			RequestHash: requestHash,
+		}
+	}
+
+	result, err := server.store.TransferTx(ctx, arg)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrInsufficientFunds):
+			return nil, reasonError(codes.FailedPrecondition, api.CodeInsufficientFunds, db.ErrInsufficientFunds.Error())
+		case errors.Is(err, db.ErrAccountInactive):
+			return nil, reasonError(codes.FailedPrecondition, api.CodeAccountInactive, db.ErrAccountInactive.Error())
+		case errors.Is(err, db.ErrIdempotencyKeyMismatch):
+			return nil, reasonError(codes.InvalidArgument, api.CodeIdempotencyKeyMismatch, "the idempotency key was already used with a different request")
+		}
+		return nil, internalError("failed to transfer the money", err)
+	}
+
+	// letting the client know the response is a replay of a previous request
+	if result.Replayed {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(idempotentReplayedHeader, "true"))
+	}
+
+	fromAccountRsp, err := server.accountResponse(ctx, result.FromAccount)
+	if err != nil {
+		return nil, err
+	}
+	toAccountRsp, err := server.accountResponse(ctx, result.ToAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.CreateTransferResponse{
+		Transfer:    convertTransfer(result.Transfer),
+		FromAccount: fromAccountRsp,
+		ToAccount:   toAccountRsp,
+		FromEntry:   convertEntry(result.FromEntry),
+		ToEntry:     convertEntry(result.ToEntry),
+		Replayed:    result.Replayed,
+	}, nil
+}
+
+// validAccount - getting the account of the given id and checking its currency
+func (server *Server) validAccount(ctx context.Context, accountID int64, currency string) (db.Account, error) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return account, reasonError(codes.NotFound, api.CodeAccountNotFound, fmt.Sprintf("account [%d] not found", accountID))
+		}
+		return account, internalError("failed to get the account", err)
+	}
+	if account.Currency != currency {
+		return account, reasonError(codes.InvalidArgument, api.CodeCurrencyMismatch, fmt.Sprintf("account [%d] currency mismatch: given currency is %s but expected currency is %s", accountID, currency, account.Currency))
+	}
+	return account, nil
+}
+
+// validateCreateTransferRequest - the invalid fields of the request (nil when the request is valid)
+func validateCreateTransferRequest(req *pb.CreateTransferRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateID(req.GetFromAccountId()); err != nil {
+		violations = append(violations, fieldViolation("from_account_id", err))
+	}
+	if err := validateID(req.GetToAccountId()); err != nil {
+		violations = append(violations, fieldViolation("to_account_id", err))
+	}
+	if req.GetAmount() <= 0 {
+		violations = append(violations, fieldViolation("amount", errors.New("must be positive")))
+	}
+	if err := validateCurrency(req.GetCurrency()); err != nil {
+		violations = append(violations, fieldViolation("currency", err))
+	}
+	return violations
+}
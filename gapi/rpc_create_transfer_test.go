@@ -0,0 +1,391 @@
+package gapi
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/totp"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestCreateTransferRPC(t *testing.T) {
+	amount := int64(10)
+
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account2.Currency = account1.Currency
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	userTotp := db.UserTotp{
+		Username:    user1.Username,
+		Secret:      secret,
+		ConfirmedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	transferResult := db.TransferTxResult{
+		Transfer: db.Transfer{
+			ID:            util.RandomInt(1, 1000),
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+			ToAmount:      amount,
+			ExchangeRate:  1,
+		},
+		FromAccount: account1,
+		ToAccount:   account2,
+		FromEntry:   db.Entry{AccountID: account1.ID, Amount: -amount},
+		ToEntry:     db.Entry{AccountID: account2.ID, Amount: amount},
+	}
+
+	testCases := []struct {
+		name           string
+		req            *pb.CreateTransferRequest
+		username       string
+		idempotencyKey string
+		mfaCode        string
+		setupServer    func(server *Server)
+		buildStubs     func(store *mockdb.MockStore)
+		checkResponse  func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error)
+	}{
+		{
+			name: "OK",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      account1.Currency,
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
+						FromAccountID: account1.ID,
+						ToAccountID:   account2.ID,
+						Amount:        amount,
+						Audit: db.AuditParams{
+							Actor:     user1.Username,
+							RequestID: testRequestID,
+						},
+					})).
+					Times(1).
+					Return(transferResult, nil)
+				store.EXPECT().GetAccountHeldAmount(gomock.Any(), gomock.Any()).Times(2).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				require.NoError(t, err)
+				require.Equal(t, transferResult.Transfer.ID, rsp.GetTransfer().GetId())
+				require.Equal(t, account1.ID, rsp.GetFromAccount().GetId())
+				require.Equal(t, account2.ID, rsp.GetToAccount().GetId())
+				require.Equal(t, -amount, rsp.GetFromEntry().GetAmount())
+				require.Equal(t, amount, rsp.GetToEntry().GetAmount())
+				require.False(t, rsp.GetReplayed())
+				require.Empty(t, header.Get(idempotentReplayedHeader))
+			},
+		},
+		{
+			name: "IdempotentReplay",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      account1.Currency,
+			},
+			username:       user1.Username,
+			idempotencyKey: "transfer-1",
+			buildStubs: func(store *mockdb.MockStore) {
+				// the request is hashed like the HTTP request - a retry through either API is recognized
+				body := fmt.Sprintf(`{"from_account_id":%d,"to_account_id":%d,"amount":%d,"currency":"%s"}`,
+					account1.ID, account2.ID, amount, account1.Currency)
+				hash := sha256.Sum256([]byte(body))
+
+				replayed := transferResult
+				replayed.Replayed = true
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
+						FromAccountID: account1.ID,
+						ToAccountID:   account2.ID,
+						Amount:        amount,
+						Idempotency: db.IdempotencyParams{
+							Username:    user1.Username,
+							Key:         "transfer-1",
+							RequestHash: hex.EncodeToString(hash[:]),
+						},
+						Audit: db.AuditParams{
+							Actor:     user1.Username,
+							RequestID: testRequestID,
+						},
+					})).
+					Times(1).
+					Return(replayed, nil)
+				store.EXPECT().GetAccountHeldAmount(gomock.Any(), gomock.Any()).Times(2).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				require.NoError(t, err)
+				require.True(t, rsp.GetReplayed())
+				require.Equal(t, []string{"true"}, header.Get(idempotentReplayedHeader))
+			},
+		},
+		{
+			name: "IdempotencyKeyMismatch",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      account1.Currency,
+			},
+			username:       user1.Username,
+			idempotencyKey: "transfer-1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrIdempotencyKeyMismatch)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      account1.Currency,
+			},
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.PermissionDenied)
+			},
+		},
+		{
+			name: "FromAccountNotFound",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      account1.Currency,
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(db.Account{}, db.ErrNotFound)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.NotFound)
+			},
+		},
+		{
+			name: "ToAccountCurrencyMismatch",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      account1.Currency,
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				otherCurrency := account2
+				otherCurrency.Currency = util.USD
+				if account1.Currency == util.USD {
+					otherCurrency.Currency = util.EUR
+				}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(otherCurrency, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      account1.Currency,
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("transfer error: %w", db.ErrInsufficientFunds))
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "EmailNotVerified",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      account1.Currency,
+			},
+			username: user1.Username,
+			setupServer: func(server *Server) {
+				server.config.RequireVerifiedEmail = true
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(user1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.PermissionDenied)
+			},
+		},
+		{
+			name: "MissingMFACode",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      account1.Currency,
+			},
+			username: user1.Username,
+			setupServer: func(server *Server) {
+				// the transfer is above the threshold
+				server.config.MFATransferThreshold = amount - 1
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+		{
+			name: "IncorrectMFACode",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      account1.Currency,
+			},
+			username: user1.Username,
+			mfaCode:  "000000",
+			setupServer: func(server *Server) {
+				server.config.MFATransferThreshold = amount - 1
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(userTotp, nil)
+				// a wrong code is counted like a failed login
+				store.EXPECT().
+					RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RecordFailedLoginTxParams) (db.RecordFailedLoginTxResult, error) {
+						require.Equal(t, user1.Username, arg.Username)
+						return db.RecordFailedLoginTxResult{}, nil
+					})
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+		{
+			name: "MFAThrottled",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      account1.Currency,
+			},
+			username: user1.Username,
+			mfaCode:  "000000",
+			setupServer: func(server *Server) {
+				server.config.MFATransferThreshold = amount - 1
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(userTotp, nil)
+				// the code can't be guessed while the user is locked out
+				store.EXPECT().ThrottleAttemptsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ThrottleAttemptsTxResult{LockedUntil: time.Now().Add(time.Minute)}, db.ErrThrottled)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.ResourceExhausted)
+			},
+		},
+		{
+			name: "InvalidAmount",
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        -amount,
+				Currency:      account1.Currency,
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, client := newTestServer(t, store)
+			if tc.setupServer != nil {
+				tc.setupServer(server)
+			}
+
+			ctx := newContextWithBearerToken(t, server.token, tc.username, util.CustomerRole, time.Minute)
+			if tc.idempotencyKey != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, idempotencyKeyHeader, tc.idempotencyKey)
+			}
+			if tc.mfaCode != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, mfaCodeHeader, tc.mfaCode)
+			}
+
+			var header metadata.MD
+			rsp, err := client.CreateTransfer(ctx, tc.req, grpc.Header(&header))
+			tc.checkResponse(t, rsp, header, err)
+		})
+	}
+}
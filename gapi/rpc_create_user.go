@@ -0,0 +1,84 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/shimon-git/simple-bank/api"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/mail"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+// the length of the secret code of the verify emails
+const verifyEmailCodeLength = 32
+
+// CreateUser - creating a new user, the verify email is sent to the email of the user
+func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	if violations := validateCreateUserRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	hashedPassword, err := util.HashedPassword(req.GetPassword())
+	if err != nil {
+		return nil, internalError("failed to hash the password", err)
+	}
+
+	// the one-time code of the verify email - only its hash is stored
+	code, err := util.RandomSecret(verifyEmailCodeLength)
+	if err != nil {
+		return nil, internalError("failed to generate the verify email code", err)
+	}
+
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.GetUsername(),
+			HashedPassword: hashedPassword,
+			FullName:       req.GetFullName(),
+			Email:          req.GetEmail(),
+		},
+		SecretCodeHash:      util.HashSecret(code),
+		VerifyEmailDuration: server.config.VerifyEmailDuration,
+	}
+
+	// inserting the user + its verify email into the DB
+	// if the username or the email is taken return code AlreadyExists
+	result, err := server.store.CreateUserTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, &db.ErrDuplicate{}) {
+			return nil, reasonError(codes.AlreadyExists, api.CodeUserAlreadyExists, "the username or the email is already taken")
+		}
+		return nil, internalError("failed to create the user", err)
+	}
+
+	// sending the verify email once the user is committed - the user exists even when the email can't be sent
+	// so the failure is only logged
+	link := mail.VerifyEmailLink(server.config.VerifyEmailURL, result.VerifyEmail.ID, code)
+	content := mail.VerifyEmailContent(result.User.FullName, link)
+	if err = server.mailer.SendEmail(mail.VerifyEmailSubject, content, []string{result.VerifyEmail.Email}); err != nil {
+		log.Printf("cannot send the verify email of %s: %v", result.User.Username, err)
+	}
+
+	return &pb.CreateUserResponse{User: convertUser(result.User)}, nil
+}
+
+// validateCreateUserRequest - the invalid fields of the request (nil when the request is valid)
+func validateCreateUserRequest(req *pb.CreateUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateUsername(req.GetUsername()); err != nil {
+		violations = append(violations, fieldViolation("username", err))
+	}
+	if err := validatePassword(req.GetPassword()); err != nil {
+		violations = append(violations, fieldViolation("password", err))
+	}
+	if err := validateFullName(req.GetFullName()); err != nil {
+		violations = append(violations, fieldViolation("full_name", err))
+	}
+	if err := validateEmail(req.GetEmail()); err != nil {
+		violations = append(violations, fieldViolation("email", err))
+	}
+	return violations
+}
@@ -0,0 +1,225 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shimon-git/simple-bank/api"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/mail"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+* eqCreateUserTxParamsMatcher - comparing the params of the new user
+* the hash of the password (and the secret code of the verify email) is random - the password is checked against the hash
+ */
+type eqCreateUserTxParamsMatcher struct {
+	arg      db.CreateUserParams
+	password string
+}
+
+// Matches - return true if the there is a match otherwise return false
+func (e eqCreateUserTxParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateUserTxParams)
+	if !ok {
+		return false
+	}
+	if err := util.CheckPassword(e.password, arg.HashedPassword); err != nil {
+		return false
+	}
+	if arg.SecretCodeHash == "" {
+		return false
+	}
+
+	e.arg.HashedPassword = arg.HashedPassword
+	return e.arg == arg.CreateUserParams
+}
+
+// String - return a string log
+func (e eqCreateUserTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v and password %v", e.arg, e.password)
+}
+
+func TestCreateUserRPC(t *testing.T) {
+	user, password := randomUser(t)
+	verifyEmail := db.VerifyEmail{
+		ID:       util.RandomInt(1, 1000),
+		Username: user.Username,
+		Email:    user.Email,
+	}
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateUserRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.CreateUserResponse, err error)
+		checkEmails   func(t *testing.T, emails []mail.Email)
+	}{
+		{
+			name: "OK",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateUserParams{
+					Username: user.Username,
+					FullName: user.FullName,
+					Email:    user.Email,
+				}
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), eqCreateUserTxParamsMatcher{arg, password}).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						// the verify email is sent once the transaction is committed
+						verifyEmail.SecretCodeHash = arg.SecretCodeHash
+						return db.CreateUserTxResult{User: user, VerifyEmail: verifyEmail}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+				require.Equal(t, user.FullName, rsp.GetUser().GetFullName())
+				require.Equal(t, user.Email, rsp.GetUser().GetEmail())
+				require.Equal(t, user.Role, rsp.GetUser().GetRole())
+			},
+			checkEmails: func(t *testing.T, emails []mail.Email) {
+				require.Len(t, emails, 1)
+				require.Equal(t, []string{user.Email}, emails[0].To)
+
+				// the link holds the id of the verify email and the code matching the stored hash
+				match := regexp.MustCompile(`\?id=(\d+)&amp;code=(\w+)`).FindStringSubmatch(emails[0].Content)
+				require.Len(t, match, 3)
+				require.Equal(t, fmt.Sprint(verifyEmail.ID), match[1])
+				require.Equal(t, verifyEmail.SecretCodeHash, util.HashSecret(match[2]))
+			},
+		},
+		{
+			name: "SendEmailError",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						// an invalid address is rejected by the sender - the committed user is still returned
+						verifyEmail := verifyEmail
+						verifyEmail.Email = "user@example.com\r\nBcc: other@example.com"
+						return db.CreateUserTxResult{User: user, VerifyEmail: verifyEmail}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+			},
+			checkEmails: func(t *testing.T, emails []mail.Email) {
+				require.Empty(t, emails)
+			},
+		},
+		{
+			name: "DuplicateUsername",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateUserTxResult{}, fmt.Errorf("transaction error: %w", &db.ErrDuplicate{Constraint: "users_pkey"}))
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				requireStatusCode(t, err, codes.AlreadyExists)
+			},
+		},
+		{
+			name: "InternalError",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateUserTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				requireStatusCode(t, err, codes.Internal)
+			},
+		},
+		{
+			name: "InvalidArguments",
+			req: &pb.CreateUserRequest{
+				Username: "invalid-user#1",
+				Password: "123",
+				FullName: user.FullName,
+				Email:    "invalid-email",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+
+				// every invalid field is reported in the details of the status
+				st, _ := status.FromError(err)
+				require.Len(t, st.Details(), 2)
+				badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+				require.True(t, ok)
+				errorInfo, ok := st.Details()[1].(*errdetails.ErrorInfo)
+				require.True(t, ok)
+				require.Equal(t, api.CodeValidationFailed, errorInfo.GetReason())
+
+				fields := make([]string, 0, len(badRequest.GetFieldViolations()))
+				for _, violation := range badRequest.GetFieldViolations() {
+					fields = append(fields, violation.GetField())
+				}
+				require.ElementsMatch(t, []string{"username", "password", "email"}, fields)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, client := newTestServer(t, store)
+			rsp, err := client.CreateUser(context.Background(), tc.req)
+			tc.checkResponse(t, rsp, err)
+
+			if tc.checkEmails != nil {
+				tc.checkEmails(t, server.mailer.(*mail.MemorySender).Emails())
+			}
+		})
+	}
+}
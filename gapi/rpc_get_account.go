@@ -0,0 +1,60 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shimon-git/simple-bank/api"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/token"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+// GetAccount - getting an account of the user, the bankers and the admins can read any account
+func (server *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	if violations := validateGetAccountRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	payload, err := authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := server.store.GetAccount(ctx, req.GetId())
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, reasonError(codes.NotFound, api.CodeAccountNotFound, "account not found")
+		}
+		return nil, internalError("failed to get the account", err)
+	}
+	// checking if the authenticated user has the authorization to get the requested account
+	if account.Owner != payload.Username && !payload.HasScope(token.ScopeAccountsReadAll) {
+		return nil, reasonError(codes.PermissionDenied, api.CodeAccountAccessDenied, "you are not authorized to get the requested account details")
+	}
+
+	rsp, err := server.accountResponse(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetAccountResponse{Account: rsp}, nil
+}
+
+// accountResponse - the account returned to the client, including the funds reserved by the active holds of the account
+func (server *Server) accountResponse(ctx context.Context, account db.Account) (*pb.Account, error) {
+	held, err := server.store.GetAccountHeldAmount(ctx, account.ID)
+	if err != nil {
+		return nil, internalError("failed to get the held amount of the account", err)
+	}
+	return convertAccount(account, held), nil
+}
+
+// validateGetAccountRequest - the invalid fields of the request (nil when the request is valid)
+func validateGetAccountRequest(req *pb.GetAccountRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateID(req.GetId()); err != nil {
+		violations = append(violations, fieldViolation("id", err))
+	}
+	return violations
+}
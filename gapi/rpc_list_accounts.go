@@ -0,0 +1,59 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// the page size of the listed accounts
+const (
+	minPageSize = 5
+	maxPageSize = 10
+)
+
+// ListAccounts - listing a page of the accounts of the user
+func (server *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	if violations := validateListAccountsRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	payload, err := authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := server.store.ListAccounts(ctx, db.ListAccountsParams{
+		Owner:  payload.Username,
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, internalError("failed to list the accounts", err)
+	}
+
+	// adding the available balance of every account
+	rsp := &pb.ListAccountsResponse{Accounts: make([]*pb.Account, 0, len(accounts))}
+	for _, account := range accounts {
+		accountRsp, err := server.accountResponse(ctx, account)
+		if err != nil {
+			return nil, err
+		}
+		rsp.Accounts = append(rsp.Accounts, accountRsp)
+	}
+	return rsp, nil
+}
+
+// validateListAccountsRequest - the invalid fields of the request (nil when the request is valid)
+func validateListAccountsRequest(req *pb.ListAccountsRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateID(int64(req.GetPageId())); err != nil {
+		violations = append(violations, fieldViolation("page_id", err))
+	}
+	if size := req.GetPageSize(); size < minPageSize || size > maxPageSize {
+		violations = append(violations, fieldViolation("page_size", fmt.Errorf("must be from %d to %d", minPageSize, maxPageSize)))
+	}
+	return violations
+}
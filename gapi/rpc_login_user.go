@@ -0,0 +1,123 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shimon-git/simple-bank/api"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/rules"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+/*
+* LoginUser - logging in the user, issuing its access + refresh tokens and recording the session of the login
+* a user with two factor authentication gets only a mfa pending token - the login is completed at POST /users/login/mfa
+ */
+func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	if violations := validateLoginUserRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	// counting the attempt before the password is verified - the attempts of a throttled username or client ip are rejected
+	mtdt := extractMetadata(ctx)
+	throttle, err := server.countLoginAttempt(ctx, req.GetUsername(), mtdt)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := server.store.GetUser(ctx, req.GetUsername())
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			// an unknown user gets the same response (and the same password check time) as an incorrect password
+			util.CheckPassword(req.GetPassword(), util.DummyPasswordHash())
+			if err = server.recordFailedLogin(ctx, req.GetUsername(), throttle, "unknown user", mtdt); err != nil {
+				return nil, err
+			}
+			return nil, reasonError(codes.Unauthenticated, api.CodeIncorrectCredentials, incorrectCredentialsErr)
+		}
+		return nil, internalError("failed to find the user", err)
+	}
+	// verifying the password is correct
+	if err = util.CheckPassword(req.GetPassword(), user.HashedPassword); err != nil {
+		if err = server.recordFailedLogin(ctx, req.GetUsername(), throttle, "incorrect password", mtdt); err != nil {
+			return nil, err
+		}
+		return nil, reasonError(codes.Unauthenticated, api.CodeIncorrectCredentials, incorrectCredentialsErr)
+	}
+
+	// a user with two factor authentication gets a mfa pending token instead
+	userTotp, err := server.store.GetUserTotp(ctx, user.Username)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return nil, internalError("failed to get the two factor authentication of the user", err)
+	}
+	if err == nil && userTotp.ConfirmedAt.Valid {
+		mfaToken, mfaPayload, err := server.token.CreateToken(user.Username, token.MFAPendingRole, server.config.MFATokenDuration, token.TokenTypeMFAToken)
+		if err != nil {
+			return nil, internalError("failed to create the mfa token", err)
+		}
+		// the second factor counts its own attempt - the attempt of the correct password is taken back
+		// so a completed login leaves nothing on the throttle of the client ip
+		err = rules.ForgetLoginAttempt(ctx, server.store, loginAttempt(ctx, user.Username, mtdt))
+		if err != nil {
+			return nil, internalError("failed to forget the login attempt", err)
+		}
+		return &pb.LoginUserResponse{
+			MfaRequired:       true,
+			MfaToken:          mfaToken,
+			MfaTokenExpiresAt: timestamppb.New(mfaPayload.ExpiredAt),
+		}, nil
+	}
+
+	// generating an access token
+	accessToken, accessPayload, err := server.token.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration, token.TokenTypeAccessToken)
+	if err != nil {
+		return nil, internalError("failed to create the access token", err)
+	}
+	// generating a refresh token - the id of its payload identifies the session
+	refreshToken, refreshPayload, err := server.token.CreateToken(user.Username, user.Role, server.config.RefreshTokenDuration, token.TokenTypeRefreshToken)
+	if err != nil {
+		return nil, internalError("failed to create the refresh token", err)
+	}
+	// recording the session of the refresh token (only its hash is stored) + the login in the audit trail
+	session, err := server.store.CreateSessionTx(ctx, db.CreateSessionTxParams{
+		CreateSessionParams: db.CreateSessionParams{
+			ID:               refreshPayload.ID,
+			Username:         user.Username,
+			RefreshTokenHash: util.HashSecret(refreshToken),
+			UserAgent:        mtdt.UserAgent,
+			ClientIp:         mtdt.ClientIP,
+			IsBlocked:        false,
+			ExpiresAt:        refreshPayload.ExpiredAt,
+		},
+		Audit: auditParams(ctx, user.Username),
+	})
+	if err != nil {
+		return nil, internalError("failed to create the session", err)
+	}
+
+	return &pb.LoginUserResponse{
+		User:                  convertUser(user),
+		SessionId:             session.ID.String(),
+		AccessToken:           accessToken,
+		RefreshToken:          refreshToken,
+		AccessTokenExpiresAt:  timestamppb.New(accessPayload.ExpiredAt),
+		RefreshTokenExpiresAt: timestamppb.New(refreshPayload.ExpiredAt),
+	}, nil
+}
+
+// validateLoginUserRequest - the invalid fields of the request (nil when the request is valid)
+func validateLoginUserRequest(req *pb.LoginUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateUsername(req.GetUsername()); err != nil {
+		violations = append(violations, fieldViolation("username", err))
+	}
+	if err := validatePassword(req.GetPassword()); err != nil {
+		violations = append(violations, fieldViolation("password", err))
+	}
+	return violations
+}
@@ -0,0 +1,262 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shimon-git/simple-bank/api"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/totp"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoginUserRPC(t *testing.T) {
+	user, password := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		req           *pb.LoginUserRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, tokenMaker token.Maker, rsp *pb.LoginUserResponse, header metadata.MD, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectCountedLogin(t, store, user.Username)
+				// the attempt is taken back by the session of the login
+				store.EXPECT().
+					ForgetLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrNotFound)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateSessionTxParams) (db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, testRequestID, arg.Audit.RequestID)
+						return db.Session{ID: arg.ID, Username: arg.Username}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, rsp *pb.LoginUserResponse, header metadata.MD, err error) {
+				require.NoError(t, err)
+				require.False(t, rsp.GetMfaRequired())
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+				require.NotEmpty(t, rsp.GetSessionId())
+
+				payload, err := tokenMaker.VerifyToken(rsp.GetAccessToken(), token.TokenTypeAccessToken)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+
+				refreshPayload, err := tokenMaker.VerifyToken(rsp.GetRefreshToken(), token.TokenTypeRefreshToken)
+				require.NoError(t, err)
+				require.Equal(t, refreshPayload.ID.String(), rsp.GetSessionId())
+			},
+		},
+		{
+			name: "MFARequired",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				secret, err := totp.GenerateSecret()
+				require.NoError(t, err)
+
+				// the password step counts a single attempt - and takes it back once the password is verified
+				// the second factor counts its own attempt, so a completed login leaves nothing on the client ip
+				expectCountedLogin(t, store, user.Username)
+				store.EXPECT().
+					ForgetLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ForgetLoginAttemptTxParams) error {
+						require.Equal(t, db.ForgetLoginAttemptTxParams{Username: user.Username, ClientIP: "bufconn"}, arg)
+						return nil
+					})
+
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserTotp(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{
+						Username:    user.Username,
+						Secret:      secret,
+						ConfirmedAt: sql.NullTime{Time: time.Now(), Valid: true},
+					}, nil)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, rsp *pb.LoginUserResponse, header metadata.MD, err error) {
+				require.NoError(t, err)
+				require.True(t, rsp.GetMfaRequired())
+				require.Empty(t, rsp.GetAccessToken())
+				require.Empty(t, rsp.GetRefreshToken())
+
+				// the mfa token only completes the login
+				payload, err := tokenMaker.VerifyToken(rsp.GetMfaToken(), token.TokenTypeMFAToken)
+				require.NoError(t, err)
+				require.True(t, payload.HasScope(token.ScopeMFAVerify))
+			},
+		},
+		{
+			name: "UnknownUser",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectCountedLogin(t, store, user.Username)
+				store.EXPECT().
+					ForgetLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, db.ErrNotFound)
+				store.EXPECT().
+					RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RecordFailedLoginTxParams) (db.RecordFailedLoginTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, db.UserLoginPolicy(util.Config{
+							LoginBackoffAfter:    3,
+							LoginBackoff:         time.Second,
+							LoginMaxAttempts:     10,
+							LoginLockoutDuration: time.Minute,
+							LoginAttemptWindow:   time.Minute,
+						}), arg.UserPolicy)
+						return db.RecordFailedLoginTxResult{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, rsp *pb.LoginUserResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+				st, _ := status.FromError(err)
+				require.Equal(t, incorrectCredentialsErr, st.Message())
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: "incorrect"},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectCountedLogin(t, store, user.Username)
+				store.EXPECT().
+					ForgetLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RecordFailedLoginTxResult{}, nil)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, rsp *pb.LoginUserResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+				st, _ := status.FromError(err)
+				require.Equal(t, incorrectCredentialsErr, st.Message())
+			},
+		},
+		{
+			name: "Throttled",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ThrottleAttemptsTxParams) (db.ThrottleAttemptsTxResult, error) {
+						require.Len(t, arg.Attempts, 2)
+						require.Equal(t, db.UserThrottleKey(user.Username), arg.Attempts[0].Key)
+						require.Equal(t, db.IPThrottleKey("bufconn"), arg.Attempts[1].Key)
+						return db.ThrottleAttemptsTxResult{LockedUntil: time.Now().Add(90 * time.Second)}, db.ErrThrottled
+					})
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, rsp *pb.LoginUserResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.ResourceExhausted)
+				require.Equal(t, []string{"90"}, header.Get(retryAfterHeader))
+
+				// the client is told when to retry
+				st, _ := status.FromError(err)
+				require.Len(t, st.Details(), 2)
+				retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+				require.True(t, ok)
+				require.Equal(t, 90*time.Second, retryInfo.GetRetryDelay().AsDuration())
+				errorInfo, ok := st.Details()[1].(*errdetails.ErrorInfo)
+				require.True(t, ok)
+				require.Equal(t, api.CodeLoginThrottled, errorInfo.GetReason())
+			},
+		},
+		{
+			name: "InvalidArguments",
+			req:  &pb.LoginUserRequest{Username: "invalid-user#1", Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, tokenMaker token.Maker, rsp *pb.LoginUserResponse, header metadata.MD, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			// the counted attempts are expected exactly - the default throttle stubs would hide a leaked attempt
+			server, client := newStrictTestServer(t, store)
+			ctx := metadata.AppendToOutgoingContext(context.Background(), requestIDHeader, testRequestID)
+
+			var header metadata.MD
+			rsp, err := client.LoginUser(ctx, tc.req, grpc.Header(&header))
+			tc.checkResponse(t, server.token, rsp, header, err)
+		})
+	}
+}
+
+// expectCountedLogin - expecting a single counted (not throttled) attempt of the username and the client ip of the test connection
+func expectCountedLogin(t *testing.T, store *mockdb.MockStore, username string) {
+	store.EXPECT().
+		ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ThrottleAttemptsTxParams) (db.ThrottleAttemptsTxResult, error) {
+			require.Len(t, arg.Attempts, 2)
+			require.Equal(t, db.UserThrottleKey(username), arg.Attempts[0].Key)
+			require.Equal(t, db.IPThrottleKey("bufconn"), arg.Attempts[1].Key)
+
+			var result db.ThrottleAttemptsTxResult
+			for _, attempt := range arg.Attempts {
+				result.Throttles = append(result.Throttles, db.LoginThrottle{Key: attempt.Key, FailedAttempts: 1})
+			}
+			return result, nil
+		})
+}
@@ -0,0 +1,73 @@
+package gapi
+
+import (
+	"fmt"
+	"net"
+
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/mail"
+	"github.com/shimon-git/simple-bank/pb"
+	"github.com/shimon-git/simple-bank/token"
+	"github.com/shimon-git/simple-bank/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+/*
+* Server serves gRPC requests for our banking service
+* it's built on the same store, token maker, revocation list and email sender as the HTTP server - the two servers can run side by side
+ */
+type Server struct {
+	pb.UnimplementedSimpleBankServer
+	config      util.Config
+	store       db.Store
+	token       token.Maker
+	revocations *token.RevocationList
+	mailer      mail.EmailSender
+	// the interceptors of every request - applied by the gRPC server and by the gateway
+	interceptors []grpc.UnaryServerInterceptor
+	grpc         *grpc.Server
+}
+
+// NewServer - creates a new gRPC server and registers the simple bank service, the revoked tokens are checked by the given revocation list
+func NewServer(config util.Config, store db.Store, revocations *token.RevocationList) (*Server, error) {
+	tokenMaker, _, err := token.NewMakerFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	mailer, err := mail.NewSenderFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create email sender: %w", err)
+	}
+	// creating a new server object
+	server := &Server{
+		config: config,
+		store:  store,
+		token:  tokenMaker,
+		mailer: mailer,
+		// the revoked tokens - checked by the auth interceptor
+		revocations: revocations,
+	}
+
+	// identifying every request (for the audit trail) before authenticating it
+	server.interceptors = []grpc.UnaryServerInterceptor{
+		requestIDInterceptor,
+		server.authInterceptor,
+	}
+	server.grpc = grpc.NewServer(grpc.ChainUnaryInterceptor(server.interceptors...))
+	pb.RegisterSimpleBankServer(server.grpc, server)
+	// allowing the clients (e.g. grpcurl) to explore the service
+	reflection.Register(server.grpc)
+
+	return server, nil
+}
+
+// Start - starting the gRPC server on a specific address
+func (server *Server) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot create the gRPC listener: %w", err)
+	}
+	return server.grpc.Serve(listener)
+}
@@ -0,0 +1,39 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shimon-git/simple-bank/api"
+	"github.com/shimon-git/simple-bank/rules"
+	"google.golang.org/grpc/codes"
+)
+
+/*
+* requireFreshMFA - demanding a fresh second factor (the x-mfa-code metadata) for a transfer above the configured threshold
+* the codes are throttled like the logins - a wrong code is recorded as a failed login, so the user is locked out after the max attempts
+* a user without two factor authentication can't make the transfer - code PermissionDenied
+ */
+func (server *Server) requireFreshMFA(ctx context.Context, username string, amount int64) error {
+	if !rules.StepUpRequired(server.config, amount) {
+		return nil
+	}
+
+	code := metadataValue(ctx, mfaCodeHeader)
+	if code == "" {
+		return reasonError(codes.Unauthenticated, api.CodeMFARequired, fmt.Sprintf("transfers above %d require a two factor code in the %s metadata", server.config.MFATransferThreshold, mfaCodeHeader))
+	}
+
+	err := rules.VerifyStepUpCode(ctx, server.store, server.config, loginAttempt(ctx, username, extractMetadata(ctx)), code)
+	if err != nil {
+		switch {
+		case errors.Is(err, rules.ErrMFANotEnabled):
+			return reasonError(codes.PermissionDenied, api.CodeMFANotEnabled, err.Error())
+		case errors.Is(err, rules.ErrInvalidMFACode):
+			return reasonError(codes.Unauthenticated, api.CodeInvalidMFACode, err.Error())
+		}
+		return loginAttemptError(ctx, err)
+	}
+	return nil
+}
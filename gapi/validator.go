@@ -0,0 +1,68 @@
+package gapi
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+
+	"github.com/shimon-git/simple-bank/util"
+)
+
+var isValidUsername = regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString
+
+// validateString - checking the length of the value is within the given range
+func validateString(value string, minLength, maxLength int) error {
+	if n := len(value); n < minLength || n > maxLength {
+		return fmt.Errorf("must contain from %d-%d characters", minLength, maxLength)
+	}
+	return nil
+}
+
+// validateUsername - the usernames are made of letters and digits only
+func validateUsername(value string) error {
+	if err := validateString(value, 1, 100); err != nil {
+		return err
+	}
+	if !isValidUsername(value) {
+		return errors.New("must contain only letters or digits")
+	}
+	return nil
+}
+
+// validatePassword - the passwords are at least 6 characters long
+func validatePassword(value string) error {
+	return validateString(value, 6, 100)
+}
+
+// validateFullName - the full name is required
+func validateFullName(value string) error {
+	return validateString(value, 1, 100)
+}
+
+// validateEmail - checking the email address is valid
+func validateEmail(value string) error {
+	if err := validateString(value, 3, 200); err != nil {
+		return err
+	}
+	if _, err := mail.ParseAddress(value); err != nil {
+		return errors.New("is not a valid email address")
+	}
+	return nil
+}
+
+// validateCurrency - checking the currency is supported
+func validateCurrency(value string) error {
+	if !util.IsSupportedCurrency(value) {
+		return fmt.Errorf("%q is not a supported currency", value)
+	}
+	return nil
+}
+
+// validateID - the ids start at 1
+func validateID(value int64) error {
+	if value < 1 {
+		return errors.New("must be at least 1")
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"errors"
+
+	"github.com/shimon-git/simple-bank/util"
+)
+
+// ErrSMTPNotConfigured - the production environment can't run without a smtp host - the emails would never be delivered
+var ErrSMTPNotConfigured = errors.New("a smtp host is required in the production environment")
+
+/*
+* NewSenderFromConfig - creates the configured email sender
+* smtp when a smtp host is configured, the outbox dir when configured (e.g. for development), otherwise the emails are kept in memory
+* in the production environment only smtp is allowed
+ */
+func NewSenderFromConfig(config util.Config) (EmailSender, error) {
+	switch {
+	case config.SMTPHost == "" && config.Environment == util.ProductionEnvironment:
+		return nil, ErrSMTPNotConfigured
+	case config.SMTPHost != "":
+		return NewSMTPSender(
+			config.EmailSenderName,
+			config.EmailSenderAddress,
+			config.SMTPHost,
+			config.SMTPPort,
+			config.SMTPUsername,
+			config.SMTPPassword,
+		)
+	case config.EmailOutboxDir != "":
+		return NewFileSender(config.EmailSenderAddress, config.EmailOutboxDir)
+	default:
+		return NewMemorySender(), nil
+	}
+}
@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+/*
+* FileSender is an email sender writing every email as an .eml file into a folder
+* for development - the emails can be opened by any mail client
+ */
+type FileSender struct {
+	from  string
+	dir   string
+	mutex sync.Mutex
+	count int
+}
+
+// NewFileSender - creates a new FileSender, the folder is created when missing
+func NewFileSender(from, dir string) (EmailSender, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create the outbox folder: %w", err)
+	}
+	return &FileSender{from: from, dir: dir}, nil
+}
+
+// SendEmail - writes the email into the outbox folder
+func (sender *FileSender) SendEmail(subject, content string, to []string) error {
+	now := time.Now()
+	msg, err := buildMessage(sender.from, Email{Subject: subject, Content: content, To: to}, now)
+	if err != nil {
+		return err
+	}
+
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	sender.count++
+	name := fmt.Sprintf("%s-%d.eml", now.Format("20060102T150405.000000"), sender.count)
+	return os.WriteFile(filepath.Join(sender.dir, name), msg, 0o600)
+}
@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"sync"
+	"time"
+)
+
+// MemorySender is an in memory email sender - the emails are kept for the tests to read
+type MemorySender struct {
+	mutex  sync.Mutex
+	emails []Email
+}
+
+// NewMemorySender - creates a new MemorySender
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+// SendEmail - keeps the email, invalid emails are rejected like by the other senders
+func (sender *MemorySender) SendEmail(subject, content string, to []string) error {
+	email := Email{Subject: subject, Content: content, To: append([]string(nil), to...)}
+	if _, err := buildMessage("", email, time.Now()); err != nil {
+		return err
+	}
+
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	sender.emails = append(sender.emails, email)
+	return nil
+}
+
+// Emails - returns the sent emails, the oldest first
+func (sender *MemorySender) Emails() []Email {
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+	return append([]Email(nil), sender.emails...)
+}
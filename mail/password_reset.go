@@ -0,0 +1,24 @@
+package mail
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"time"
+)
+
+// PasswordResetSubject - the subject of the email sent for resetting a forgotten password
+const PasswordResetSubject = "Reset your Simple Bank password"
+
+// PasswordResetLink - the password reset link of the given reset token
+func PasswordResetLink(passwordResetURL string, resetToken string) string {
+	return fmt.Sprintf("%s?token=%s", passwordResetURL, url.QueryEscape(resetToken))
+}
+
+// PasswordResetContent - the html content of the password reset email, the user data is html escaped
+func PasswordResetContent(fullName, link string, duration time.Duration) string {
+	return fmt.Sprintf(`Hello %s,<br/>
+	A password reset was requested for your account.<br/>
+	Please <a href="%s">click here</a> to choose a new password, the link is valid for %s.<br/>
+	If you didn't request it you can ignore this email.<br/>`, html.EscapeString(fullName), html.EscapeString(link), duration)
+}
@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// ErrInvalidHeader - a header value (subject or address) contains a line break
+var ErrInvalidHeader = errors.New("email headers can't contain line breaks")
+
+// EmailSender is an interface for sending emails
+type EmailSender interface {
+	// SendEmail - sends an html email to the given addresses
+	SendEmail(subject, content string, to []string) error
+}
+
+// Email - a sent email
+type Email struct {
+	Subject string   `json:"subject"`
+	Content string   `json:"content"`
+	To      []string `json:"to"`
+}
+
+/*
+* buildMessage - building the RFC 5322 message of an html email
+* the headers are validated - a line break in a header would allow injecting more headers (e.g. Bcc)
+ */
+func buildMessage(from string, email Email, date time.Time) ([]byte, error) {
+	for _, header := range append([]string{from, email.Subject}, email.To...) {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+	if len(email.To) == 0 {
+		return nil, errors.New("at least one recipient is required")
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(email.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(email.Content)
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,148 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildMessage(t *testing.T) {
+	email := Email{
+		Subject: "Verify your email",
+		Content: "<a href=\"https://bank.test/verify\">verify</a>",
+		To:      []string{"a@bank.test", "b@bank.test"},
+	}
+
+	msg, err := buildMessage("Simple Bank <noreply@bank.test>", email, time.Now())
+	require.NoError(t, err)
+
+	headers, body, found := strings.Cut(string(msg), "\r\n\r\n")
+	require.True(t, found)
+	require.Contains(t, headers, "From: Simple Bank <noreply@bank.test>\r\n")
+	require.Contains(t, headers, "To: a@bank.test, b@bank.test\r\n")
+	require.Contains(t, headers, "Subject: Verify your email\r\n")
+	require.Contains(t, headers, "Content-Type: text/html")
+	require.Equal(t, email.Content, body)
+}
+
+func TestBuildMessageHeaderInjection(t *testing.T) {
+	testCases := []Email{
+		{Subject: "hi\r\nBcc: victim@bank.test", To: []string{"a@bank.test"}},
+		{Subject: "hi", To: []string{"a@bank.test\nBcc: victim@bank.test"}},
+		{Subject: "hi"},
+	}
+
+	for _, email := range testCases {
+		_, err := buildMessage("noreply@bank.test", email, time.Now())
+		require.Error(t, err)
+	}
+}
+
+func TestFileSender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	sender, err := NewFileSender("noreply@bank.test", dir)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		err = sender.SendEmail("Verify your email", "content", []string{"a@bank.test"})
+		require.NoError(t, err)
+	}
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "To: a@bank.test\r\n")
+}
+
+func TestMemorySender(t *testing.T) {
+	sender := NewMemorySender()
+
+	err := sender.SendEmail("Verify your email", "content", []string{"a@bank.test"})
+	require.NoError(t, err)
+	err = sender.SendEmail("hi\nBcc: victim@bank.test", "content", []string{"a@bank.test"})
+	require.ErrorIs(t, err, ErrInvalidHeader)
+
+	emails := sender.Emails()
+	require.Len(t, emails, 1)
+	require.Equal(t, "Verify your email", emails[0].Subject)
+	require.Equal(t, []string{"a@bank.test"}, emails[0].To)
+}
+
+func TestNewSMTPSender(t *testing.T) {
+	_, err := NewSMTPSender("Simple Bank", "noreply@bank.test", "smtp.bank.test", 587, "user", "password")
+	require.NoError(t, err)
+
+	_, err = NewSMTPSender("Simple Bank", "not an address", "smtp.bank.test", 587, "", "")
+	require.Error(t, err)
+
+	_, err = NewSMTPSender("Simple Bank", "noreply@bank.test", "", 587, "", "")
+	require.Error(t, err)
+}
+
+func TestNewSenderFromConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config util.Config
+		sender EmailSender
+		err    error
+	}{
+		{
+			name:   "SMTP",
+			config: util.Config{Environment: util.ProductionEnvironment, EmailSenderAddress: "noreply@bank.test", SMTPHost: "smtp.bank.test", SMTPPort: 587},
+			sender: &SMTPSender{},
+		},
+		{
+			name:   "Outbox",
+			config: util.Config{Environment: util.DevelopmentEnvironment, EmailSenderAddress: "noreply@bank.test", EmailOutboxDir: t.TempDir()},
+			sender: &FileSender{},
+		},
+		{
+			name:   "Memory",
+			config: util.Config{Environment: util.DevelopmentEnvironment},
+			sender: &MemorySender{},
+		},
+		{
+			name:   "ProductionWithoutSMTP",
+			config: util.Config{Environment: util.ProductionEnvironment, EmailOutboxDir: t.TempDir()},
+			err:    ErrSMTPNotConfigured,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			sender, err := NewSenderFromConfig(tc.config)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.IsType(t, tc.sender, sender)
+		})
+	}
+}
+
+func TestEmailContentEscaping(t *testing.T) {
+	fullName := `<script>alert("x")</script>`
+	link := `https://bank.test/verify?id=1&code="><img src=x>`
+
+	contents := []string{
+		VerifyEmailContent(fullName, link),
+		PasswordResetContent(fullName, link, time.Minute),
+	}
+	for _, content := range contents {
+		// the user data can't inject html into the emails
+		require.NotContains(t, content, "<script>")
+		require.NotContains(t, content, `"><img`)
+		require.Contains(t, content, "&lt;script&gt;")
+	}
+}
@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPSender is an email sender delivering the emails through an SMTP server
+type SMTPSender struct {
+	from     mail.Address
+	address  string
+	host     string
+	username string
+	password string
+}
+
+/*
+* NewSMTPSender - creates a new SMTPSender
+* name/fromAddress: the sender of the emails
+* the server is authenticated with PLAIN auth when a username is given (requires TLS - STARTTLS is used when offered)
+ */
+func NewSMTPSender(name, fromAddress, host string, port int, username, password string) (EmailSender, error) {
+	if host == "" || fromAddress == "" {
+		return nil, fmt.Errorf("the smtp host and the sender address are required")
+	}
+	if _, err := mail.ParseAddress(fromAddress); err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	return &SMTPSender{
+		from:     mail.Address{Name: name, Address: fromAddress},
+		address:  net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+		username: username,
+		password: password,
+	}, nil
+}
+
+// SendEmail - sends an html email to the given addresses
+func (sender *SMTPSender) SendEmail(subject, content string, to []string) error {
+	msg, err := buildMessage(sender.from.String(), Email{Subject: subject, Content: content, To: to}, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if sender.username != "" {
+		auth = smtp.PlainAuth("", sender.username, sender.password, sender.host)
+	}
+	return smtp.SendMail(sender.address, auth, sender.from.Address, to, msg)
+}
@@ -0,0 +1,22 @@
+package mail
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+)
+
+// VerifyEmailSubject - the subject of the email sent to the new users
+const VerifyEmailSubject = "Welcome to Simple Bank"
+
+// VerifyEmailLink - the verify email link of the given verify email id + secret code
+func VerifyEmailLink(verifyEmailURL string, id int64, code string) string {
+	return fmt.Sprintf("%s?id=%d&code=%s", verifyEmailURL, id, url.QueryEscape(code))
+}
+
+// VerifyEmailContent - the html content of the email sent to the new users, the user data is html escaped
+func VerifyEmailContent(fullName, link string) string {
+	return fmt.Sprintf(`Hello %s,<br/>
+	Thank you for registering with us!<br/>
+	Please <a href="%s">click here</a> to verify your email address.<br/>`, html.EscapeString(fullName), html.EscapeString(link))
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
 	"log"
+	"os"
 
-	_ "github.com/lib/pq"
 	"github.com/shimon-git/simple-bank/api"
 	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/gapi"
+	"github.com/shimon-git/simple-bank/token"
 	"github.com/shimon-git/simple-bank/util"
+	"github.com/shimon-git/simple-bank/worker"
 )
 
 const (
-	confFolder = "."
+	confFolder          = "."
+	reconcileCommand    = "reconcile"
+	revokeTokensCommand = "revoke-tokens"
+	setRoleCommand      = "set-role"
+	verifyAuditCommand  = "verify-audit"
 )
 
 func main() {
@@ -20,20 +30,227 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to load configurations: %v", err)
 	}
-	// Creating a DB connection objects
-	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	// Creating the DB connection pool
+	connPool, err := db.NewConnPool(context.Background(), config)
 	if err != nil {
 		log.Fatal("cannot connect to db:", err)
 	}
+	// the pool connects lazily - failing fast when the DB can't be reached
+	if err = connPool.Ping(context.Background()); err != nil {
+		log.Fatal("cannot connect to db:", err)
+	}
 	// creating a new store object
-	store := db.NewStore(conn)
-	// creating a new server object
-	server, err := api.NewServer(config, store)
+	store := db.NewStore(connPool)
+	// running a one-off subcommand instead of the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case reconcileCommand:
+			os.Exit(reconcile(store, os.Args[2:]))
+		case revokeTokensCommand:
+			os.Exit(revokeTokens(store, config, os.Args[2:]))
+		case setRoleCommand:
+			os.Exit(setRole(store, config, os.Args[2:]))
+		case verifyAuditCommand:
+			os.Exit(verifyAudit(store, os.Args[2:]))
+		}
+	}
+	// executing the scheduled transfers in the background
+	executor := worker.NewScheduledTransferExecutor(store, config)
+	go executor.Start(context.Background())
+	// snapshotting the account balances for the point-in-time balance queries
+	snapshotter := worker.NewBalanceSnapshotter(store, config)
+	go snapshotter.Start(context.Background())
+	// verifying the ledger integrity in the background
+	reconciler := worker.NewReconciler(store, config)
+	go reconciler.Start(context.Background())
+	// chaining the recorded audit events into the hash chain
+	chainer := worker.NewAuditChainer(store, config)
+	go chainer.Start(context.Background())
+	// pruning the revocations of tokens that would have expired anyway
+	pruner := worker.NewRevocationPruner(store, config)
+	go pruner.Start(context.Background())
+	// the revoked tokens - a single list shared by the HTTP and the gRPC servers
+	revocations := token.NewRevocationList(db.NewTokenRevocations(store), config.RevocationCacheSize, config.RevocationCacheTTL)
+	// serving the HTTP and the gRPC APIs side by side - the process exits when one of the servers fails
+	grpcServer, err := gapi.NewServer(config, store, revocations)
+	if err != nil {
+		log.Fatal("cannot create the gRPC server:", err)
+	}
+	errs := make(chan error, 2)
+	go func() { errs <- runHTTPServer(config, store, revocations, grpcServer) }()
+	go func() { errs <- runGRPCServer(config, grpcServer) }()
+	log.Fatal(<-errs)
+}
+
+// runHTTPServer - creating the HTTP server, with the REST gateway of the gRPC server, and starting it on the given interface and port
+func runHTTPServer(config util.Config, store db.Store, revocations *token.RevocationList, grpcServer *gapi.Server) error {
+	server, err := api.NewServer(config, store, revocations)
 	if err != nil {
-		log.Fatalf("cannot create server: %v", err)
+		return fmt.Errorf("cannot create the HTTP server: %w", err)
 	}
-	// starting the server on the given interface and port
+	gateway, err := grpcServer.NewGatewayHandler(context.Background())
+	if err != nil {
+		return fmt.Errorf("cannot create the gRPC gateway: %w", err)
+	}
+	server.MountGateway(gateway)
+	log.Printf("starting the HTTP server on %s", config.ServerAddress)
 	if err = server.Start(config.ServerAddress); err != nil {
-		log.Fatal("cannot starting the server on:", config.ServerAddress, ":", err)
+		return fmt.Errorf("cannot start the HTTP server on %s: %w", config.ServerAddress, err)
+	}
+	return nil
+}
+
+// runGRPCServer - starting the gRPC server on the given interface and port
+func runGRPCServer(config util.Config, server *gapi.Server) error {
+	log.Printf("starting the gRPC server on %s", config.GRPCServerAddress)
+	if err := server.Start(config.GRPCServerAddress); err != nil {
+		return fmt.Errorf("cannot start the gRPC server on %s: %w", config.GRPCServerAddress, err)
+	}
+	return nil
+}
+
+/*
+* reconcile - the reconcile subcommand, verifies the ledger integrity and writes the discrepancy report
+* usage: simple-bank reconcile [-output report.json]
+* the report is written to stdout by default
+* returns the exit code - 0 for a consistent ledger, 1 when discrepancies were found, 2 on failure
+ */
+func reconcile(store db.Store, args []string) int {
+	flags := flag.NewFlagSet(reconcileCommand, flag.ContinueOnError)
+	output := flags.String("output", "", "the report file (stdout when empty)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	report, err := store.Reconcile(context.Background())
+	if err != nil {
+		log.Printf("failed to reconcile the ledger: %v", err)
+		return 2
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			log.Printf("failed to create the report file: %v", err)
+			return 2
+		}
+		defer out.Close()
+	}
+
+	if err = worker.WriteReconcileReport(out, report); err != nil {
+		log.Printf("failed to write the report: %v", err)
+		return 2
+	}
+
+	if report.Discrepancies() > 0 {
+		log.Printf("found %d discrepancies", report.Discrepancies())
+		return 1
+	}
+	return 0
+}
+
+/*
+* revokeTokens - the revoke-tokens subcommand, revokes all the tokens issued to the user so far and blocks the user sessions
+* usage: simple-bank revoke-tokens -username <username>
+* returns the exit code - 0 on success, 2 on failure
+ */
+func revokeTokens(store db.Store, config util.Config, args []string) int {
+	flags := flag.NewFlagSet(revokeTokensCommand, flag.ContinueOnError)
+	username := flags.String("username", "", "the user whose tokens are revoked")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *username == "" {
+		log.Print("the username is required")
+		return 2
+	}
+
+	revocations := token.NewRevocationList(db.NewTokenRevocations(store), config.RevocationCacheSize, config.RevocationCacheTTL)
+	if err := revocations.RevokeUser(context.Background(), *username); err != nil {
+		log.Printf("failed to revoke the tokens of %s: %v", *username, err)
+		return 2
+	}
+
+	log.Printf("revoked the tokens of %s", *username)
+	return 0
+}
+
+/*
+* setRole - the set-role subcommand, changes the role of the user (customer, banker or admin)
+* usage: simple-bank set-role -username <username> -role <role>
+* the tokens of the user are revoked - the new role is embedded in the tokens of the next login
+* returns the exit code - 0 on success, 2 on failure
+ */
+func setRole(store db.Store, config util.Config, args []string) int {
+	flags := flag.NewFlagSet(setRoleCommand, flag.ContinueOnError)
+	username := flags.String("username", "", "the user whose role is changed")
+	role := flags.String("role", "", "the new role - customer, banker or admin")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *username == "" || !util.IsSupportedRole(*role) {
+		log.Print("the username and a supported role are required")
+		return 2
+	}
+
+	if _, err := store.UpdateUserRole(context.Background(), db.UpdateUserRoleParams{
+		Username: *username,
+		Role:     *role,
+	}); err != nil {
+		log.Printf("failed to set the role of %s: %v", *username, err)
+		return 2
+	}
+
+	revocations := token.NewRevocationList(db.NewTokenRevocations(store), config.RevocationCacheSize, config.RevocationCacheTTL)
+	if err := revocations.RevokeUser(context.Background(), *username); err != nil {
+		log.Printf("failed to revoke the tokens of %s: %v", *username, err)
+		return 2
+	}
+
+	log.Printf("%s is now %s", *username, *role)
+	return 0
+}
+
+/*
+* verifyAudit - the verify-audit subcommand, recomputes the hash chain of the audit log
+* usage: simple-bank verify-audit [-anchor <head of a previous run>]
+* an edited or deleted event breaks the chain, events deleted from the end of the log are detected by the anchor
+* returns the exit code - 0 for an intact log, 1 when the chain is broken, 2 on failure
+ */
+func verifyAudit(store db.Store, args []string) int {
+	flags := flag.NewFlagSet(verifyAuditCommand, flag.ContinueOnError)
+	anchorHex := flags.String("anchor", "", "the head hash printed by a previous run - must still be in the log")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var anchor []byte
+	if *anchorHex != "" {
+		var err error
+		if anchor, err = hex.DecodeString(*anchorHex); err != nil {
+			log.Printf("invalid anchor: %v", err)
+			return 2
+		}
+	}
+
+	report, err := store.VerifyAuditLog(context.Background(), anchor)
+	if err != nil {
+		log.Printf("failed to verify the audit log: %v", err)
+		return 2
+	}
+
+	broken := len(report.Breaks) > 0
+	for _, chainBreak := range report.Breaks {
+		log.Printf("event %d: %s", chainBreak.EventID, chainBreak.Reason)
+	}
+	if anchor != nil && !report.AnchorSeen {
+		log.Print("the anchor is missing - events were deleted from the end of the log")
+		broken = true
+	}
+
+	log.Printf("verified %d events, head: %s, %d events not chained yet", report.Events, hex.EncodeToString(report.Head), report.Pending)
+	if broken {
+		return 1
 	}
+	return 0
 }
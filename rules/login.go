@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+)
+
+/*
+* LoginAttempt - a login attempt (a password or a second factor) of a username
+* ClientIP + UserAgent: the client of the attempt - the client ip is throttled too
+* Audit: the audit trail params of the request
+ */
+type LoginAttempt struct {
+	Username  string
+	ClientIP  string
+	UserAgent string
+	Audit     db.AuditParams
+}
+
+/*
+* ThrottledError - the attempt was rejected by the throttle of the username or the client ip
+* LockedUntil: until when the attempts are rejected - the clients are told when to retry
+ */
+type ThrottledError struct {
+	LockedUntil time.Time
+}
+
+func (err *ThrottledError) Error() string {
+	return db.ErrThrottled.Error()
+}
+
+// Unwrap - a throttled attempt is db.ErrThrottled
+func (err *ThrottledError) Unwrap() error {
+	return db.ErrThrottled
+}
+
+/*
+* CountLoginAttempt - counting the login attempt of the username and the client ip, before the password (or the second factor) is verified
+* the attempt is counted and checked at once under the lock of the throttles - so concurrent guesses can't slip past the throttle
+* returns the throttle of the username, a *ThrottledError when the attempt is rejected
+ */
+func CountLoginAttempt(ctx context.Context, store db.Store, config util.Config, attempt LoginAttempt) (db.LoginThrottle, error) {
+	result, err := store.ThrottleAttemptsTx(ctx, db.LoginAttemptsParams(config, attempt.Username, attempt.ClientIP))
+	if err != nil {
+		if errors.Is(err, db.ErrThrottled) {
+			return db.LoginThrottle{}, &ThrottledError{LockedUntil: result.LockedUntil}
+		}
+		return db.LoginThrottle{}, fmt.Errorf("failed to count the login attempt: %w", err)
+	}
+	return result.Throttle(db.UserThrottleKey(attempt.Username)), nil
+}
+
+/*
+* RecordFailedLogin - recording the failed login attempt in the audit trail - with the lockout of the username when the attempt locked it
+* throttle: the throttle of the username after the attempt was counted (by CountLoginAttempt)
+* reason: why the attempt failed (e.g. "incorrect password")
+ */
+func RecordFailedLogin(ctx context.Context, store db.Store, config util.Config, attempt LoginAttempt, throttle db.LoginThrottle, reason string) error {
+	_, err := store.RecordFailedLoginTx(ctx, db.RecordFailedLoginTxParams{
+		Username:     attempt.Username,
+		ClientIP:     attempt.ClientIP,
+		UserThrottle: throttle,
+		UserPolicy:   db.UserLoginPolicy(config),
+		Details: map[string]string{
+			"reason":     reason,
+			"user_agent": attempt.UserAgent,
+			"client_ip":  attempt.ClientIP,
+		},
+		Audit: attempt.Audit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record the failed login: %w", err)
+	}
+	return nil
+}
+
+// StepUpRequired - a transfer above the configured threshold demands a fresh second factor
+func StepUpRequired(config util.Config, amount int64) bool {
+	return config.MFATransferThreshold > 0 && amount > config.MFATransferThreshold
+}
+
+/*
+* VerifyStepUpCode - verifying the fresh totp code of a sensitive operation (e.g. a large transfer)
+* the codes are throttled like the logins - a wrong code is recorded as a failed login, so the user is locked out after the max attempts
+* a valid code doesn't count towards the throttle of the user
+* a user without two factor authentication isn't counted at all - there is no code to guess
+* returns a *ThrottledError, ErrMFANotEnabled or ErrInvalidMFACode when the operation can't be made
+ */
+func VerifyStepUpCode(ctx context.Context, store db.Store, config util.Config, attempt LoginAttempt, code string) error {
+	userTotp, err := confirmedTotp(ctx, store, attempt.Username)
+	if err != nil {
+		return err
+	}
+
+	throttle, err := CountLoginAttempt(ctx, store, config, attempt)
+	if err != nil {
+		return err
+	}
+
+	err = useTotp(ctx, store, userTotp, code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if recordErr := RecordFailedLogin(ctx, store, config, attempt, throttle, "incorrect step-up code"); recordErr != nil {
+				return recordErr
+			}
+		}
+		return err
+	}
+
+	return ForgetLoginAttempt(ctx, store, attempt)
+}
+
+/*
+* ForgetLoginAttempt - taking back the counted attempt of a successful step (e.g. a valid step-up code, or the password of a login completed by its second factor)
+* the attempts of the username are forgotten, the attempt is taken back from the client ip - its failed attempts are kept
+ */
+func ForgetLoginAttempt(ctx context.Context, store db.Store, attempt LoginAttempt) error {
+	err := store.ForgetLoginAttemptTx(ctx, db.ForgetLoginAttemptTxParams{
+		Username: attempt.Username,
+		ClientIP: attempt.ClientIP,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to forget the login attempt: %w", err)
+	}
+	return nil
+}
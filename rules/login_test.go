@@ -0,0 +1,165 @@
+package rules
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/totp"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyStepUpCode(t *testing.T) {
+	attempt := LoginAttempt{
+		Username: util.RandomOwner(),
+		ClientIP: "192.0.2.1",
+		Audit:    db.AuditParams{RequestID: util.RandomString(10)},
+	}
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	userTotp := db.UserTotp{
+		Username:    attempt.Username,
+		Secret:      secret,
+		ConfirmedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	throttle := db.LoginThrottle{Key: db.UserThrottleKey(attempt.Username), FailedAttempts: 1}
+	counted := db.ThrottleAttemptsTxResult{Throttles: []db.LoginThrottle{throttle}}
+
+	testCases := []struct {
+		name       string
+		code       string
+		buildStubs func(store *mockdb.MockStore)
+		err        error
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ThrottleAttemptsTx(gomock.Any(), gomock.Any()).Times(1).Return(counted, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(attempt.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(1).Return(userTotp, nil)
+				// a valid code doesn't count towards the throttle of the user
+				store.EXPECT().
+					ForgetLoginAttemptTx(gomock.Any(), gomock.Eq(db.ForgetLoginAttemptTxParams{Username: attempt.Username, ClientIP: attempt.ClientIP})).
+					Times(1).
+					Return(nil)
+			},
+		},
+		{
+			name: "Throttled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ThrottleAttemptsTxResult{LockedUntil: time.Now().Add(time.Minute)}, db.ErrThrottled)
+				// the code isn't verified while the user is locked out
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(attempt.Username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: db.ErrThrottled,
+		},
+		{
+			name: "IncorrectCode",
+			code: "000000",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ThrottleAttemptsTx(gomock.Any(), gomock.Any()).Times(1).Return(counted, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(attempt.Username)).Times(1).Return(userTotp, nil)
+				// the wrong code is recorded as a failed login
+				store.EXPECT().
+					RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RecordFailedLoginTxParams) (db.RecordFailedLoginTxResult, error) {
+						require.Equal(t, attempt.Username, arg.Username)
+						require.Equal(t, throttle, arg.UserThrottle)
+						require.Equal(t, attempt.Audit, arg.Audit)
+						return db.RecordFailedLoginTxResult{}, nil
+					})
+				store.EXPECT().ForgetLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: ErrInvalidMFACode,
+		},
+		{
+			name: "NotEnabled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				// the attempt isn't counted - so a retry of the user can't lock the user out
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(attempt.Username)).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+				store.EXPECT().ThrottleAttemptsTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ForgetLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RecordFailedLoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: ErrMFANotEnabled,
+		},
+		{
+			name: "NotConfirmed",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				pending := userTotp
+				pending.ConfirmedAt = sql.NullTime{}
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(attempt.Username)).Times(1).Return(pending, nil)
+				store.EXPECT().ThrottleAttemptsTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ForgetLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RecordFailedLoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: ErrMFANotEnabled,
+		},
+		{
+			name: "RecordFailedLoginError",
+			code: "000000",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ThrottleAttemptsTx(gomock.Any(), gomock.Any()).Times(1).Return(counted, nil)
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Any()).Times(1).Return(userTotp, nil)
+				store.EXPECT().
+					RecordFailedLoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RecordFailedLoginTxResult{}, sql.ErrConnDone)
+			},
+			err: sql.ErrConnDone,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			err := VerifyStepUpCode(context.Background(), store, util.Config{}, attempt, tc.code)
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestCountLoginAttemptThrottled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	lockedUntil := time.Now().Add(time.Minute)
+	store.EXPECT().
+		ThrottleAttemptsTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.ThrottleAttemptsTxResult{LockedUntil: lockedUntil}, db.ErrThrottled)
+
+	// the clients are told until when the attempts are rejected
+	_, err := CountLoginAttempt(context.Background(), store, util.Config{}, LoginAttempt{Username: util.RandomOwner()})
+	var throttled *ThrottledError
+	require.ErrorAs(t, err, &throttled)
+	require.Equal(t, lockedUntil, throttled.LockedUntil)
+	require.ErrorIs(t, err, db.ErrThrottled)
+}
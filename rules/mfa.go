@@ -0,0 +1,64 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/totp"
+)
+
+// different types of errors returned by the two factor authentication
+var (
+	ErrInvalidMFACode = errors.New("invalid two factor authentication code")
+	ErrMFANotEnabled  = errors.New("two factor authentication is not enabled")
+)
+
+/*
+* UseTotpCode - verifying the totp code of the user and marking its step as used
+* returns ErrMFANotEnabled when the user has no confirmed totp secret
+* returns ErrInvalidMFACode when the code is wrong or was already used
+ */
+func UseTotpCode(ctx context.Context, store db.Store, username, code string) error {
+	userTotp, err := confirmedTotp(ctx, store, username)
+	if err != nil {
+		return err
+	}
+	return useTotp(ctx, store, userTotp, code)
+}
+
+// confirmedTotp - the confirmed totp secret of the user, ErrMFANotEnabled when the user has none
+func confirmedTotp(ctx context.Context, store db.Store, username string) (db.UserTotp, error) {
+	userTotp, err := store.GetUserTotp(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return db.UserTotp{}, ErrMFANotEnabled
+		}
+		return db.UserTotp{}, err
+	}
+	if !userTotp.ConfirmedAt.Valid {
+		return db.UserTotp{}, ErrMFANotEnabled
+	}
+	return userTotp, nil
+}
+
+// useTotp - verifying the code against the totp secret and marking its step as used, ErrInvalidMFACode when the code can't be used
+func useTotp(ctx context.Context, store db.Store, userTotp db.UserTotp, code string) error {
+	step, err := totp.Validate(userTotp.Secret, code, time.Now())
+	if err != nil {
+		if errors.Is(err, totp.ErrInvalidCode) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+	// a code can be used only once - the step must be newer than the last used step
+	_, err = store.UseTotpStep(ctx, db.UseTotpStepParams{
+		Username:     userTotp.Username,
+		LastUsedStep: step,
+	})
+	if errors.Is(err, db.ErrNotFound) {
+		return ErrInvalidMFACode
+	}
+	return err
+}
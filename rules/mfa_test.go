@@ -0,0 +1,108 @@
+package rules
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/totp"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseTotpCode(t *testing.T) {
+	username := util.RandomOwner()
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	userTotp := db.UserTotp{
+		Username:    username,
+		Secret:      secret,
+		ConfirmedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		code       string
+		buildStubs func(store *mockdb.MockStore)
+		err        error
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().
+					UseTotpStep(gomock.Any(), gomock.Eq(db.UseTotpStepParams{Username: username, LastUsedStep: totp.Step(time.Now())})).
+					Times(1).
+					Return(userTotp, nil)
+			},
+		},
+		{
+			name: "UsedCode",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(username)).Times(1).Return(userTotp, nil)
+				// the step of the code was already used
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+			},
+			err: ErrInvalidMFACode,
+		},
+		{
+			name: "IncorrectCode",
+			code: "000000",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(username)).Times(1).Return(userTotp, nil)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(0)
+			},
+			err: ErrInvalidMFACode,
+		},
+		{
+			name: "NotEnabled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(username)).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+			},
+			err: ErrMFANotEnabled,
+		},
+		{
+			name: "NotConfirmed",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Eq(username)).Times(1).Return(db.UserTotp{Username: username, Secret: secret}, nil)
+			},
+			err: ErrMFANotEnabled,
+		},
+		{
+			name: "InternalError",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTotp(gomock.Any(), gomock.Any()).Times(1).Return(db.UserTotp{}, sql.ErrConnDone)
+			},
+			err: sql.ErrConnDone,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			err := UseTotpCode(context.Background(), store, username, tc.code)
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package rules
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+)
+
+// ErrEmailNotVerified - the user must verify the email before moving money
+var ErrEmailNotVerified = errors.New("the email of the user must be verified first")
+
+/*
+* RequireVerifiedEmail - blocking the transfers of the user until the email is verified
+* the check is enabled by the REQUIRE_VERIFIED_EMAIL configuration
+* returns ErrEmailNotVerified when the email of the user is not verified
+ */
+func RequireVerifiedEmail(ctx context.Context, store db.Store, config util.Config, username string) error {
+	if !config.RequireVerifiedEmail {
+		return nil
+	}
+
+	user, err := store.GetUser(ctx, username)
+	if err != nil {
+		return fmt.Errorf("failed to get the user: %w", err)
+	}
+	if !user.IsEmailVerified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+/*
+* TransferRequest - the hashed fields of a transfer request
+* the same for both APIs - so a retry through either API with the same idempotency key is recognized
+ */
+type TransferRequest struct {
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	QuoteID       string `json:"quote_id,omitempty"`
+}
+
+// HashTransferRequest - returns the sha256 (hex) of the json encoded request - stored with its idempotency key
+func HashTransferRequest(req TransferRequest) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,94 @@
+package rules
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name       string
+		required   bool
+		buildStubs func(store *mockdb.MockStore)
+		err        error
+	}{
+		{
+			name:     "NotRequired",
+			required: false,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:     "Verified",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(username)).Times(1).Return(db.User{Username: username, IsEmailVerified: true}, nil)
+			},
+		},
+		{
+			name:     "NotVerified",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(username)).Times(1).Return(db.User{Username: username}, nil)
+			},
+			err: ErrEmailNotVerified,
+		},
+		{
+			name:     "InternalError",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			err: sql.ErrConnDone,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			err := RequireVerifiedEmail(context.Background(), store, util.Config{RequireVerifiedEmail: tc.required}, username)
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestHashTransferRequest(t *testing.T) {
+	req := TransferRequest{
+		FromAccountID: 1,
+		ToAccountID:   2,
+		Amount:        10,
+		Currency:      util.USD,
+	}
+
+	hash1, err := HashTransferRequest(req)
+	require.NoError(t, err)
+	hash2, err := HashTransferRequest(req)
+	require.NoError(t, err)
+	require.Equal(t, hash1, hash2)
+
+	// a different request can't reuse the idempotency key
+	req.Amount = 20
+	hash3, err := HashTransferRequest(req)
+	require.NoError(t, err)
+	require.NotEqual(t, hash1, hash3)
+}
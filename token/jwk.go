@@ -0,0 +1,47 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"math/big"
+)
+
+// JSONWebKey - a public verification key (RFC 7517)
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JSONWebKeySet - the verification keys of the key ring
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// newJSONWebKey - creates the JWK of the public key, the key must be supported by the key ring
+func newJSONWebKey(kid string, publicKey crypto.PublicKey) JSONWebKey {
+	jwk := JSONWebKey{
+		KeyID: kid,
+		Use:   "sig",
+	}
+
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = keyTypeOKP
+		jwk.Algorithm = "EdDSA"
+		jwk.Curve = "Ed25519"
+		jwk.X = encodeSegment(key)
+	case *rsa.PublicKey:
+		jwk.KeyType = keyTypeRSA
+		jwk.Algorithm = "RS256"
+		jwk.N = encodeSegment(key.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(key.E)).Bytes())
+	}
+	return jwk
+}
@@ -0,0 +1,76 @@
+package token
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// AsymmetricJWTMaker is a Json Web Token maker signing with the signing key of the key ring
+type AsymmetricJWTMaker struct {
+	method jwt.SigningMethod
+	keys   *KeyRing
+}
+
+// NewEdDSAJwtMaker - creates a new AsymmetricJWTMaker for EdDSA (ed25519 keys)
+func NewEdDSAJwtMaker(keys *KeyRing) (Maker, error) {
+	return newAsymmetricJwtMaker(jwt.SigningMethodEdDSA, keyTypeOKP, keys)
+}
+
+// NewRS256JwtMaker - creates a new AsymmetricJWTMaker for RS256 (rsa keys)
+func NewRS256JwtMaker(keys *KeyRing) (Maker, error) {
+	return newAsymmetricJwtMaker(jwt.SigningMethodRS256, keyTypeRSA, keys)
+}
+
+// newAsymmetricJwtMaker - creates a new AsymmetricJWTMaker, the keys must match the signing method
+func newAsymmetricJwtMaker(method jwt.SigningMethod, keyType string, keys *KeyRing) (Maker, error) {
+	if keys.keyType != keyType {
+		return nil, fmt.Errorf("invalid key type: %s requires %s keys", method.Alg(), keyType)
+	}
+
+	return &AsymmetricJWTMaker{
+		method: method,
+		keys:   keys,
+	}, nil
+}
+
+// CreateToken - creates token of the given type for specific username, role and duration
+func (maker *AsymmetricJWTMaker) CreateToken(username string, role string, duration time.Duration, tokenType TokenType) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration, tokenType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// creating the jwt token - the kid header tells the verifier which key signed the token
+	kid, signingKey := maker.keys.signer()
+	jwtToken := jwt.NewWithClaims(maker.method, payload)
+	jwtToken.Header["kid"] = kid
+
+	// return the signed(with the signing key) jwt token
+	token, err := jwtToken.SignedString(signingKey)
+	return token, payload, err
+}
+
+// VerifyToken - verify the token validation and its type
+func (maker *AsymmetricJWTMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
+	// keyFunc - anonymous function returning the verification key of the token kid
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		// the token must be signed with the algorithm of the maker
+		if token.Method.Alg() != maker.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		key, ok := maker.keys.verificationKey(kid)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return key, nil
+	}
+
+	return parseJWT(token, tokenType, keyFunc)
+}
@@ -0,0 +1,140 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdDSAJWTMaker(t *testing.T) {
+	keys, err := NewKeyRing(randomEd25519Key(t))
+	require.NoError(t, err)
+
+	maker, err := NewEdDSAJwtMaker(keys)
+	require.NoError(t, err)
+
+	username := util.RandomString(6)
+	role := util.RandomRole()
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := time.Now().Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, duration, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.NotEmpty(t, payload.Scopes)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestRS256JWTMaker(t *testing.T) {
+	keys, err := NewKeyRing(randomRSAKey(t))
+	require.NoError(t, err)
+
+	maker, err := NewRS256JwtMaker(keys)
+	require.NoError(t, err)
+
+	username := util.RandomString(6)
+
+	token, payload, err := maker.CreateToken(username, util.RandomRole(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.Equal(t, username, payload.Username)
+
+	// rsa keys can't be used for EdDSA
+	_, err = NewEdDSAJwtMaker(keys)
+	require.Error(t, err)
+}
+
+func TestExpiredAsymmetricJWTToken(t *testing.T) {
+	keys, err := NewKeyRing(randomEd25519Key(t))
+	require.NoError(t, err)
+
+	maker, err := NewEdDSAJwtMaker(keys)
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(util.RandomString(6), util.RandomRole(), -time.Second, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+// TestAsymmetricJWTKeyRotation - tokens signed with the rotated key are valid as long as it's a verification key
+func TestAsymmetricJWTKeyRotation(t *testing.T) {
+	oldKey := randomEd25519Key(t)
+	newKey := randomEd25519Key(t)
+
+	oldKeys, err := NewKeyRing(oldKey)
+	require.NoError(t, err)
+	oldMaker, err := NewEdDSAJwtMaker(oldKeys)
+	require.NoError(t, err)
+
+	token, _, err := oldMaker.CreateToken(util.RandomString(6), util.RandomRole(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// signing with the new key - the old key is still a verification key
+	rotatedKeys, err := NewKeyRing(newKey, oldKey.Public())
+	require.NoError(t, err)
+	rotatedMaker, err := NewEdDSAJwtMaker(rotatedKeys)
+	require.NoError(t, err)
+
+	payload, err := rotatedMaker.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	// the old key is dropped
+	newKeys, err := NewKeyRing(newKey)
+	require.NoError(t, err)
+	newMaker, err := NewEdDSAJwtMaker(newKeys)
+	require.NoError(t, err)
+
+	payload, err = newMaker.VerifyToken(token, TokenTypeAccessToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+// TestInvalidAsymmetricJWTTokenAlgorithm - a token signed with the public key as an HMAC secret
+func TestInvalidAsymmetricJWTTokenAlgorithm(t *testing.T) {
+	signingKey := randomEd25519Key(t)
+	keys, err := NewKeyRing(signingKey)
+	require.NoError(t, err)
+
+	payload, err := NewPayload(util.RandomString(6), util.RandomRole(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	kid, _ := keys.signer()
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	jwtToken.Header["kid"] = kid
+	token, err := jwtToken.SignedString([]byte(signingKey.Public().(ed25519.PublicKey)))
+	require.NoError(t, err)
+
+	maker, err := NewEdDSAJwtMaker(keys)
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 const minSecretKeySize = 32
@@ -24,22 +24,23 @@ func NewJwtMaker(secretKey string) (Maker, error) {
 	return &JWTMaker{secretKey}, nil
 }
 
-// CreateToken - creates token for specific username and duration
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+// CreateToken - creates token of the given type for specific username, role and duration
+func (maker *JWTMaker) CreateToken(username string, role string, duration time.Duration, tokenType TokenType) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration, tokenType)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	// creating the jwt token
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 
 	// return the signed(with the secret key) jwt token
-	return jwtToken.SignedString([]byte(maker.secretKey))
+	token, err := jwtToken.SignedString([]byte(maker.secretKey))
+	return token, payload, err
 }
 
-// VerifyToken - verify the token validation
-func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
+// VerifyToken - verify the token validation and its type
+func (maker *JWTMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
 	// keyFunc - anonymous function for key validation
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
 		// trying to convert token method into jwt.SigningMethodHMAC
@@ -51,6 +52,11 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return []byte(maker.secretKey), nil
 	}
 
+	return parseJWT(token, tokenType, keyFunc)
+}
+
+// parseJWT - parses the jwt token of the given type with the given key function and returns its payload
+func parseJWT(token string, tokenType TokenType, keyFunc jwt.Keyfunc) (*Payload, error) {
 	// parsing the token
 	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
 	// checking for errors - return the err message based on the error type
@@ -67,6 +73,9 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 	if !ok {
 		return nil, ErrInvalidToken
 	}
+	if err = payload.verifyType(tokenType); err != nil {
+		return nil, err
+	}
 
 	return payload, nil
 }
@@ -4,7 +4,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/shimon-git/simple-bank/util"
 	"github.com/stretchr/testify/require"
 )
@@ -14,21 +14,26 @@ func TestJWTMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	username := util.RandomString(6)
+	role := util.RandomRole()
 	duration := time.Minute
 
 	issuedAt := time.Now()
 	expiredAt := time.Now().Add(duration)
 
-	token, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(username, role, duration, TokenTypeAccessToken)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
 	require.NotZero(t, payload.ID)
+	require.Equal(t, TokenTypeAccessToken, payload.Type)
 	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.NotEmpty(t, payload.Scopes)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
@@ -39,11 +44,12 @@ func TestExpiredJWTToken(t *testing.T) {
 
 	username := util.RandomString(6)
 
-	token, err := maker.CreateToken(username, -time.Second)
+	token, payload, err := maker.CreateToken(username, util.RandomRole(), -time.Second, TokenTypeAccessToken)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Empty(t, payload)
@@ -52,7 +58,7 @@ func TestExpiredJWTToken(t *testing.T) {
 // TestInvalidJWTTokenAlgNone - testing a case of a none algorithm token
 func TestInvalidJWTTokenAlgNone(t *testing.T) {
 	username := util.RandomString(6)
-	payload, err := NewPayload(username, time.Minute)
+	payload, err := NewPayload(username, util.RandomRole(), time.Minute, TokenTypeAccessToken)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
@@ -63,9 +69,28 @@ func TestInvalidJWTTokenAlgNone(t *testing.T) {
 	maker, err := NewJwtMaker(util.RandomString(32))
 	require.NoError(t, err)
 
-	payload, err = maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrInvalidToken.Error())
 	require.Nil(t, payload)
 
 }
+
+// TestWrongTypeJWTToken - testing a case of a token verified as another type (e.g. a refresh token used as an access token)
+func TestWrongTypeJWTToken(t *testing.T) {
+	maker, err := NewJwtMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(util.RandomString(6), util.RandomRole(), time.Minute, TokenTypeRefreshToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeRefreshToken)
+	require.NoError(t, err)
+	require.Equal(t, TokenTypeRefreshToken, payload.Type)
+}
@@ -0,0 +1,216 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+)
+
+// key types of the key ring (the JWK kty values)
+const (
+	keyTypeOKP = "OKP"
+	keyTypeRSA = "RSA"
+)
+
+const minRSAKeySize = 2048
+
+/*
+* KeyRing - the keys of the asymmetric token makers
+* one signing key - signs the new tokens
+* several verification keys - verify the tokens signed by the current key and the rotated keys
+* every key is identified by its kid - the JWK thumbprint (RFC 7638) of its public key
+ */
+type KeyRing struct {
+	keyType          string
+	signingKeyID     string
+	signingKey       crypto.Signer
+	verificationKeys map[string]crypto.PublicKey
+}
+
+/*
+* NewKeyRing - creates a new key ring from the signing key and the public keys of the rotated keys
+* the public key of the signing key is always a verification key
+* all the keys must be of the same type - ed25519 or rsa (at least 2048 bits)
+ */
+func NewKeyRing(signingKey crypto.Signer, verificationKeys ...crypto.PublicKey) (*KeyRing, error) {
+	keyType, err := publicKeyType(signingKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	ring := &KeyRing{
+		keyType:          keyType,
+		signingKey:       signingKey,
+		verificationKeys: make(map[string]crypto.PublicKey),
+	}
+
+	for _, publicKey := range append([]crypto.PublicKey{signingKey.Public()}, verificationKeys...) {
+		currentType, err := publicKeyType(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if currentType != keyType {
+			return nil, fmt.Errorf("mixed key types: %s and %s keys", keyType, currentType)
+		}
+
+		kid, err := KeyID(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		ring.verificationKeys[kid] = publicKey
+	}
+
+	ring.signingKeyID, err = KeyID(signingKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+/*
+* LoadKeyRing - loads the key ring from PEM files
+* signingKeyFile: PKCS #8 private key (e.g. openssl genpkey -algorithm ed25519)
+* verificationKeyFiles: PKIX public keys (or private keys) of the rotated keys
+* rotating the signing key:
+* I) add the public key of the new key to the verification keys of all the instances
+* II) sign with the new key and move the public key of the old key to the verification keys
+* III) drop the old key once the tokens signed with it have expired
+ */
+func LoadKeyRing(signingKeyFile string, verificationKeyFiles []string) (*KeyRing, error) {
+	block, err := readPEMFile(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	signingKey, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key %s: %w", signingKeyFile, err)
+	}
+
+	verificationKeys := make([]crypto.PublicKey, 0, len(verificationKeyFiles))
+	for _, file := range verificationKeyFiles {
+		block, err := readPEMFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var publicKey crypto.PublicKey
+		if block.Type == "PUBLIC KEY" {
+			publicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+		} else {
+			var privateKey crypto.Signer
+			privateKey, err = parsePrivateKey(block)
+			if privateKey != nil {
+				publicKey = privateKey.Public()
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid verification key %s: %w", file, err)
+		}
+		verificationKeys = append(verificationKeys, publicKey)
+	}
+
+	return NewKeyRing(signingKey, verificationKeys...)
+}
+
+// signer - returns the signing key and its kid
+func (ring *KeyRing) signer() (string, crypto.Signer) {
+	return ring.signingKeyID, ring.signingKey
+}
+
+// verificationKey - returns the verification key of the kid
+func (ring *KeyRing) verificationKey(kid string) (crypto.PublicKey, bool) {
+	key, ok := ring.verificationKeys[kid]
+	return key, ok
+}
+
+// JWKS - returns the verification keys as a JSON Web Key Set - the signing key first
+func (ring *KeyRing) JWKS() JSONWebKeySet {
+	kids := make([]string, 0, len(ring.verificationKeys))
+	for kid := range ring.verificationKeys {
+		if kid != ring.signingKeyID {
+			kids = append(kids, kid)
+		}
+	}
+	sort.Strings(kids)
+	kids = append([]string{ring.signingKeyID}, kids...)
+
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(kids))}
+	for _, kid := range kids {
+		set.Keys = append(set.Keys, newJSONWebKey(kid, ring.verificationKeys[kid]))
+	}
+	return set
+}
+
+// KeyID - returns the kid of the public key - its JWK thumbprint (RFC 7638)
+func KeyID(publicKey crypto.PublicKey) (string, error) {
+	// the required members of the key in lexicographic order
+	var members string
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		members = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, encodeSegment(key))
+	case *rsa.PublicKey:
+		members = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+			encodeSegment(big.NewInt(int64(key.E)).Bytes()), encodeSegment(key.N.Bytes()))
+	default:
+		return "", fmt.Errorf("unsupported key type %T", publicKey)
+	}
+
+	sum := sha256.Sum256([]byte(members))
+	return encodeSegment(sum[:]), nil
+}
+
+// publicKeyType - returns the type of the public key - only ed25519 and rsa keys are supported
+func publicKeyType(publicKey crypto.PublicKey) (string, error) {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		return keyTypeOKP, nil
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeySize {
+			return "", fmt.Errorf("invalid key size: rsa keys must be at least %d bits", minRSAKeySize)
+		}
+		return keyTypeRSA, nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+// readPEMFile - reads the first PEM block of the file
+func readPEMFile(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", file)
+	}
+	return block, nil
+}
+
+// parsePrivateKey - parses a PKCS #8 private key
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
+// encodeSegment - base64url encoding without padding
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
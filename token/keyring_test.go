@@ -0,0 +1,139 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// randomEd25519Key - generates a new ed25519 key
+func randomEd25519Key(t *testing.T) ed25519.PrivateKey {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return privateKey
+}
+
+// randomRSAKey - generates a new rsa key
+func randomRSAKey(t *testing.T) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, minRSAKeySize)
+	require.NoError(t, err)
+	return privateKey
+}
+
+// writePrivateKeyFile - writes the private key as a PKCS #8 PEM file
+func writePrivateKeyFile(t *testing.T, privateKey crypto.Signer) string {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "private.pem")
+	err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+	return file
+}
+
+// writePublicKeyFile - writes the public key as a PKIX PEM file
+func writePublicKeyFile(t *testing.T, publicKey crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "public.pem")
+	err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+	require.NoError(t, err)
+	return file
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	signingKey := randomEd25519Key(t)
+	rotatedKey := randomEd25519Key(t)
+	nextKey := randomEd25519Key(t)
+
+	keys, err := LoadKeyRing(writePrivateKeyFile(t, signingKey), []string{
+		writePublicKeyFile(t, rotatedKey.Public()),
+		writePrivateKeyFile(t, nextKey),
+	})
+	require.NoError(t, err)
+
+	signingKeyID, err := KeyID(signingKey.Public())
+	require.NoError(t, err)
+	kid, signer := keys.signer()
+	require.Equal(t, signingKeyID, kid)
+	require.Equal(t, signingKey, signer)
+
+	for _, privateKey := range []ed25519.PrivateKey{signingKey, rotatedKey, nextKey} {
+		kid, err := KeyID(privateKey.Public())
+		require.NoError(t, err)
+
+		publicKey, ok := keys.verificationKey(kid)
+		require.True(t, ok)
+		require.Equal(t, privateKey.Public(), publicKey)
+	}
+
+	// the signing key is the first key of the set
+	jwks := keys.JWKS()
+	require.Len(t, jwks.Keys, 3)
+	require.Equal(t, signingKeyID, jwks.Keys[0].KeyID)
+	for _, jwk := range jwks.Keys {
+		require.Equal(t, "OKP", jwk.KeyType)
+		require.Equal(t, "Ed25519", jwk.Curve)
+		require.Equal(t, "EdDSA", jwk.Algorithm)
+		require.Equal(t, "sig", jwk.Use)
+		require.NotEmpty(t, jwk.X)
+	}
+}
+
+func TestLoadKeyRingInvalidFiles(t *testing.T) {
+	_, err := LoadKeyRing(filepath.Join(t.TempDir(), "missing.pem"), nil)
+	require.Error(t, err)
+
+	notPEM := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a key"), 0600))
+	_, err = LoadKeyRing(notPEM, nil)
+	require.Error(t, err)
+
+	// the public key can't sign
+	publicKeyFile := writePublicKeyFile(t, randomEd25519Key(t).Public())
+	_, err = LoadKeyRing(publicKeyFile, nil)
+	require.Error(t, err)
+}
+
+func TestNewKeyRingMixedKeyTypes(t *testing.T) {
+	_, err := NewKeyRing(randomEd25519Key(t), randomRSAKey(t).Public())
+	require.Error(t, err)
+}
+
+func TestNewKeyRingRSA(t *testing.T) {
+	signingKey := randomRSAKey(t)
+
+	keys, err := NewKeyRing(signingKey)
+	require.NoError(t, err)
+
+	jwks := keys.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	require.Equal(t, "RSA", jwks.Keys[0].KeyType)
+	require.Equal(t, "RS256", jwks.Keys[0].Algorithm)
+	require.Equal(t, "AQAB", jwks.Keys[0].E)
+	require.NotEmpty(t, jwks.Keys[0].N)
+}
+
+// TestKeyID - the thumbprint example of RFC 7638 section 3.1
+func TestKeyID(t *testing.T) {
+	n := "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+	modulus, err := base64.RawURLEncoding.DecodeString(n)
+	require.NoError(t, err)
+
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: 65537}
+
+	kid, err := KeyID(publicKey)
+	require.NoError(t, err)
+	require.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", kid)
+}
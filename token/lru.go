@@ -0,0 +1,75 @@
+package token
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache - a fixed size cache evicting the least recently used entry, safe for concurrent use
+type lruCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[K]*list.Element
+}
+
+// lruEntry - the value stored in the list elements
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// newLRUCache - creates a new cache holding up to size entries
+func newLRUCache[K comparable, V any](size int) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[K]*list.Element),
+	}
+}
+
+// get - returns the cached value of the key and marks it as recently used
+func (cache *lruCache[K, V]) get(key K) (V, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	cache.order.MoveToFront(element)
+	return element.Value.(*lruEntry[K, V]).value, true
+}
+
+// add - caches the value of the key, evicting the least recently used entry when the cache is full
+func (cache *lruCache[K, V]) add(key K, value V) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if element, ok := cache.entries[key]; ok {
+		element.Value.(*lruEntry[K, V]).value = value
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+	if cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+// removeFunc - removes every entry the given function returns true for
+func (cache *lruCache[K, V]) removeFunc(fn func(key K, value V) bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	for key, element := range cache.entries {
+		if fn(key, element.Value.(*lruEntry[K, V]).value) {
+			cache.order.Remove(element)
+			delete(cache.entries, key)
+		}
+	}
+}
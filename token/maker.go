@@ -4,19 +4,25 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/shimon-git/simple-bank/util"
 )
 
 const (
 	JWT    = "JWT"
 	PASETO = "PASETO"
+	// the asymmetric token types - signed and verified with the keys of a key ring
+	JWTEdDSA     = "JWT_EDDSA"
+	JWTRS256     = "JWT_RS256"
+	PASETOPublic = "PASETO_V4_PUBLIC"
 )
 
 // Maker is an interface for managing token
 type Maker interface {
-	// CreateToken - creates token for specific username and duration
-	CreateToken(username string, duration time.Duration) (string, error)
-	// VerifyToken - verify the token validation
-	VerifyToken(token string) (*Payload, error)
+	// CreateToken - creates token of the given type for specific username, role and duration, returns the token + its payload
+	CreateToken(username string, role string, duration time.Duration, tokenType TokenType) (string, *Payload, error)
+	// VerifyToken - verify the token validation and its type
+	VerifyToken(token string, tokenType TokenType) (*Payload, error)
 }
 
 // this function generate a maker for the given token type and signed it with the secret key
@@ -30,3 +36,47 @@ func CreateNewToken(tokenType, secretKey string) (Maker, error) {
 		return nil, fmt.Errorf("%s is unknown token type", tokenType)
 	}
 }
+
+// IsAsymmetric - checks if the token type is signed with the keys of a key ring
+func IsAsymmetric(tokenType string) bool {
+	switch strings.ToUpper(tokenType) {
+	case JWTEdDSA, JWTRS256, PASETOPublic:
+		return true
+	default:
+		return false
+	}
+}
+
+// this function generate an asymmetric maker for the given token type and signed it with the signing key of the key ring
+func CreateNewAsymmetricToken(tokenType string, keys *KeyRing) (Maker, error) {
+	switch strings.ToUpper(tokenType) {
+	case JWTEdDSA:
+		return NewEdDSAJwtMaker(keys)
+	case JWTRS256:
+		return NewRS256JwtMaker(keys)
+	case PASETOPublic:
+		return NewPasetoPublicMaker(keys)
+	default:
+		return nil, fmt.Errorf("%s is unknown asymmetric token type", tokenType)
+	}
+}
+
+/*
+* NewMakerFromConfig - creates the token maker of the configured token type
+* the symmetric token types are signed with the symmetric key
+* the asymmetric token types are signed with the key ring loaded from the configured key files (the key ring is nil otherwise)
+ */
+func NewMakerFromConfig(config util.Config) (Maker, *KeyRing, error) {
+	if !IsAsymmetric(config.TokenType) {
+		maker, err := CreateNewToken(config.TokenType, config.TokenSymmetricKey)
+		return maker, nil, err
+	}
+
+	keys, err := LoadKeyRing(config.TokenSigningKeyFile, config.TokenVerificationKeyFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	maker, err := CreateNewAsymmetricToken(config.TokenType, keys)
+	return maker, keys, err
+}
@@ -27,19 +27,20 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 
 }
 
-// CreateToken - creates token for specific username and duration
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
+// CreateToken - creates token of the given type for specific username, role and duration
+func (maker *PasetoMaker) CreateToken(username string, role string, duration time.Duration, tokenType TokenType) (string, *Payload, error) {
 	// crating a new payload token with the given user and duration
-	payload, err := NewPayload(username, duration)
+	payload, err := NewPayload(username, role, duration, tokenType)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 	// returning the signed token
-	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	return token, payload, err
 }
 
-// VerifyToken - verify the token validation
-func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
+// VerifyToken - verify the token validation and its type
+func (maker *PasetoMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
 	// decrypting the payload token into the payload var
 	payload := &Payload{}
 	err := maker.paseto.Decrypt(token, maker.symmetricKey, payload, nil)
@@ -47,11 +48,14 @@ func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
-	// validating the payload
+	// validating the payload and its type
 	err = payload.Valid()
 	if err != nil {
 		return nil, err
 	}
+	if err = payload.verifyType(tokenType); err != nil {
+		return nil, err
+	}
 
 	return payload, nil
 
@@ -0,0 +1,105 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+// PasetoPublicMaker is a v4.public paseto token maker signing with the signing key of the key ring
+type PasetoPublicMaker struct {
+	keys *KeyRing
+}
+
+// pasetoFooter - the footer of the token - the kid of the signing key
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// NewPasetoPublicMaker - creates a new PasetoPublicMaker, v4.public requires ed25519 keys
+func NewPasetoPublicMaker(keys *KeyRing) (Maker, error) {
+	if keys.keyType != keyTypeOKP {
+		return nil, fmt.Errorf("invalid key type: v4.public requires %s keys", keyTypeOKP)
+	}
+
+	return &PasetoPublicMaker{keys}, nil
+}
+
+// CreateToken - creates token of the given type for specific username, role and duration
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, duration time.Duration, tokenType TokenType) (string, *Payload, error) {
+	// crating a new payload token with the given user and duration
+	payload, err := NewPayload(username, role, duration, tokenType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	kid, signingKey := maker.keys.signer()
+	claims, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, err
+	}
+	footer, err := json.Marshal(pasetoFooter{KeyID: kid})
+	if err != nil {
+		return "", nil, err
+	}
+
+	pasetoToken, err := paseto.NewTokenFromClaimsJSON(claims, footer)
+	if err != nil {
+		return "", nil, err
+	}
+	secretKey, err := paseto.NewV4AsymmetricSecretKeyFromEd25519(signingKey.(ed25519.PrivateKey))
+	if err != nil {
+		return "", nil, err
+	}
+
+	// returning the signed token
+	return pasetoToken.V4Sign(secretKey, nil), payload, nil
+}
+
+// VerifyToken - verify the token validation and its type
+func (maker *PasetoPublicMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
+	// the expiration is validated by the payload
+	parser := paseto.NewParserWithoutExpiryCheck()
+
+	// finding the verification key through the (not yet verified) footer kid
+	rawFooter, err := parser.UnsafeParseFooter(paseto.V4Public, token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var footer pasetoFooter
+	if err = json.Unmarshal(rawFooter, &footer); err != nil {
+		return nil, ErrInvalidToken
+	}
+	key, ok := maker.keys.verificationKey(footer.KeyID)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	publicKey, err := paseto.NewV4AsymmetricPublicKeyFromEd25519(key.(ed25519.PublicKey))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// verifying the signature and extracting the payload
+	pasetoToken, err := parser.ParseV4Public(publicKey, token, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	payload := &Payload{}
+	if err = json.Unmarshal(pasetoToken.ClaimsJSON(), payload); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// validating the payload and its type
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+	if err = payload.verifyType(tokenType); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
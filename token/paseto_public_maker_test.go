@@ -0,0 +1,96 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasetoPublicMaker(t *testing.T) {
+	keys, err := NewKeyRing(randomEd25519Key(t))
+	require.NoError(t, err)
+
+	maker, err := NewPasetoPublicMaker(keys)
+	require.NoError(t, err)
+	require.NotEmpty(t, maker)
+
+	username := util.RandomString(6)
+	role := util.RandomRole()
+	duration := time.Minute
+	issuedAt := time.Now()
+	expiredAt := time.Now().Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, duration, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+	require.Contains(t, token, "v4.public.")
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.NotEmpty(t, payload.Scopes)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	keys, err := NewKeyRing(randomEd25519Key(t))
+	require.NoError(t, err)
+
+	maker, err := NewPasetoPublicMaker(keys)
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(util.RandomString(6), util.RandomRole(), -time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestPasetoPublicKeyRotation(t *testing.T) {
+	oldKey := randomEd25519Key(t)
+
+	oldKeys, err := NewKeyRing(oldKey)
+	require.NoError(t, err)
+	oldMaker, err := NewPasetoPublicMaker(oldKeys)
+	require.NoError(t, err)
+
+	token, _, err := oldMaker.CreateToken(util.RandomString(6), util.RandomRole(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// the old key is still a verification key
+	rotatedKeys, err := NewKeyRing(randomEd25519Key(t), oldKey.Public())
+	require.NoError(t, err)
+	rotatedMaker, err := NewPasetoPublicMaker(rotatedKeys)
+	require.NoError(t, err)
+
+	payload, err := rotatedMaker.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	// an unknown key can't verify the token
+	otherKeys, err := NewKeyRing(randomEd25519Key(t))
+	require.NoError(t, err)
+	otherMaker, err := NewPasetoPublicMaker(otherKeys)
+	require.NoError(t, err)
+
+	payload, err = otherMaker.VerifyToken(token, TokenTypeAccessToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	// rsa keys can't be used for v4.public
+	rsaKeys, err := NewKeyRing(randomRSAKey(t))
+	require.NoError(t, err)
+	_, err = NewPasetoPublicMaker(rsaKeys)
+	require.Error(t, err)
+}
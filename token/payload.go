@@ -13,16 +13,37 @@ var (
 	ErrInvalidToken = errors.New("invalid token")
 )
 
+// TokenType - what the token is used for - a token is accepted only where its type is expected
+type TokenType byte
+
+// the types of the tokens
+const (
+	// TokenTypeAccessToken - authorizes the requests
+	TokenTypeAccessToken TokenType = 1
+	// TokenTypeRefreshToken - only renews the access token of its session
+	TokenTypeRefreshToken TokenType = 2
+	// TokenTypeMFAToken - only completes a login with the second factor
+	TokenTypeMFAToken TokenType = 3
+)
+
 // Payload - contain the payload data of the token
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
+	Type      TokenType `json:"token_type"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Scopes    []string  `json:"scopes"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
-// NewPayload - creates a new token payload with the given username for the given duration
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// NewPayload - creates a new token payload of the given type with the given username and role (granting its scopes) for the given duration
+func NewPayload(username string, role string, duration time.Duration, tokenType TokenType) (*Payload, error) {
+	scopes, err := RoleScopes(role)
+	if err != nil {
+		return nil, err
+	}
+
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -30,7 +51,10 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 
 	return &Payload{
 		ID:        tokenID,
+		Type:      tokenType,
 		Username:  username,
+		Role:      role,
+		Scopes:    scopes,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}, nil
@@ -44,3 +68,12 @@ func (payload *Payload) Valid() error {
 
 	return nil
 }
+
+// verifyType - checks the token is of the expected type - e.g. a refresh token can't be used as an access token
+func (payload *Payload) verifyType(tokenType TokenType) error {
+	if payload.Type != tokenType {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
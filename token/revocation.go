@@ -0,0 +1,118 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrRevokedToken - the token was revoked before its expiration
+var ErrRevokedToken = errors.New("token has been revoked")
+
+/*
+* RevocationStore - where the revoked tokens are stored (e.g. postgres)
+* IsRevoked: true when the token was revoked by its id, by revoking the tokens of its user issued before issuedAt
+* or by changing the password of its user after issuedAt
+* Revoke: revokes the token until its expiration
+* RevokeUser: revokes every token of the user issued before revokedBefore and blocks the user sessions
+ */
+type RevocationStore interface {
+	IsRevoked(ctx context.Context, id uuid.UUID, username string, issuedAt time.Time) (bool, error)
+	Revoke(ctx context.Context, id uuid.UUID, username string, expiresAt time.Time) error
+	RevokeUser(ctx context.Context, username string, revokedBefore time.Time) error
+}
+
+/*
+* RevocationList - the revoked tokens, stored in the revocation store with an in-memory LRU cache in front
+* a revoked token stays cached until evicted
+* a valid token is cached for the cache TTL - revocations made by other instances
+* are noticed once the cached entry expires
+* a single list is shared by the HTTP and the gRPC servers of the instance - a revocation made by one of them is noticed by the other at once
+ */
+type RevocationList struct {
+	store RevocationStore
+	ttl   time.Duration
+	cache *lruCache[uuid.UUID, revocationEntry]
+}
+
+// revocationEntry - the cached revocation status of a token
+type revocationEntry struct {
+	username string
+	revoked  bool
+	cachedAt time.Time
+}
+
+// NewRevocationList - creates a new revocation list caching up to cacheSize tokens
+func NewRevocationList(store RevocationStore, cacheSize int, cacheTTL time.Duration) *RevocationList {
+	return &RevocationList{
+		store: store,
+		ttl:   cacheTTL,
+		cache: newLRUCache[uuid.UUID, revocationEntry](cacheSize),
+	}
+}
+
+/*
+* IsRevoked - returns true if the token was revoked
+* a token is revoked by its id, by revoking every token of its user issued before a given time
+* or by changing the password of its user after the token was issued
+ */
+func (list *RevocationList) IsRevoked(ctx context.Context, payload *Payload) (bool, error) {
+	if entry, ok := list.cache.get(payload.ID); ok {
+		if entry.revoked || time.Since(entry.cachedAt) < list.ttl {
+			return entry.revoked, nil
+		}
+	}
+
+	revoked, err := list.store.IsRevoked(ctx, payload.ID, payload.Username, payload.IssuedAt)
+	if err != nil {
+		return false, err
+	}
+
+	list.cache.add(payload.ID, revocationEntry{
+		username: payload.Username,
+		revoked:  revoked,
+		cachedAt: time.Now(),
+	})
+	return revoked, nil
+}
+
+// Revoke - revokes the given token until its expiration
+func (list *RevocationList) Revoke(ctx context.Context, payload *Payload) error {
+	err := list.store.Revoke(ctx, payload.ID, payload.Username, payload.ExpiredAt)
+	if err != nil {
+		return err
+	}
+
+	list.cache.add(payload.ID, revocationEntry{
+		username: payload.Username,
+		revoked:  true,
+		cachedAt: time.Now(),
+	})
+	return nil
+}
+
+/*
+* RevokeUser - revokes every token issued to the user so far and blocks the user sessions
+* the cached tokens of the user are dropped - so they are checked again
+ */
+func (list *RevocationList) RevokeUser(ctx context.Context, username string) error {
+	err := list.store.RevokeUser(ctx, username, time.Now())
+	if err != nil {
+		return err
+	}
+
+	list.ForgetUser(username)
+	return nil
+}
+
+/*
+* ForgetUser - drops the cached tokens of the user - so they are checked again on their next use
+* e.g. after the password of the user was changed
+ */
+func (list *RevocationList) ForgetUser(username string) {
+	list.cache.removeFunc(func(_ uuid.UUID, entry revocationEntry) bool {
+		return entry.username == username
+	})
+}
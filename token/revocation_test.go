@@ -0,0 +1,195 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mocktoken "github.com/shimon-git/simple-bank/token/mock"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationListCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mocktoken.NewMockRevocationStore(ctrl)
+
+	payload, err := NewPayload(util.RandomOwner(), util.RandomRole(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// the store is checked once - the result is cached for the ttl
+	store.EXPECT().
+		IsRevoked(gomock.Any(), gomock.Eq(payload.ID), gomock.Eq(payload.Username), gomock.Eq(payload.IssuedAt)).
+		Times(1).
+		Return(false, nil)
+
+	list := NewRevocationList(store, 10, time.Minute)
+	for i := 0; i < 3; i++ {
+		revoked, err := list.IsRevoked(context.Background(), payload)
+		require.NoError(t, err)
+		require.False(t, revoked)
+	}
+
+	// a revoked token is cached as revoked
+	store.EXPECT().
+		Revoke(gomock.Any(), gomock.Eq(payload.ID), gomock.Eq(payload.Username), gomock.Eq(payload.ExpiredAt)).
+		Times(1).
+		Return(nil)
+
+	err = list.Revoke(context.Background(), payload)
+	require.NoError(t, err)
+
+	revoked, err := list.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestRevocationListExpiredCacheEntry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mocktoken.NewMockRevocationStore(ctrl)
+
+	payload, err := NewPayload(util.RandomOwner(), util.RandomRole(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// the token is revoked by another instance after the first check
+	gomock.InOrder(
+		store.EXPECT().IsRevoked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(false, nil),
+		store.EXPECT().IsRevoked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(true, nil),
+	)
+
+	list := NewRevocationList(store, 10, -time.Second)
+
+	revoked, err := list.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = list.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestRevocationListRevokeUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mocktoken.NewMockRevocationStore(ctrl)
+
+	username := util.RandomOwner()
+	payload, err := NewPayload(username, util.RandomRole(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	gomock.InOrder(
+		store.EXPECT().IsRevoked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(false, nil),
+		store.EXPECT().
+			RevokeUser(gomock.Any(), gomock.Eq(username), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, _ string, revokedBefore time.Time) error {
+				require.WithinDuration(t, time.Now(), revokedBefore, time.Second)
+				return nil
+			}),
+		// the cached token of the user is checked again
+		store.EXPECT().IsRevoked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(true, nil),
+	)
+
+	list := NewRevocationList(store, 10, time.Minute)
+
+	revoked, err := list.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = list.RevokeUser(context.Background(), username)
+	require.NoError(t, err)
+
+	revoked, err = list.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestRevocationListForgetUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mocktoken.NewMockRevocationStore(ctrl)
+
+	username := util.RandomOwner()
+	payload, err := NewPayload(username, util.RandomRole(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+	other, err := NewPayload(util.RandomOwner(), util.RandomRole(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	gomock.InOrder(
+		store.EXPECT().IsRevoked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2).Return(false, nil),
+		// e.g. the password of the user was changed - the cached token of the user is checked again
+		store.EXPECT().
+			IsRevoked(gomock.Any(), gomock.Eq(payload.ID), gomock.Eq(username), gomock.Eq(payload.IssuedAt)).
+			Times(1).
+			Return(true, nil),
+	)
+
+	list := NewRevocationList(store, 10, time.Minute)
+
+	for _, p := range []*Payload{payload, other} {
+		revoked, err := list.IsRevoked(context.Background(), p)
+		require.NoError(t, err)
+		require.False(t, revoked)
+	}
+
+	list.ForgetUser(username)
+
+	revoked, err := list.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// the tokens of the other users stay cached
+	revoked, err = list.IsRevoked(context.Background(), other)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestRevocationListStoreError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mocktoken.NewMockRevocationStore(ctrl)
+
+	payload, err := NewPayload(util.RandomOwner(), util.RandomRole(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// errors are not cached
+	store.EXPECT().
+		IsRevoked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(2).
+		Return(false, errors.New("connection refused"))
+
+	list := NewRevocationList(store, 10, time.Minute)
+	for i := 0; i < 2; i++ {
+		_, err = list.IsRevoked(context.Background(), payload)
+		require.Error(t, err)
+	}
+}
+
+func TestLRUCacheEviction(t *testing.T) {
+	cache := newLRUCache[int, string](2)
+	cache.add(1, "one")
+	cache.add(2, "two")
+
+	// using 1 - so 2 is the least recently used entry
+	value, ok := cache.get(1)
+	require.True(t, ok)
+	require.Equal(t, "one", value)
+
+	cache.add(3, "three")
+	_, ok = cache.get(2)
+	require.False(t, ok)
+	_, ok = cache.get(1)
+	require.True(t, ok)
+	_, ok = cache.get(3)
+	require.True(t, ok)
+
+	cache.removeFunc(func(key int, _ string) bool {
+		return key == 1
+	})
+	_, ok = cache.get(1)
+	require.False(t, ok)
+}
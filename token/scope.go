@@ -0,0 +1,56 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/shimon-git/simple-bank/util"
+)
+
+// the scopes of the tokens - granted by the role of the user
+const (
+	// ScopeAccountsRead - reading the accounts of the user
+	ScopeAccountsRead = "accounts:read"
+	// ScopeAccountsWrite - creating accounts and moving money from the accounts of the user
+	ScopeAccountsWrite = "accounts:write"
+	// ScopeAccountsReadAll - reading any account
+	ScopeAccountsReadAll = "accounts:read_all"
+	// ScopeBalancesAdjust - posting manual adjustments to any account
+	ScopeBalancesAdjust = "balances:adjust"
+	// ScopeBackOffice - using the back-office (searching users, freezing and closing accounts)
+	ScopeBackOffice = "backoffice"
+	// ScopeMFAVerify - completing a login with the second factor, the only scope of the mfa pending tokens
+	ScopeMFAVerify = "mfa:verify"
+)
+
+/*
+* MFAPendingRole - the role of the mfa pending tokens
+* issued after the password check of a user with two factor authentication - it can't authorize other requests
+ */
+const MFAPendingRole = "mfa_pending"
+
+// roleScopes - the scopes granted to every role
+var roleScopes = map[string][]string{
+	util.CustomerRole: {ScopeAccountsRead, ScopeAccountsWrite},
+	util.BankerRole:   {ScopeAccountsRead, ScopeAccountsWrite, ScopeAccountsReadAll},
+	util.AdminRole:    {ScopeAccountsRead, ScopeAccountsWrite, ScopeAccountsReadAll, ScopeBalancesAdjust, ScopeBackOffice},
+	MFAPendingRole:    {ScopeMFAVerify},
+}
+
+// RoleScopes - returns the scopes granted to the role
+func RoleScopes(role string) ([]string, error) {
+	scopes, ok := roleScopes[role]
+	if !ok {
+		return nil, fmt.Errorf("%s is unknown role", role)
+	}
+	return append([]string(nil), scopes...), nil
+}
+
+// HasScope - checks if the token grants the scope
+func (payload *Payload) HasScope(scope string) bool {
+	for _, granted := range payload.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleScopes(t *testing.T) {
+	testCases := []struct {
+		role    string
+		granted []string
+		denied  []string
+	}{
+		{
+			role:    util.CustomerRole,
+			granted: []string{ScopeAccountsRead, ScopeAccountsWrite},
+			denied:  []string{ScopeAccountsReadAll, ScopeBalancesAdjust, ScopeBackOffice},
+		},
+		{
+			role:    util.BankerRole,
+			granted: []string{ScopeAccountsRead, ScopeAccountsWrite, ScopeAccountsReadAll},
+			denied:  []string{ScopeBalancesAdjust, ScopeBackOffice},
+		},
+		{
+			role:    util.AdminRole,
+			granted: []string{ScopeAccountsRead, ScopeAccountsWrite, ScopeAccountsReadAll, ScopeBalancesAdjust, ScopeBackOffice},
+			denied:  []string{ScopeMFAVerify},
+		},
+		{
+			role:    MFAPendingRole,
+			granted: []string{ScopeMFAVerify},
+			denied:  []string{ScopeAccountsRead, ScopeAccountsWrite, ScopeAccountsReadAll, ScopeBalancesAdjust, ScopeBackOffice},
+		},
+	}
+
+	for _, test := range testCases {
+		payload, err := NewPayload(util.RandomOwner(), test.role, time.Minute, TokenTypeAccessToken)
+		require.NoError(t, err)
+		require.Equal(t, test.role, payload.Role)
+
+		for _, scope := range test.granted {
+			require.True(t, payload.HasScope(scope), "%s should have %s", test.role, scope)
+		}
+		for _, scope := range test.denied {
+			require.False(t, payload.HasScope(scope), "%s shouldn't have %s", test.role, scope)
+		}
+	}
+
+	_, err := NewPayload(util.RandomOwner(), "unknown", time.Minute, TokenTypeAccessToken)
+	require.Error(t, err)
+}
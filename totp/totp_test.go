@@ -0,0 +1,85 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// the SHA1 test vectors of RFC 6238 (appendix B) - truncated to 6 digits
+func TestCodeRFCVectors(t *testing.T) {
+	secret := secretEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(secret, Step(time.Unix(tc.unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, tc.code, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := Code(secret, Step(now))
+	require.NoError(t, err)
+
+	step, err := Validate(secret, code, now)
+	require.NoError(t, err)
+	require.Equal(t, Step(now), step)
+
+	// the code of the previous period is still accepted (clock drift)
+	step, err = Validate(secret, code, now.Add(Period))
+	require.NoError(t, err)
+	require.Equal(t, Step(now), step)
+
+	// older codes are rejected
+	_, err = Validate(secret, code, now.Add(2*Period))
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	_, err = Validate(secret, "12345", now)
+	require.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret1, err := GenerateSecret()
+	require.NoError(t, err)
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret1)
+	require.NoError(t, err)
+	require.Len(t, key, secretSize)
+
+	secret2, err := GenerateSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, secret1, secret2)
+
+	_, err = Code("not base32!", 1)
+	require.Error(t, err)
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("Simple Bank", "user@example.com", "SECRET"))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/Simple Bank:user@example.com", uri.Path)
+	require.Equal(t, "SECRET", uri.Query().Get("secret"))
+	require.Equal(t, "Simple Bank", uri.Query().Get("issuer"))
+	require.Equal(t, "6", uri.Query().Get("digits"))
+	require.Equal(t, "30", uri.Query().Get("period"))
+}
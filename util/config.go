@@ -6,17 +6,87 @@ import (
 	"github.com/spf13/viper"
 )
 
+// the environments of the application - the production environment demands a real email sender
+const (
+	DevelopmentEnvironment = "development"
+	ProductionEnvironment  = "production"
+)
+
 /*
 * Config stores the all configuration off the application
 * The configurations are read by viper from a config file or env file
  */
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
+	Environment string `mapstructure:"ENVIRONMENT"`
+	DBSource    string `mapstructure:"DB_SOURCE"`
+	// the connection pool of the DB
+	DBMaxConns          int32         `mapstructure:"DB_MAX_CONNS"`
+	DBMinConns          int32         `mapstructure:"DB_MIN_CONNS"`
+	DBMaxConnLifetime   time.Duration `mapstructure:"DB_MAX_CONN_LIFETIME"`
+	DBMaxConnIdleTime   time.Duration `mapstructure:"DB_MAX_CONN_IDLE_TIME"`
+	DBHealthCheckPeriod time.Duration `mapstructure:"DB_HEALTH_CHECK_PERIOD"`
 	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
-	TokenType           string        `mapstructure:"TOKEN_TYPE"`
+	// the proxies (ips or cidrs, comma separated) trusted to set the client ip in X-Forwarded-For - none by default
+	TrustedProxies          []string      `mapstructure:"TRUSTED_PROXIES"`
+	GRPCServerAddress       string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	TokenSymmetricKey       string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	AccessTokenDuration     time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration    time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	RevocationCacheSize     int           `mapstructure:"REVOCATION_CACHE_SIZE"`
+	RevocationCacheTTL      time.Duration `mapstructure:"REVOCATION_CACHE_TTL"`
+	RevocationPruneInterval time.Duration `mapstructure:"REVOCATION_PRUNE_INTERVAL"`
+	TokenType               string        `mapstructure:"TOKEN_TYPE"`
+	// the keys of the asymmetric token types - the verification key files are comma separated
+	TokenSigningKeyFile       string        `mapstructure:"TOKEN_SIGNING_KEY_FILE"`
+	TokenVerificationKeyFiles []string      `mapstructure:"TOKEN_VERIFICATION_KEY_FILES"`
+	FXRatesFile               string        `mapstructure:"FX_RATES_FILE"`
+	FXQuoteDuration           time.Duration `mapstructure:"FX_QUOTE_DURATION"`
+	HoldDuration              time.Duration `mapstructure:"HOLD_DURATION"`
+	SchedulerInterval         time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	SchedulerBatchSize        int32         `mapstructure:"SCHEDULER_BATCH_SIZE"`
+	SchedulerMaxAttempts      int32         `mapstructure:"SCHEDULER_MAX_ATTEMPTS"`
+	SchedulerRetryBackoff     time.Duration `mapstructure:"SCHEDULER_RETRY_BACKOFF"`
+	BatchTransferMaxLegs      int           `mapstructure:"BATCH_TRANSFER_MAX_LEGS"`
+	BalanceSnapshotInterval   time.Duration `mapstructure:"BALANCE_SNAPSHOT_INTERVAL"`
+	ReconcileInterval         time.Duration `mapstructure:"RECONCILE_INTERVAL"`
+	ReconcileReportFile       string        `mapstructure:"RECONCILE_REPORT_FILE"`
+	// the audit events are chained by a single appender - every interval, a batch at a time
+	AuditChainInterval  time.Duration `mapstructure:"AUDIT_CHAIN_INTERVAL"`
+	AuditChainBatchSize int32         `mapstructure:"AUDIT_CHAIN_BATCH_SIZE"`
+	// the sender of the emails - smtp when the host is set, otherwise the emails are written to the outbox dir (or kept in memory)
+	// smtp is required in the production environment
+	EmailSenderName    string `mapstructure:"EMAIL_SENDER_NAME"`
+	EmailSenderAddress string `mapstructure:"EMAIL_SENDER_ADDRESS"`
+	SMTPHost           string `mapstructure:"SMTP_HOST"`
+	SMTPPort           int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername       string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword       string `mapstructure:"SMTP_PASSWORD"`
+	EmailOutboxDir     string `mapstructure:"EMAIL_OUTBOX_DIR"`
+	// the link sent to the new users - the id and the code of the verify email are added as query params
+	VerifyEmailURL      string        `mapstructure:"VERIFY_EMAIL_URL"`
+	VerifyEmailDuration time.Duration `mapstructure:"VERIFY_EMAIL_DURATION"`
+	// the link sent for resetting a forgotten password - the reset token is added as a query param
+	PasswordResetURL      string        `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetDuration time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+	// the throttling of the password reset requests - per email and per client ip, in every window
+	PasswordResetMaxRequests   int32         `mapstructure:"PASSWORD_RESET_MAX_REQUESTS"`
+	PasswordResetIPMaxRequests int32         `mapstructure:"PASSWORD_RESET_IP_MAX_REQUESTS"`
+	PasswordResetRequestWindow time.Duration `mapstructure:"PASSWORD_RESET_REQUEST_WINDOW"`
+	// the two factor authentication - the issuer shown by the authenticator apps and the lifetime of the mfa pending tokens
+	MFAIssuer        string        `mapstructure:"MFA_ISSUER"`
+	MFATokenDuration time.Duration `mapstructure:"MFA_TOKEN_DURATION"`
+	// transfers above this amount demand a fresh second factor, 0 disables the check
+	MFATransferThreshold int64 `mapstructure:"MFA_TRANSFER_THRESHOLD"`
+	// the throttling of the logins - per username (exponential back-off, then a lockout) and per client ip (a lockout)
+	// every attempt is counted before the password is verified, a login forgets the attempts of the username
+	LoginBackoffAfter    int32         `mapstructure:"LOGIN_BACKOFF_AFTER"`
+	LoginBackoff         time.Duration `mapstructure:"LOGIN_BACKOFF"`
+	LoginMaxAttempts     int32         `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginIPMaxAttempts   int32         `mapstructure:"LOGIN_IP_MAX_ATTEMPTS"`
+	LoginLockoutDuration time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginAttemptWindow   time.Duration `mapstructure:"LOGIN_ATTEMPT_WINDOW"`
+	// blocking the transfers of the users until their email is verified
+	RequireVerifiedEmail bool `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
 }
 
 // LoadConfig - reads the conf file ot the env file
@@ -27,6 +97,50 @@ func LoadConfig(path string) (config Config, err error) {
 	viper.SetConfigName(".app")
 	// setting the config type as enf file
 	viper.SetConfigType("env")
+	// default values for optional configurations
+	viper.SetDefault("ENVIRONMENT", DevelopmentEnvironment)
+	// the unset pool settings are left to the DB source (e.g. pool_max_conns) or to the pgx defaults
+	viper.SetDefault("DB_MAX_CONNS", 0)
+	viper.SetDefault("DB_MIN_CONNS", 0)
+	viper.SetDefault("DB_MAX_CONN_LIFETIME", 0)
+	viper.SetDefault("DB_MAX_CONN_IDLE_TIME", 0)
+	viper.SetDefault("DB_HEALTH_CHECK_PERIOD", 0)
+	viper.SetDefault("GRPC_SERVER_ADDRESS", "0.0.0.0:9090")
+	viper.SetDefault("REFRESH_TOKEN_DURATION", "24h")
+	viper.SetDefault("REVOCATION_CACHE_SIZE", 10000)
+	viper.SetDefault("REVOCATION_CACHE_TTL", "30s")
+	viper.SetDefault("REVOCATION_PRUNE_INTERVAL", "1h")
+	viper.SetDefault("FX_QUOTE_DURATION", "30s")
+	viper.SetDefault("HOLD_DURATION", "168h")
+	viper.SetDefault("SCHEDULER_INTERVAL", "1m")
+	viper.SetDefault("SCHEDULER_BATCH_SIZE", 10)
+	viper.SetDefault("SCHEDULER_MAX_ATTEMPTS", 5)
+	viper.SetDefault("SCHEDULER_RETRY_BACKOFF", "1m")
+	viper.SetDefault("BATCH_TRANSFER_MAX_LEGS", 500)
+	viper.SetDefault("BALANCE_SNAPSHOT_INTERVAL", "1h")
+	viper.SetDefault("RECONCILE_INTERVAL", "24h")
+	viper.SetDefault("AUDIT_CHAIN_INTERVAL", "1s")
+	viper.SetDefault("AUDIT_CHAIN_BATCH_SIZE", 1000)
+	viper.SetDefault("EMAIL_SENDER_NAME", "Simple Bank")
+	viper.SetDefault("EMAIL_SENDER_ADDRESS", "no-reply@simplebank.local")
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("VERIFY_EMAIL_URL", "http://localhost:8080/verify_email")
+	viper.SetDefault("VERIFY_EMAIL_DURATION", "15m")
+	viper.SetDefault("REQUIRE_VERIFIED_EMAIL", false)
+	viper.SetDefault("PASSWORD_RESET_URL", "http://localhost:8080/reset_password")
+	viper.SetDefault("PASSWORD_RESET_DURATION", "15m")
+	viper.SetDefault("PASSWORD_RESET_MAX_REQUESTS", 3)
+	viper.SetDefault("PASSWORD_RESET_IP_MAX_REQUESTS", 20)
+	viper.SetDefault("PASSWORD_RESET_REQUEST_WINDOW", "1h")
+	viper.SetDefault("MFA_ISSUER", "Simple Bank")
+	viper.SetDefault("MFA_TOKEN_DURATION", "5m")
+	viper.SetDefault("MFA_TRANSFER_THRESHOLD", 0)
+	viper.SetDefault("LOGIN_BACKOFF_AFTER", 3)
+	viper.SetDefault("LOGIN_BACKOFF", "1s")
+	viper.SetDefault("LOGIN_MAX_ATTEMPTS", 10)
+	viper.SetDefault("LOGIN_IP_MAX_ATTEMPTS", 100)
+	viper.SetDefault("LOGIN_LOCKOUT_DURATION", "15m")
+	viper.SetDefault("LOGIN_ATTEMPT_WINDOW", "15m")
 	// overwrite environment variables from the file with corresponding values if they already exist.
 	viper.AutomaticEnv()
 	// read the configurations from the disk
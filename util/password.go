@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"sync"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -21,3 +22,15 @@ func HashedPassword(password string) (string, error) {
 func CheckPassword(password, hashPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashPassword), []byte(password))
 }
+
+/*
+* DummyPasswordHash - a hash checked against the password of an unknown user
+* so the login of an unknown user takes as long as the login of a known user with an incorrect password
+ */
+var DummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := HashedPassword("dummy-password")
+	if err != nil {
+		panic(fmt.Sprintf("cannot hash the dummy password: %v", err))
+	}
+	return hash
+})
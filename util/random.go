@@ -67,3 +67,10 @@ func RandomTokenType() string {
 
 	return suuportedTokens[RandomInt(0, int64(len(suuportedTokens)-1))]
 }
+
+// RandomRole - return random user role
+func RandomRole() string {
+	roles := []string{CustomerRole, BankerRole, AdminRole}
+
+	return roles[rand.Intn(len(roles))]
+}
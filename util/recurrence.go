@@ -0,0 +1,86 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Constants for all supported recurrence intervals
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+)
+
+// ErrInvalidRecurrence - the recurrence rule is not one of the supported rules
+var ErrInvalidRecurrence = errors.New("recurrence must be daily, weekly or monthly:<day of month 1-31>")
+
+/*
+* Recurrence - a rule for repeating an operation
+* Interval: daily, weekly or monthly
+* Day: the day of the month for monthly recurrences - short months use their last day
+ */
+type Recurrence struct {
+	Interval string
+	Day      int
+}
+
+/*
+* ParseRecurrence - parses a recurrence rule
+* supported rules: "daily", "weekly" and "monthly:<day>" (e.g. "monthly:1")
+ */
+func ParseRecurrence(rule string) (Recurrence, error) {
+	switch rule {
+	case Daily, Weekly:
+		return Recurrence{Interval: rule}, nil
+	}
+
+	interval, day, found := strings.Cut(rule, ":")
+	if !found || interval != Monthly {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+
+	n, err := strconv.Atoi(day)
+	if err != nil || n < 1 || n > 31 {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+	return Recurrence{Interval: Monthly, Day: n}, nil
+}
+
+// IsSupportedRecurrence - returns boolean if the given recurrence rule is supported or not
+func IsSupportedRecurrence(rule string) bool {
+	_, err := ParseRecurrence(rule)
+	return err == nil
+}
+
+// String - returns the recurrence rule
+func (r Recurrence) String() string {
+	if r.Interval == Monthly {
+		return fmt.Sprintf("%s:%d", Monthly, r.Day)
+	}
+	return r.Interval
+}
+
+// Next - returns the first occurrence after the given occurrence, keeping its time of the day
+func (r Recurrence) Next(t time.Time) time.Time {
+	switch r.Interval {
+	case Daily:
+		return t.AddDate(0, 0, 1)
+	case Weekly:
+		return t.AddDate(0, 0, 7)
+	}
+
+	// the first day of the next month - AddDate normalizes overflowing days (Jan 31 + 1 month = Mar 3)
+	year, month, _ := t.Date()
+	next := time.Date(year, month+1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+
+	// using the last day of the month when the month is shorter than the recurrence day
+	day := r.Day
+	if lastDay := next.AddDate(0, 1, -1).Day(); day > lastDay {
+		day = lastDay
+	}
+	return next.AddDate(0, 0, day-1)
+}
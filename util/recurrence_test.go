@@ -0,0 +1,50 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	for _, rule := range []string{"daily", "weekly", "monthly:1", "monthly:31"} {
+		recurrence, err := ParseRecurrence(rule)
+		require.NoError(t, err)
+		require.Equal(t, rule, recurrence.String())
+	}
+
+	for _, rule := range []string{"", "yearly", "monthly", "monthly:0", "monthly:32", "monthly:x", "daily:1"} {
+		_, err := ParseRecurrence(rule)
+		require.ErrorIs(t, err, ErrInvalidRecurrence)
+		require.False(t, IsSupportedRecurrence(rule))
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	start := time.Date(2023, time.January, 31, 9, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		rule     string
+		expected time.Time
+	}{
+		{rule: "daily", expected: time.Date(2023, time.February, 1, 9, 30, 0, 0, time.UTC)},
+		{rule: "weekly", expected: time.Date(2023, time.February, 7, 9, 30, 0, 0, time.UTC)},
+		{rule: "monthly:1", expected: time.Date(2023, time.February, 1, 9, 30, 0, 0, time.UTC)},
+		{rule: "monthly:15", expected: time.Date(2023, time.February, 15, 9, 30, 0, 0, time.UTC)},
+		// february is shorter than the recurrence day
+		{rule: "monthly:31", expected: time.Date(2023, time.February, 28, 9, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		recurrence, err := ParseRecurrence(tc.rule)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, recurrence.Next(start), tc.rule)
+	}
+
+	// a short month doesn't move the following occurrences
+	recurrence, err := ParseRecurrence("monthly:31")
+	require.NoError(t, err)
+	next := recurrence.Next(recurrence.Next(start))
+	require.Equal(t, time.Date(2023, time.March, 31, 9, 30, 0, 0, time.UTC), next)
+}
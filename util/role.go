@@ -0,0 +1,18 @@
+package util
+
+// Constants for all the user roles
+const (
+	CustomerRole = "customer"
+	BankerRole   = "banker"
+	AdminRole    = "admin"
+)
+
+// IsSupportedRole - returns boolean if the given role is supported or not
+func IsSupportedRole(role string) bool {
+	switch role {
+	case CustomerRole, BankerRole, AdminRole:
+		return true
+	default:
+		return false
+	}
+}
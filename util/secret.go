@@ -0,0 +1,32 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+/*
+* RandomSecret - generates a secret string of length n (e.g. the codes sent by email)
+* unlike RandomString the characters are read from crypto/rand - so the secret can't be predicted
+ */
+func RandomSecret(n int) (string, error) {
+	secret := make([]byte, n)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range secret {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate a secret: %w", err)
+		}
+		secret[i] = alphabet[idx.Int64()]
+	}
+	return string(secret), nil
+}
+
+// HashSecret - the sha256 (hex) of a secret sent to the user (e.g. the codes sent by email), only the hash is stored in the DB
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
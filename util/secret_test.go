@@ -0,0 +1,18 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomSecret(t *testing.T) {
+	secret1, err := RandomSecret(32)
+	require.NoError(t, err)
+	require.Len(t, secret1, 32)
+	require.Regexp(t, "^[a-z]+$", secret1)
+
+	secret2, err := RandomSecret(32)
+	require.NoError(t, err)
+	require.NotEqual(t, secret1, secret2)
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+)
+
+/*
+* AuditChainer - the single appender of the audit hash chain
+* the business transactions record their audit events unchained - the chainer chains them every interval
+* so the transactions never wait for each other on the audit log
+ */
+type AuditChainer struct {
+	store     db.Store
+	interval  time.Duration
+	batchSize int32
+}
+
+// NewAuditChainer - creates a new audit chainer from the audit chain configurations
+func NewAuditChainer(store db.Store, config util.Config) *AuditChainer {
+	return &AuditChainer{
+		store:     store,
+		interval:  config.AuditChainInterval,
+		batchSize: config.AuditChainBatchSize,
+	}
+}
+
+// Start - chaining the recorded events every interval until the context is done
+func (chainer *AuditChainer) Start(ctx context.Context) {
+	ticker := time.NewTicker(chainer.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := chainer.Chain(ctx); err != nil {
+			log.Printf("failed to chain the audit events: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+* Chain - chains every event recorded so far, a batch at a time
+* returns the number of the chained events
+ */
+func (chainer *AuditChainer) Chain(ctx context.Context) (int, error) {
+	var total int
+	for {
+		chained, err := chainer.store.ChainAuditEvents(ctx, chainer.batchSize)
+		total += chained
+		if err != nil {
+			return total, err
+		}
+		// a partial batch - no more events to chain
+		if chained < int(chainer.batchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditChainerChain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// full batches are followed by another batch - until a partial batch
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().ChainAuditEvents(gomock.Any(), gomock.Eq(int32(10))).Times(2).Return(10, nil),
+		store.EXPECT().ChainAuditEvents(gomock.Any(), gomock.Eq(int32(10))).Times(1).Return(3, nil),
+	)
+
+	chainer := NewAuditChainer(store, util.Config{
+		AuditChainInterval:  time.Second,
+		AuditChainBatchSize: 10,
+	})
+
+	chained, err := chainer.Chain(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 23, chained)
+}
+
+func TestAuditChainerChainError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().ChainAuditEvents(gomock.Any(), gomock.Any()).Times(1).Return(10, nil),
+		store.EXPECT().ChainAuditEvents(gomock.Any(), gomock.Any()).Times(1).Return(0, errors.New("connection refused")),
+	)
+
+	chainer := NewAuditChainer(store, util.Config{
+		AuditChainInterval:  time.Second,
+		AuditChainBatchSize: 10,
+	})
+
+	chained, err := chainer.Chain(context.Background())
+	require.Error(t, err)
+	require.Equal(t, 10, chained)
+}
@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+)
+
+/*
+* snapshotLag - entries newer than the lag are left for the next snapshot
+* a snapshot covers the entries up to an id, so an entry of a transaction still in flight
+* (with a smaller id than the committed ones) must not be skipped over
+ */
+const snapshotLag = time.Minute
+
+// BalanceSnapshotter - periodically snapshots the account balances in the background
+type BalanceSnapshotter struct {
+	store    db.Store
+	interval time.Duration
+}
+
+// NewBalanceSnapshotter - creates a new balance snapshotter from the snapshot configurations
+func NewBalanceSnapshotter(store db.Store, config util.Config) *BalanceSnapshotter {
+	return &BalanceSnapshotter{
+		store:    store,
+		interval: config.BalanceSnapshotInterval,
+	}
+}
+
+// Start - snapshotting the balances every interval until the context is done
+func (snapshotter *BalanceSnapshotter) Start(ctx context.Context) {
+	ticker := time.NewTicker(snapshotter.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := snapshotter.Snapshot(ctx); err != nil {
+			log.Printf("failed to snapshot the account balances: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+* Snapshot - creates a new balance snapshot for every account with new entries
+* each snapshot continues the previous snapshot of the account
+* returns the number of created snapshots
+ */
+func (snapshotter *BalanceSnapshotter) Snapshot(ctx context.Context) (int64, error) {
+	return snapshotter.store.CreateBalanceSnapshots(ctx, time.Now().Add(-snapshotLag))
+}
@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceSnapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	snapshotter := NewBalanceSnapshotter(store, util.Config{BalanceSnapshotInterval: time.Hour})
+
+	// the entries of the last lag are left for the next snapshot
+	store.EXPECT().
+		CreateBalanceSnapshots(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, before time.Time) (int64, error) {
+			require.WithinDuration(t, time.Now().Add(-snapshotLag), before, time.Second)
+			return 3, nil
+		})
+
+	created, err := snapshotter.Snapshot(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(3), created)
+
+	store.EXPECT().
+		CreateBalanceSnapshots(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(int64(0), errors.New("connection refused"))
+
+	_, err = snapshotter.Snapshot(context.Background())
+	require.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+)
+
+// Reconciler - periodically verifies the ledger integrity in the background
+type Reconciler struct {
+	store      db.Store
+	interval   time.Duration
+	reportFile string
+}
+
+// NewReconciler - creates a new reconciler from the reconciliation configurations
+func NewReconciler(store db.Store, config util.Config) *Reconciler {
+	return &Reconciler{
+		store:      store,
+		interval:   config.ReconcileInterval,
+		reportFile: config.ReconcileReportFile,
+	}
+}
+
+// Start - reconciling the ledger every interval until the context is done
+func (reconciler *Reconciler) Start(ctx context.Context) {
+	ticker := time.NewTicker(reconciler.interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := reconciler.Run(ctx)
+		if err != nil {
+			log.Printf("failed to reconcile the ledger: %v", err)
+		} else if report.Discrepancies() > 0 {
+			log.Printf("the ledger reconciliation found %d discrepancies", report.Discrepancies())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+* Run - verifies the ledger integrity and returns the discrepancy report
+* the report is also written to the configured report file (if any) - replacing the previous report
+ */
+func (reconciler *Reconciler) Run(ctx context.Context) (db.ReconcileReport, error) {
+	report, err := reconciler.store.Reconcile(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	if reconciler.reportFile == "" {
+		return report, nil
+	}
+
+	file, err := os.Create(reconciler.reportFile)
+	if err != nil {
+		return report, err
+	}
+	defer file.Close()
+
+	if err = WriteReconcileReport(file, report); err != nil {
+		return report, err
+	}
+	return report, file.Close()
+}
+
+// WriteReconcileReport - writes the report as indented json
+func WriteReconcileReport(w io.Writer, report db.ReconcileReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomReconcileReport() db.ReconcileReport {
+	return db.ReconcileReport{
+		CheckedAt: time.Now().Truncate(time.Second),
+		AccountBalanceMismatches: []db.ListAccountBalanceMismatchesRow{{
+			AccountID:      util.RandomInt(1, 1000),
+			Currency:       util.RandomCurrency(),
+			Balance:        util.RandomMoney(),
+			EntriesBalance: util.RandomMoney() + 1000,
+		}},
+		TransferEntryMismatches: []db.ListTransferEntryMismatchesRow{{
+			TransferID:      util.RandomInt(1, 1000),
+			EntryCount:      1,
+			MatchingDebits:  1,
+			MatchingCredits: 0,
+		}},
+		CurrencyImbalances: []db.ListCurrencyImbalancesRow{},
+	}
+}
+
+func TestReconcilerRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	report := randomReconcileReport()
+	reportFile := filepath.Join(t.TempDir(), "reconcile.json")
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		Reconcile(gomock.Any()).
+		Times(1).
+		Return(report, nil)
+
+	reconciler := NewReconciler(store, util.Config{
+		ReconcileInterval:   time.Hour,
+		ReconcileReportFile: reportFile,
+	})
+
+	result, err := reconciler.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Discrepancies())
+
+	// the report file holds the machine readable report
+	data, err := os.ReadFile(reportFile)
+	require.NoError(t, err)
+
+	var written db.ReconcileReport
+	err = json.Unmarshal(data, &written)
+	require.NoError(t, err)
+	require.Equal(t, report.AccountBalanceMismatches, written.AccountBalanceMismatches)
+	require.Equal(t, report.TransferEntryMismatches, written.TransferEntryMismatches)
+	require.True(t, report.CheckedAt.Equal(written.CheckedAt))
+}
+
+func TestReconcilerRunError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	reportFile := filepath.Join(t.TempDir(), "reconcile.json")
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		Reconcile(gomock.Any()).
+		Times(1).
+		Return(db.ReconcileReport{}, errors.New("connection refused"))
+
+	reconciler := NewReconciler(store, util.Config{ReconcileReportFile: reportFile})
+
+	_, err := reconciler.Run(context.Background())
+	require.Error(t, err)
+
+	// a failed run doesn't replace the report
+	_, err = os.Stat(reportFile)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestWriteReconcileReport(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteReconcileReport(&buf, db.ReconcileReport{})
+	require.NoError(t, err)
+
+	var fields map[string]interface{}
+	err = json.Unmarshal(buf.Bytes(), &fields)
+	require.NoError(t, err)
+	require.Contains(t, fields, "checked_at")
+	require.Contains(t, fields, "account_balance_mismatches")
+	require.Contains(t, fields, "transfer_entry_mismatches")
+	require.Contains(t, fields, "currency_imbalances")
+}
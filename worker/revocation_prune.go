@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+)
+
+// RevocationPruner - periodically deletes the revocations of tokens that would have expired anyway (and the stale login throttles)
+type RevocationPruner struct {
+	store    db.Store
+	interval time.Duration
+	// tokenLifetime - the longest lifetime of an issued token (access or refresh)
+	tokenLifetime time.Duration
+	// loginWindow - the failed login attempts older than the window are forgotten anyway
+	loginWindow time.Duration
+}
+
+// NewRevocationPruner - creates a new revocation pruner from the token configurations
+func NewRevocationPruner(store db.Store, config util.Config) *RevocationPruner {
+	tokenLifetime := config.AccessTokenDuration
+	if config.RefreshTokenDuration > tokenLifetime {
+		tokenLifetime = config.RefreshTokenDuration
+	}
+	return &RevocationPruner{
+		store:         store,
+		interval:      config.RevocationPruneInterval,
+		tokenLifetime: tokenLifetime,
+		loginWindow:   config.LoginAttemptWindow,
+	}
+}
+
+// Start - pruning the revocations every interval until the context is done
+func (pruner *RevocationPruner) Start(ctx context.Context) {
+	ticker := time.NewTicker(pruner.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := pruner.Prune(ctx); err != nil {
+			log.Printf("failed to prune the token revocations: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+* Prune - deletes the revoked tokens that are already expired
+* and the user revocations that are older than the longest token lifetime - every token issued before them is expired
+* and the login throttles which are not locked and whose failed attempts are already forgotten
+* returns the number of the deleted rows
+ */
+func (pruner *RevocationPruner) Prune(ctx context.Context) (int64, error) {
+	now := time.Now()
+
+	tokens, err := pruner.store.DeleteExpiredRevokedTokens(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	users, err := pruner.store.DeleteUserTokenRevocations(ctx, now.Add(-pruner.tokenLifetime))
+	if err != nil {
+		return tokens, err
+	}
+
+	throttles, err := pruner.store.DeleteStaleLoginThrottles(ctx, now.Add(-pruner.loginWindow))
+	if err != nil {
+		return tokens + users, err
+	}
+	return tokens + users + throttles, nil
+}
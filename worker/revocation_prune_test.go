@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationPrunerPrune(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		DeleteExpiredRevokedTokens(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, expiresAt time.Time) (int64, error) {
+			require.WithinDuration(t, time.Now(), expiresAt, time.Second)
+			return 3, nil
+		})
+	store.EXPECT().
+		DeleteUserTokenRevocations(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, revokedBefore time.Time) (int64, error) {
+			// the refresh token lives longer - so it bounds the user revocations
+			require.WithinDuration(t, time.Now().Add(-24*time.Hour), revokedBefore, time.Second)
+			return 2, nil
+		})
+	store.EXPECT().
+		DeleteStaleLoginThrottles(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, lastFailedAt time.Time) (int64, error) {
+			require.WithinDuration(t, time.Now().Add(-15*time.Minute), lastFailedAt, time.Second)
+			return 1, nil
+		})
+
+	pruner := NewRevocationPruner(store, util.Config{
+		AccessTokenDuration:     15 * time.Minute,
+		RefreshTokenDuration:    24 * time.Hour,
+		RevocationPruneInterval: time.Hour,
+		LoginAttemptWindow:      15 * time.Minute,
+	})
+
+	deleted, err := pruner.Prune(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(6), deleted)
+}
+
+func TestRevocationPrunerPruneError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		DeleteExpiredRevokedTokens(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(int64(0), errors.New("connection refused"))
+	store.EXPECT().
+		DeleteUserTokenRevocations(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	pruner := NewRevocationPruner(store, util.Config{
+		AccessTokenDuration:     15 * time.Minute,
+		RevocationPruneInterval: time.Hour,
+	})
+
+	_, err := pruner.Prune(context.Background())
+	require.Error(t, err)
+}
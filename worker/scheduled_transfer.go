@@ -0,0 +1,176 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+)
+
+// executionLease - how long a claimed scheduled transfer is hidden from other executors while it runs
+const executionLease = 5 * time.Minute
+
+// ScheduledTransferExecutor - executes the due scheduled transfers in the background
+type ScheduledTransferExecutor struct {
+	store        db.Store
+	interval     time.Duration
+	batchSize    int32
+	maxAttempts  int32
+	retryBackoff time.Duration
+}
+
+// NewScheduledTransferExecutor - creates a new scheduled transfers executor from the scheduler configurations
+func NewScheduledTransferExecutor(store db.Store, config util.Config) *ScheduledTransferExecutor {
+	return &ScheduledTransferExecutor{
+		store:        store,
+		interval:     config.SchedulerInterval,
+		batchSize:    config.SchedulerBatchSize,
+		maxAttempts:  config.SchedulerMaxAttempts,
+		retryBackoff: config.SchedulerRetryBackoff,
+	}
+}
+
+// Start - executing the due transfers every interval until the context is done
+func (executor *ScheduledTransferExecutor) Start(ctx context.Context) {
+	ticker := time.NewTicker(executor.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := executor.RunDue(ctx); err != nil {
+			log.Printf("failed to execute the scheduled transfers: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+* RunDue - claims a batch of due scheduled transfers and executes them
+* the claim skips rows locked by other executors (FOR UPDATE SKIP LOCKED) and leases the claimed rows
+* so several executors can run concurrently
+* returns the number of executed transfers
+ */
+func (executor *ScheduledTransferExecutor) RunDue(ctx context.Context) (int, error) {
+	scheduled, err := executor.store.ClaimDueScheduledTransfers(ctx, db.ClaimDueScheduledTransfersParams{
+		LeaseUntil: time.Now().Add(executionLease),
+		BatchSize:  executor.batchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, s := range scheduled {
+		if err = executor.execute(ctx, s); err != nil {
+			return i, err
+		}
+	}
+	return len(scheduled), nil
+}
+
+/*
+* execute - transfers the money of a single occurrence and records the attempt
+* the idempotency key identifies the occurrence - an attempt that transferred the money
+* but failed to be recorded (e.g. a crash) is replayed instead of transferring twice
+ */
+func (executor *ScheduledTransferExecutor) execute(ctx context.Context, scheduled db.ScheduledTransfer) error {
+	key := fmt.Sprintf("scheduled-transfer-%d-%d", scheduled.ID, scheduled.NextRunAt.Unix())
+
+	result, err := executor.store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: scheduled.FromAccountID,
+		ToAccountID:   scheduled.ToAccountID,
+		Amount:        scheduled.Amount,
+		Idempotency: db.IdempotencyParams{
+			Username:    scheduled.Owner,
+			Key:         key,
+			RequestHash: key,
+		},
+		// the transfer is made on behalf of the owner - the occurrence key identifies it in the audit trail
+		Audit: db.AuditParams{
+			Actor:     scheduled.Owner,
+			RequestID: key,
+		},
+	})
+
+	arg := db.RecordScheduledTransferRunTxParams{
+		Run: db.CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			ScheduledFor:        scheduled.NextRunAt,
+			Attempt:             scheduled.Attempts + 1,
+		},
+	}
+	if err != nil {
+		arg.Run.Error = err.Error()
+		arg.Reschedule = executor.retry(scheduled, time.Now())
+	} else {
+		arg.Run.TransferID = sql.NullInt64{Int64: result.Transfer.ID, Valid: true}
+		arg.Reschedule = next(scheduled, time.Now())
+	}
+
+	_, err = executor.store.RecordScheduledTransferRunTx(ctx, arg)
+	return err
+}
+
+/*
+* retry - the state of a scheduled transfer after a failed attempt
+* the attempt is retried with an exponential backoff up to the max attempts
+* after the last attempt a one-off transfer fails and a recurring transfer moves to its next occurrence
+ */
+func (executor *ScheduledTransferExecutor) retry(scheduled db.ScheduledTransfer, now time.Time) db.RescheduleScheduledTransferParams {
+	attempts := scheduled.Attempts + 1
+	if attempts >= executor.maxAttempts {
+		if scheduled.Recurrence == "" {
+			return db.RescheduleScheduledTransferParams{
+				ID:        scheduled.ID,
+				Status:    db.ScheduledTransferFailed,
+				NextRunAt: scheduled.NextRunAt,
+				DueAt:     scheduled.NextRunAt,
+				Attempts:  attempts,
+			}
+		}
+		return next(scheduled, now)
+	}
+
+	return db.RescheduleScheduledTransferParams{
+		ID:        scheduled.ID,
+		Status:    db.ScheduledTransferActive,
+		NextRunAt: scheduled.NextRunAt,
+		DueAt:     now.Add(executor.retryBackoff << (attempts - 1)),
+		Attempts:  attempts,
+	}
+}
+
+/*
+* next - the state of a scheduled transfer after its occurrence is done
+* a one-off transfer is completed, a recurring transfer moves to its next future occurrence
+* occurrences missed while the executor was down are skipped
+ */
+func next(scheduled db.ScheduledTransfer, now time.Time) db.RescheduleScheduledTransferParams {
+	recurrence, err := util.ParseRecurrence(scheduled.Recurrence)
+	if err != nil {
+		return db.RescheduleScheduledTransferParams{
+			ID:        scheduled.ID,
+			Status:    db.ScheduledTransferCompleted,
+			NextRunAt: scheduled.NextRunAt,
+			DueAt:     scheduled.NextRunAt,
+		}
+	}
+
+	nextRun := recurrence.Next(scheduled.NextRunAt)
+	for !nextRun.After(now) {
+		nextRun = recurrence.Next(nextRun)
+	}
+	return db.RescheduleScheduledTransferParams{
+		ID:        scheduled.ID,
+		Status:    db.ScheduledTransferActive,
+		NextRunAt: nextRun,
+		DueAt:     nextRun,
+	}
+}
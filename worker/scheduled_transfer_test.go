@@ -0,0 +1,175 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/shimon-git/simple-bank/db/mock"
+	db "github.com/shimon-git/simple-bank/db/sqlc"
+	"github.com/shimon-git/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestExecutor(store db.Store) *ScheduledTransferExecutor {
+	return NewScheduledTransferExecutor(store, util.Config{
+		SchedulerInterval:     time.Minute,
+		SchedulerBatchSize:    10,
+		SchedulerMaxAttempts:  3,
+		SchedulerRetryBackoff: time.Minute,
+	})
+}
+
+func randomScheduledTransfer(recurrence string) db.ScheduledTransfer {
+	runAt := time.Now().Add(-time.Second).Truncate(time.Second)
+	return db.ScheduledTransfer{
+		ID:            util.RandomInt(1, 1000),
+		Owner:         util.RandomOwner(),
+		FromAccountID: util.RandomInt(1, 1000),
+		ToAccountID:   util.RandomInt(1, 1000),
+		Amount:        util.RandomInt(1, 1000),
+		Recurrence:    recurrence,
+		Status:        db.ScheduledTransferActive,
+		NextRunAt:     runAt,
+		DueAt:         runAt,
+	}
+}
+
+func TestRunDue(t *testing.T) {
+	oneOff := randomScheduledTransfer("")
+	monthly := randomScheduledTransfer("monthly:1")
+	transferID := util.RandomInt(1, 1000)
+
+	testCases := []struct {
+		name       string
+		scheduled  db.ScheduledTransfer
+		transferTx error
+		checkArg   func(t *testing.T, arg db.RecordScheduledTransferRunTxParams)
+	}{{
+		name:      "OneOffSucceeded",
+		scheduled: oneOff,
+		checkArg: func(t *testing.T, arg db.RecordScheduledTransferRunTxParams) {
+			require.Equal(t, transferID, arg.Run.TransferID.Int64)
+			require.Empty(t, arg.Run.Error)
+			require.Equal(t, db.ScheduledTransferCompleted, arg.Reschedule.Status)
+		},
+	}, {
+		name:      "RecurringSucceeded",
+		scheduled: monthly,
+		checkArg: func(t *testing.T, arg db.RecordScheduledTransferRunTxParams) {
+			require.True(t, arg.Run.TransferID.Valid)
+			require.Equal(t, db.ScheduledTransferActive, arg.Reschedule.Status)
+			require.Equal(t, 1, arg.Reschedule.NextRunAt.Day())
+			require.True(t, arg.Reschedule.NextRunAt.After(time.Now()))
+			require.Equal(t, arg.Reschedule.NextRunAt, arg.Reschedule.DueAt)
+			require.Zero(t, arg.Reschedule.Attempts)
+		},
+	}, {
+		name:       "FailedWithRetry",
+		scheduled:  oneOff,
+		transferTx: db.ErrInsufficientFunds,
+		checkArg: func(t *testing.T, arg db.RecordScheduledTransferRunTxParams) {
+			require.False(t, arg.Run.TransferID.Valid)
+			require.Equal(t, db.ErrInsufficientFunds.Error(), arg.Run.Error)
+			require.Equal(t, int32(1), arg.Run.Attempt)
+			require.Equal(t, db.ScheduledTransferActive, arg.Reschedule.Status)
+			require.Equal(t, int32(1), arg.Reschedule.Attempts)
+			require.Equal(t, oneOff.NextRunAt, arg.Reschedule.NextRunAt)
+			require.WithinDuration(t, time.Now().Add(time.Minute), arg.Reschedule.DueAt, time.Second)
+		},
+	}, {
+		name: "OneOffLastAttemptFailed",
+		scheduled: func() db.ScheduledTransfer {
+			s := oneOff
+			s.Attempts = 2
+			return s
+		}(),
+		transferTx: db.ErrInsufficientFunds,
+		checkArg: func(t *testing.T, arg db.RecordScheduledTransferRunTxParams) {
+			require.Equal(t, int32(3), arg.Run.Attempt)
+			require.Equal(t, db.ScheduledTransferFailed, arg.Reschedule.Status)
+		},
+	}, {
+		name: "RecurringLastAttemptFailed",
+		scheduled: func() db.ScheduledTransfer {
+			s := monthly
+			s.Attempts = 2
+			return s
+		}(),
+		transferTx: db.ErrInsufficientFunds,
+		checkArg: func(t *testing.T, arg db.RecordScheduledTransferRunTxParams) {
+			// the failed occurrence is skipped
+			require.Equal(t, db.ScheduledTransferActive, arg.Reschedule.Status)
+			require.True(t, arg.Reschedule.NextRunAt.After(monthly.NextRunAt))
+			require.Zero(t, arg.Reschedule.Attempts)
+		},
+	}}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+
+			store.EXPECT().
+				ClaimDueScheduledTransfers(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return([]db.ScheduledTransfer{tc.scheduled}, nil)
+
+			store.EXPECT().
+				TransferTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+					// every occurrence is executed under its own idempotency key
+					require.Equal(t, tc.scheduled.Owner, arg.Idempotency.Username)
+					require.NotEmpty(t, arg.Idempotency.Key)
+					require.Equal(t, tc.scheduled.Amount, arg.Amount)
+					// the transfer is recorded in the audit trail on behalf of the owner
+					require.Equal(t, tc.scheduled.Owner, arg.Audit.Actor)
+					require.Equal(t, arg.Idempotency.Key, arg.Audit.RequestID)
+
+					var result db.TransferTxResult
+					result.Transfer.ID = transferID
+					return result, tc.transferTx
+				})
+
+			store.EXPECT().
+				RecordScheduledTransferRunTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.RecordScheduledTransferRunTxParams) (db.RecordScheduledTransferRunTxResult, error) {
+					require.Equal(t, tc.scheduled.ID, arg.Run.ScheduledTransferID)
+					require.Equal(t, tc.scheduled.ID, arg.Reschedule.ID)
+					require.Equal(t, tc.scheduled.NextRunAt, arg.Run.ScheduledFor)
+					tc.checkArg(t, arg)
+					return db.RecordScheduledTransferRunTxResult{}, nil
+				})
+
+			executed, err := newTestExecutor(store).RunDue(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, executed)
+		})
+	}
+}
+
+func TestRunDueClaimError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().
+		ClaimDueScheduledTransfers(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, errors.New("connection refused"))
+
+	store.EXPECT().
+		TransferTx(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	executed, err := newTestExecutor(store).RunDue(context.Background())
+	require.Error(t, err)
+	require.Zero(t, executed)
+}